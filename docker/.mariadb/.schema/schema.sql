@@ -7,6 +7,9 @@ CREATE TABLE IF NOT EXISTS videos (
     updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     source_path     VARCHAR(64) NOT NULL,
     cover_path      VARCHAR(64),
+    loudness        DOUBLE,
+    owner_id        VARCHAR(36),
+    visibility      VARCHAR(16) NOT NULL DEFAULT 'public',
 
     CONSTRAINT pk PRIMARY KEY (id),
     CONSTRAINT unique_title UNIQUE (title)
@@ -23,3 +26,128 @@ CREATE TABLE IF NOT EXISTS uploads (
     CONSTRAINT pk PRIMARY KEY (id),
     CONSTRAINT fk_v_id FOREIGN KEY (video_id) REFERENCES videos (id)
 );
+
+CREATE TABLE IF NOT EXISTS storage_usages (
+    video_id        VARCHAR(36) NOT NULL,
+    username        VARCHAR(64) NOT NULL,
+    size_bytes      BIGINT NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (video_id),
+    CONSTRAINT fk_su_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS subtitles (
+    id              VARCHAR(36) NOT NULL,
+    video_id        VARCHAR(36) NOT NULL,
+    language        VARCHAR(35) NOT NULL,
+    label           VARCHAR(64) NOT NULL,
+    is_default      BOOLEAN NOT NULL DEFAULT FALSE,
+    forced          BOOLEAN NOT NULL DEFAULT FALSE,
+    path            VARCHAR(256) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT fk_s_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS clips (
+    video_id        VARCHAR(36) NOT NULL,
+    parent_id       VARCHAR(36),
+    start_seconds   DOUBLE NOT NULL,
+    end_seconds     DOUBLE NOT NULL,
+    accurate        BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (video_id),
+    CONSTRAINT fk_c_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    CONSTRAINT fk_c_p_id FOREIGN KEY (parent_id) REFERENCES videos (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS renders (
+    video_id        VARCHAR(36) NOT NULL,
+    parent_id       VARCHAR(36),
+    filters         TEXT NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (video_id),
+    CONSTRAINT fk_r_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    CONSTRAINT fk_r_p_id FOREIGN KEY (parent_id) REFERENCES videos (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id              VARCHAR(36) NOT NULL,
+    username        VARCHAR(64) NOT NULL,
+    password_hash   VARCHAR(255) NOT NULL,
+    role            VARCHAR(16) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    oidc_subject    VARCHAR(255),
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT unique_username UNIQUE (username),
+    CONSTRAINT unique_oidc_subject UNIQUE (oidc_subject)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token_hash      CHAR(64) NOT NULL,
+    user_id         VARCHAR(36) NOT NULL,
+    expires_at      DATETIME NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (token_hash),
+    CONSTRAINT fk_s_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id              VARCHAR(36) NOT NULL,
+    user_id         VARCHAR(36),
+    name            VARCHAR(64) NOT NULL,
+    prefix          VARCHAR(16) NOT NULL,
+    key_hash        CHAR(64) NOT NULL,
+    scope           VARCHAR(16) NOT NULL,
+    expires_at      DATETIME,
+    last_used_at    DATETIME,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT unique_key_hash UNIQUE (key_hash),
+    CONSTRAINT fk_ak_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS video_shares (
+    video_id        VARCHAR(36) NOT NULL,
+    user_id         VARCHAR(36) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (video_id, user_id),
+    CONSTRAINT fk_vs_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+    CONSTRAINT fk_vs_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id              VARCHAR(36) NOT NULL,
+    url             VARCHAR(2048) NOT NULL,
+    secret          VARCHAR(128) NOT NULL,
+    events          VARCHAR(255) NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              VARCHAR(36) NOT NULL,
+    webhook_id      VARCHAR(36) NOT NULL,
+    delivery_id     VARCHAR(36) NOT NULL,
+    event           VARCHAR(32) NOT NULL,
+    video_id        VARCHAR(36) NOT NULL,
+    attempt         INT NOT NULL,
+    status_code     INT,
+    error           VARCHAR(512),
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk PRIMARY KEY (id),
+    CONSTRAINT fk_wd_w_id FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
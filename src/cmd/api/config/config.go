@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"time"
+
 	"github.com/caarlos0/env/v6"
 )
 
@@ -9,8 +12,28 @@ type Config struct {
 	LocalAddr string `env:"LOCAL_ADDR" envDefault:""`
 	DevMode   bool   `env:"DEV_MODE" envDefault:"false"`
 
-	UserAuth string `env:"USER_AUTH,required"`
-	PwdAuth  string `env:"PWD_AUTH,required"`
+	// Service account with the admin role, also seeded as the first admin user
+	UserAuth   string        `env:"USER_AUTH" envDefault:""`
+	PwdAuth    string        `env:"PWD_AUTH" envDefault:""`
+	SessionTTL time.Duration `env:"SESSION_TTL" envDefault:"24h"`
+
+	// OpenID Connect single sign-on, disabled when no issuer is given. The role of the users is given by
+	// the groups of their token, the users outside of the viewer groups are refused when some are given.
+	OIDCIssuer         string   `env:"OIDC_ISSUER" envDefault:""`
+	OIDCAudience       string   `env:"OIDC_AUDIENCE" envDefault:""`
+	OIDCUsernameClaim  string   `env:"OIDC_USERNAME_CLAIM" envDefault:"preferred_username"`
+	OIDCGroupsClaim    string   `env:"OIDC_GROUPS_CLAIM" envDefault:"groups"`
+	OIDCAdminGroups    []string `env:"OIDC_ADMIN_GROUPS" envSeparator:","`
+	OIDCUploaderGroups []string `env:"OIDC_UPLOADER_GROUPS" envSeparator:","`
+	OIDCViewerGroups   []string `env:"OIDC_VIEWER_GROUPS" envSeparator:","`
+
+	// Origins of the browsers allowed to open a websocket, those of the API host name when empty
+	WSAllowedOrigins []string `env:"WS_ALLOWED_ORIGINS" envSeparator:","`
+
+	// Outgoing webhooks, a failed notification is retried after a delay doubled at each attempt
+	WebhookTimeout     time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookMaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"5"`
+	WebhookRetryDelay  time.Duration `env:"WEBHOOK_RETRY_DELAY" envDefault:"10s"`
 
 	S3Host    string `env:"S3_HOST" envDefault:""`
 	S3AuthKey string `env:"S3_AUTH_KEY,required"`
@@ -29,12 +52,33 @@ type Config struct {
 	MariadbPort    string `env:"MARIADB_PORT,required"`
 
 	ConsulHost string `env:"CONSUL_URL,required"`
+
+	// Upload limits, 0 means unlimited
+	MaxUploadBytes   int64         `env:"MAX_UPLOAD_BYTES" envDefault:"0"`
+	MaxVideoDuration time.Duration `env:"MAX_VIDEO_DURATION" envDefault:"0"`
+	MaxVideoWidth    uint64        `env:"MAX_VIDEO_WIDTH" envDefault:"0"`
+	MaxVideoHeight   uint64        `env:"MAX_VIDEO_HEIGHT" envDefault:"0"`
+	UserStorageQuota int64         `env:"USER_STORAGE_QUOTA" envDefault:"0"`
+
+	// Tokens of the public playback routes. A random secret is generated when none is given,
+	// it must be shared by all the instances of the API otherwise.
+	StreamTokenSecret      string        `env:"STREAM_TOKEN_SECRET" envDefault:""`
+	StreamTokenTTL         time.Duration `env:"STREAM_TOKEN_TTL" envDefault:"6h"`
+	StreamTokenKeyRotation time.Duration `env:"STREAM_TOKEN_KEY_ROTATION" envDefault:"24h"`
+	StreamTokenBindIP      bool          `env:"STREAM_TOKEN_BIND_IP" envDefault:"false"`
 }
 
 func NewConfig() (Config, error) {
 	config := Config{}
 
-	err := env.Parse(&config)
+	if err := env.Parse(&config); err != nil {
+		return config, err
+	}
+
+	// The resolution limit compares the long and short sides of the videos, it needs both
+	if (config.MaxVideoWidth > 0) != (config.MaxVideoHeight > 0) {
+		return config, errors.New("MAX_VIDEO_WIDTH and MAX_VIDEO_HEIGHT must be set together")
+	}
 
-	return config, err
+	return config, nil
 }
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const maxApiKeyNameLength = 64
+
+type ApiKeyCreateRequest struct {
+	Name      string     `json:"name" example:"ci-uploader"`
+	Scope     string     `json:"scope" example:"upload"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2023-04-15T12:59:52Z"`
+}
+
+// ApiKeyCreateResponse holds the key itself, it is only sent once
+type ApiKeyCreateResponse struct {
+	Key    string             `json:"key" example:"vgl_Xk3v9aQe..."`
+	ApiKey jsonDTO.ApiKeyJson `json:"apiKey"`
+}
+
+type ApiKeysListResponse struct {
+	ApiKeys []jsonDTO.ApiKeyJson `json:"apiKeys"`
+}
+
+type ApiKeysListHandler struct {
+	ApiKeysDAO *dao.ApiKeysDAO
+}
+
+// ApiKeysListHandler godoc
+// @Summary List API keys
+// @Description List the API keys of the user, or all of them for an admin, the most recent first
+// @Tags apikeys
+// @Produce json
+// @Success 200 {object} ApiKeysListResponse
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/apikeys [get]
+func (a ApiKeysListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET ApiKeysListHandler")
+
+	user := UserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var apiKeys []models.ApiKey
+	var err error
+	if user.Role == models.ADMIN {
+		apiKeys, err = a.ApiKeysDAO.GetApiKeys(r.Context())
+	} else {
+		apiKeys, err = a.ApiKeysDAO.GetUserApiKeys(r.Context(), user.ID)
+	}
+	if err != nil {
+		log.Error("Cannot get api keys : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := ApiKeysListResponse{ApiKeys: make([]jsonDTO.ApiKeyJson, 0, len(apiKeys))}
+	for i := range apiKeys {
+		response.ApiKeys = append(response.ApiKeys, jsonDTO.ApiKeyToApiKeyJson(&apiKeys[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+type ApiKeyCreateHandler struct {
+	ApiKeysDAO *dao.ApiKeysDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// ApiKeyCreateHandler godoc
+// @Summary Create an API key
+// @Description Create a key acting as the user, with at most the rights of its scope among read, upload and admin. The scope cannot exceed the role of the user. The key is only returned by this request, send it as "Authorization: Bearer <key>".
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param apiKey body ApiKeyCreateRequest true "Name, scope and optional expiry"
+// @Success 200 {object} ApiKeyCreateResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string "The scope exceeds the role of the user, or the request is authenticated by an API key"
+// @Failure 500 {string} string
+// @Router /api/v1/apikeys [post]
+func (a ApiKeyCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST ApiKeyCreateHandler")
+
+	user := UserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// The keys are only managed with the credentials of the user, a leaked key could otherwise be
+	// replaced by a permanent one before being revoked
+	if callerKey := ApiKeyFromContext(r.Context()); callerKey != nil {
+		log.Errorf("Api key %v cannot manage api keys", callerKey.Name)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var request ApiKeyCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode api key request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if request.Name == "" || len(request.Name) > maxApiKeyNameLength {
+		log.Error("Invalid api key name ", request.Name)
+		http.Error(w, "name must be 1 to 64 characters long", http.StatusBadRequest)
+		return
+	}
+	scope, err := models.StringToApiKeyScope(request.Scope)
+	if err != nil {
+		log.Error("Invalid scope : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		log.Error("Api key expiry is in the past : ", request.ExpiresAt)
+		http.Error(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+	if !user.Role.Includes(scope.Role()) {
+		log.Errorf("User %v (%v) cannot create an api key with scope %v", user.Username, user.Role, scope)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, prefix, keyHash, err := newApiKey()
+	if err != nil {
+		log.Error("Cannot generate api key : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	apiKeyID, err := a.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The service account has no user ID, its keys have no owner
+	var userID *string
+	if user.ID != "" {
+		userID = &user.ID
+	}
+
+	apiKey, err := a.ApiKeysDAO.CreateApiKey(r.Context(), &models.ApiKey{
+		ID:        apiKeyID,
+		UserID:    userID,
+		Name:      request.Name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scope:     scope,
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		log.Error("Cannot create api key "+request.Name+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, ApiKeyCreateResponse{Key: key, ApiKey: jsonDTO.ApiKeyToApiKeyJson(apiKey)})
+	log.Infof("Api key %v created by %v with scope %v", apiKey.Name, user.Username, apiKey.Scope)
+}
+
+type ApiKeyDeleteHandler struct {
+	ApiKeysDAO *dao.ApiKeysDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// ApiKeyDeleteHandler godoc
+// @Summary Revoke an API key
+// @Description Delete an API key of the user, or any of them for an admin
+// @Tags apikeys
+// @Produce plain
+// @Param keyID path string true "API key ID"
+// @Success 200 {string} string "OK"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string "The request is authenticated by an API key"
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/apikeys/{keyID} [delete]
+func (a ApiKeyDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE ApiKeyDeleteHandler - Parameters: ", vars)
+
+	user := UserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// The keys are only managed with the credentials of the user, a leaked key could otherwise be
+	// replaced by a permanent one before being revoked
+	if callerKey := ApiKeyFromContext(r.Context()); callerKey != nil {
+		log.Errorf("Api key %v cannot manage api keys", callerKey.Name)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	id := vars["keyID"]
+	if !a.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid api key id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	apiKey, err := a.ApiKeysDAO.GetApiKey(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get api key "+id+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The keys of the other users are not disclosed
+	if user.Role != models.ADMIN && (apiKey.UserID == nil || *apiKey.UserID != user.ID) {
+		log.Errorf("User %v cannot revoke api key %v of another user", user.Username, id)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := a.ApiKeysDAO.DeleteApiKey(r.Context(), id); err != nil {
+		log.Error("Cannot delete api key "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Api key %v revoked by %v", apiKey.Name, user.Username)
+}
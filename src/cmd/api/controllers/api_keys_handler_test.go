@@ -0,0 +1,385 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestApiKeys(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	otherUserID := "9d6f2b7e-3c1a-4f0e-8a52-7b3e1d0c4f21"
+	keyID := "7e0b9c2a-4d1f-4a8e-9b3c-5f6a7d8e9f10"
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	token := "q1Xv-session-token"
+	key := controllers.ApiKeyPrefix + "Xk3v9aQe-test-key"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+	t1 := time.Now()
+	past := t1.Add(-time.Hour)
+
+	apiKeysColumns := []string{"id", "user_id", "name", "prefix", "key_hash", "scope", "expires_at", "last_used_at", "created_at"}
+	getApiKeyQuery := regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKey])
+	getApiKeyFromHashQuery := regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeyFromHash])
+	updateLastUsedQuery := regexp.QuoteMeta(dao.ApiKeysRequests[dao.UpdateApiKeyLastUsed])
+	apiKeyRow := func(owner interface{}, scope models.ApiKeyScope, lastUsedAt interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows(apiKeysColumns).
+			AddRow(keyID, owner, "ci", key[:12], controllers.HashSessionToken(key), string(scope), nil, lastUsedAt, t1)
+	}
+	userRow := func(role models.Role) *sqlmock.Rows {
+		return sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(role), t1, t1, nil)
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveBody         string
+		giveAuth         string
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedBody     []string
+	}{
+		{
+			name:        "POST api key",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/apikeys",
+			giveBody:    `{"name": "ci", "scope": "upload"}`,
+			giveAuth:    "session",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateApiKey])).
+					WithArgs(keyID, userID, "ci", sqlmock.AnyArg(), sqlmock.AnyArg(), models.UPLOAD_SCOPE, nil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(getApiKeyQuery).WithArgs(keyID).WillReturnRows(apiKeyRow(userID, models.UPLOAD_SCOPE, nil))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"key":"vgl_`, `"id":"` + keyID + `"`, `"scope":"upload"`},
+		},
+		{
+			name:        "POST api key as service account",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/apikeys",
+			giveBody:    `{"name": "ci", "scope": "admin", "expiresAt": "` + t1.Add(time.Hour).Format(time.RFC3339) + `"}`,
+			giveAuth:    "basic",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateApiKey])).
+					WithArgs(keyID, nil, "ci", sqlmock.AnyArg(), sqlmock.AnyArg(), models.ADMIN_SCOPE, AnyTime{}).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(getApiKeyQuery).WithArgs(keyID).WillReturnRows(apiKeyRow(nil, models.ADMIN_SCOPE, nil))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"key":"vgl_`, `"scope":"admin"`},
+		},
+		{
+			name:             "POST api key fails with scope above the user role",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/apikeys",
+			giveBody:         `{"name": "ci", "scope": "admin"}`,
+			giveAuth:         "session",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "POST api key fails with unknown scope",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/apikeys",
+			giveBody:         `{"name": "ci", "scope": "write"}`,
+			giveAuth:         "session",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST api key fails with past expiry",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/apikeys",
+			giveBody:         `{"name": "ci", "scope": "read", "expiresAt": "` + past.Format(time.RFC3339) + `"}`,
+			giveAuth:         "session",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST api key fails without name",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/apikeys",
+			giveBody:         `{"scope": "read"}`,
+			giveAuth:         "session",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET own api keys",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/apikeys",
+			giveAuth:    "session",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetUserApiKeys])).WithArgs(userID).
+					WillReturnRows(apiKeyRow(userID, models.READ_SCOPE, t1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"apiKeys":[{"id":"` + keyID + `","userId":"` + userID + `","name":"ci","prefix":"` + key[:12] + `","scope":"read"`},
+		},
+		{
+			name:        "GET all api keys as admin",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/apikeys",
+			giveAuth:    "basic",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeys])).
+					WillReturnRows(apiKeyRow(otherUserID, models.READ_SCOPE, nil))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"userId":"` + otherUserID + `"`},
+		},
+		{
+			name:        "DELETE own api key",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/apikeys/" + keyID,
+			giveAuth:    "session",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyQuery).WithArgs(keyID).WillReturnRows(apiKeyRow(userID, models.READ_SCOPE, nil))
+				mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.DeleteApiKey])).WithArgs(keyID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "DELETE api key fails for key of another user",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/apikeys/" + keyID,
+			giveAuth:    "session",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyQuery).WithArgs(keyID).WillReturnRows(apiKeyRow(otherUserID, models.READ_SCOPE, nil))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "DELETE api key fails with unknown key",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/apikeys/" + keyID,
+			giveAuth:    "session",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyQuery).WithArgs(keyID).WillReturnRows(sqlmock.NewRows(apiKeysColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "DELETE api key fails with invalid id",
+			giveMethod:       "DELETE",
+			giveRequest:      "/api/v1/apikeys/not-a-uuid",
+			giveAuth:         "session",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET me with api key has the role of its scope",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/users/me",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.READ_SCOPE, nil))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.ADMIN))
+				mock.ExpectExec(updateLastUsedQuery).WithArgs(AnyTime{}, keyID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"username":"alice"`, `"role":"viewer"`},
+		},
+		{
+			name:        "GET me with api key has at most the role of its user",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/users/me",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				// Used recently, the last use is not updated
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.ADMIN_SCOPE, t1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.UPLOADER))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"role":"uploader"`},
+		},
+		{
+			name:        "GET me with api key of the service account",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/users/me",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(nil, models.UPLOAD_SCOPE, past))
+				mock.ExpectExec(updateLastUsedQuery).WithArgs(AnyTime{}, keyID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"username":"apikey:ci"`, `"role":"uploader"`},
+		},
+		{
+			name:        "GET users fails with api key of read scope",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/users",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.READ_SCOPE, t1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.ADMIN))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "POST api key fails with api key",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/apikeys",
+			giveBody:    `{"name": "permanent", "scope": "admin"}`,
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.ADMIN_SCOPE, t1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.ADMIN))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "DELETE api key fails with api key",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/apikeys/" + keyID,
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.ADMIN_SCOPE, t1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.ADMIN))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "DELETE video fails with api key of upload scope",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/videos/" + videoID + "/delete",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.UPLOAD_SCOPE, t1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.UPLOADER))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "PUT archive fails with api key of upload scope",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + videoID + "/archive",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(nil, models.UPLOAD_SCOPE, t1))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			// The scope is granted, the request is then checked by the handler
+			name:        "POST upload with api key of upload scope",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/videos/upload",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.UPLOAD_SCOPE, t1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.UPLOADER))
+			},
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET me fails with unknown or expired api key",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/users/me",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(sqlmock.NewRows(apiKeysColumns))
+			},
+			expectedHTTPCode: 401,
+		},
+		{
+			name:        "GET me with api key even if its last use cannot be saved",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/users/me",
+			giveAuth:    "apikey",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getApiKeyFromHashQuery).WithArgs(controllers.HashSessionToken(key), AnyTime{}).
+					WillReturnRows(apiKeyRow(userID, models.READ_SCOPE, nil))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.VIEWER))
+				mock.ExpectExec(updateLastUsedQuery).WithArgs(AnyTime{}, keyID).WillReturnError(sqlmock.ErrCancelled)
+			},
+			expectedHTTPCode: 200,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			dao_test.ExpectApiKeysDAOCreation(mock)
+
+			if tt.giveAuth == "session" {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser])).
+					WithArgs(controllers.HashSessionToken(token), AnyTime{}).
+					WillReturnRows(userRow(models.UPLOADER))
+			}
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+			apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return keyID, nil }, UUIDValidFunc),
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO, ApiKeysDAO: *apiKeysDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			switch tt.giveAuth {
+			case "session":
+				req.Header.Set("Authorization", "Bearer "+token)
+			case "apikey":
+				req.Header.Set("Authorization", "Bearer "+key)
+			default:
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				require.Contains(t, w.Body.String(), expected)
+			}
+			require.NotContains(t, w.Body.String(), controllers.HashSessionToken(key))
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
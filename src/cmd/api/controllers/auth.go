@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/oidc"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	MinPasswordLength = 8
+	// bcrypt ignores the bytes after the 72th
+	MaxPasswordLength = 72
+
+	sessionTokenBytes = 32
+
+	// Prefix of the API keys, tells them apart from the session tokens
+	ApiKeyPrefix = "vgl_"
+	// Length of the start of the key kept in database to recognize it
+	apiKeyDisplayLength = 12
+	// The last use of a key is not written more often
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrForbidden       = errors.New("user not allowed")
+
+	// Compared against when the username is unknown, so that the response time does not tell it exists
+	dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("voogle-dummy-password"), bcrypt.DefaultCost)
+)
+
+type userContextKey struct{}
+
+// ContextWithUser returns a copy of ctx carrying the authenticated user
+func ContextWithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user of the request, nil if there is none
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey{}).(*models.User)
+	return user
+}
+
+type apiKeyContextKey struct{}
+
+// ContextWithApiKey returns a copy of ctx carrying the API key the request is authenticated with
+func ContextWithApiKey(ctx context.Context, apiKey *models.ApiKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKey)
+}
+
+// ApiKeyFromContext returns the API key the request is authenticated with, nil for the other credentials
+func ApiKeyFromContext(ctx context.Context) *models.ApiKey {
+	apiKey, _ := ctx.Value(apiKeyContextKey{}).(*models.ApiKey)
+	return apiKey
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func checkPassword(passwordHash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return errors.New("password must be at least 8 characters long")
+	}
+	if len(password) > MaxPasswordLength {
+		return errors.New("password must be at most 72 bytes long")
+	}
+	return nil
+}
+
+// newSessionToken returns a random session token and the hash stored in database
+func newSessionToken() (token, tokenHash string, err error) {
+	raw := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashSessionToken(token), nil
+}
+
+func HashSessionToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// newApiKey returns a random API key, the start of it shown to recognize it and the hash stored in database
+func newApiKey() (key, prefix, keyHash string, err error) {
+	token, _, err := newSessionToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = ApiKeyPrefix + token
+	return key, key[:apiKeyDisplayLength], HashSessionToken(key), nil
+}
+
+// bearerToken returns the token of a "Bearer <token>" authorization, an empty string otherwise
+func bearerToken(authorization string) string {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Authenticator finds the user of the credentials sent with a request
+type Authenticator struct {
+	Config      config.Config
+	SessionsDAO *dao.SessionsDAO
+	UsersDAO    *dao.UsersDAO
+	ApiKeysDAO  *dao.ApiKeysDAO
+	UUIDGen     clients.IUUIDGenerator
+	// Nil when the single sign-on is disabled
+	OIDCVerifier *oidc.Verifier
+}
+
+// Authenticate returns the user of an Authorization value: either a "Bearer" session token, API key or JWT
+// of the OpenID Connect provider, or the "Basic" credentials of the USER_AUTH/PWD_AUTH service account which has
+// the admin role. The API key is returned too when it is the credential, nil otherwise.
+// It returns ErrUnauthenticated when the credentials are missing, unknown or expired,
+// and ErrForbidden when the provider user cannot be given an account.
+func (a Authenticator) Authenticate(ctx context.Context, authorization string) (*models.User, *models.ApiKey, error) {
+	if token := bearerToken(authorization); token != "" {
+		if strings.HasPrefix(token, ApiKeyPrefix) {
+			return a.authenticateApiKey(ctx, token)
+		}
+		if a.OIDCVerifier != nil && oidc.IsJWT(token) {
+			user, err := a.authenticateOIDC(ctx, token)
+			return user, nil, err
+		}
+
+		user, err := a.SessionsDAO.GetSessionUser(ctx, HashSessionToken(token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, ErrUnauthenticated
+			}
+			return nil, nil, err
+		}
+		return user, nil, nil
+	}
+
+	request := http.Request{Header: http.Header{"Authorization": []string{authorization}}}
+	username, password, ok := request.BasicAuth()
+	if !ok || !isServiceAccount(a.Config, username, password) {
+		return nil, nil, ErrUnauthenticated
+	}
+	return &models.User{Username: a.Config.UserAuth, Role: models.ADMIN}, nil, nil
+}
+
+// AuthenticationStatusCode returns the HTTP status of an error of Authenticate
+func AuthenticationStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// authenticateApiKey returns the user who created the key, with the role of the key scope when it is lower
+// than the user one. The keys created by the service account act on their own, with the role of their scope.
+func (a Authenticator) authenticateApiKey(ctx context.Context, key string) (*models.User, *models.ApiKey, error) {
+	apiKey, err := a.ApiKeysDAO.GetApiKeyFromHash(ctx, HashSessionToken(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrUnauthenticated
+		}
+		return nil, nil, err
+	}
+
+	user := &models.User{Username: "apikey:" + apiKey.Name, Role: apiKey.Scope.Role()}
+	if apiKey.UserID != nil {
+		user, err = a.UsersDAO.GetUser(ctx, *apiKey.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, nil, ErrUnauthenticated
+			}
+			return nil, nil, err
+		}
+		user.Role = user.Role.Lowest(apiKey.Scope.Role())
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedPrecision {
+		// The request is served anyway, the last use is only informative
+		if err := a.ApiKeysDAO.UpdateApiKeyLastUsed(ctx, apiKey.ID, now); err != nil {
+			log.Error("Cannot update last use of api key "+apiKey.ID+" : ", err)
+		}
+	}
+
+	return user, apiKey, nil
+}
+
+// authenticateOIDC returns the local user of the token subject, created on its first request.
+// Its role is updated from the groups of each token, so that the provider stays the reference.
+func (a Authenticator) authenticateOIDC(ctx context.Context, token string) (*models.User, error) {
+	claims, err := a.OIDCVerifier.Verify(ctx, token)
+	if err != nil {
+		log.Error("Invalid OIDC token : ", err)
+		return nil, ErrUnauthenticated
+	}
+
+	role, ok := a.oidcRole(claims)
+	if !ok {
+		log.Errorf("OIDC subject %v is in none of the allowed groups", claims.Subject())
+		return nil, ErrForbidden
+	}
+
+	user, err := a.UsersDAO.GetUserFromOIDCSubject(ctx, claims.Subject())
+	if errors.Is(err, sql.ErrNoRows) {
+		return a.createOIDCUser(ctx, claims, role)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if user.Role != role {
+		if err := a.UsersDAO.UpdateUserRole(ctx, user.ID, role); err != nil {
+			return nil, err
+		}
+		log.Infof("Role of user %v changed from %v to %v by its OIDC groups", user.Username, user.Role, role)
+		user.Role = role
+	}
+	return user, nil
+}
+
+func (a Authenticator) createOIDCUser(ctx context.Context, claims oidc.Claims, role models.Role) (*models.User, error) {
+	username := claims.String(a.Config.OIDCUsernameClaim)
+	if !usernameRegexp.MatchString(username) {
+		log.Errorf("Invalid username %q in claim %v of OIDC subject %v", username, a.Config.OIDCUsernameClaim, claims.Subject())
+		return nil, ErrForbidden
+	}
+
+	// Local accounts are never linked to the provider, it could otherwise take them over
+	if _, err := a.UsersDAO.GetUserFromUsername(ctx, username); err == nil {
+		log.Errorf("Username %v of OIDC subject %v is already used by another user", username, claims.Subject())
+		return nil, ErrForbidden
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	userID, err := a.UUIDGen.GenerateUuid()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.UsersDAO.CreateOIDCUser(ctx, userID, username, role, claims.Subject())
+	if err != nil {
+		// Concurrent first requests of the same user, one of them created it
+		if user, getErr := a.UsersDAO.GetUserFromOIDCSubject(ctx, claims.Subject()); getErr == nil {
+			return user, nil
+		}
+		return nil, err
+	}
+
+	log.Infof("User %v created from OIDC subject %v with role %v", user.Username, claims.Subject(), user.Role)
+	return user, nil
+}
+
+// oidcRole returns the highest role given by the groups of the token
+func (a Authenticator) oidcRole(claims oidc.Claims) (models.Role, bool) {
+	groups := claims.Strings(a.Config.OIDCGroupsClaim)
+	switch {
+	case containsAny(groups, a.Config.OIDCAdminGroups):
+		return models.ADMIN, true
+	case containsAny(groups, a.Config.OIDCUploaderGroups):
+		return models.UPLOADER, true
+	case len(a.Config.OIDCViewerGroups) == 0 || containsAny(groups, a.Config.OIDCViewerGroups):
+		return models.VIEWER, true
+	default:
+		return "", false
+	}
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, value := range values {
+		for _, w := range wanted {
+			if value == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isServiceAccount(cfg config.Config, username, password string) bool {
+	if cfg.UserAuth == "" || cfg.PwdAuth == "" {
+		return false
+	}
+	userMatch := subtle.ConstantTimeCompare([]byte(username), []byte(cfg.UserAuth)) == 1
+	pwdMatch := subtle.ConstantTimeCompare([]byte(password), []byte(cfg.PwdAuth)) == 1
+	return userMatch && pwdMatch
+}
+
+// RequireScope only lets through the requests authenticated by an API key whose scope includes the given one.
+// The requests authenticated by the other credentials are let through.
+func RequireScope(scope models.ApiKeyScope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := ApiKeyFromContext(r.Context()); apiKey != nil && !apiKey.Scope.Includes(scope) {
+			log.Errorf("Api key %v (%v) is not allowed, %v scope required", apiKey.Name, apiKey.Scope, scope)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole only lets through the authenticated users having at least the given role
+func RequireRole(role models.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			log.Error("Unauthenticated request")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !user.Role.Includes(role) {
+			log.Errorf("User %v (%v) is not allowed, %v role required", user.Username, user.Role, role)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
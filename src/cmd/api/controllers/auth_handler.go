@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type LoginRequest struct {
+	Username string `json:"username" example:"alice"`
+	Password string `json:"password" example:"correct horse battery staple"`
+}
+
+type LoginResponse struct {
+	Token     string           `json:"token" example:"q1Xv..."`
+	ExpiresAt time.Time        `json:"expiresAt" example:"2022-04-16T12:59:52Z"`
+	User      jsonDTO.UserJson `json:"user"`
+}
+
+type LoginHandler struct {
+	Config      config.Config
+	UsersDAO    *dao.UsersDAO
+	SessionsDAO *dao.SessionsDAO
+}
+
+// LoginHandler godoc
+// @Summary Log in
+// @Description Check the user credentials and open a session. The token must then be sent as "Authorization: Bearer <token>".
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Username and password"
+// @Success 200 {object} LoginResponse "Session token, its expiration and the user"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/login [post]
+func (l LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST LoginHandler")
+
+	var request LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode login request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if request.Username == "" || request.Password == "" {
+		log.Error("Missing username or password")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := l.UsersDAO.GetUserFromUsername(r.Context(), request.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Error("Cannot get user "+request.Username+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	passwordHash := string(dummyPasswordHash)
+	if user != nil {
+		passwordHash = user.PasswordHash
+	}
+	if !checkPassword(passwordHash, request.Password) || user == nil {
+		log.Error("Invalid credentials for user ", request.Username)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err := l.SessionsDAO.DeleteExpiredSessions(r.Context()); err != nil {
+		log.Error("Cannot delete expired sessions : ", err)
+	}
+
+	token, tokenHash, err := newSessionToken()
+	if err != nil {
+		log.Error("Cannot generate session token : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	session := models.Session{
+		TokenHash: tokenHash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(l.Config.SessionTTL),
+	}
+	if err := l.SessionsDAO.CreateSession(r.Context(), &session); err != nil {
+		log.Error("Cannot create session : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, LoginResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		User:      jsonDTO.UserToUserJson(user),
+	})
+	log.Info("User " + user.Username + " logged in")
+}
+
+type LogoutHandler struct {
+	SessionsDAO *dao.SessionsDAO
+}
+
+// LogoutHandler godoc
+// @Summary Log out
+// @Description Close the session of the bearer token. Nothing is done for the basic auth service account.
+// @Tags auth
+// @Produce plain
+// @Success 204 {string} string
+// @Failure 401 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/logout [post]
+func (l LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST LogoutHandler")
+
+	if token := bearerToken(r.Header.Get("Authorization")); token != "" {
+		if err := l.SessionsDAO.DeleteSession(r.Context(), HashSessionToken(token)); err != nil {
+			log.Error("Cannot delete session : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,460 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/oidc"
+	"github.com/Sogilis/Voogle/src/pkg/oidc/oidctest"
+)
+
+var usersColumns = []string{"id", "username", "password_hash", "role", "created_at", "updated_at", "oidc_subject"}
+
+func TestLogin(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	username := "alice"
+	password := "correct horse"
+	passwordHash, err := controllers.HashPassword(password)
+	require.NoError(t, err)
+	t1 := time.Now()
+
+	cases := []struct {
+		name             string
+		giveBody         string
+		giveUnknownUser  bool
+		giveDbGetErr     bool
+		giveDbSessionErr bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "POST login",
+			giveBody:         `{"username": "` + username + `", "password": "` + password + `"}`,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST fails with wrong password",
+			giveBody:         `{"username": "` + username + `", "password": "wrong password"}`,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with unknown user",
+			giveBody:         `{"username": "bob", "password": "` + password + `"}`,
+			giveUnknownUser:  true,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST fails with missing password",
+			giveBody:         `{"username": "` + username + `"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid body",
+			giveBody:         `{"username": `,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with database error",
+			giveBody:         `{"username": "` + username + `", "password": "` + password + `"}`,
+			giveDbGetErr:     true,
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "POST fails when session cannot be created",
+			giveBody:         `{"username": "` + username + `", "password": "` + password + `"}`,
+			giveDbSessionErr: true,
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+
+			if tt.expectedHTTPCode != 400 {
+				getUserQuery := mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername]))
+				if tt.giveDbGetErr {
+					getUserQuery.WillReturnError(fmt.Errorf("database error"))
+				} else if tt.giveUnknownUser {
+					getUserQuery.WillReturnRows(sqlmock.NewRows(usersColumns))
+				} else {
+					getUserQuery.WithArgs(username).
+						WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, username, passwordHash, "uploader", t1, t1, nil))
+				}
+
+				if tt.expectedHTTPCode == 200 || tt.giveDbSessionErr {
+					mock.ExpectExec(regexp.QuoteMeta(dao.SessionsRequests[dao.DeleteExpiredSessions])).
+						WithArgs(AnyTime{}).
+						WillReturnResult(sqlmock.NewResult(0, 0))
+					createSession := mock.ExpectExec(regexp.QuoteMeta(dao.SessionsRequests[dao.CreateSession])).
+						WithArgs(sqlmock.AnyArg(), userID, AnyTime{})
+					if tt.giveDbSessionErr {
+						createSession.WillReturnError(fmt.Errorf("database error"))
+					} else {
+						createSession.WillReturnResult(sqlmock.NewResult(0, 1))
+					}
+				}
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r := router.NewRouter(config.Config{
+				UserAuth:   givenUsername,
+				PwdAuth:    givenUserPwd,
+				SessionTTL: time.Hour,
+			}, &router.Clients{}, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/login", strings.NewReader(tt.giveBody))
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.LoginResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.NotEmpty(t, response.Token)
+				require.Equal(t, username, response.User.Username)
+				require.Equal(t, "uploader", response.User.Role)
+				require.WithinDuration(t, time.Now().Add(time.Hour), response.ExpiresAt, time.Minute)
+				require.NotContains(t, w.Body.String(), passwordHash)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSessionAuthentication(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	token := "q1Xv-session-token"
+	t1 := time.Now()
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveAuth         string
+		giveRole         models.Role
+		giveNoSession    bool
+		giveDbErr        bool
+		expectLogout     bool
+		expectedHTTPCode int
+	}{
+		{
+			name:             "GET me with session",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users/me",
+			giveAuth:         "Bearer " + token,
+			giveRole:         models.VIEWER,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails with unknown or expired session",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users/me",
+			giveAuth:         "Bearer " + token,
+			giveNoSession:    true,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET fails with database error",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users/me",
+			giveAuth:         "Bearer " + token,
+			giveDbErr:        true,
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "GET fails with unknown auth scheme",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users/me",
+			giveAuth:         "Token " + token,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "POST upload fails for viewer",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/videos/upload",
+			giveAuth:         "Bearer " + token,
+			giveRole:         models.VIEWER,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "DELETE video fails for uploader",
+			giveMethod:       "DELETE",
+			giveRequest:      "/api/v1/videos/" + videoID + "/delete",
+			giveAuth:         "Bearer " + token,
+			giveRole:         models.UPLOADER,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET users fails for uploader",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users",
+			giveAuth:         "Bearer " + token,
+			giveRole:         models.UPLOADER,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "POST logout",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/logout",
+			giveAuth:         "Bearer " + token,
+			giveRole:         models.VIEWER,
+			expectLogout:     true,
+			expectedHTTPCode: 204,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectSessionsDAOCreation(mock)
+
+			if strings.HasPrefix(tt.giveAuth, "Bearer ") {
+				getSessionUserQuery := mock.ExpectQuery(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser])).
+					WithArgs(controllers.HashSessionToken(token), AnyTime{})
+				if tt.giveDbErr {
+					getSessionUserQuery.WillReturnError(fmt.Errorf("database error"))
+				} else if tt.giveNoSession {
+					getSessionUserQuery.WillReturnRows(sqlmock.NewRows(usersColumns))
+				} else {
+					getSessionUserQuery.WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(tt.giveRole), t1, t1, nil))
+				}
+			}
+
+			if tt.expectLogout {
+				mock.ExpectExec(regexp.QuoteMeta(dao.SessionsRequests[dao.DeleteSession])).
+					WithArgs(controllers.HashSessionToken(token)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &router.Clients{UUIDGen: clients.NewUuidGenerator()}, &router.DAOs{SessionsDAO: *sessionsDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, nil)
+			req.Header.Set("Authorization", tt.giveAuth)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				require.Contains(t, w.Body.String(), `"username":"alice"`)
+				require.Contains(t, w.Body.String(), `"role":"viewer"`)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestOIDCAuthentication(t *testing.T) {
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	subject := "248289761001"
+	audience := "voogle"
+	t1 := time.Now()
+
+	issuer, err := oidctest.NewIssuer()
+	require.NoError(t, err)
+	defer issuer.Close()
+
+	verifier, err := oidc.NewVerifier(context.Background(), issuer.URL(), audience, nil)
+	require.NoError(t, err)
+
+	getUserFromSubjectQuery := regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromOIDCSubject])
+	userRow := func(role models.Role) *sqlmock.Rows {
+		return sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "", string(role), t1, t1, subject)
+	}
+
+	cases := []struct {
+		name             string
+		giveGroups       []string
+		giveAudience     string
+		giveTTL          time.Duration
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedBody     []string
+	}{
+		{
+			name:       "GET me with known subject",
+			giveGroups: []string{"voogle-uploaders"},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromSubjectQuery).WithArgs(subject).WillReturnRows(userRow(models.UPLOADER))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"id":"` + userID + `"`, `"role":"uploader"`},
+		},
+		{
+			name:       "GET me updates the role from the groups",
+			giveGroups: []string{"voogle-viewers", "voogle-admins"},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromSubjectQuery).WithArgs(subject).WillReturnRows(userRow(models.VIEWER))
+				mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.UpdateUserRole])).
+					WithArgs(models.ADMIN, userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"role":"admin"`},
+		},
+		{
+			name:       "GET me creates the user of a new subject",
+			giveGroups: []string{"voogle-viewers"},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromSubjectQuery).WithArgs(subject).WillReturnRows(sqlmock.NewRows(usersColumns))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])).WithArgs("alice").WillReturnRows(sqlmock.NewRows(usersColumns))
+				mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.CreateOIDCUser])).
+					WithArgs(userID, "alice", models.VIEWER, subject).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).WithArgs(userID).WillReturnRows(userRow(models.VIEWER))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"username":"alice"`, `"role":"viewer"`},
+		},
+		{
+			name:       "GET fails when the username belongs to a local user",
+			giveGroups: []string{"voogle-viewers"},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromSubjectQuery).WithArgs(subject).WillReturnRows(sqlmock.NewRows(usersColumns))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])).
+					WithArgs("alice").
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", "admin", t1, t1, nil))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails outside of the allowed groups",
+			giveGroups:       []string{"staff"},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with expired token",
+			giveGroups:       []string{"voogle-viewers"},
+			giveTTL:          -time.Hour,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET fails with token for another audience",
+			giveGroups:       []string{"voogle-viewers"},
+			giveAudience:     "other",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:       "GET fails with database error",
+			giveGroups: []string{"voogle-viewers"},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromSubjectQuery).WithArgs(subject).WillReturnError(fmt.Errorf("database error"))
+			},
+			expectedHTTPCode: 500,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			giveAudience := audience
+			if tt.giveAudience != "" {
+				giveAudience = tt.giveAudience
+			}
+			giveTTL := time.Hour
+			if tt.giveTTL != 0 {
+				giveTTL = tt.giveTTL
+			}
+			token, err := issuer.Token(subject, giveAudience, giveTTL, map[string]interface{}{
+				"preferred_username": "alice",
+				"groups":             tt.giveGroups,
+			})
+			require.NoError(t, err)
+
+			routerClients := router.Clients{
+				UUIDGen:      clients.NewUuidGeneratorDummy(func() (string, error) { return userID, nil }, nil),
+				OIDCVerifier: verifier,
+			}
+
+			r := router.NewRouter(config.Config{
+				OIDCUsernameClaim:  "preferred_username",
+				OIDCGroupsClaim:    "groups",
+				OIDCAdminGroups:    []string{"voogle-admins"},
+				OIDCUploaderGroups: []string{"voogle-uploaders"},
+				OIDCViewerGroups:   []string{"voogle-viewers"},
+			}, &routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/users/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				require.Contains(t, w.Body.String(), expected)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
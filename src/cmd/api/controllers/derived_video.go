@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// createDerivedVideo registers a video whose source the encoder derives from the source of
+// the parent video, like a clip. On failure, the error response is written and ok is false.
+func createDerivedVideo(w http.ResponseWriter, r *http.Request, videosDAO *dao.VideosDAO, uuidGen clients.IUUIDGenerator, parentID, title string) (parent, video *models.Video, ok bool) {
+	parent, err := videosDAO.GetVideo(r.Context(), parentID)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			return nil, nil, false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if !hasUploadedSource(parent) {
+		log.Error("Source of video " + parentID + " is not uploaded")
+		http.Error(w, "The parent source is not uploaded", http.StatusConflict)
+		return nil, nil, false
+	}
+
+	// Derived videos are videos on their own, their titles are unique too
+	existing, err := videosDAO.GetVideoFromTitle(r.Context(), title)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if existing != nil {
+		log.Error("A video with this title already exists")
+		http.Error(w, "This title already exists", http.StatusConflict)
+		return nil, nil, false
+	}
+
+	videoID, err := uuidGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new video ID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	// The encoder uploads the derived source, and generates the cover.
+	// The derived video belongs to its creator, who may not own the parent.
+	sourcePath := videoID + "/" + "source" + filepath.Ext(parent.SourcePath)
+	video, err = videosDAO.CreateVideo(r.Context(), videoID, title, int(models.UPLOADED), sourcePath, "", ownerIDOf(UserFromContext(r.Context())), DefaultVisibility)
+	if err != nil {
+		log.Error("Cannot create derived video : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	uploadDate := time.Now()
+	video.UploadedAt = &uploadDate
+
+	return parent, video, true
+}
+
+// removeDerivedVideo deletes a derived video which could not be registered completely
+func removeDerivedVideo(r *http.Request, videosDAO *dao.VideosDAO, video *models.Video) {
+	if err := videosDAO.DeleteVideo(r.Context(), video.ID); err != nil {
+		log.Error("Cannot delete derived video "+video.ID+" : ", err)
+	}
+}
+
+// sendDerivedVideoForEncoding publishes the derived video to the encoder, with the instructions of videoProto
+func sendDerivedVideoForEncoding(r *http.Request, amqpClient, amqpVideoStatusUpdate clients.AmqpClient, videosDAO *dao.VideosDAO, video *models.Video, videoProto *contracts.Video) error {
+	uploadHandler := VideoUploadHandler{
+		AmqpClient:            amqpClient,
+		AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
+		VideosDAO:             videosDAO,
+	}
+	return uploadHandler.sendVideoProtoForEncoding(r.Context(), video, videoProto)
+}
+
+// hasUploadedSource tells whether the source of the video is stored on S3
+func hasUploadedSource(video *models.Video) bool {
+	switch video.Status {
+	case models.UPLOADED, models.ENCODING, models.COMPLETE, models.ARCHIVE, models.FAIL_ENCODE:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Interval of the comments keeping the stream open through the proxies
+const sseKeepAliveInterval = 15 * time.Second
+
+type EventsHandler struct {
+	VideoEvents    *eventhandler.Hub
+	VideosDAO      *dao.VideosDAO
+	VideoSharesDAO *dao.VideoSharesDAO
+	UUIDGen        clients.IUUIDGenerator
+}
+
+// EventsHandler godoc
+// @Summary Stream the events of the videos
+// @Description Server-sent events fallback of the websocket, for the clients or proxies without websocket support. Each event has the ID to resume from, its kind (status, upload_progress, encode_progress, deleted, renamed) as name and the same JSON data as the websocket events. A client reconnecting with the Last-Event-ID header first receives the events it missed, among the last ones kept by the API instance.
+// @Tags events
+// @Produce text/event-stream
+// @Param video query []string false "Video ID, or \"all\" for every video the user can list (default)" collectionFormat(multi)
+// @Param Authorization header string false "Bearer token or basic auth, browsers use the access_token parameter or the cookie instead"
+// @Param access_token query string false "Bearer token"
+// @Param Last-Event-ID header string false "ID of the last event received, to resume the stream"
+// @Success 200 {object} jsonDTO.VideoEventJson "Data of the events"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/events [get]
+func (e EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET EventsHandler - Parameters: ", r.URL.Query())
+
+	user := UserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Error("Cannot stream events : response writer cannot be flushed")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	videoIDs := r.URL.Query()["video"]
+	if len(videoIDs) == 0 {
+		videoIDs = []string{AllVideos}
+	}
+	subscriptions := newVideoSubscriptions()
+	for _, videoID := range videoIDs {
+		if statusCode, err := checkVideoSubscription(r.Context(), e.VideosDAO, e.VideoSharesDAO, e.UUIDGen, user, videoID); err != nil {
+			http.Error(w, videoSubscriptionError(statusCode), statusCode)
+			return
+		}
+		subscriptions.subscribe(videoID)
+	}
+
+	// An invalid ID is ignored, the stream starts from the next event
+	var lastEventID uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		var err error
+		if lastEventID, err = strconv.ParseUint(header, 10, 64); err != nil {
+			log.Error("Invalid Last-Event-ID : ", err)
+		}
+	}
+
+	replay, videoEvents, unsubscribe := e.VideoEvents.SubscribeAfter(lastEventID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Disables the buffering of nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		if err := writeEvent(r.Context(), w, e.VideoSharesDAO, user, subscriptions, event); err != nil {
+			log.Error("Cannot send event : ", err)
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Debug("Events stream closed by ", user.Username)
+			return
+		case event := <-videoEvents:
+			if err := writeEvent(r.Context(), w, e.VideoSharesDAO, user, subscriptions, event); err != nil {
+				log.Error("Cannot send event : ", err)
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				log.Error("Cannot send keep-alive : ", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event in the stream when the user follows the video
+func writeEvent(ctx context.Context, w http.ResponseWriter, videoSharesDAO *dao.VideoSharesDAO, user *models.User, subscriptions *videoSubscriptions, event *models.VideoEvent) error {
+	if !isEventSent(ctx, videoSharesDAO, user, subscriptions, event) {
+		return nil
+	}
+
+	data, err := json.Marshal(jsonDTO.VideoEventToVideoEventJson(event))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Kind, data); err != nil {
+		return err
+	}
+
+	if event.Kind == models.DELETED_EVENT {
+		subscriptions.unsubscribe(event.Video.ID)
+	}
+	return nil
+}
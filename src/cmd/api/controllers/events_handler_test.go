@@ -0,0 +1,240 @@
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestEvents(t *testing.T) { //nolint:cyclop
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "7f3c2d1e-8b4a-4c6d-9e0f-1a2b3c4d5e6f"
+	ownerID := "9d6f2b7e-3c1a-4f0e-8a52-7b3e1d0c4f21"
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	token := "q1Xv-session-token"
+	t1 := time.Now()
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	getVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+	videoRow := func(owner string, visibility models.Visibility) *sqlmock.Rows {
+		return sqlmock.NewRows(videosColumns).
+			AddRow(videoID, "title", int(models.COMPLETE), t1, t1, t1, videoID+"/source.mp4", "", nil, owner, string(visibility))
+	}
+
+	videoEvent := func(kind models.VideoEventKind, id, owner string, visibility models.Visibility, progress float64) *models.VideoEvent {
+		return &models.VideoEvent{
+			Kind:     kind,
+			Video:    models.Video{ID: id, Title: "title " + id[:4], Status: models.ENCODING, OwnerID: &owner, Visibility: visibility},
+			Progress: progress,
+		}
+	}
+	// eventFrame is the event sent in the stream, without its final blank line
+	eventFrame := func(sequence int, event *models.VideoEvent) string {
+		data, err := json.Marshal(jsonDTO.VideoEventToVideoEventJson(event))
+		require.NoError(t, err)
+		return fmt.Sprintf("id: %d\nevent: %s\ndata: %s", sequence, event.Kind, data)
+	}
+
+	privateEvent := videoEvent(models.STATUS_EVENT, otherVideoID, ownerID, models.PRIVATE, 0)
+	publicEvent := videoEvent(models.STATUS_EVENT, otherVideoID, ownerID, models.PUBLIC, 0)
+	ownProgressEvent := videoEvent(models.ENCODE_PROGRESS_EVENT, videoID, userID, models.PRIVATE, 0.7)
+	ownRenamedEvent := videoEvent(models.RENAMED_EVENT, videoID, userID, models.PRIVATE, 0)
+	ownDeletedEvent := videoEvent(models.DELETED_EVENT, videoID, userID, models.PRIVATE, 0)
+
+	cases := []struct {
+		name             string
+		giveQuery        string
+		giveLastEventID  string
+		giveNoAuth       bool
+		givePastEvents   []*models.VideoEvent
+		giveEvents       []*models.VideoEvent
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedEvents   []string
+	}{
+		{
+			name:             "Stream the events of every listed video",
+			giveEvents:       []*models.VideoEvent{privateEvent, publicEvent, ownProgressEvent},
+			expectedHTTPCode: 200,
+			expectedEvents:   []string{eventFrame(2, publicEvent), eventFrame(3, ownProgressEvent)},
+		},
+		{
+			name:       "Stream the events of a video until deleted",
+			giveQuery:  "?video=" + videoID,
+			giveEvents: []*models.VideoEvent{publicEvent, ownRenamedEvent, ownDeletedEvent},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+			},
+			expectedHTTPCode: 200,
+			expectedEvents:   []string{eventFrame(2, ownRenamedEvent), eventFrame(3, ownDeletedEvent)},
+		},
+		{
+			name:             "Resume the stream after the last event received",
+			giveLastEventID:  "1",
+			givePastEvents:   []*models.VideoEvent{publicEvent, ownProgressEvent, privateEvent, publicEvent},
+			giveEvents:       []*models.VideoEvent{ownRenamedEvent},
+			expectedHTTPCode: 200,
+			expectedEvents:   []string{eventFrame(2, ownProgressEvent), eventFrame(4, publicEvent), eventFrame(5, ownRenamedEvent)},
+		},
+		{
+			name:             "Resume from an unknown event replays nothing",
+			giveLastEventID:  "42",
+			givePastEvents:   []*models.VideoEvent{publicEvent},
+			giveEvents:       []*models.VideoEvent{ownProgressEvent},
+			expectedHTTPCode: 200,
+			expectedEvents:   []string{eventFrame(2, ownProgressEvent)},
+		},
+		{
+			name:             "Resume from an invalid event ID replays nothing",
+			giveLastEventID:  "invalid",
+			givePastEvents:   []*models.VideoEvent{publicEvent},
+			giveEvents:       []*models.VideoEvent{ownProgressEvent},
+			expectedHTTPCode: 200,
+			expectedEvents:   []string{eventFrame(2, ownProgressEvent)},
+		},
+		{
+			name:             "Stream fails with invalid video ID",
+			giveQuery:        "?video=invalid",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:      "Stream fails for a private video of another user",
+			giveQuery: "?video=all&video=" + videoID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PRIVATE))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:      "Stream fails for an unknown video",
+			giveQuery: "?video=" + videoID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnError(sql.ErrNoRows)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:      "Stream fails with database error",
+			giveQuery: "?video=" + videoID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnError(fmt.Errorf("database error"))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "Stream fails without authentication",
+			giveNoAuth:       true,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectVideoSharesDAOCreation(mock)
+
+			if !tt.giveNoAuth {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser])).
+					WithArgs(controllers.HashSessionToken(token), AnyTime{}).
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(models.UPLOADER), t1, t1, nil))
+			}
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			videoSharesDAO, err := dao.CreateVideoSharesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			videoEvents := eventhandler.NewHub(clients.NewAmqpClientDummy(nil, nil, nil))
+			for _, event := range tt.givePastEvents {
+				videoEvents.Dispatch(event)
+			}
+			routerClients := router.Clients{
+				UUIDGen:     clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+				VideoEvents: videoEvents,
+			}
+
+			server := httptest.NewServer(router.NewRouter(config.Config{},
+				&routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO, VideosDAO: *videosDAO, VideoSharesDAO: *videoSharesDAO}))
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/events"+tt.giveQuery, nil)
+			require.NoError(t, err)
+			if !tt.giveNoAuth {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			if tt.giveLastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.giveLastEventID)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tt.expectedHTTPCode, res.StatusCode)
+
+			if tt.expectedHTTPCode == 200 {
+				require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+				// The stream is subscribed once the headers are received
+				for _, event := range tt.giveEvents {
+					videoEvents.Dispatch(event)
+				}
+
+				reader := bufio.NewReader(res.Body)
+				for _, expected := range tt.expectedEvents {
+					var frame []string
+					for {
+						line, err := reader.ReadString('\n')
+						require.NoError(t, err)
+						if line == "\n" {
+							break
+						}
+						frame = append(frame, strings.TrimSuffix(line, "\n"))
+					}
+					require.Equal(t, expected, strings.Join(frame, "\n"))
+				}
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
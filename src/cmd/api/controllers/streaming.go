@@ -0,0 +1,468 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+	"github.com/Sogilis/Voogle/src/pkg/streamtoken"
+	"github.com/Sogilis/Voogle/src/pkg/transformer/v1"
+)
+
+const (
+	// Segments and encoded files never change once uploaded, they can be cached by any CDN for good
+	cacheControlImmutable = "public, max-age=31536000, immutable"
+	// Playlists and subtitles may be edited, caches must revalidate them soon
+	cacheControlRevalidate = "public, max-age=60"
+)
+
+var streamContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+	".ts":   "video/mp2t",
+	".vtt":  "text/vtt; charset=utf-8",
+	".jpg":  "image/jpeg",
+}
+
+const (
+	// Transformer stamping the watermark given by the watermark parameters
+	watermarkTransformer = "watermark"
+	// Watermark images are stored on S3 under this prefix, viewers cannot stamp any other object
+	watermarkImagesPrefix = "watermarks/"
+)
+
+// Transformers the filters of the streams can name
+var streamFilters = []string{"gray", "flip", watermarkTransformer}
+
+var watermarkQueryParams = []string{"watermarkImage", "watermarkText", "watermarkPosition", "watermarkOpacity"}
+
+var (
+	playlistURIAttributeRegexp = regexp.MustCompile(`URI="([^"]*)"`)
+	playlistResolutionRegexp   = regexp.MustCompile(`RESOLUTION=[0-9]+x([0-9]+)`)
+	playlistCodecsRegexp       = regexp.MustCompile(`CODECS="([^"]*)"`)
+	manifestURLAttributeRegexp = regexp.MustCompile(`(sourceURL|media)="([^"]*)"`)
+)
+
+// streamContentType returns the MIME type of a streamed file from its extension
+func streamContentType(filename string) string {
+	if contentType, ok := streamContentTypes[strings.ToLower(path.Ext(filename))]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// isMediaSegment tells whether the file holds media samples, unlike playlists and initialization segments
+func isMediaSegment(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".m4s", ".ts":
+		return true
+	}
+	return false
+}
+
+// serveObject streams an S3 object with its validators. Conditional requests are answered
+// with 304 and HEAD requests only get the headers.
+func serveObject(w http.ResponseWriter, r *http.Request, object *s3.GetObjectOutput, filename, cacheControl string) {
+	defer object.Body.Close()
+
+	etag := ""
+	if object.ETag != nil {
+		etag = *object.ETag
+	}
+	lastModified := time.Time{}
+	if object.LastModified != nil {
+		lastModified = *object.LastModified
+	}
+
+	if !writeValidators(w, r, filename, cacheControl, etag, lastModified) {
+		return
+	}
+	if object.ContentLength > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(object.ContentLength, 10))
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if _, err := io.Copy(w, object.Body); err != nil {
+		log.Error("Unable to stream "+filename+" : ", err)
+	}
+}
+
+// servePlaylistObject serves a HLS playlist stored on S3, rewritten when it has to pass parameters on
+func servePlaylistObject(w http.ResponseWriter, r *http.Request, object *s3.GetObjectOutput, filename string) {
+	query, err := playlistQuery(r)
+	if err != nil {
+		object.Body.Close()
+		log.Error("Invalid filters : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(query) == 0 {
+		serveObject(w, r, object, filename, cacheControlRevalidate)
+		return
+	}
+	defer object.Body.Close()
+
+	playlist, err := io.ReadAll(object.Body)
+	if err != nil {
+		log.Error("Unable to read playlist "+filename+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	lastModified := time.Time{}
+	if object.LastModified != nil {
+		lastModified = *object.LastModified
+	}
+	serveGeneratedContent(w, r, addQueryToPlaylist(playlist, query), filename, cacheControlRevalidate, lastModified)
+}
+
+// serveGeneratedContent serves a file built by the API, its ETag is derived from the content
+func serveGeneratedContent(w http.ResponseWriter, r *http.Request, content []byte, filename, cacheControl string, lastModified time.Time) {
+	sum := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	if !writeValidators(w, r, filename, cacheControl, etag, lastModified) {
+		return
+	}
+	// ServeContent handles the length, the ranges and HEAD requests
+	http.ServeContent(w, r, filename, time.Time{}, bytes.NewReader(content))
+}
+
+// writeValidators sets the caching headers of a streamed file. It returns false when the
+// client copy is still fresh, the 304 response being already written.
+func writeValidators(w http.ResponseWriter, r *http.Request, filename, cacheControl, etag string, lastModified time.Time) bool {
+	w.Header().Set("Content-Type", streamContentType(filename))
+	w.Header().Set("Cache-Control", cacheControl)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(r, etag, lastModified) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+	return true
+}
+
+// isNotModified evaluates the conditional headers of a GET or HEAD request (RFC 7232).
+// If-None-Match takes precedence over If-Modified-Since.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have a one second precision
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// playlistQuery returns the parameters a playlist has to pass on to the files it refers to:
+// the filters applied to the segments, with the watermark parameters, and the stream token
+func playlistQuery(r *http.Request) (url.Values, error) {
+	if err := checkFilters(r.URL.Query()); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for _, filter := range r.URL.Query()["filter"] {
+		query.Add("filter", filter)
+	}
+	for _, param := range watermarkQueryParams {
+		if value := r.URL.Query().Get(param); value != "" {
+			query.Set(param, value)
+		}
+	}
+	if token := r.URL.Query().Get(streamtoken.QueryParam); token != "" {
+		query.Set(streamtoken.QueryParam, token)
+	}
+	return query, nil
+}
+
+// checkFilters returns an error when a filter of the query is empty or names an unknown transformer
+func checkFilters(query url.Values) error {
+	for _, filter := range query["filter"] {
+		known := false
+		for _, name := range streamFilters {
+			known = known || filter == name
+		}
+		if !known {
+			return fmt.Errorf("unknown filter %q", filter)
+		}
+	}
+	return nil
+}
+
+// WatermarkQuery returns the watermark parameters of the query in a canonical form, empty when there are none.
+// The stream tokens are bound to it, so that their holder can neither remove nor change the watermark.
+func WatermarkQuery(query url.Values) string {
+	watermark := url.Values{}
+	for _, param := range watermarkQueryParams {
+		if value := query.Get(param); value != "" {
+			watermark.Set(param, value)
+		}
+	}
+	return watermark.Encode()
+}
+
+// segmentTransformers returns the transformers applied to a media segment. The watermark is stamped whenever
+// its parameters are given, even if the filters do not request it.
+func segmentTransformers(query url.Values) []string {
+	transformers := query["filter"]
+	if WatermarkQuery(query) == "" {
+		return transformers
+	}
+	for _, name := range transformers {
+		if name == watermarkTransformer {
+			return transformers
+		}
+	}
+	return append(transformers, watermarkTransformer)
+}
+
+// parseWatermark returns the parameters of the watermark transformer, nil when it is not requested
+func parseWatermark(query url.Values, transformers []string) (*transformer.Watermark, error) {
+	requested := false
+	for _, name := range transformers {
+		requested = requested || name == watermarkTransformer
+	}
+	if !requested {
+		return nil, nil
+	}
+
+	watermark := &transformer.Watermark{
+		ImagePath: query.Get("watermarkImage"),
+		Text:      query.Get("watermarkText"),
+		Position:  query.Get("watermarkPosition"),
+	}
+	if watermark.ImagePath == "" && watermark.Text == "" {
+		return nil, errors.New("watermark without image nor text")
+	}
+	if watermark.ImagePath != "" && (!strings.HasPrefix(watermark.ImagePath, watermarkImagesPrefix) || strings.Contains(watermark.ImagePath, "..")) {
+		return nil, fmt.Errorf("watermark image must be under %v", watermarkImagesPrefix)
+	}
+	if rawOpacity := query.Get("watermarkOpacity"); rawOpacity != "" {
+		opacity, err := strconv.ParseFloat(rawOpacity, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watermark opacity %v", rawOpacity)
+		}
+		watermark.Opacity = opacity
+	}
+
+	if err := (ffmpeg.Watermark{Text: watermark.Text, Position: watermark.Position, Opacity: watermark.Opacity}).Validate(); err != nil {
+		return nil, err
+	}
+	return watermark, nil
+}
+
+// addQueryToPlaylist appends the query to every URI of a HLS playlist, the renditions,
+// segments and subtitles are then requested with it
+func addQueryToPlaylist(playlist []byte, query url.Values) []byte {
+	if len(query) == 0 {
+		return playlist
+	}
+
+	encodedQuery := query.Encode()
+	addQuery := func(uri string) string {
+		if strings.Contains(uri, "?") {
+			return uri + "&" + encodedQuery
+		}
+		return uri + "?" + encodedQuery
+	}
+
+	lines := strings.Split(string(playlist), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			lines[i] = playlistURIAttributeRegexp.ReplaceAllStringFunc(line, func(attribute string) string {
+				return `URI="` + addQuery(playlistURIAttributeRegexp.FindStringSubmatch(attribute)[1]) + `"`
+			})
+		default:
+			lines[i] = addQuery(line)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// addQueryToManifest appends the query to the initialization and media segment URLs of a DASH manifest
+func addQueryToManifest(manifest []byte, query url.Values) []byte {
+	if len(query) == 0 {
+		return manifest
+	}
+
+	// The manifest is XML, the separators of the query have to be escaped
+	encodedQuery := strings.ReplaceAll(query.Encode(), "&", "&amp;")
+	return manifestURLAttributeRegexp.ReplaceAllFunc(manifest, func(attribute []byte) []byte {
+		match := manifestURLAttributeRegexp.FindSubmatch(attribute)
+		separator := "?"
+		if bytes.Contains(match[2], []byte("?")) {
+			separator = "&amp;"
+		}
+		return []byte(string(match[1]) + `="` + string(match[2]) + separator + encodedQuery + `"`)
+	})
+}
+
+// masterVariant locates a variant of a HLS master: the line of its #EXT-X-STREAM-INF tag and of its URI
+type masterVariant struct {
+	tag, uri int
+}
+
+// masterVariants lists the variants of the lines of a HLS master
+func masterVariants(lines []string) []masterVariant {
+	variants := []masterVariant{}
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "#EXT-X-STREAM-INF:") {
+			continue
+		}
+
+		// The URI is the first line after the tag which is not a tag itself
+		uri := i + 1
+		for uri < len(lines) && (strings.TrimSpace(lines[uri]) == "" || strings.HasPrefix(lines[uri], "#")) {
+			uri++
+		}
+		variants = append(variants, masterVariant{tag: i, uri: uri})
+		i = uri
+	}
+	return variants
+}
+
+// removeMasterVariants returns the lines of a HLS master without the given variants
+func removeMasterVariants(lines []string, variants []masterVariant) []byte {
+	removed := map[int]bool{}
+	for _, v := range variants {
+		removed[v.tag], removed[v.uri] = true, true
+	}
+
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if !removed[i] {
+			kept = append(kept, line)
+		}
+	}
+	return []byte(strings.Join(kept, "\n"))
+}
+
+// capMasterRenditions removes the variants higher than maxHeight from a HLS master.
+// The lowest variant is kept when none fits.
+func capMasterRenditions(master []byte, maxHeight int) []byte {
+	lines := strings.Split(string(master), "\n")
+	variants := masterVariants(lines)
+
+	removed := []masterVariant{}
+	lowest, lowestHeight := -1, 0
+	for i, v := range variants {
+		// Variants without resolution are audio only, they always fit
+		height := 0
+		if match := playlistResolutionRegexp.FindStringSubmatch(lines[v.tag]); match != nil {
+			height, _ = strconv.Atoi(match[1])
+		}
+
+		if height > maxHeight {
+			removed = append(removed, v)
+		}
+		if lowest < 0 || height < lowestHeight {
+			lowest, lowestHeight = i, height
+		}
+	}
+	if lowest >= 0 && len(removed) == len(variants) {
+		removed = append(variants[:lowest:lowest], variants[lowest+1:]...)
+	}
+
+	return removeMasterVariants(lines, removed)
+}
+
+// filterMasterCodecs removes the variants using a video codec the client does not support from
+// a HLS master. Variants without CODECS and unknown codecs are kept, as is the master when no variant fits.
+func filterMasterCodecs(master []byte, supported []ffmpeg.VideoCodec) []byte {
+	lines := strings.Split(string(master), "\n")
+	variants := masterVariants(lines)
+
+	removed := []masterVariant{}
+	for _, v := range variants {
+		match := playlistCodecsRegexp.FindStringSubmatch(lines[v.tag])
+		if match == nil {
+			continue
+		}
+		for _, codec := range strings.Split(match[1], ",") {
+			if family := ffmpeg.CodecFamily(codec); family != "" && !containsVideoCodec(supported, family) {
+				removed = append(removed, v)
+				break
+			}
+		}
+	}
+	if len(removed) == len(variants) {
+		return master
+	}
+
+	return removeMasterVariants(lines, removed)
+}
+
+func containsVideoCodec(codecs []ffmpeg.VideoCodec, codec ffmpeg.VideoCodec) bool {
+	for _, c := range codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCodecsHint returns the video codecs a client declares supporting, e.g. codecs=avc1,hvc1
+func parseCodecsHint(r *http.Request) ([]ffmpeg.VideoCodec, error) {
+	codecs := []ffmpeg.VideoCodec{}
+	for _, value := range r.URL.Query()["codecs"] {
+		for _, name := range strings.Split(value, ",") {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			codec, err := ffmpeg.ParseVideoCodec(name)
+			if err != nil {
+				return nil, err
+			}
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs, nil
+}
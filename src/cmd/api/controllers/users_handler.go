@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._@-]{3,64}$`)
+
+type UserCreateRequest struct {
+	Username string `json:"username" example:"alice"`
+	Password string `json:"password" example:"correct horse battery staple"`
+	Role     string `json:"role" example:"uploader"`
+}
+
+// UserUpdateRequest changes the role and/or the password of a user, empty fields are left unchanged
+type UserUpdateRequest struct {
+	Password string `json:"password,omitempty" example:"correct horse battery staple"`
+	Role     string `json:"role,omitempty" example:"admin"`
+}
+
+type UsersListResponse struct {
+	Users []jsonDTO.UserJson `json:"users"`
+}
+
+type UserGetMeHandler struct{}
+
+// UserGetMeHandler godoc
+// @Summary Get the authenticated user
+// @Description Get the user of the session, or the service account when using basic auth
+// @Tags users
+// @Produce json
+// @Success 200 {object} jsonDTO.UserJson
+// @Failure 401 {string} string
+// @Router /api/v1/users/me [get]
+func (u UserGetMeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET UserGetMeHandler")
+
+	user := UserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, jsonDTO.UserToUserJson(user))
+}
+
+type UsersListHandler struct {
+	UsersDAO *dao.UsersDAO
+}
+
+// UsersListHandler godoc
+// @Summary List users
+// @Description List the users, sorted by username. Admin only.
+// @Tags users
+// @Produce json
+// @Success 200 {object} UsersListResponse
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/users [get]
+func (u UsersListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET UsersListHandler")
+
+	users, err := u.UsersDAO.GetUsers(r.Context())
+	if err != nil {
+		log.Error("Cannot get users : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := UsersListResponse{Users: make([]jsonDTO.UserJson, 0, len(users))}
+	for i := range users {
+		response.Users = append(response.Users, jsonDTO.UserToUserJson(&users[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+type UserCreateHandler struct {
+	UsersDAO *dao.UsersDAO
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// UserCreateHandler godoc
+// @Summary Create a user
+// @Description Create a user with a role among viewer, uploader and admin. Admin only.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body UserCreateRequest true "Username, password and role"
+// @Success 200 {object} jsonDTO.UserJson
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string "This username already exists"
+// @Failure 500 {string} string
+// @Router /api/v1/users [post]
+func (u UserCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST UserCreateHandler")
+
+	var request UserCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode user request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !usernameRegexp.MatchString(request.Username) {
+		log.Error("Invalid username ", request.Username)
+		http.Error(w, "username must be 3 to 64 letters, digits or ._@- characters", http.StatusBadRequest)
+		return
+	}
+	if err := validatePassword(request.Password); err != nil {
+		log.Error("Invalid password : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	role, err := models.StringToRole(request.Role)
+	if err != nil {
+		log.Error("Invalid role : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = u.UsersDAO.GetUserFromUsername(r.Context(), request.Username)
+	if err == nil {
+		log.Error("Cannot create user : username " + request.Username + " already exists")
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Error("Cannot get user "+request.Username+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	passwordHash, err := HashPassword(request.Password)
+	if err != nil {
+		log.Error("Cannot hash password : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	userID, err := u.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	user, err := u.UsersDAO.CreateUser(r.Context(), userID, request.Username, passwordHash, role)
+	if err != nil {
+		log.Error("Cannot create user "+request.Username+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, jsonDTO.UserToUserJson(user))
+	log.Infof("User %v created with role %v", user.Username, user.Role)
+}
+
+type UserGetHandler struct {
+	UsersDAO *dao.UsersDAO
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// UserGetHandler godoc
+// @Summary Get a user
+// @Description Get a user. Admin only.
+// @Tags users
+// @Produce json
+// @Param userID path string true "User ID"
+// @Success 200 {object} jsonDTO.UserJson
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/users/{userID} [get]
+func (u UserGetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET UserGetHandler - Parameters: ", vars)
+
+	user, statusCode, err := getUserFromVars(r, u.UsersDAO, u.UUIDGen)
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	writeJSON(w, jsonDTO.UserToUserJson(user))
+}
+
+type UserUpdateHandler struct {
+	UsersDAO    *dao.UsersDAO
+	SessionsDAO *dao.SessionsDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// UserUpdateHandler godoc
+// @Summary Update a user
+// @Description Change the role and/or the password of a user. A new password closes all the sessions of the user. Admin only.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param userID path string true "User ID"
+// @Param user body UserUpdateRequest true "New role and/or password"
+// @Success 200 {object} jsonDTO.UserJson
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/users/{userID} [put]
+func (u UserUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT UserUpdateHandler - Parameters: ", vars)
+
+	var request UserUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode user request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var role models.Role
+	var err error
+	if request.Role != "" {
+		if role, err = models.StringToRole(request.Role); err != nil {
+			log.Error("Invalid role : ", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if request.Password != "" {
+		if err := validatePassword(request.Password); err != nil {
+			log.Error("Invalid password : ", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, statusCode, err := getUserFromVars(r, u.UsersDAO, u.UUIDGen)
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if role != "" && role != user.Role {
+		if err := u.UsersDAO.UpdateUserRole(r.Context(), user.ID, role); err != nil {
+			log.Error("Cannot update role of user "+user.ID+" : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if request.Password != "" {
+		passwordHash, err := HashPassword(request.Password)
+		if err != nil {
+			log.Error("Cannot hash password : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := u.UsersDAO.UpdateUserPassword(r.Context(), user.ID, passwordHash); err != nil {
+			log.Error("Cannot update password of user "+user.ID+" : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := u.SessionsDAO.DeleteUserSessions(r.Context(), user.ID); err != nil {
+			log.Error("Cannot close sessions of user "+user.ID+" : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	user, err = u.UsersDAO.GetUser(r.Context(), user.ID)
+	if err != nil {
+		log.Error("Cannot get user : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, jsonDTO.UserToUserJson(user))
+}
+
+type UserDeleteHandler struct {
+	UsersDAO *dao.UsersDAO
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// UserDeleteHandler godoc
+// @Summary Delete a user
+// @Description Delete a user and close its sessions. Admins cannot delete themselves. Admin only.
+// @Tags users
+// @Produce plain
+// @Param userID path string true "User ID"
+// @Success 200 {string} string "OK"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string "Cannot delete yourself"
+// @Failure 500 {string} string
+// @Router /api/v1/users/{userID} [delete]
+func (u UserDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE UserDeleteHandler - Parameters: ", vars)
+
+	user, statusCode, err := getUserFromVars(r, u.UsersDAO, u.UUIDGen)
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if current := UserFromContext(r.Context()); current != nil && current.ID == user.ID {
+		log.Error("User " + user.Username + " cannot delete itself")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	if err := u.UsersDAO.DeleteUser(r.Context(), user.ID); err != nil {
+		log.Error("Cannot delete user "+user.ID+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("User " + user.Username + " deleted")
+}
+
+func getUserFromVars(r *http.Request, usersDAO *dao.UsersDAO, uuidGen clients.IUUIDGenerator) (*models.User, int, error) {
+	id := mux.Vars(r)["userID"]
+	if !uuidGen.IsValidUUID(id) {
+		err := errors.New("invalid user id")
+		log.Error(err)
+		return nil, http.StatusBadRequest, err
+	}
+
+	user, err := usersDAO.GetUser(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get user "+id+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return user, 0, nil
+}
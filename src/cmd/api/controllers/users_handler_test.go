@@ -0,0 +1,238 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestUsers(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	unknownUserID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+	t1 := time.Now()
+
+	getUserQuery := regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])
+	getUserFromUsernameQuery := regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername])
+	userRow := func(role models.Role) *sqlmock.Rows {
+		return sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(role), t1, t1, nil)
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveBody         string
+		giveWithAuth     bool
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedBody     []string
+	}{
+		{
+			name:         "GET users",
+			giveMethod:   "GET",
+			giveRequest:  "/api/v1/users",
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUsers])).WillReturnRows(userRow(models.VIEWER))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"users":[{"id":"` + userID + `","username":"alice","role":"viewer"`},
+		},
+		{
+			name:             "GET me as service account",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users/me",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"username":"dev"`, `"role":"admin"`},
+		},
+		{
+			name:         "POST user",
+			giveMethod:   "POST",
+			giveRequest:  "/api/v1/users",
+			giveBody:     `{"username": "alice", "password": "correct horse", "role": "uploader"}`,
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromUsernameQuery).WithArgs("alice").WillReturnRows(sqlmock.NewRows(usersColumns))
+				mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.CreateUser])).
+					WithArgs(userID, "alice", sqlmock.AnyArg(), models.UPLOADER).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(getUserQuery).WithArgs(userID).WillReturnRows(userRow(models.UPLOADER))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"username":"alice"`, `"role":"uploader"`},
+		},
+		{
+			name:         "POST fails with existing username",
+			giveMethod:   "POST",
+			giveRequest:  "/api/v1/users",
+			giveBody:     `{"username": "alice", "password": "correct horse", "role": "uploader"}`,
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserFromUsernameQuery).WithArgs("alice").WillReturnRows(userRow(models.VIEWER))
+			},
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "POST fails with unknown role",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/users",
+			giveBody:         `{"username": "alice", "password": "correct horse", "role": "root"}`,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with short password",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/users",
+			giveBody:         `{"username": "alice", "password": "short", "role": "viewer"}`,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid username",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/users",
+			giveBody:         `{"username": "a b", "password": "correct horse", "role": "viewer"}`,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:         "PUT user role and password",
+			giveMethod:   "PUT",
+			giveRequest:  "/api/v1/users/" + userID,
+			giveBody:     `{"role": "admin", "password": "new password"}`,
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserQuery).WithArgs(userID).WillReturnRows(userRow(models.VIEWER))
+				mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.UpdateUserRole])).
+					WithArgs(models.ADMIN, userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.UpdateUserPassword])).
+					WithArgs(sqlmock.AnyArg(), userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.SessionsRequests[dao.DeleteUserSessions])).
+					WithArgs(userID).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectQuery(getUserQuery).WithArgs(userID).WillReturnRows(userRow(models.ADMIN))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"role":"admin"`},
+		},
+		{
+			name:         "PUT fails with unknown user",
+			giveMethod:   "PUT",
+			giveRequest:  "/api/v1/users/" + unknownUserID,
+			giveBody:     `{"role": "admin"}`,
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserQuery).WithArgs(unknownUserID).WillReturnRows(sqlmock.NewRows(usersColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "PUT fails with invalid user ID",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/users/invaliduserid",
+			giveBody:         `{"role": "admin"}`,
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:         "DELETE user",
+			giveMethod:   "DELETE",
+			giveRequest:  "/api/v1/users/" + userID,
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserQuery).WithArgs(userID).WillReturnRows(userRow(models.VIEWER))
+				mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.DeleteUser])).
+					WithArgs(userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:         "DELETE fails with database error",
+			giveMethod:   "DELETE",
+			giveRequest:  "/api/v1/users/" + userID,
+			giveWithAuth: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getUserQuery).WithArgs(userID).WillReturnError(fmt.Errorf("database error"))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/users",
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return userID, nil }, UUIDValidFunc),
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				require.Contains(t, w.Body.String(), expected)
+			}
+			require.NotContains(t, w.Body.String(), `"hash"`)
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
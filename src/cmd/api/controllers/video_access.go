@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type VideoAccess int
+
+const (
+	// See the video, its streams and its metadata
+	VIEW_VIDEO VideoAccess = iota
+	// Edit, archive, delete the video and choose who can see it
+	MANAGE_VIDEO
+	// Create a video from it, a clip or a render, owned by the user. Only the public videos are open to
+	// every user, deriving a shared or unlisted video would let its copy be published.
+	DERIVE_VIDEO
+)
+
+// Uploads are private until their owner shares them
+const DefaultVisibility = models.PRIVATE
+
+// ownerIDOf returns the owner of the videos created by the user, nil for the service account
+func ownerIDOf(user *models.User) *string {
+	if user == nil || user.ID == "" {
+		return nil
+	}
+	id := user.ID
+	return &id
+}
+
+// parseVisibility returns the default visibility when none is given
+func parseVisibility(visibility string) (models.Visibility, error) {
+	if visibility == "" {
+		return DefaultVisibility, nil
+	}
+	return models.StringToVisibility(visibility)
+}
+
+func canManageVideo(user *models.User, video *models.Video) bool {
+	return user != nil && (user.Role.Includes(models.ADMIN) || video.IsOwnedBy(user))
+}
+
+func canViewVideo(ctx context.Context, videoSharesDAO *dao.VideoSharesDAO, user *models.User, video *models.Video) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	if canManageVideo(user, video) {
+		return true, nil
+	}
+
+	switch video.Visibility {
+	case models.PUBLIC, models.UNLISTED:
+		return true, nil
+	case models.SHARED:
+		if user.ID == "" {
+			return false, nil
+		}
+		return videoSharesDAO.IsVideoSharedWith(ctx, video.ID, user.ID)
+	default:
+		return false, nil
+	}
+}
+
+// checkVideoAccess returns 404 when the user cannot see the video, so that its existence is not disclosed,
+// and 403 when it can see but not manage or derive it
+func checkVideoAccess(ctx context.Context, videoSharesDAO *dao.VideoSharesDAO, user *models.User, video *models.Video, access VideoAccess) (int, error) {
+	canView, err := canViewVideo(ctx, videoSharesDAO, user, video)
+	if err != nil {
+		log.Error("Cannot check the shares of video "+video.ID+" : ", err)
+		return http.StatusInternalServerError, err
+	}
+	if !canView {
+		err := errors.New("user " + user.Username + " cannot see video " + video.ID)
+		log.Error(err)
+		return http.StatusNotFound, err
+	}
+
+	if access == DERIVE_VIDEO && video.Visibility != models.PUBLIC && !canManageVideo(user, video) {
+		err := errors.New("user " + user.Username + " cannot derive video " + video.ID)
+		log.Error(err)
+		return http.StatusForbidden, err
+	}
+
+	if access == MANAGE_VIDEO && !canManageVideo(user, video) {
+		err := errors.New("user " + user.Username + " cannot manage video " + video.ID)
+		log.Error(err)
+		return http.StatusForbidden, err
+	}
+
+	return 0, nil
+}
+
+// RequireVideoAccess only lets through the users having the access to the video of the route.
+// Admins have access to every video, their requests are not checked.
+func RequireVideoAccess(access VideoAccess, videosDAO *dao.VideosDAO, videoSharesDAO *dao.VideoSharesDAO, uuidGen clients.IUUIDGenerator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user == nil {
+			log.Error("Unauthenticated request")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if user.Role.Includes(models.ADMIN) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		if !uuidGen.IsValidUUID(id) {
+			log.Error("Invalid id")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		video, err := videosDAO.GetVideo(r.Context(), id)
+		if err != nil {
+			log.Error("Cannot found video : ", err)
+			if errors.Is(err, sql.ErrNoRows) {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if statusCode, err := checkVideoAccess(r.Context(), videoSharesDAO, user, video, access); err != nil {
+			w.WriteHeader(statusCode)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -12,12 +12,14 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
 type VideoArchiveHandler struct {
-	VideosDAO *dao.VideosDAO
-	UUIDGen   clients.IUUIDGenerator
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UUIDGen               clients.IUUIDGenerator
 }
 
 // VideoArchiveHandler godoc
@@ -58,6 +60,8 @@ func (v VideoArchiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(statusCode)
 		return
 	}
+
+	eventhandler.PublishVideoStatusEvent(v.AmqpVideoStatusUpdate, video, models.COMPLETE)
 }
 
 func (v VideoArchiveHandler) archiveVideo(ctx context.Context, video *models.Video) (int, error) {
@@ -112,7 +112,8 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 			defer db.Close()
 
 			routerClients := router.Clients{
-				UUIDGen: clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
 			}
 
 			dao_test.ExpectVideosDAOCreation(mock)
@@ -126,7 +127,7 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -136,7 +137,7 @@ func TestVideoArchive(t *testing.T) { //nolint:cyclop
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/archive" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath, nil, nil, "public")
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
 					if tt.status == models.COMPLETE {
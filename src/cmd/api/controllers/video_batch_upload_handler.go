@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type BatchUploadResult string
+
+const (
+	BATCH_CREATED                BatchUploadResult = "created"
+	BATCH_CONFLICT               BatchUploadResult = "conflict"
+	BATCH_UNSUPPORTED_MEDIA_TYPE BatchUploadResult = "unsupported_media_type"
+	BATCH_PAYLOAD_TOO_LARGE      BatchUploadResult = "payload_too_large"
+	BATCH_UNPROCESSABLE_ENTITY   BatchUploadResult = "unprocessable_entity"
+	BATCH_BAD_REQUEST            BatchUploadResult = "bad_request"
+	BATCH_ERROR                  BatchUploadResult = "error"
+)
+
+type VideoBatchUploadHandler struct {
+	Config                config.Config
+	S3Client              clients.IS3Client
+	AmqpClient            clients.AmqpClient
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UploadsDAO            *dao.UploadsDAO
+	StorageUsagesDAO      *dao.StorageUsagesDAO
+	SubtitlesDAO          *dao.SubtitlesDAO
+	UUIDGen               clients.IUUIDGenerator
+	VideoProber           clients.IVideoProber
+}
+
+type BatchUploadItemResponse struct {
+	Index  int                         `json:"index" example:"0"`
+	Title  string                      `json:"title" example:"A Title"`
+	Result BatchUploadResult           `json:"result" example:"created"`
+	Code   int                         `json:"code" example:"200"`
+	Error  *UploadLimitError           `json:"error,omitempty"`
+	Video  *jsonDTO.VideoJson          `json:"video,omitempty"`
+	Links  map[string]jsonDTO.LinkJson `json:"_links,omitempty"`
+}
+
+type BatchUploadResponse struct {
+	Items []BatchUploadItemResponse `json:"items"`
+}
+
+// batchUploadItem gathers all the parts related to one video of the batch
+type batchUploadItem struct {
+	title             string
+	video             *multipart.FileHeader
+	cover             *multipart.FileHeader
+	subtitles         *multipart.FileHeader
+	subtitlesLanguage string
+	subtitlesLabel    string
+	visibility        models.Visibility
+}
+
+// VideoBatchUploadHandler godoc
+// @Summary Upload several video files
+// @Description Upload several video files in a single request. The n-th "video" part is paired with the n-th "title" part.
+// @Description Cover and subtitles are optional and given for the n-th video as "cover[n]" and "subs[n]" (zero based),
+// @Description the subtitles being described by "subsLanguage[n]" and "subsLabel[n]".
+// @Description Each video is processed independently : a failing item does not roll back the others.
+// @Description The upload size limit applies to the whole request, the other limits are checked for each video.
+// @Tags video
+// @Accept multipart/form-data
+// @Produce json
+// @Param video formData file true "videos"
+// @Param title formData []string true "titles, in the same order as the videos"
+// @Param visibility formData string false "private (default), unlisted, shared or public, for all the videos"
+// @Success 200 {object} BatchUploadResponse "One result per video, in the same order as the videos"
+// @Failure 400 {string} string
+// @Failure 413 {object} UploadLimitError "Upload too large"
+// @Router /api/v1/videos/upload/batch [post]
+func (v VideoBatchUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST VideoBatchUploadHandler")
+
+	uploadHandler := VideoUploadHandler{
+		Config:                v.Config,
+		S3Client:              v.S3Client,
+		AmqpClient:            v.AmqpClient,
+		AmqpVideoStatusUpdate: v.AmqpVideoStatusUpdate,
+		VideosDAO:             v.VideosDAO,
+		UploadsDAO:            v.UploadsDAO,
+		StorageUsagesDAO:      v.StorageUsagesDAO,
+		SubtitlesDAO:          v.SubtitlesDAO,
+		UUIDGen:               v.UUIDGen,
+		VideoProber:           v.VideoProber,
+	}
+
+	if violation := uploadHandler.parseUploadForm(w, r); violation != nil {
+		writeUploadLimitError(w, violation)
+		return
+	}
+	defer func() {
+		_ = r.MultipartForm.RemoveAll()
+	}()
+
+	items, err := extractBatchUploadItems(r.MultipartForm)
+	if err != nil {
+		log.Error("Invalid batch upload request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	log.Infof("Receive batch upload request with %v videos", len(items))
+
+	// The visibility applies to all the videos of the batch
+	visibility, err := parseVisibility(r.FormValue("visibility"))
+	if err != nil {
+		log.Error("Invalid visibility : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range items {
+		items[i].visibility = visibility
+	}
+
+	uploader := uploaderName(r)
+	response := BatchUploadResponse{Items: make([]BatchUploadItemResponse, 0, len(items))}
+	for i, item := range items {
+		itemResponse := uploadHandler.uploadBatchItem(r.Context(), item, uploader)
+		itemResponse.Index = i
+		response.Items = append(response.Items, itemResponse)
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+func extractBatchUploadItems(form *multipart.Form) ([]batchUploadItem, error) {
+	videos := form.File["video"]
+	titles := form.Value["title"]
+	if len(videos) == 0 {
+		return nil, errors.New("no video part")
+	}
+	if len(videos) != len(titles) {
+		return nil, fmt.Errorf("got %v video parts but %v title parts", len(videos), len(titles))
+	}
+
+	items := make([]batchUploadItem, len(videos))
+	for i := range videos {
+		items[i] = batchUploadItem{
+			title: titles[i],
+			video: videos[i],
+		}
+		if covers := form.File[fmt.Sprintf("cover[%d]", i)]; len(covers) > 0 {
+			items[i].cover = covers[0]
+		}
+		if subtitles := form.File[fmt.Sprintf("subs[%d]", i)]; len(subtitles) > 0 {
+			items[i].subtitles = subtitles[0]
+		}
+		if languages := form.Value[fmt.Sprintf("subsLanguage[%d]", i)]; len(languages) > 0 {
+			items[i].subtitlesLanguage = languages[0]
+		}
+		if labels := form.Value[fmt.Sprintf("subsLabel[%d]", i)]; len(labels) > 0 {
+			items[i].subtitlesLabel = labels[0]
+		}
+	}
+
+	return items, nil
+}
+
+func batchItemFailure(title string, result BatchUploadResult, code int) BatchUploadItemResponse {
+	return BatchUploadItemResponse{
+		Title:  title,
+		Result: result,
+		Code:   code,
+	}
+}
+
+func batchSubtitlesFailure(title string, err error) BatchUploadItemResponse {
+	switch code := subtitlesErrorStatus(err); code {
+	case http.StatusUnsupportedMediaType:
+		return batchItemFailure(title, BATCH_UNSUPPORTED_MEDIA_TYPE, code)
+	case http.StatusUnprocessableEntity:
+		return batchItemFailure(title, BATCH_UNPROCESSABLE_ENTITY, code)
+	default:
+		return batchItemFailure(title, BATCH_BAD_REQUEST, code)
+	}
+}
+
+func batchItemLimitFailure(title string, violation *uploadLimitViolation) BatchUploadItemResponse {
+	result := BATCH_ERROR
+	switch violation.Code {
+	case http.StatusRequestEntityTooLarge:
+		result = BATCH_PAYLOAD_TOO_LARGE
+	case http.StatusUnprocessableEntity:
+		result = BATCH_UNPROCESSABLE_ENTITY
+	}
+
+	return BatchUploadItemResponse{
+		Title:  title,
+		Result: result,
+		Code:   violation.Code,
+		Error:  violation.Body,
+	}
+}
+
+func batchItemSuccess(video *models.Video) BatchUploadItemResponse {
+	videoJson := jsonDTO.VideoToVideoJson(video)
+	return BatchUploadItemResponse{
+		Title:  video.Title,
+		Result: BATCH_CREATED,
+		Code:   http.StatusOK,
+		Video:  &videoJson,
+		Links:  uploadedVideoLinks(video),
+	}
+}
+
+func (v VideoUploadHandler) uploadBatchItem(ctx context.Context, item batchUploadItem, uploader string) BatchUploadItemResponse { //nolint:cyclop
+	if item.title == "" {
+		log.Error("Missing title for batch item ", item.video.Filename)
+		return batchItemFailure(item.title, BATCH_BAD_REQUEST, http.StatusBadRequest)
+	}
+
+	fileVideo, err := item.video.Open()
+	if err != nil {
+		log.Error("Cannot open video part : ", err)
+		return batchItemFailure(item.title, BATCH_BAD_REQUEST, http.StatusBadRequest)
+	}
+	defer fileVideo.Close()
+
+	if !isSupportedVideoType(fileVideo) {
+		return batchItemFailure(item.title, BATCH_UNSUPPORTED_MEDIA_TYPE, http.StatusUnsupportedMediaType)
+	}
+
+	var fileCover multipart.File
+	if item.cover != nil {
+		fileCover, err = item.cover.Open()
+		if err != nil {
+			log.Error("Cannot open cover part : ", err)
+			return batchItemFailure(item.title, BATCH_BAD_REQUEST, http.StatusBadRequest)
+		}
+		defer fileCover.Close()
+
+		if !isSupportedCoverType(fileCover) {
+			return batchItemFailure(item.title, BATCH_UNSUPPORTED_MEDIA_TYPE, http.StatusUnsupportedMediaType)
+		}
+	}
+
+	var subtitle *models.Subtitle
+	var vtt []byte
+	if item.subtitles != nil {
+		subtitles, err := item.subtitles.Open()
+		if err != nil {
+			log.Error("Cannot open subtitles part : ", err)
+			return batchItemFailure(item.title, BATCH_BAD_REQUEST, http.StatusBadRequest)
+		}
+		defer subtitles.Close()
+
+		subtitle, vtt, err = readUploadedSubtitles(item.subtitlesLanguage, item.subtitlesLabel, subtitles, item.subtitles)
+		if err != nil {
+			return batchSubtitlesFailure(item.title, err)
+		}
+	}
+
+	if violation := v.checkUploadLimits(ctx, fileVideo, item.video.Size, uploader); violation != nil {
+		return batchItemLimitFailure(item.title, violation)
+	}
+
+	// Check if a video with this title already exists
+	video, err := v.VideosDAO.GetVideoFromTitle(ctx, item.title)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return batchItemFailure(item.title, BATCH_ERROR, http.StatusInternalServerError)
+	}
+	if video != nil {
+		if (video.Status != models.FAIL_UPLOAD && video.Status != models.FAIL_ENCODE) || !canManageVideo(UserFromContext(ctx), video) {
+			log.Errorf("A video with the title '%v' already uploaded and encoded", item.title)
+			return batchItemFailure(item.title, BATCH_CONFLICT, http.StatusConflict)
+		}
+
+		video, err = v.resumeVideoUpload(ctx, video, fileCover, fileVideo, item.cover, item.video.Size, uploader, subtitle, vtt)
+		if err != nil {
+			return batchItemFailure(item.title, BATCH_ERROR, http.StatusInternalServerError)
+		}
+		return batchItemSuccess(video)
+	}
+
+	videoID, err := v.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new video ID : ", err)
+		return batchItemFailure(item.title, BATCH_ERROR, http.StatusInternalServerError)
+	}
+
+	coverPath, err := v.uploadCover(ctx, fileCover, videoID, item.cover)
+	if err != nil {
+		log.Error("Cannot upload cover image : ", err)
+		return batchItemFailure(item.title, BATCH_ERROR, http.StatusInternalServerError)
+	}
+
+	videoPath := videoID + "/" + "source" + filepath.Ext(item.video.Filename)
+	videoCreated, err := v.uploadVideo(ctx, videoID, item.title, videoPath, coverPath, fileVideo, item.video.Size, nil, item.visibility)
+	if err != nil {
+		log.Error("Cannot upload video : ", err)
+		return batchItemFailure(item.title, BATCH_ERROR, http.StatusInternalServerError)
+	}
+	v.recordStorageUsage(ctx, videoCreated, uploader, item.video.Size)
+	v.addUploadedSubtitles(ctx, videoCreated, subtitle, vtt)
+
+	if err = v.sendVideoForEncoding(ctx, videoCreated); err != nil {
+		log.Error("Cannot send video for encoding : ", err)
+		return batchItemFailure(item.title, BATCH_ERROR, http.StatusInternalServerError)
+	}
+
+	log.Infof("Video '%v' successfully uploaded", item.title)
+	return batchItemSuccess(videoCreated)
+}
@@ -0,0 +1,277 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+// Webm magic number, enough to be detected as a video
+var webmHeader = []byte{
+	0x1a, 0x45, 0xdf, 0xa3, 0x9f, 0x42, 0x86, 0x81, 0x01, 0x42, 0xf7, 0x81, 0x01, 0x42, 0xf2, 0x81,
+	0x04, 0x42, 0xf3, 0x81, 0x08, 0x42, 0x82, 0x84, 0x77, 0x65, 0x62, 0x6d, 0x42, 0x87, 0x81, 0x02,
+	0x42, 0x85, 0x81, 0x02, 0x18, 0x53, 0x80, 0x67, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4a, 0xf7,
+}
+
+type batchItem struct {
+	title     string
+	isVideo   bool
+	alreadyIn bool
+	// The video exists with a failed encoding, its upload is resumed
+	failedEncode bool
+	// WebVTT subtitles given with the video
+	subtitles  string
+	wantResult controllers.BatchUploadResult
+	wantCode   int
+}
+
+func TestVideoBatchUploadHandler(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+	videoID := "AUniqueId"
+	vtt := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n"
+	subtitlePath := videoID + "/subtitles/" + videoID + ".vtt"
+
+	cases := []struct {
+		name             string
+		giveWithAuth     bool
+		giveItems        []batchItem
+		giveExtraTitle   bool
+		expectedHTTPCode int
+	}{
+		{
+			name:         "POST batch with one result per video",
+			giveWithAuth: true,
+			giveItems: []batchItem{
+				{title: "first-video", isVideo: true, wantResult: controllers.BATCH_CREATED, wantCode: 200},
+				{title: "existing-video", isVideo: true, alreadyIn: true, wantResult: controllers.BATCH_CONFLICT, wantCode: 409},
+				{title: "not-a-video", isVideo: false, wantResult: controllers.BATCH_UNSUPPORTED_MEDIA_TYPE, wantCode: 415},
+				{title: "", isVideo: true, wantResult: controllers.BATCH_BAD_REQUEST, wantCode: 400},
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:         "POST batch resuming a video with its subtitles",
+			giveWithAuth: true,
+			giveItems: []batchItem{
+				{title: "failed-video", isVideo: true, failedEncode: true, subtitles: vtt, wantResult: controllers.BATCH_CREATED, wantCode: 200},
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "POST batch fails with titles not matching videos",
+			giveWithAuth:     true,
+			giveItems:        []batchItem{{title: "first-video", isVideo: true}},
+			giveExtraTitle:   true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST batch fails with no video",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST batch fails with no auth",
+			giveWithAuth:     false,
+			giveItems:        []batchItem{{title: "first-video", isVideo: true}},
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			uploads := map[string]string{}
+			putObject := func(f io.Reader, s string) error {
+				content, err := io.ReadAll(f)
+				uploads[s] = string(content)
+				return err
+			}
+			s3Client := clients.NewS3ClientDummy(nil, nil, putObject, nil, nil)
+			amqpClient := clients.NewAmqpClientDummy(func(string, []byte) error { return nil }, nil, nil)
+			amqpVideoStatusUpdate := clients.NewAmqpClientDummy(nil, nil, nil)
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			routerClients := router.Clients{
+				S3Client:              s3Client,
+				AmqpClient:            amqpClient,
+				AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
+				UUIDGen:               clients.NewUuidGeneratorDummy(func() (string, error) { return videoID, nil }, nil),
+			}
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+			dao_test.ExpectStorageUsagesDAOCreation(mock)
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+
+			videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+			uploadsColumns := []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at"}
+			t1 := time.Now()
+			sourcePath := videoID + "/source.mp4"
+
+			if tt.giveWithAuth && !tt.giveExtraTitle {
+				for _, item := range tt.giveItems {
+					if !item.isVideo || item.title == "" {
+						continue
+					}
+
+					getVideoFromTitleQuery := mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs(item.title)
+					if item.alreadyIn {
+						getVideoFromTitleQuery.WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(videoID, item.title, models.COMPLETE, t1, t1, t1, sourcePath, "", nil, nil, "public"))
+						continue
+					}
+					if item.failedEncode {
+						getVideoFromTitleQuery.WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(videoID, item.title, models.FAIL_ENCODE, t1, t1, t1, sourcePath, "", nil, nil, "public"))
+
+						// The subtitles are added before the video is encoded again
+						mock.ExpectBegin()
+						mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.CreateSubtitle])).
+							WithArgs(videoID, videoID, "fr", "Subtitles", false, false, subtitlePath).
+							WillReturnResult(sqlmock.NewResult(1, 1))
+						mock.ExpectCommit()
+						mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+							WithArgs(item.title, models.ENCODING, AnyTime{}, sourcePath, "", videoID).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+						continue
+					}
+					getVideoFromTitleQuery.WillReturnRows(sqlmock.NewRows(videosColumns))
+
+					mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo])).
+						WithArgs(videoID, item.title, models.UPLOADING, sourcePath, "", nil, models.PRIVATE).
+						WillReturnResult(sqlmock.NewResult(1, 1))
+					mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(videoID).
+						WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(videoID, item.title, models.UPLOADING, nil, t1, t1, sourcePath, "", nil, nil, "public"))
+
+					mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.CreateUpload])).
+						WithArgs(videoID, videoID, models.STARTED).
+						WillReturnResult(sqlmock.NewResult(1, 1))
+					mock.ExpectQuery(regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])).WithArgs(videoID).
+						WillReturnRows(sqlmock.NewRows(uploadsColumns).AddRow(videoID, videoID, models.STARTED, nil, t1, t1))
+
+					mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+						WithArgs(item.title, models.UPLOADED, AnyTime{}, sourcePath, "", videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])).
+						WithArgs(videoID, models.DONE, AnyTime{}, videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(regexp.QuoteMeta(dao.StorageUsagesRequests[dao.CreateStorageUsage])).
+						WithArgs(videoID, givenUsername, 262).
+						WillReturnResult(sqlmock.NewResult(1, 1))
+					mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+						WithArgs(item.title, models.ENCODING, AnyTime{}, sourcePath, "", videoID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			}
+
+			// Dummy multipart body creation
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			for _, item := range tt.giveItems {
+				require.NoError(t, writer.WriteField("title", item.title))
+
+				fileWriter, err := writer.CreateFormFile("video", "video.mp4")
+				require.NoError(t, err)
+				content := make([]byte, 262)
+				if item.isVideo {
+					copy(content, webmHeader)
+				}
+				_, err = fileWriter.Write(content)
+				require.NoError(t, err)
+			}
+			for i, item := range tt.giveItems {
+				if item.subtitles == "" {
+					continue
+				}
+				require.NoError(t, writer.WriteField(fmt.Sprintf("subsLanguage[%d]", i), "fr"))
+				fileWriter, err := writer.CreateFormFile(fmt.Sprintf("subs[%d]", i), "subs.vtt")
+				require.NoError(t, err)
+				_, err = fileWriter.Write([]byte(item.subtitles))
+				require.NoError(t, err)
+			}
+			if tt.giveExtraTitle {
+				require.NoError(t, writer.WriteField("title", "extra-title"))
+			}
+			writer.Close()
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			storageUsagesDAO, err := dao.CreateStorageUsagesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:        *videosDAO,
+				UploadsDAO:       *uploadsDAO,
+				StorageUsagesDAO: *storageUsagesDAO,
+				SubtitlesDAO:     *subtitlesDAO,
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/upload/batch", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.BatchUploadResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Len(t, response.Items, len(tt.giveItems))
+				for i, item := range tt.giveItems {
+					require.Equal(t, i, response.Items[i].Index)
+					require.Equal(t, item.wantResult, response.Items[i].Result)
+					require.Equal(t, item.wantCode, response.Items[i].Code)
+					if item.wantResult == controllers.BATCH_CREATED {
+						require.NotNil(t, response.Items[i].Video)
+						require.Equal(t, videoID, response.Items[i].Video.ID)
+					}
+					if item.subtitles != "" {
+						require.Equal(t, item.subtitles, uploads[subtitlePath])
+					}
+				}
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	protobufDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type ClipRequest struct {
+	Title    string `json:"title" example:"Best goal"`
+	Start    string `json:"start" example:"00:01:12.5"`
+	End      string `json:"end" example:"90"`
+	Accurate bool   `json:"accurate" example:"false"`
+}
+
+type ClipResponse struct {
+	Video *jsonDTO.VideoJson          `json:"video,omitempty"`
+	Clip  jsonDTO.ClipJson            `json:"clip"`
+	Links map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type ClipsListResponse struct {
+	Clips []ClipResponse `json:"clips"`
+}
+
+type VideoClipsCreateHandler struct {
+	AmqpClient            clients.AmqpClient
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	ClipsDAO              *dao.ClipsDAO
+	UUIDGen               clients.IUUIDGenerator
+}
+
+// VideoClipsCreateHandler godoc
+// @Summary Cut a clip from a video
+// @Description Create a new video from the [start, end] part of the video source, then encode it.
+// @Description Timestamps are seconds or [HH:]MM:SS[.mmm]. The cut is keyframe-accurate, unless accurate is set: the clip is then re-encoded to start on the exact frame.
+// @Tags video, clips
+// @Accept json
+// @Produce json
+// @Param id path string true "Parent video ID"
+// @Param clip body ClipRequest true "Clip title and boundaries"
+// @Success 200 {object} ClipResponse "Clip video and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string "This title already exists, or the parent source is not uploaded"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/clips [post]
+func (v VideoClipsCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("POST VideoClipsCreateHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request ClipRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode clip request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	clip, err := parseClipRequest(request)
+	if err != nil {
+		log.Error("Invalid clip request : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	clip.ParentID = &id
+
+	parent, video, ok := createDerivedVideo(w, r, v.VideosDAO, v.UUIDGen, id, request.Title)
+	if !ok {
+		return
+	}
+	clip.VideoID = video.ID
+
+	if err := v.ClipsDAO.CreateClip(r.Context(), clip); err != nil {
+		log.Error("Cannot create clip : ", err)
+		removeDerivedVideo(r, v.VideosDAO, video)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	videoProto := protobufDTO.VideoToVideoProtobuf(video)
+	videoProto.Clip = protobufDTO.ClipToClipProtobuf(clip, parent.SourcePath)
+	if err := sendDerivedVideoForEncoding(r, v.AmqpClient, v.AmqpVideoStatusUpdate, v.VideosDAO, video, videoProto); err != nil {
+		log.Error("Cannot send clip for encoding : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := clipToResponse(clip)
+	videoJson := jsonDTO.VideoToVideoJson(video)
+	response.Video = &videoJson
+	writeJSON(w, response)
+	log.Infof("Clip '%v' of video %v sent for encoding", request.Title, id)
+}
+
+type VideoClipsListHandler struct {
+	VideosDAO *dao.VideosDAO
+	ClipsDAO  *dao.ClipsDAO
+	UUIDGen   clients.IUUIDGenerator
+}
+
+// VideoClipsListHandler godoc
+// @Summary List video clips
+// @Description List the clips cut from a video
+// @Tags video, clips
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} ClipsListResponse "Clips and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/clips [get]
+func (v VideoClipsListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoClipsListHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if statusCode, err := checkVideoExists(r.Context(), v.VideosDAO, id); err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	// The derived videos the user cannot see are left out, as in the videos list
+	user := UserFromContext(r.Context())
+	userID, seeAll := "", false
+	if user != nil {
+		userID, seeAll = user.ID, user.Role.Includes(models.ADMIN)
+	}
+
+	videoClips, err := v.ClipsDAO.GetVideoClips(r.Context(), id, userID, seeAll)
+	if err != nil {
+		log.Error("Cannot get clips of video "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := ClipsListResponse{Clips: make([]ClipResponse, 0, len(videoClips))}
+	for i := range videoClips {
+		response.Clips = append(response.Clips, clipToResponse(&videoClips[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+func clipToResponse(clip *models.Clip) ClipResponse {
+	links := map[string]jsonDTO.LinkJson{
+		"info":   jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + clip.VideoID + "/info", Method: "GET"}),
+		"status": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + clip.VideoID + "/status", Method: "GET"}),
+		"stream": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + clip.VideoID + "/streams/master.m3u8", Method: "GET"}),
+	}
+	if clip.ParentID != nil {
+		links["parent"] = jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + *clip.ParentID + "/info", Method: "GET"})
+	}
+
+	return ClipResponse{
+		Clip:  jsonDTO.ClipToClipJson(clip),
+		Links: links,
+	}
+}
+
+// parseClipRequest validates the title and the boundaries of a clip
+func parseClipRequest(request ClipRequest) (*models.Clip, error) {
+	if strings.TrimSpace(request.Title) == "" {
+		return nil, errors.New("missing title")
+	}
+
+	start, err := parseTimestamp(request.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start : %w", err)
+	}
+	end, err := parseTimestamp(request.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end : %w", err)
+	}
+	if end <= start {
+		return nil, errors.New("end must be after start")
+	}
+
+	return &models.Clip{Start: start, End: end, Accurate: request.Accurate}, nil
+}
+
+// parseTimestamp returns the seconds of a timestamp given as seconds ("72.5") or [HH:]MM:SS[.mmm] ("01:12.5")
+func parseTimestamp(timestamp string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(timestamp), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%q is not a timestamp", timestamp)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) || (len(parts) > 1 && seconds >= 60) {
+		return 0, fmt.Errorf("%q is not a timestamp", timestamp)
+	}
+
+	// Hours then minutes, a minute only has 60 seconds when there are hours
+	multiplier := 60.0
+	for i := len(parts) - 2; i >= 0; i-- {
+		value, err := strconv.ParseUint(parts[i], 10, 32)
+		if err != nil || (i > 0 && value >= 60) {
+			return 0, fmt.Errorf("%q is not a timestamp", timestamp)
+		}
+		seconds += float64(value) * multiplier
+		multiplier *= 60
+	}
+	return seconds, nil
+}
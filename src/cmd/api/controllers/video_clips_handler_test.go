@@ -0,0 +1,323 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+	"github.com/Sogilis/Voogle/src/pkg/events"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoClips(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	parentID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	clipID := "2d0f9a40-3a6c-4a3e-a3a4-5b8e2f0c6a51"
+	parentSource := parentID + "/source.mkv"
+	clipSource := clipID + "/source.mkv"
+	clipTitle := "Best goal"
+	t1 := time.Now()
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	clipsColumns := []string{"video_id", "parent_id", "start_seconds", "end_seconds", "accurate", "created_at"}
+	expectParent := func(mock sqlmock.Sqlmock, status models.VideoStatus) {
+		rows := sqlmock.NewRows(videosColumns).AddRow(parentID, "title", int(status), t1, t1, t1, parentSource, "", nil, nil, "public")
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(parentID).WillReturnRows(rows)
+	}
+	expectClipCreation := func(mock sqlmock.Sqlmock) {
+		expectParent(mock, models.COMPLETE)
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs(clipTitle).
+			WillReturnRows(sqlmock.NewRows(videosColumns))
+		mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo])).
+			WithArgs(clipID, clipTitle, int(models.UPLOADED), clipSource, "", nil, models.PRIVATE).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(clipID).
+			WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(clipID, clipTitle, int(models.UPLOADED), nil, t1, t1, clipSource, "", nil, nil, "public"))
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveWithAuth     bool
+		giveBody         string
+		givePublishErr   bool
+		expectQueries    func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedClip     *contracts.Clip
+		expectedClips    int
+	}{
+		{
+			name:         "POST clip sent for encoding",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Best goal", "start": "00:00:12.5", "end": "42"}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectClipCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.ClipsRequests[dao.CreateClip])).
+					WithArgs(clipID, parentID, 12.5, 42.0, false).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+					WithArgs(clipTitle, int(models.ENCODING), AnyTime{}, clipSource, "", clipID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedClip:     &contracts.Clip{ParentSource: parentSource, Start: 12.5, End: 42},
+		},
+		{
+			name:         "POST frame-accurate clip",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Best goal", "start": "1:02", "end": "1:03.040", "accurate": true}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectClipCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.ClipsRequests[dao.CreateClip])).
+					WithArgs(clipID, parentID, 62.0, 63.04, true).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+					WithArgs(clipTitle, int(models.ENCODING), AnyTime{}, clipSource, "", clipID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedClip:     &contracts.Clip{ParentSource: parentSource, Start: 62, End: 63.04, Accurate: true},
+		},
+		{
+			name:         "POST fails when the clip cannot be recorded",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectClipCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.ClipsRequests[dao.CreateClip])).WillReturnError(fmt.Errorf("database error"))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])).WithArgs(clipID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:           "POST fails when the clip cannot be sent for encoding",
+			giveMethod:     http.MethodPost,
+			giveRequest:    "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth:   true,
+			giveBody:       `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			givePublishErr: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectClipCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.ClipsRequests[dao.CreateClip])).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+					WithArgs(clipTitle, int(models.FAIL_ENCODE), AnyTime{}, clipSource, "", clipID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:         "POST fails with existing title",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, models.COMPLETE)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs(clipTitle).
+					WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(clipID, clipTitle, int(models.COMPLETE), t1, t1, t1, clipSource, "", nil, nil, "public"))
+			},
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "POST fails when the parent source is not uploaded",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			expectQueries:    func(mock sqlmock.Sqlmock) { expectParent(mock, models.FAIL_UPLOAD) },
+			expectedHTTPCode: 409,
+		},
+		{
+			name:         "POST fails with unknown parent",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(parentID).WillReturnRows(sqlmock.NewRows(videosColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "POST fails with end before start",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Best goal", "start": "42", "end": "12.5"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid timestamp",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Best goal", "start": "00:75:00", "end": "01:30:00"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails without title",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth:     true,
+			giveBody:         `{"start": "12.5", "end": "42"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid body",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth:     true,
+			giveBody:         `{"title": `,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid video ID",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/invalidvideoid/clips",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with no auth",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/clips",
+			giveBody:         `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:         "GET clips list",
+			giveMethod:   http.MethodGet,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, models.COMPLETE)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ClipsRequests[dao.GetVideoClips])).WithArgs(parentID, true, "", "").
+					WillReturnRows(sqlmock.NewRows(clipsColumns).AddRow(clipID, parentID, 12.5, 42.0, false, t1))
+			},
+			expectedHTTPCode: 200,
+			expectedClips:    1,
+		},
+		{
+			name:         "GET clips list fails with unknown video",
+			giveMethod:   http.MethodGet,
+			giveRequest:  "/api/v1/videos/" + parentID + "/clips",
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(parentID).WillReturnRows(sqlmock.NewRows(videosColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var published *contracts.Video
+			publish := func(queue string, message []byte) error {
+				if tt.givePublishErr {
+					return fmt.Errorf("Cannot publish to rabbitmq")
+				}
+				require.Equal(t, events.VideoUploaded, queue)
+				published = &contracts.Video{}
+				return proto.Unmarshal(message, published)
+			}
+
+			routerClients := router.Clients{
+				AmqpClient:            clients.NewAmqpClientDummy(publish, nil, nil),
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen: clients.NewUuidGeneratorDummy(
+					func() (string, error) { return clipID, nil },
+					func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectClipsDAOCreation(mock)
+			if tt.expectQueries != nil {
+				tt.expectQueries(mock)
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			clipsDAO, err := dao.CreateClipsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO: *videosDAO,
+				ClipsDAO:  *clipsDAO,
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedClip != nil {
+				require.NotNil(t, published)
+				require.Equal(t, clipID, published.Id)
+				require.Equal(t, clipSource, published.Source)
+				require.True(t, proto.Equal(tt.expectedClip, published.Clip))
+
+				var response controllers.ClipResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, clipID, response.Clip.VideoID)
+				require.Equal(t, parentID, *response.Clip.ParentID)
+				require.Equal(t, "api/v1/videos/"+parentID+"/info", response.Links["parent"].Href)
+				require.NotNil(t, response.Video)
+			}
+			if tt.expectedClips > 0 {
+				var response controllers.ClipsListResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Len(t, response.Clips, tt.expectedClips)
+				require.Equal(t, clipID, response.Clips[0].Clip.VideoID)
+			}
+
+			// we make sure that all expectations were met
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,16 +1,32 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
 )
 
+// Covers can be replaced by an edit, so they are not cached forever
+const coverCacheControl = "public, max-age=3600"
+
+var coverContentTypes = map[string]string{
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+}
+
 type VideoCoverHandler struct {
 	S3Client  clients.IS3Client
 	VideosDAO *dao.VideosDAO
@@ -18,13 +34,15 @@ type VideoCoverHandler struct {
 }
 
 // VideoCoverHandler godoc
-// @Summary Get video cover image in base64
-// @Description Get video cover image in base64
+// @Summary Get video cover image
+// @Description Get video cover image. Without size, the original cover is returned.
+// @Description The cover is the uploaded one, or a frame of the video generated by the encoder.
 // @Tags video
-// @Accept plain
-// @Produce plain
+// @Produce image/jpeg,image/png,image/webp
 // @Param id path string true "Video ID"
-// @Success 200 {string} string "video cover image in base64"
+// @Param size query string false "small, medium or large"
+// @Param format query string false "jpeg or webp, negotiated with the Accept header by default"
+// @Success 200 {file} binary "video cover image"
 // @Failure 400 {string} string
 // @Failure 404 {string} string
 // @Failure 500 {string} string
@@ -40,6 +58,13 @@ func (v VideoCoverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	size, format, ok := coverVariant(r)
+	if !ok {
+		log.Error("Invalid cover size or format : ", r.URL.RawQuery)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	// Fetch video cover path from DB
 	video, err := v.VideosDAO.GetVideo(r.Context(), id)
 	if err != nil {
@@ -48,27 +73,94 @@ func (v VideoCoverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If there is no cover file, return nothing, but no error
-	if video.CoverPath != "" {
-		if r.Method == "head" {
-			w.WriteHeader(http.StatusOK)
-			return
+	// No cover uploaded, and the encoder did not generate one yet
+	if video.CoverPath == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	coverPath := video.CoverPath
+	var object io.Reader
+	if size != "" {
+		// Covers of videos encoded before the sizes were generated, or replaced since, only exist in their original size
+		coverPath = id + "/" + ffmpeg.CoverVariantPath(size, format)
+		object, err = v.S3Client.GetObject(r.Context(), coverPath)
+		if err != nil {
+			log.Debug("Cover "+coverPath+" not found, fallback on the original cover : ", err)
+			coverPath = video.CoverPath
 		}
-		// Fetch cover image from S3
-		object, err := v.S3Client.GetObject(r.Context(), video.CoverPath)
+	}
+
+	// Fetch cover image from S3
+	if object == nil {
+		object, err = v.S3Client.GetObject(r.Context(), coverPath)
 		if err != nil {
-			log.Error("Failed to open video cover "+video.CoverPath+": ", err)
+			log.Error("Failed to open video cover "+coverPath+": ", err)
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/octet-stream")
+	}
 
-		if _, err := io.Copy(w, object); err != nil {
-			log.Error("Unable to stream cover", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+	// Covers are small enough to be served from memory
+	content, err := io.ReadAll(object)
+	if err != nil {
+		log.Error("Unable to read cover", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	contentType, ok := coverContentTypes[strings.ToLower(filepath.Ext(coverPath))]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", coverCacheControl)
+	w.Header().Set("Vary", "Accept")
+
+	modTime := time.Time{}
+	if video.UpdatedAt != nil {
+		modTime = *video.UpdatedAt
+	}
+	// Handles HEAD and If-Modified-Since requests
+	http.ServeContent(w, r, filepath.Base(coverPath), modTime, bytes.NewReader(content))
+}
+
+// removeCoverVariants deletes the sizes generated from a replaced cover, the new one is then served instead
+func removeCoverVariants(ctx context.Context, s3Client clients.IS3Client, videoID string) {
+	for _, size := range ffmpeg.CoverSizes {
+		for _, format := range ffmpeg.CoverFormats {
+			coverPath := videoID + "/" + ffmpeg.CoverVariantPath(size.Name, format)
+			if err := s3Client.RemoveObject(ctx, coverPath); err != nil {
+				log.Error("Unable to remove cover "+coverPath+" : ", err)
+			}
 		}
 	}
-	w.WriteHeader(http.StatusNotFound)
+}
+
+// coverVariant returns the requested size and format, an empty size means the original cover
+func coverVariant(r *http.Request) (string, string, bool) {
+	size := r.URL.Query().Get("size")
+	if size != "" {
+		found := false
+		for _, coverSize := range ffmpeg.CoverSizes {
+			found = found || coverSize.Name == size
+		}
+		if !found {
+			return "", "", false
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "jpeg"
+		if strings.Contains(r.Header.Get("Accept"), "image/webp") {
+			format = "webp"
+		}
+	case "jpeg", "webp":
+	default:
+		return "", "", false
+	}
+
+	return size, format, true
 }
@@ -40,20 +40,80 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 	sourcePath := validVideoID + "/" + "source.mp4"
 	coverPath := validVideoID + "/" + "cover.jpg"
 
+	getCoverS3 := func(v string) (io.Reader, error) {
+		if v == validVideoID+"/covers/small.webp" || v == coverPath {
+			return strings.NewReader(v), nil
+		}
+		return nil, fmt.Errorf("S3 error")
+	}
+
 	cases := []struct {
-		name             string
-		giveRequest      string
-		giveWithAuth     bool
-		giveDatabaseErr  bool
-		expectedHTTPCode int
-		isValidUUID      func(string) bool
-		getObject        func(string) (io.Reader, error)
+		name                string
+		giveRequest         string
+		giveWithAuth        bool
+		giveAccept          string
+		giveDatabaseErr     bool
+		giveNoCover         bool
+		expectedHTTPCode    int
+		expectedContentType string
+		expectedBody        string
+		isValidUUID         func(string) bool
+		getObject           func(string) (io.Reader, error)
 	}{
 		{
-			name:             "GET video cover",
+			name:                "GET video cover",
+			giveRequest:         "/api/v1/videos/" + validVideoID + "/cover",
+			giveWithAuth:        true,
+			expectedHTTPCode:    200,
+			expectedContentType: "image/jpeg",
+			isValidUUID:         UUIDValidFunc,
+			getObject:           getObjectS3,
+		},
+		{
+			name:                "GET public video cover",
+			giveRequest:         "/videos/" + validVideoID + "/cover" + streamTokenQuery(validVideoID),
+			giveWithAuth:        false,
+			expectedHTTPCode:    200,
+			expectedContentType: "image/jpeg",
+			isValidUUID:         UUIDValidFunc,
+			getObject:           getObjectS3,
+		},
+		{
+			name:                "GET video cover size negotiated in WebP",
+			giveRequest:         "/api/v1/videos/" + validVideoID + "/cover?size=small",
+			giveWithAuth:        true,
+			giveAccept:          "image/avif,image/webp,*/*",
+			expectedHTTPCode:    200,
+			expectedContentType: "image/webp",
+			expectedBody:        validVideoID + "/covers/small.webp",
+			isValidUUID:         UUIDValidFunc,
+			getObject:           getCoverS3,
+		},
+		{
+			name:                "GET video cover size falls back on the original cover",
+			giveRequest:         "/api/v1/videos/" + validVideoID + "/cover?size=large&format=jpeg",
+			giveWithAuth:        true,
+			giveAccept:          "image/webp",
+			expectedHTTPCode:    200,
+			expectedContentType: "image/jpeg",
+			expectedBody:        coverPath,
+			isValidUUID:         UUIDValidFunc,
+			getObject:           getCoverS3,
+		},
+		{
+			name:             "GET fails with invalid size",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/cover?size=huge",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			isValidUUID:      UUIDValidFunc,
+			getObject:        getObjectS3,
+		},
+		{
+			name:             "GET fails with video without cover",
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/cover",
 			giveWithAuth:     true,
-			expectedHTTPCode: 200,
+			giveNoCover:      true,
+			expectedHTTPCode: 404,
 			isValidUUID:      UUIDValidFunc,
 			getObject:        getObjectS3,
 		},
@@ -122,7 +182,7 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 				UUIDGen:  clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
 			}
 
-			if !tt.giveWithAuth || tt.giveRequest == "/api/v1/videos/"+invalidVideoID+"/cover" {
+			if tt.expectedHTTPCode == 401 || tt.expectedHTTPCode == 400 {
 				// All these cases will stop before modifying the database : Nothing to do
 
 			} else {
@@ -130,7 +190,7 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -139,15 +199,20 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/cover" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
+				} else if tt.giveNoCover {
+					videosRows.AddRow(validVideoID, videoTitle, int(models.ENCODING), t1, t1, nil, sourcePath, "", nil, nil, "public")
+					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath, nil, nil, "public")
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				}
 			}
 
 			r := router.NewRouter(config.Config{
-				UserAuth: givenUsername,
-				PwdAuth:  givenUserPwd,
+				UserAuth:          givenUsername,
+				PwdAuth:           givenUserPwd,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
 			}, &routerClients, &routerDAO)
 
 			w := httptest.NewRecorder()
@@ -156,9 +221,19 @@ func TestVideoCover(t *testing.T) { //nolint:cyclop
 			if tt.giveWithAuth {
 				req.SetBasicAuth(givenUsername, givenUserPwd)
 			}
+			if tt.giveAccept != "" {
+				req.Header.Set("Accept", tt.giveAccept)
+			}
 
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			if tt.expectedContentType != "" {
+				require.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+				require.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+			}
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
 		})
 	}
 
@@ -12,14 +12,16 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
 type VideoDeleteHandler struct {
-	S3Client   clients.IS3Client
-	VideosDAO  *dao.VideosDAO
-	UploadsDAO *dao.UploadsDAO
-	UUIDGen    clients.IUUIDGenerator
+	S3Client              clients.IS3Client
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UploadsDAO            *dao.UploadsDAO
+	UUIDGen               clients.IUUIDGenerator
 }
 
 // VideoDeleteHandler godoc
@@ -67,6 +69,7 @@ func (v VideoDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(statusCode)
 		return
 	}
+	eventhandler.PublishVideoEvent(v.AmqpVideoStatusUpdate, models.DELETED_EVENT, video, 0)
 
 	if err = v.S3Client.RemoveObject(r.Context(), id); err != nil {
 		log.Error("Cannot remove video "+id+" from S3 : ", err)
@@ -136,8 +136,9 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 			defer db.Close()
 
 			routerClients := router.Clients{
-				S3Client: s3Client,
-				UUIDGen:  clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
+				S3Client:              s3Client,
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
 			}
 
 			dao_test.ExpectVideosDAOCreation(mock)
@@ -154,7 +155,7 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 				getVideoFromIdQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				if tt.giveDatabaseErr {
@@ -165,10 +166,10 @@ func TestVideoDelete(t *testing.T) { //nolint:cyclop
 
 				} else {
 					if tt.giveVideoNotArchived {
-						videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath)
+						videosRows.AddRow(validVideoID, videoTitle, int(models.COMPLETE), t1, t1, nil, sourcePath, coverPath, nil, nil, "public")
 						mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 					} else {
-						videosRows.AddRow(validVideoID, videoTitle, int(models.ARCHIVE), t1, t1, nil, sourcePath, coverPath)
+						videosRows.AddRow(validVideoID, videoTitle, int(models.ARCHIVE), t1, t1, nil, sourcePath, coverPath, nil, nil, "public")
 						mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
 						mock.ExpectBegin()
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Subscription to the events of every video the user can list
+const AllVideos = "all"
+
+// videoSubscriptions are the videos whose events are sent to a client
+type videoSubscriptions struct {
+	mutex    sync.Mutex
+	all      bool
+	videoIDs map[string]struct{}
+}
+
+func newVideoSubscriptions() *videoSubscriptions {
+	return &videoSubscriptions{videoIDs: map[string]struct{}{}}
+}
+
+func (s *videoSubscriptions) subscribe(videoID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if videoID == AllVideos {
+		s.all = true
+	} else {
+		s.videoIDs[videoID] = struct{}{}
+	}
+}
+
+func (s *videoSubscriptions) unsubscribe(videoID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if videoID == AllVideos {
+		s.all = false
+	} else {
+		delete(s.videoIDs, videoID)
+	}
+}
+
+// subscribed returns whether the client subscribed to the video itself, and to every video
+func (s *videoSubscriptions) subscribed(videoID string) (video bool, all bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, video = s.videoIDs[videoID]
+	return video, s.all
+}
+
+// checkVideoSubscription returns 400 when the video ID is invalid, 404 when the video does not exist or the user
+// cannot see it, so that its existence is not disclosed, and 500 on database errors
+func checkVideoSubscription(ctx context.Context, videosDAO *dao.VideosDAO, videoSharesDAO *dao.VideoSharesDAO, uuidGen clients.IUUIDGenerator, user *models.User, videoID string) (int, error) {
+	if videoID == AllVideos {
+		return http.StatusOK, nil
+	}
+	if !uuidGen.IsValidUUID(videoID) {
+		log.Error("Invalid video id ", videoID)
+		return http.StatusBadRequest, errors.New("invalid video id " + videoID)
+	}
+
+	video, err := videosDAO.GetVideo(ctx, videoID)
+	if err != nil {
+		log.Error("Cannot get video "+videoID+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	canView, err := canViewVideo(ctx, videoSharesDAO, user, video)
+	if err != nil {
+		log.Error("Cannot check the shares of video "+videoID+" : ", err)
+		return http.StatusInternalServerError, err
+	}
+	if !canView {
+		log.Errorf("User %v cannot see video %v", user.Username, videoID)
+		return http.StatusNotFound, errors.New("video not found")
+	}
+
+	return http.StatusOK, nil
+}
+
+// videoSubscriptionError describes the status code of checkVideoSubscription
+func videoSubscriptionError(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "videoId must be a video ID or \"" + AllVideos + "\""
+	case http.StatusNotFound:
+		return "video not found"
+	default:
+		return "internal error"
+	}
+}
+
+// isEventSent returns whether the client subscribed to the video and can see it. With the subscription
+// to every video, the unlisted videos of the other users are left out.
+func isEventSent(ctx context.Context, videoSharesDAO *dao.VideoSharesDAO, user *models.User, subscriptions *videoSubscriptions, event *models.VideoEvent) bool {
+	video, all := subscriptions.subscribed(event.Video.ID)
+	if !video && !all {
+		return false
+	}
+	if !video && event.Video.Visibility == models.UNLISTED && !canManageVideo(user, &event.Video) {
+		return false
+	}
+
+	canView, err := canViewVideo(ctx, videoSharesDAO, user, &event.Video)
+	if err != nil {
+		log.Error("Cannot check the shares of video "+event.Video.ID+" : ", err)
+		return false
+	}
+	return canView
+}
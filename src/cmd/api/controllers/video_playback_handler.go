@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+	"github.com/Sogilis/Voogle/src/pkg/streamtoken"
+)
+
+type PlaybackResponse struct {
+	Token     string                      `json:"token" example:"19052.1652180457.0.mJ4wAa..."`
+	ExpiresAt time.Time                   `json:"expiresAt" example:"2022-05-10T12:00:57Z"`
+	Links     map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type VideoGetPlaybackHandler struct {
+	VideosDAO    *dao.VideosDAO
+	UUIDGen      clients.IUUIDGenerator
+	StreamSigner *streamtoken.Signer
+}
+
+// VideoGetPlaybackHandler godoc
+// @Summary Get video playback links
+// @Description Mint a signed and time-limited token giving access to the public playback routes of the video, and the links carrying it
+// @Tags video
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param watermarkImage query string false "Path on S3 of the image stamped on the segments, under watermarks/, bound to the token"
+// @Param watermarkText query string false "Text stamped on the segments, bound to the token"
+// @Param watermarkPosition query string false "top-left, top-right, bottom-left, bottom-right (default) or center"
+// @Param watermarkOpacity query number false "Opacity of the watermark, from 0 to 1 (0.5 by default)"
+// @Success 200 {object} PlaybackResponse "Token and playback links"
+// @Failure 400 {string} string "Invalid ID or watermark"
+// @Failure 403 {string} string "Archived video"
+// @Failure 404 {string} string
+// @Failure 409 {string} string "Video not encoded yet"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/playback [get]
+func (v VideoGetPlaybackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetPlaybackHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// The watermark is bound to the token, the segments are stamped with it and cannot be requested without it
+	watermark := WatermarkQuery(r.URL.Query())
+	if watermark != "" {
+		if _, err := parseWatermark(r.URL.Query(), []string{watermarkTransformer}); err != nil {
+			log.Error("Invalid watermark : ", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	video, err := v.VideosDAO.GetVideo(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch video.Status {
+	case models.COMPLETE:
+	case models.ARCHIVE:
+		log.Error("Cannot play archived video " + id)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	default:
+		log.Error("Cannot play video "+id+" with status ", video.Status)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	token, expiresAt := v.StreamSigner.Sign(id, streamtoken.ClientIP(r), watermark)
+	response := PlaybackResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.UTC(),
+		Links: map[string]jsonDTO.LinkJson{
+			"stream":     signedLink(id, "/streams/master.m3u8", token, watermark),
+			"dash":       signedLink(id, "/streams/"+ffmpeg.DASHManifest, token, watermark),
+			"cover":      signedLink(id, "/cover", token, watermark),
+			"preview":    signedLink(id, "/preview", token, watermark),
+			"thumbnails": signedLink(id, "/"+ffmpeg.ThumbnailsFolder+"/"+ffmpeg.ThumbnailsVTT, token, watermark),
+		},
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Every request gets its own token
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
+
+// signedLink returns the link to a public route of the video, carrying the stream token and the watermark it is bound to
+func signedLink(videoID, route, token, watermark string) jsonDTO.LinkJson {
+	query, _ := url.ParseQuery(watermark)
+	query.Set(streamtoken.QueryParam, token)
+	return jsonDTO.LinkToLinkJson(models.CreateLink("videos/"+videoID+route+"?"+query.Encode(), "GET"))
+}
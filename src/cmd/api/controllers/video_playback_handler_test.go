@@ -0,0 +1,298 @@
+package controllers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/streamtoken"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+const givenStreamTokenSecret = "stream-token-secret"
+
+// streamTokenQuery returns the query giving access to the public routes of the video
+func streamTokenQuery(videoID string) string {
+	token, _ := streamtoken.NewSigner([]byte(givenStreamTokenSecret), time.Hour, 0, false).Sign(videoID, "", "")
+	return "?" + streamtoken.QueryParam + "=" + url.QueryEscape(token)
+}
+
+// previewTokenQuery returns the query of the videos lists, only giving access to the cover and the preview
+func previewTokenQuery(videoID string) string {
+	token, _ := streamtoken.NewSigner([]byte(givenStreamTokenSecret), time.Hour, 0, false).Sign(videoID, "", controllers.PreviewTokenBinding)
+	return "?" + streamtoken.QueryParam + "=" + url.QueryEscape(token)
+}
+
+func TestVideoPlayback(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	invalidVideoID := "invalidvideoid"
+	t1 := time.Now()
+
+	videoRow := func(status models.VideoStatus) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}).
+			AddRow(validVideoID, "title", int(status), &t1, &t1, &t1, validVideoID+"/source.mp4", "", nil, nil, "public")
+	}
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveVideo        *sqlmock.Rows
+		giveDatabaseErr  error
+		expectedHTTPCode int
+		// Watermark the token is bound to
+		expectedWatermark string
+	}{
+		{
+			name:             "GET playback of complete video",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback",
+			giveWithAuth:     true,
+			giveVideo:        videoRow(models.COMPLETE),
+			expectedHTTPCode: 200,
+		},
+		{
+			name:              "GET playback with watermark",
+			giveRequest:       "/api/v1/videos/" + validVideoID + "/playback?watermarkText=viewer%40example.com&watermarkPosition=top-left",
+			giveWithAuth:      true,
+			giveVideo:         videoRow(models.COMPLETE),
+			expectedHTTPCode:  200,
+			expectedWatermark: "watermarkPosition=top-left&watermarkText=viewer%40example.com",
+		},
+		{
+			name:             "GET fails with invalid watermark",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback?watermarkPosition=middle",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with archived video",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback",
+			giveWithAuth:     true,
+			giveVideo:        videoRow(models.ARCHIVE),
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with encoding video",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback",
+			giveWithAuth:     true,
+			giveVideo:        videoRow(models.ENCODING),
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "GET fails with unknown video",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback",
+			giveWithAuth:     true,
+			giveDatabaseErr:  sql.ErrNoRows,
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with database error",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback",
+			giveWithAuth:     true,
+			giveDatabaseErr:  errors.New("Server Error"),
+			expectedHTTPCode: 500,
+		},
+		{
+			name:             "GET fails with invalid video ID",
+			giveRequest:      "/api/v1/videos/" + invalidVideoID + "/playback",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/playback",
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			if tt.giveVideo != nil {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(validVideoID).WillReturnRows(tt.giveVideo)
+			} else if tt.giveDatabaseErr != nil {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(validVideoID).WillReturnError(tt.giveDatabaseErr)
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r := router.NewRouter(config.Config{
+				UserAuth:          givenUsername,
+				PwdAuth:           givenUserPwd,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
+			}, &routerClients, &router.DAOs{VideosDAO: *videosDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.PlaybackResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+				require.NoError(t, streamtoken.NewSigner([]byte(givenStreamTokenSecret), time.Hour, 0, false).Verify(response.Token, validVideoID, "", tt.expectedWatermark))
+				require.WithinDuration(t, time.Now().Add(time.Hour), response.ExpiresAt, 2*time.Second)
+				// The links carry the watermark the token is bound to
+				query := "?token=" + response.Token
+				if tt.expectedWatermark != "" {
+					query += "&" + tt.expectedWatermark
+				}
+				require.Equal(t, "videos/"+validVideoID+"/streams/master.m3u8"+query, response.Links["stream"].Href)
+				require.Equal(t, "videos/"+validVideoID+"/streams/manifest.mpd"+query, response.Links["dash"].Href)
+				require.Equal(t, "videos/"+validVideoID+"/cover"+query, response.Links["cover"].Href)
+			}
+
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestStreamToken(t *testing.T) {
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	preview := "preview clip content"
+
+	expiredToken, _ := streamtoken.NewSigner([]byte(givenStreamTokenSecret), -time.Minute, 0, false).Sign(validVideoID, "", "")
+	otherSecretToken, _ := streamtoken.NewSigner([]byte("other secret"), time.Hour, 0, false).Sign(validVideoID, "", "")
+	watermark := "watermarkText=viewer%40example.com"
+	watermarkedToken, _ := streamtoken.NewSigner([]byte(givenStreamTokenSecret), time.Hour, 0, false).Sign(validVideoID, "", watermark)
+
+	cases := []struct {
+		name             string
+		giveRoute        string
+		giveQuery        string
+		expectedHTTPCode int
+	}{
+		{
+			name:             "GET with valid token",
+			giveQuery:        streamTokenQuery(validVideoID),
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails without token",
+			giveQuery:        "",
+			expectedHTTPCode: 401,
+		},
+		{
+			name:             "GET fails with token of another video",
+			giveQuery:        streamTokenQuery(otherVideoID),
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with expired token",
+			giveQuery:        "?token=" + expiredToken,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with token signed by another secret",
+			giveQuery:        "?token=" + otherSecretToken,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET with token and its watermark",
+			giveQuery:        "?token=" + url.QueryEscape(watermarkedToken) + "&" + watermark,
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails with token without its watermark",
+			giveQuery:        "?token=" + url.QueryEscape(watermarkedToken),
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with token and another watermark",
+			giveQuery:        "?token=" + url.QueryEscape(watermarkedToken) + "&watermarkText=someone",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with token and an added watermark",
+			giveQuery:        streamTokenQuery(validVideoID) + "&watermarkText=someone",
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET preview with token of the videos list",
+			giveQuery:        previewTokenQuery(validVideoID),
+			expectedHTTPCode: 200,
+		},
+		{
+			name:             "GET fails to stream with token of the videos list",
+			giveRoute:        "/streams/master.m3u8",
+			giveQuery:        previewTokenQuery(validVideoID),
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails to get segment with token of the videos list",
+			giveRoute:        "/streams/v0/segment_0.m4s",
+			giveQuery:        previewTokenQuery(validVideoID),
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "GET fails with malformed token",
+			giveQuery:        "?token=token",
+			expectedHTTPCode: 403,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			routerClients := router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, func(string) (io.Reader, error) { return strings.NewReader(preview), nil }, nil, nil, nil),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			r := router.NewRouter(config.Config{
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
+			}, &routerClients, &router.DAOs{})
+
+			w := httptest.NewRecorder()
+			route := tt.giveRoute
+			if route == "" {
+				route = "/preview"
+			}
+			req := httptest.NewRequest(http.MethodGet, "/videos/"+validVideoID+route+tt.giveQuery, nil)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+)
+
+type VideoGetPreviewHandler struct {
+	S3Client clients.IS3Client
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// VideoGetPreviewHandler godoc
+// @Summary Get video preview clip
+// @Description Get the short muted MP4 clip sampled across the video, shown when hovering it in the catalogue
+// @Tags video
+// @Produce video/mp4
+// @Param id path string true "Video ID"
+// @Success 200 {file} binary "Preview clip"
+// @Success 206 {file} binary "Requested range of the preview clip"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/preview [get]
+func (v VideoGetPreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetPreviewHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// The preview is generated with the renditions, it does not exist while encoding
+	object, err := v.S3Client.GetObject(r.Context(), id+"/"+ffmpeg.PreviewFile)
+	if err != nil {
+		log.Error("Failed to get preview of video "+id+" : ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Preview clips are small enough to be served from memory, which gives range requests support
+	content, err := io.ReadAll(object)
+	if err != nil {
+		log.Error("Unable to read preview", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeContent(w, r, ffmpeg.PreviewFile, time.Time{}, bytes.NewReader(content))
+}
@@ -0,0 +1,109 @@
+package controllers_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoPreview(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	encodingVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	invalidVideoID := "invalidvideoid"
+	previewContent := "preview clip content"
+
+	getObject := func(path string) (io.Reader, error) {
+		if path == validVideoID+"/preview.mp4" {
+			return strings.NewReader(previewContent), nil
+		}
+		return nil, fmt.Errorf("S3 error")
+	}
+
+	cases := []struct {
+		name             string
+		giveRequest      string
+		giveWithAuth     bool
+		giveRange        string
+		expectedHTTPCode int
+		expectedBody     string
+	}{
+		{
+			name:             "GET video preview",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/preview",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			expectedBody:     previewContent,
+		},
+		{
+			name:             "GET public video preview range",
+			giveRequest:      "/videos/" + validVideoID + "/preview" + streamTokenQuery(validVideoID),
+			giveRange:        "bytes=0-6",
+			expectedHTTPCode: 206,
+			expectedBody:     "preview",
+		},
+		{
+			name:             "GET fails with video without preview",
+			giveRequest:      "/videos/" + encodingVideoID + "/preview" + streamTokenQuery(encodingVideoID),
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with invalid video ID",
+			giveRequest:      "/videos/" + invalidVideoID + "/preview" + streamTokenQuery(invalidVideoID),
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/preview",
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			routerClients := router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, nil, nil, nil),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth:          givenUsername,
+				PwdAuth:           givenUserPwd,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
+			}, &routerClients, &router.DAOs{})
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+			if tt.giveRange != "" {
+				req.Header.Set("Range", tt.giveRange)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+				require.Equal(t, "video/mp4", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
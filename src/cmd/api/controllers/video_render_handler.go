@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	protobufDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type RenderRequest struct {
+	Title   string   `json:"title" example:"Gray version"`
+	Filters []string `json:"filters" example:"gray,flip"`
+}
+
+type RenderResponse struct {
+	Video  *jsonDTO.VideoJson          `json:"video,omitempty"`
+	Render jsonDTO.RenderJson          `json:"render"`
+	Links  map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type RendersListResponse struct {
+	Renders []RenderResponse `json:"renders"`
+}
+
+type VideoRenderCreateHandler struct {
+	AmqpClient            clients.AmqpClient
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	RendersDAO            *dao.RendersDAO
+	UUIDGen               clients.IUUIDGenerator
+}
+
+// VideoRenderCreateHandler godoc
+// @Summary Burn transformations into a new video
+// @Description Create a new video from the video source with the transformer filters applied, then encode it.
+// @Description The filters are the ones of the streams filter parameter, applied in order. The new video plays without transformation at runtime.
+// @Tags video, renders
+// @Accept json
+// @Produce json
+// @Param id path string true "Parent video ID"
+// @Param render body RenderRequest true "Render title and filters"
+// @Success 200 {object} RenderResponse "Rendered video and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string "This title already exists, or the parent source is not uploaded"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/render [post]
+func (v VideoRenderCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("POST VideoRenderCreateHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request RenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode render request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	render, err := parseRenderRequest(request)
+	if err != nil {
+		log.Error("Invalid render request : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	render.ParentID = &id
+
+	parent, video, ok := createDerivedVideo(w, r, v.VideosDAO, v.UUIDGen, id, request.Title)
+	if !ok {
+		return
+	}
+	render.VideoID = video.ID
+
+	if err := v.RendersDAO.CreateRender(r.Context(), render); err != nil {
+		log.Error("Cannot create render : ", err)
+		removeDerivedVideo(r, v.VideosDAO, video)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	videoProto := protobufDTO.VideoToVideoProtobuf(video)
+	videoProto.Render = protobufDTO.RenderToRenderProtobuf(render, parent.SourcePath)
+	if err := sendDerivedVideoForEncoding(r, v.AmqpClient, v.AmqpVideoStatusUpdate, v.VideosDAO, video, videoProto); err != nil {
+		log.Error("Cannot send render for encoding : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := renderToResponse(render)
+	videoJson := jsonDTO.VideoToVideoJson(video)
+	response.Video = &videoJson
+	writeJSON(w, response)
+	log.Infof("Render '%v' of video %v sent for encoding", request.Title, id)
+}
+
+type VideoRendersListHandler struct {
+	VideosDAO  *dao.VideosDAO
+	RendersDAO *dao.RendersDAO
+	UUIDGen    clients.IUUIDGenerator
+}
+
+// VideoRendersListHandler godoc
+// @Summary List video renders
+// @Description List the videos rendered from a video, with their filters
+// @Tags video, renders
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} RendersListResponse "Renders and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/renders [get]
+func (v VideoRendersListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoRendersListHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if statusCode, err := checkVideoExists(r.Context(), v.VideosDAO, id); err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	// The derived videos the user cannot see are left out, as in the videos list
+	user := UserFromContext(r.Context())
+	userID, seeAll := "", false
+	if user != nil {
+		userID, seeAll = user.ID, user.Role.Includes(models.ADMIN)
+	}
+
+	videoRenders, err := v.RendersDAO.GetVideoRenders(r.Context(), id, userID, seeAll)
+	if err != nil {
+		log.Error("Cannot get renders of video "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := RendersListResponse{Renders: make([]RenderResponse, 0, len(videoRenders))}
+	for i := range videoRenders {
+		response.Renders = append(response.Renders, renderToResponse(&videoRenders[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+func renderToResponse(render *models.Render) RenderResponse {
+	links := map[string]jsonDTO.LinkJson{
+		"info":   jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + render.VideoID + "/info", Method: "GET"}),
+		"status": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + render.VideoID + "/status", Method: "GET"}),
+		"stream": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + render.VideoID + "/streams/master.m3u8", Method: "GET"}),
+	}
+	if render.ParentID != nil {
+		links["parent"] = jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + *render.ParentID + "/info", Method: "GET"})
+	}
+
+	return RenderResponse{
+		Render: jsonDTO.RenderToRenderJson(render),
+		Links:  links,
+	}
+}
+
+// parseRenderRequest validates the title and the transformers of a render
+func parseRenderRequest(request RenderRequest) (*models.Render, error) {
+	if strings.TrimSpace(request.Title) == "" {
+		return nil, errors.New("missing title")
+	}
+	if _, err := ffmpeg.TransformationFilterChain(request.Filters); err != nil {
+		return nil, err
+	}
+
+	return &models.Render{Filters: request.Filters}, nil
+}
@@ -0,0 +1,299 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+	"github.com/Sogilis/Voogle/src/pkg/events"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoRenders(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	parentID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	renderID := "2d0f9a40-3a6c-4a3e-a3a4-5b8e2f0c6a51"
+	parentSource := parentID + "/source.mkv"
+	renderSource := renderID + "/source.mkv"
+	renderTitle := "Gray version"
+	t1 := time.Now()
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	rendersColumns := []string{"video_id", "parent_id", "filters", "created_at"}
+	expectParent := func(mock sqlmock.Sqlmock, status models.VideoStatus) {
+		rows := sqlmock.NewRows(videosColumns).AddRow(parentID, "title", int(status), t1, t1, t1, parentSource, "", nil, nil, "public")
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(parentID).WillReturnRows(rows)
+	}
+	expectRenderCreation := func(mock sqlmock.Sqlmock) {
+		expectParent(mock, models.COMPLETE)
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs(renderTitle).
+			WillReturnRows(sqlmock.NewRows(videosColumns))
+		mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo])).
+			WithArgs(renderID, renderTitle, int(models.UPLOADED), renderSource, "", nil, models.PRIVATE).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(renderID).
+			WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(renderID, renderTitle, int(models.UPLOADED), nil, t1, t1, renderSource, "", nil, nil, "public"))
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveWithAuth     bool
+		giveBody         string
+		givePublishErr   bool
+		expectQueries    func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedRender   *contracts.Render
+		expectedRenders  int
+	}{
+		{
+			name:         "POST render sent for encoding",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Gray version", "filters": ["gray", "flip"]}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectRenderCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.RendersRequests[dao.CreateRender])).
+					WithArgs(renderID, parentID, "gray,flip").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+					WithArgs(renderTitle, int(models.ENCODING), AnyTime{}, renderSource, "", renderID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedRender:   &contracts.Render{ParentSource: parentSource, Filters: []string{"gray", "flip"}},
+		},
+		{
+			name:         "POST fails when the render cannot be recorded",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Gray version", "filters": ["gray"]}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectRenderCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.RendersRequests[dao.CreateRender])).WillReturnError(fmt.Errorf("database error"))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.DeleteVideo])).WithArgs(renderID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:           "POST fails when the render cannot be sent for encoding",
+			giveMethod:     http.MethodPost,
+			giveRequest:    "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth:   true,
+			giveBody:       `{"title": "Gray version", "filters": ["gray"]}`,
+			givePublishErr: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectRenderCreation(mock)
+				mock.ExpectExec(regexp.QuoteMeta(dao.RendersRequests[dao.CreateRender])).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])).
+					WithArgs(renderTitle, int(models.FAIL_ENCODE), AnyTime{}, renderSource, "", renderID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:         "POST fails with existing title",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Gray version", "filters": ["gray"]}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, models.COMPLETE)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs(renderTitle).
+					WillReturnRows(sqlmock.NewRows(videosColumns).AddRow(renderID, renderTitle, int(models.COMPLETE), t1, t1, t1, renderSource, "", nil, nil, "public"))
+			},
+			expectedHTTPCode: 409,
+		},
+		{
+			name:             "POST fails when the parent source is not uploaded",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Gray version", "filters": ["gray"]}`,
+			expectQueries:    func(mock sqlmock.Sqlmock) { expectParent(mock, models.UPLOADING) },
+			expectedHTTPCode: 409,
+		},
+		{
+			name:         "POST fails with unknown parent",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth: true,
+			giveBody:     `{"title": "Gray version", "filters": ["gray"]}`,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(parentID).WillReturnRows(sqlmock.NewRows(videosColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "POST fails with unknown filter",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Gray version", "filters": ["gray", "sepia"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails without filter",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Gray version", "filters": []}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails without title",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/render",
+			giveWithAuth:     true,
+			giveBody:         `{"filters": ["gray"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with invalid video ID",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/invalidvideoid/render",
+			giveWithAuth:     true,
+			giveBody:         `{"title": "Gray version", "filters": ["gray"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST fails with no auth",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + parentID + "/render",
+			giveBody:         `{"title": "Gray version", "filters": ["gray"]}`,
+			expectedHTTPCode: 401,
+		},
+		{
+			name:         "GET renders list",
+			giveMethod:   http.MethodGet,
+			giveRequest:  "/api/v1/videos/" + parentID + "/renders",
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, models.COMPLETE)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.RendersRequests[dao.GetVideoRenders])).WithArgs(parentID, true, "", "").
+					WillReturnRows(sqlmock.NewRows(rendersColumns).AddRow(renderID, parentID, "gray,flip", t1))
+			},
+			expectedHTTPCode: 200,
+			expectedRenders:  1,
+		},
+		{
+			name:         "GET renders list fails with unknown video",
+			giveMethod:   http.MethodGet,
+			giveRequest:  "/api/v1/videos/" + parentID + "/renders",
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(parentID).WillReturnRows(sqlmock.NewRows(videosColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var published *contracts.Video
+			publish := func(queue string, message []byte) error {
+				if tt.givePublishErr {
+					return fmt.Errorf("Cannot publish to rabbitmq")
+				}
+				require.Equal(t, events.VideoUploaded, queue)
+				published = &contracts.Video{}
+				return proto.Unmarshal(message, published)
+			}
+
+			routerClients := router.Clients{
+				AmqpClient:            clients.NewAmqpClientDummy(publish, nil, nil),
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen: clients.NewUuidGeneratorDummy(
+					func() (string, error) { return renderID, nil },
+					func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectRendersDAOCreation(mock)
+			if tt.expectQueries != nil {
+				tt.expectQueries(mock)
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			rendersDAO, err := dao.CreateRendersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:  *videosDAO,
+				RendersDAO: *rendersDAO,
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &routerDAO)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			if tt.expectedRender != nil {
+				require.NotNil(t, published)
+				require.Equal(t, renderID, published.Id)
+				require.Equal(t, renderSource, published.Source)
+				require.True(t, proto.Equal(tt.expectedRender, published.Render))
+
+				var response controllers.RenderResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, renderID, response.Render.VideoID)
+				require.Equal(t, parentID, *response.Render.ParentID)
+				require.Equal(t, "api/v1/videos/"+parentID+"/info", response.Links["parent"].Href)
+				require.Equal(t, tt.expectedRender.Filters, response.Render.Filters)
+				require.NotNil(t, response.Video)
+			}
+			if tt.expectedRenders > 0 {
+				var response controllers.RendersListResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Len(t, response.Renders, tt.expectedRenders)
+				require.Equal(t, renderID, response.Renders[0].Render.VideoID)
+				require.Equal(t, []string{"gray", "flip"}, response.Renders[0].Render.Filters)
+			}
+
+			// we make sure that all expectations were met
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+)
+
+type VisibilityRequest struct {
+	Visibility string `json:"visibility" example:"shared"`
+}
+
+type VideoVisibilityHandler struct {
+	VideosDAO *dao.VideosDAO
+	UUIDGen   clients.IUUIDGenerator
+}
+
+// VideoVisibilityHandler godoc
+// @Summary Change the visibility of a video
+// @Description Visibility is private (owner only), unlisted (anyone with the ID), shared (users chosen by the owner) or public. Owner or admin only.
+// @Tags video, shares
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param visibility body VisibilityRequest true "New visibility"
+// @Success 200 {object} jsonDTO.VideoJson
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/visibility [put]
+func (v VideoVisibilityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoVisibilityHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request VisibilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode visibility request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if request.Visibility == "" {
+		log.Error("Missing visibility")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	visibility, err := parseVisibility(request.Visibility)
+	if err != nil {
+		log.Error("Invalid visibility : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	video, err := v.VideosDAO.GetVideo(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if visibility != video.Visibility {
+		if err := v.VideosDAO.UpdateVideoVisibility(r.Context(), id, visibility); err != nil {
+			log.Error("Cannot update visibility of video "+id+" : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		video.Visibility = visibility
+	}
+
+	writeJSON(w, jsonDTO.VideoToVideoJson(video))
+}
+
+type VideoSharesListHandler struct {
+	VideoSharesDAO *dao.VideoSharesDAO
+	UUIDGen        clients.IUUIDGenerator
+}
+
+// VideoSharesListHandler godoc
+// @Summary List the users a video is shared with
+// @Description The shares are only used when the visibility of the video is shared. Owner or admin only.
+// @Tags video, shares
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} UsersListResponse
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/shares [get]
+func (v VideoSharesListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoSharesListHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	users, err := v.VideoSharesDAO.GetVideoSharesUsers(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get shares of video "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := UsersListResponse{Users: make([]jsonDTO.UserJson, 0, len(users))}
+	for i := range users {
+		response.Users = append(response.Users, jsonDTO.UserToUserJson(&users[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+type VideoShareCreateHandler struct {
+	UsersDAO       *dao.UsersDAO
+	VideoSharesDAO *dao.VideoSharesDAO
+	UUIDGen        clients.IUUIDGenerator
+}
+
+// VideoShareCreateHandler godoc
+// @Summary Share a video with a user
+// @Description The user can see the video while its visibility is shared. Owner or admin only.
+// @Tags video, shares
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param userID path string true "User ID"
+// @Success 200 {object} jsonDTO.UserJson
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/shares/{userID} [put]
+func (v VideoShareCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoShareCreateHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, statusCode, err := getUserFromVars(r, v.UsersDAO, v.UUIDGen)
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if err := v.VideoSharesDAO.CreateVideoShare(r.Context(), id, user.ID); err != nil {
+		log.Error("Cannot share video "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, jsonDTO.UserToUserJson(user))
+	log.Infof("Video %v shared with %v", id, user.Username)
+}
+
+type VideoShareDeleteHandler struct {
+	VideoSharesDAO *dao.VideoSharesDAO
+	UUIDGen        clients.IUUIDGenerator
+}
+
+// VideoShareDeleteHandler godoc
+// @Summary Stop sharing a video with a user
+// @Description Owner or admin only.
+// @Tags video, shares
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param userID path string true "User ID"
+// @Success 200 {string} string "OK"
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string "The video is not shared with this user"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/shares/{userID} [delete]
+func (v VideoShareDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE VideoShareDeleteHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	userID := vars["userID"]
+	if !v.UUIDGen.IsValidUUID(id) || !v.UUIDGen.IsValidUUID(userID) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := v.VideoSharesDAO.DeleteVideoShare(r.Context(), id, userID); err != nil {
+		log.Error("Cannot stop sharing video "+id+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+}
@@ -0,0 +1,356 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestVideoShares(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	ownerID := "9d6f2b7e-3c1a-4f0e-8a52-7b3e1d0c4f21"
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	clipID := "2d0f9a40-3a6c-4a3e-a3a4-5b8e2f0c6a51"
+	token := "q1Xv-session-token"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+	t1 := time.Now()
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	getVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+	getVideoShareQuery := regexp.QuoteMeta(dao.VideoSharesRequests[dao.GetVideoShare])
+	videoRow := func(owner string, visibility models.Visibility) *sqlmock.Rows {
+		return sqlmock.NewRows(videosColumns).
+			AddRow(videoID, "title", int(models.COMPLETE), t1, t1, t1, videoID+"/source.mp4", "", nil, owner, string(visibility))
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveBody         string
+		giveSession      bool
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedBody     []string
+	}{
+		{
+			name:        "GET info of own private video",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/info",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "GET info fails for private video of another user",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/info",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PRIVATE))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET info of unlisted video of another user",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/info",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.UNLISTED))
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.UNLISTED))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "GET info of video shared with the user",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/info",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.SHARED))
+				mock.ExpectQuery(getVideoShareQuery).WithArgs(videoID, userID).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.SHARED))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "GET info fails for video shared with other users",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/info",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.SHARED))
+				mock.ExpectQuery(getVideoShareQuery).WithArgs(videoID, userID).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET info fails with database error",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/info",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnError(fmt.Errorf("database error"))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:        "POST clip fails for video shared with the user",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/videos/" + videoID + "/clips",
+			giveBody:    `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.SHARED))
+				mock.ExpectQuery(getVideoShareQuery).WithArgs(videoID, userID).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "POST render fails for unlisted video of another user",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/videos/" + videoID + "/render",
+			giveBody:    `{"title": "Gray", "filters": ["gray"]}`,
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.UNLISTED))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "POST clip fails for private video of another user",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/videos/" + videoID + "/clips",
+			giveBody:    `{"title": "Best goal", "start": "12.5", "end": "42"}`,
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PRIVATE))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			// The access is granted, the request is then checked by the handler
+			name:        "POST clip of public video of another user",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/videos/" + videoID + "/clips",
+			giveBody:    `{"title": "Best goal", "start": "42", "end": "12.5"}`,
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PUBLIC))
+			},
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET clips only lists the clips the user can see",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/clips",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PUBLIC))
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PUBLIC))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.ClipsRequests[dao.GetVideoClips])).WithArgs(videoID, false, userID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"video_id", "parent_id", "start_seconds", "end_seconds", "accurate", "created_at"}).
+						AddRow(clipID, videoID, 12.5, 42.0, false, t1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"videoId":"` + clipID + `"`},
+		},
+		{
+			name:        "GET renders only lists the renders the user can see",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/renders",
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PUBLIC))
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PUBLIC))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.RendersRequests[dao.GetVideoRenders])).WithArgs(videoID, false, userID, userID).
+					WillReturnRows(sqlmock.NewRows([]string{"video_id", "parent_id", "filters", "created_at"}))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"renders":[]`},
+		},
+		{
+			name:        "PUT visibility of own video",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + videoID + "/visibility",
+			giveBody:    `{"visibility": "shared"}`,
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoVisibility])).
+					WithArgs(models.SHARED, videoID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"ownerId":"` + userID + `"`, `"visibility":"shared"`},
+		},
+		{
+			name:        "PUT visibility fails for public video of another user",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + videoID + "/visibility",
+			giveBody:    `{"visibility": "private"}`,
+			giveSession: true,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PUBLIC))
+			},
+			expectedHTTPCode: 403,
+		},
+		{
+			name:             "PUT visibility fails with unknown visibility",
+			giveMethod:       "PUT",
+			giveRequest:      "/api/v1/videos/" + videoID + "/visibility",
+			giveBody:         `{"visibility": "secret"}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET shares",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/videos/" + videoID + "/shares",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideoSharesRequests[dao.GetVideoSharesUsers])).
+					WithArgs(videoID).
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", "viewer", t1, t1, nil))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"users":[{"id":"` + userID + `","username":"alice"`},
+		},
+		{
+			name:        "PUT share",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + videoID + "/shares/" + userID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).
+					WithArgs(userID).
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", "viewer", t1, t1, nil))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideoSharesRequests[dao.CreateVideoShare])).
+					WithArgs(videoID, userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"username":"alice"`},
+		},
+		{
+			name:        "PUT share fails with unknown user",
+			giveMethod:  "PUT",
+			giveRequest: "/api/v1/videos/" + videoID + "/shares/" + userID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser])).
+					WithArgs(userID).
+					WillReturnRows(sqlmock.NewRows(usersColumns))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "DELETE share",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/videos/" + videoID + "/shares/" + userID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideoSharesRequests[dao.DeleteVideoShare])).
+					WithArgs(videoID, userID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "DELETE share fails when the video is not shared with the user",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/videos/" + videoID + "/shares/" + userID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideoSharesRequests[dao.DeleteVideoShare])).
+					WithArgs(videoID, userID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectVideoSharesDAOCreation(mock)
+			dao_test.ExpectClipsDAOCreation(mock)
+			dao_test.ExpectRendersDAOCreation(mock)
+
+			if tt.giveSession {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser])).
+					WithArgs(controllers.HashSessionToken(token), AnyTime{}).
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(models.UPLOADER), t1, t1, nil))
+			}
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			videoSharesDAO, err := dao.CreateVideoSharesDAO(context.Background(), db)
+			require.NoError(t, err)
+			clipsDAO, err := dao.CreateClipsDAO(context.Background(), db)
+			require.NoError(t, err)
+			rendersDAO, err := dao.CreateRendersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(nil, UUIDValidFunc),
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO, VideosDAO: *videosDAO, VideoSharesDAO: *videoSharesDAO, ClipsDAO: *clipsDAO, RendersDAO: *rendersDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveSession {
+				req.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				require.Contains(t, w.Body.String(), expected)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
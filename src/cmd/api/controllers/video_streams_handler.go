@@ -10,6 +10,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,23 +21,32 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
 	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
 	"github.com/Sogilis/Voogle/src/pkg/transformer/v1"
 )
 
 type VideoGetMasterHandler struct {
-	S3Client clients.IS3Client
-	UUIDGen  clients.IUUIDGenerator
+	S3Client     clients.IS3Client
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
 }
 
 // VideoGetMasterHandler godoc
 // @Summary Get video master
-// @Description Get video master
+// @Description Get video master. Its URIs carry the filters and the stream token, so that the whole stream is transformed.
+// @Description Supports HEAD and conditional requests (If-None-Match, If-Modified-Since)
 // @Tags video
-// @Produce plain
+// @Produce application/vnd.apple.mpegurl
 // @Param id path string true "Video ID"
+// @Param filter query []string false "List of required filters: gray, flip or watermark"
+// @Param maxHeight query int false "Highest rendition of the master"
+// @Param codecs query []string false "Video codecs supported by the client (h264, hevc, av1 or RFC 6381 sample entries)"
 // @Success 200 {string} string "HLS video master"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {string} string
 // @Failure 404 {string} string
 // @Failure 500 {string} string
@@ -52,18 +62,137 @@ func (v VideoGetMasterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	object, err := v.S3Client.GetObject(r.Context(), id+"/master.m3u8")
+	maxHeight := 0
+	if rawMaxHeight := r.URL.Query().Get("maxHeight"); rawMaxHeight != "" {
+		var err error
+		if maxHeight, err = strconv.Atoi(rawMaxHeight); err != nil || maxHeight <= 0 {
+			log.Error("Invalid maxHeight : ", rawMaxHeight)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	codecs, err := parseCodecsHint(r)
+	if err != nil {
+		log.Error("Invalid codecs : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	query, err := playlistQuery(r)
+	if err != nil {
+		log.Error("Invalid filters : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	object, err := v.S3Client.GetObjectFull(r.Context(), id+"/master.m3u8")
 	if err != nil {
 		log.Error("Failed to open video "+id+"/master.m3u8 ", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	defer object.Body.Close()
 
-	if _, err = io.Copy(w, object); err != nil {
-		log.Error("Unable to stream video master", err)
+	master, err := io.ReadAll(object.Body)
+	if err != nil {
+		log.Error("Unable to read video master", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The video stays playable without its subtitles
+	videoSubtitles, err := v.SubtitlesDAO.GetVideoSubtitles(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get subtitles of video "+id+" : ", err)
+	} else {
+		master = addSubtitlesToMaster(master, videoSubtitles)
+	}
+	if len(codecs) > 0 {
+		master = filterMasterCodecs(master, codecs)
+	}
+	if maxHeight > 0 {
+		master = capMasterRenditions(master, maxHeight)
+	}
+	master = addQueryToPlaylist(master, query)
+
+	// The served master changes with the subtitles, so does its modification date
+	lastModified := time.Time{}
+	if object.LastModified != nil {
+		lastModified = *object.LastModified
+	}
+	for _, subtitle := range videoSubtitles {
+		if subtitle.UpdatedAt != nil && subtitle.UpdatedAt.After(lastModified) {
+			lastModified = *subtitle.UpdatedAt
+		}
+	}
+
+	serveGeneratedContent(w, r, master, "master.m3u8", cacheControlRevalidate, lastModified)
+}
+
+type VideoGetManifestHandler struct {
+	S3Client clients.IS3Client
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// VideoGetManifestHandler godoc
+// @Summary Get video DASH manifest
+// @Description Get the MPEG-DASH manifest of the video, referencing the fMP4 segments of the HLS renditions.
+// @Description Its URLs carry the filters and the stream token. Supports HEAD and conditional requests
+// @Tags video
+// @Produce application/dash+xml
+// @Param id path string true "Video ID"
+// @Param filter query []string false "List of required filters: gray, flip or watermark"
+// @Success 200 {string} string "DASH video manifest"
+// @Success 304 {string} string "Not modified"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/streams/manifest.mpd [get]
+func (v VideoGetManifestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetManifestHandler - parameters ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	query, err := playlistQuery(r)
+	if err != nil {
+		log.Error("Invalid filters : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Videos encoded before the DASH output have no manifest
+	object, err := v.S3Client.GetObjectFull(r.Context(), id+"/"+ffmpeg.DASHManifest)
+	if err != nil {
+		log.Error("Failed to open video "+id+"/"+ffmpeg.DASHManifest+" ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(query) == 0 {
+		serveObject(w, r, object, ffmpeg.DASHManifest, cacheControlRevalidate)
+		return
+	}
+	defer object.Body.Close()
+
+	manifest, err := io.ReadAll(object.Body)
+	if err != nil {
+		log.Error("Unable to read video manifest ", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	lastModified := time.Time{}
+	if object.LastModified != nil {
+		lastModified = *object.LastModified
+	}
+	serveGeneratedContent(w, r, addQueryToManifest(manifest, query), ffmpeg.DASHManifest, cacheControlRevalidate, lastModified)
 }
 
 type VideoGetSourceHandler struct {
@@ -147,14 +276,20 @@ type VideoGetSubPartHandler struct {
 
 // VideoGetSubPartHandler godoc
 // @Summary Get sub part stream video
-// @Description Get sub part stream video
+// @Description Get a variant playlist or a segment of the video. The URIs of the playlists carry the filters and the stream token.
+// @Description Segments are immutable and cached for a year. Supports HEAD and conditional requests
 // @Tags video
-// @Produce plain
+// @Produce application/vnd.apple.mpegurl,video/iso.segment,video/mp2t
 // @Param id path string true "Video ID"
 // @Param quality path string true "Video quality"
 // @Param filename path string true "Video sub part name"
-// @Param filter query []string false "List of required filters"
-// @Success 200 {string} string "Video sub part (.ts)"
+// @Param filter query []string false "List of required filters: gray, flip or watermark"
+// @Param watermarkImage query string false "Path on S3 of the image stamped by the watermark filter, under watermarks/"
+// @Param watermarkText query string false "Text stamped by the watermark filter"
+// @Param watermarkPosition query string false "top-left, top-right, bottom-left, bottom-right (default) or center"
+// @Param watermarkOpacity query number false "Opacity of the watermark, from 0 to 1 (0.5 by default)"
+// @Success 200 {string} string "Video sub part"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {string} string
 // @Failure 404 {string} string
 // @Failure 500 {string} string
@@ -171,41 +306,69 @@ func (v VideoGetSubPartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := checkFilters(query); err != nil {
+		log.Error("Invalid filters : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	quality := vars["quality"]
 	filename := vars["filename"]
-	transformers := query["filter"]
+	transformers := segmentTransformers(query)
 	s3VideoPath := id + "/" + quality + "/" + filename
 
-	if strings.Contains(filename, "segment_index") || transformers == nil {
-		object, err := v.S3Client.GetObject(r.Context(), id+"/"+quality+"/"+filename)
+	// Only the media segments are transformed, playlists carry the filters to them
+	if !isMediaSegment(filename) || transformers == nil {
+		object, err := v.S3Client.GetObjectFull(r.Context(), s3VideoPath)
 		if err != nil {
 			log.Error("Failed to open video videoPath", err)
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 
-		if _, err := io.Copy(w, object); err != nil {
-			log.Error("Unable to stream subpart", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		if strings.HasSuffix(filename, ".m3u8") {
+			servePlaylistObject(w, r, object, filename)
+		} else {
+			serveObject(w, r, object, filename, cacheControlImmutable)
 		}
 	} else {
+		watermark, err := parseWatermark(query, transformers)
+		if err != nil {
+			log.Error("Invalid watermark : ", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The transformation is only run for the GET requests
+		w.Header().Set("Content-Type", streamContentType(filename))
+		w.Header().Set("Cache-Control", cacheControlImmutable)
+		if r.Method == http.MethodHead {
+			if err := v.S3Client.HeadObject(r.Context(), s3VideoPath); err != nil {
+				log.Error("Failed to find video part "+s3VideoPath+" : ", err)
+				w.WriteHeader(http.StatusNotFound)
+			}
+			return
+		}
+
 		// Add metrics (should be move into transformations service implem)
 		for _, service := range transformers {
 			if service == "gray" {
 				metrics.CounterVideoTransformGray.Inc()
 			} else if service == "flip" {
 				metrics.CounterVideoTransformFlip.Inc()
+			} else if service == watermarkTransformer {
+				metrics.CounterVideoTransformWatermark.Inc()
 			}
 		}
 		_range := r.Header.Get("Range")
-		videoPart, err := v.getVideoPart(r.Context(), s3VideoPath, _range, transformers, w)
+		videoPart, err := v.getVideoPart(r.Context(), s3VideoPath, _range, transformers, watermark, w)
 		if err != nil {
 			log.Error("Cannot get video part : ", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		// The filters are part of the query, hence of the cache key of the transformed segment
 		if _, err := io.Copy(w, videoPart); err != nil {
 			log.Error("Unable to stream subpart", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -214,7 +377,7 @@ func (v VideoGetSubPartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 	}
 }
 
-func (v VideoGetSubPartHandler) getVideoPart(ctx context.Context, s3VideoPath, rangeBytes string, transformers []string, w http.ResponseWriter) (io.Reader, error) {
+func (v VideoGetSubPartHandler) getVideoPart(ctx context.Context, s3VideoPath, rangeBytes string, transformers []string, watermark *transformer.Watermark, w http.ResponseWriter) (io.Reader, error) {
 	if len(transformers) == 0 {
 		// Retrieve the video part from aws S3
 		var err error
@@ -259,6 +422,7 @@ func (v VideoGetSubPartHandler) getVideoPart(ctx context.Context, s3VideoPath, r
 		request := transformer.TransformVideoRequest{
 			Videopath:       s3VideoPath,
 			TransformerList: transformers,
+			Watermark:       watermark,
 		}
 		streamResponse, err := clientRPC.TransformVideo(ctx, &request)
 		if err != nil {
@@ -318,12 +482,13 @@ type VideoGetSubtitlesHandler struct {
 
 // VideoGetSubtitlesHandler godoc
 // @Summary Get subtitles for video
-// @Description Get subtitles for video
+// @Description Get subtitles for video. Supports HEAD and conditional requests
 // @Tags video, subtitles
-// @Produce plain
+// @Produce text/vtt
 // @Param id path string true "Video ID"
 // @Param filename path string true "Subtitles file nams"
 // @Success 200 {string} string "Video subtitles"
+// @Success 304 {string} string "Not modified"
 // @Failure 400 {string} string
 // @Failure 404 {string} string
 // @Failure 500 {string} string
@@ -342,41 +507,44 @@ func (v VideoGetSubtitlesHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	filename := vars["filename"]
 	s3VideoPath := id + "/" + filename
 
-	var err error
-	videoPart, err := v.S3Client.GetObject(r.Context(), s3VideoPath)
+	object, err := v.S3Client.GetObjectFull(r.Context(), s3VideoPath)
 	if err != nil {
-		log.Error("Failed to get video from S3 : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	if _, err := io.Copy(w, videoPart); err != nil {
-		log.Error("Unable to stream subpart", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		log.Error("Failed to get subtitles from S3 : ", err)
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	serveObject(w, r, object, filename, cacheControlRevalidate)
 }
 
 type VideoEditDataHandler struct {
-	S3Client         clients.IS3Client
-	UUIDGen          clients.IUUIDGenerator
-	ServiceDiscovery clients.ServiceDiscovery
-	VideosDAO        *dao.VideosDAO
+	S3Client              clients.IS3Client
+	AmqpVideoStatusUpdate clients.AmqpClient
+	UUIDGen               clients.IUUIDGenerator
+	ServiceDiscovery      clients.ServiceDiscovery
+	VideosDAO             *dao.VideosDAO
+	SubtitlesDAO          *dao.SubtitlesDAO
 }
 
 // VideoEditDataHandler godoc
-// @Summary Upload subtitles for video
-// @Description Upload subtitles for video
+// @Summary Edit video data
+// @Description Edit the title and the cover of a video, and add subtitles to it
 // @Tags video, subtitles
 // @Accept multipart/form-data
 // @Produce plain
 // @Param id path string true "Video ID"
+// @Param title formData string true "Video title"
+// @Param cover formData file false "Cover image"
+// @Param subs formData file false "SRT or WebVTT subtitles"
+// @Param subsLanguage formData string false "Subtitles language tag (BCP 47), und by default"
+// @Param subsLabel formData string false "Subtitles name displayed by the players"
 // @Success 200 {string} string
 // @Failure 400 {string} string
 // @Failure 404 {string} string
+// @Failure 415 {string} string
+// @Failure 422 {string} string "Invalid subtitles"
 // @Failure 500 {string} string
-// @Router /api/v1/videos/{id}/subtitles [get]
+// @Router /api/v1/videos/{id}/edit [post]
 func (v VideoEditDataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	log.Debug("GET VideoEditDataHandler - Parameters: ", vars)
@@ -397,6 +565,18 @@ func (v VideoEditDataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Check if a video with this id exists, before storing its cover and subtitles
+	video, err := v.VideosDAO.GetVideo(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot found video "+id+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
 	// Fetch cover image. Not mandatory
 	fileCover, fileHandlerCover, err := r.FormFile("cover")
 	if err != nil && !errors.Is(err, http.ErrMissingFile) {
@@ -426,6 +606,7 @@ func (v VideoEditDataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		removeCoverVariants(r.Context(), v.S3Client, id)
 	}
 
 	// Fetch subtitles. Not mandatory
@@ -437,23 +618,21 @@ func (v VideoEditDataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 	if subtitles != nil {
 		defer subtitles.Close()
-		//TODO check subtitles are valid
-	}
 
-	// Upload subtitles (if file exists) on S3
-	// TODO update database?
-	_, err = v.uploadSubtitles(r.Context(), subtitles, id, subtitileHandler)
-	if err != nil {
-		log.Error("Cannot upload subtitles : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	// Check if a video with this id exists
-	video, err := v.VideosDAO.GetVideo(r.Context(), id)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		subtitle, vtt, err := readUploadedSubtitles(r.FormValue("subsLanguage"), r.FormValue("subsLabel"), subtitles, subtitileHandler)
+		if err != nil {
+			w.WriteHeader(subtitlesErrorStatus(err))
+			return
+		}
+		subtitle.VideoID = id
+
+		if err := createSubtitle(r.Context(), v.S3Client, v.SubtitlesDAO, v.UUIDGen, subtitle, vtt); err != nil {
+			log.Error("Cannot save subtitles : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 	}
+
 	if video.Title != title {
 		// Check if a video with this title already exists
 		videoConflict, err := v.VideosDAO.GetVideoFromTitle(r.Context(), title)
@@ -470,21 +649,11 @@ func (v VideoEditDataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		video.Title = title
+		eventhandler.PublishVideoEvent(v.AmqpVideoStatusUpdate, models.RENAMED_EVENT, video, 0)
 	}
 }
 
-func (v VideoEditDataHandler) uploadSubtitles(ctx context.Context, cover multipart.File, videoID string, fileHandler *multipart.FileHeader) (string, error) {
-	subtitlesPath := ""
-	if cover != nil {
-		subtitlesPath = videoID + "/" + "subs" + filepath.Ext(fileHandler.Filename)
-		if err := v.S3Client.PutObjectInput(ctx, cover, subtitlesPath); err != nil {
-			log.Error("Cannot upload subtitles : ", err)
-			return "", err
-		}
-	}
-	return subtitlesPath, nil
-}
-
 func (v VideoEditDataHandler) uploadCover(ctx context.Context, cover multipart.File, videoID string, fileHandler *multipart.FileHeader) (string, error) {
 	coverPath := ""
 	if cover != nil {
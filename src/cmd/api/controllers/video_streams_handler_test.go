@@ -1,19 +1,31 @@
 package controllers_test
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 )
 
@@ -28,6 +40,18 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 	validSubPart := "part1.ts"
 	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
 	getServices := func(u string) (string, error) { return "", fmt.Errorf("Error services unreachable") }
+	subtitleID := "2d0f9a40-3a6c-4a3e-a3a4-5b8e2f0c6a51"
+	master := "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"\nv0/segment_index.m3u8\n"
+	subtitlesColumns := []string{"id", "video_id", "language", "label", "is_default", "forced", "path", "created_at", "updated_at"}
+	ladderMaster := "#EXTM3U\n#EXT-X-VERSION:7\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480\nv0/segment_index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2420000,RESOLUTION=1280x720\nv1/segment_index.m3u8\n"
+	manifest := "<MPD>\n<Initialization sourceURL=\"v0/init_0.mp4\"></Initialization>\n<SegmentURL media=\"v0/segment0.m4s\"></SegmentURL>\n</MPD>\n"
+	codecsMaster := "#EXTM3U\n#EXT-X-VERSION:7\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001E,mp4a.40.2\"\nv0/segment_index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=810000,RESOLUTION=640x480,CODECS=\"hvc1.1.6.L90.B0,mp4a.40.2\"\nv1/segment_index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=650000,RESOLUTION=640x480,CODECS=\"av01.0.04M.08,mp4a.40.2\"\nv2/segment_index.m3u8\n"
+	variantPlaylist := "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-MAP:URI=\"init_0.mp4\"\n#EXTINF:4.000000,\nsegment_0.m4s\n#EXT-X-ENDLIST\n"
 
 	cases := []struct {
 		name             string
@@ -37,13 +61,175 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 		getObjectID      func(string) (io.Reader, error)
 		isValidUUID      func(string) bool
 		getServices      func(u string) (string, error)
+		giveSubtitles    *sqlmock.Rows
+		expectedBody     string
 	}{
 		{
 			name:             "GET video stream master",
 			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8",
 			giveWithAuth:     true,
 			expectedHTTPCode: 200,
-			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(""), nil },
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(master), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody:     master},
+		{
+			name:             "GET video stream master with subtitles",
+			giveRequest:      "/videos/" + validVideoID + "/streams/master.m3u8" + streamTokenQuery(validVideoID),
+			giveWithAuth:     false,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(master), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles: sqlmock.NewRows(subtitlesColumns).
+				AddRow(subtitleID, validVideoID, "fr-CA", "Français", true, false, validVideoID+"/subtitles/"+subtitleID+".vtt", time.Now(), time.Now()),
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:7\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"Français\",LANGUAGE=\"fr-CA\",DEFAULT=YES,AUTOSELECT=YES,FORCED=NO,URI=\"../subtitles/" + subtitleID + "/playlist.m3u8" + streamTokenQuery(validVideoID) + "\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\",SUBTITLES=\"subs\"\nv0/segment_index.m3u8" + streamTokenQuery(validVideoID) + "\n"},
+		{
+			name:             "GET video stream master with filters",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?filter=gray&filter=flip",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(ladderMaster), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:7\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480\nv0/segment_index.m3u8?filter=gray&filter=flip\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2420000,RESOLUTION=1280x720\nv1/segment_index.m3u8?filter=gray&filter=flip\n"},
+		{
+			name:             "GET video stream master with rendition cap",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?maxHeight=480",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(ladderMaster), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody:     "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480\nv0/segment_index.m3u8\n"},
+		{
+			name:             "GET video stream master with rendition cap under the lowest one",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?maxHeight=144&filter=gray",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(ladderMaster), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody:     "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480\nv0/segment_index.m3u8?filter=gray\n"},
+		{
+			name:             "GET fails to video stream master with invalid rendition cap",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?maxHeight=high",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(ladderMaster), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET video stream master for a H.264 only client",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?codecs=avc1",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(codecsMaster), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody:     "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001E,mp4a.40.2\"\nv0/segment_index.m3u8\n"},
+		{
+			name:             "GET video stream master for a H.264 and HEVC client",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?codecs=h264,hevc",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(codecsMaster), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:7\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001E,mp4a.40.2\"\nv0/segment_index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=810000,RESOLUTION=640x480,CODECS=\"hvc1.1.6.L90.B0,mp4a.40.2\"\nv1/segment_index.m3u8\n"},
+		{
+			name:             "GET video stream master unfiltered when no codec fits",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?codecs=av1",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(master), nil },
+			isValidUUID:      UUIDValidFunc,
+			giveSubtitles:    sqlmock.NewRows(subtitlesColumns),
+			expectedBody:     master},
+		{
+			name:             "GET fails to video stream master with unknown codec",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/master.m3u8?codecs=vp8",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(codecsMaster), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET variant playlist with filters",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/segment_index.m3u8?filter=gray",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(variantPlaylist), nil },
+			isValidUUID:      UUIDValidFunc,
+			expectedBody:     "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-MAP:URI=\"init_0.mp4?filter=gray\"\n#EXTINF:4.000000,\nsegment_0.m4s?filter=gray\n#EXT-X-ENDLIST\n"},
+		{
+			name:             "GET variant playlist with watermark",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/segment_index.m3u8?filter=watermark&watermarkText=viewer-42&watermarkPosition=top-left",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(variantPlaylist), nil },
+			isValidUUID:      UUIDValidFunc,
+			expectedBody: "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-MAP:URI=\"init_0.mp4?filter=watermark&watermarkPosition=top-left&watermarkText=viewer-42\"\n#EXTINF:4.000000,\n" +
+				"segment_0.m4s?filter=watermark&watermarkPosition=top-left&watermarkText=viewer-42\n#EXT-X-ENDLIST\n"},
+		{
+			name:             "GET fails to watermark segment with image out of the watermarks",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/segment_0.m4s?filter=watermark&watermarkImage=" + validVideoID + "/cover.png",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET fails to watermark segment without image nor text",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/segment_0.m4s?filter=gray&filter=watermark",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET fails to watermark segment with invalid opacity",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/segment_0.m4s?filter=watermark&watermarkText=viewer&watermarkOpacity=2",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			isValidUUID:      UUIDValidFunc},
+		{
+			// The watermark parameters stamp the segment even without the filter
+			name:             "GET fails to watermark segment without filter with invalid opacity",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/" + validQuality + "/segment_0.m4s?watermarkText=viewer&watermarkOpacity=2",
+			giveWithAuth:     true,
+			expectedHTTPCode: 400,
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET video DASH manifest",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/manifest.mpd",
+			giveWithAuth:     true,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(manifest), nil },
+			isValidUUID:      UUIDValidFunc,
+			expectedBody:     manifest},
+		{
+			name:             "GET video DASH manifest with filters and token",
+			giveRequest:      "/videos/" + validVideoID + "/streams/manifest.mpd" + streamTokenQuery(validVideoID) + "&filter=gray",
+			giveWithAuth:     false,
+			expectedHTTPCode: 200,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(manifest), nil },
+			isValidUUID:      UUIDValidFunc,
+			expectedBody: "<MPD>\n" +
+				"<Initialization sourceURL=\"v0/init_0.mp4?filter=gray&amp;" + strings.TrimPrefix(streamTokenQuery(validVideoID), "?") + "\"></Initialization>\n" +
+				"<SegmentURL media=\"v0/segment0.m4s?filter=gray&amp;" + strings.TrimPrefix(streamTokenQuery(validVideoID), "?") + "\"></SegmentURL>\n</MPD>\n"},
+		{
+			name:             "GET fails to video DASH manifest without token",
+			giveRequest:      "/videos/" + validVideoID + "/streams/manifest.mpd",
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+			getObjectID:      func(s string) (io.Reader, error) { return strings.NewReader(manifest), nil },
+			isValidUUID:      UUIDValidFunc},
+		{
+			name:             "GET fails to video DASH manifest of a video encoded without it",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/streams/manifest.mpd",
+			giveWithAuth:     true,
+			expectedHTTPCode: 404,
+			getObjectID:      func(s string) (io.Reader, error) { return nil, errors.New("Not found") },
 			isValidUUID:      UUIDValidFunc},
 		{
 			name:             "GET fails to video stream master with invalid id",
@@ -123,15 +309,31 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 			serviceDiscovery := clients.NewDummyServiceDiscovery(nil, getServices, nil, nil, nil)
 
 			routerClients := router.Clients{
-				S3Client:         s3Client,
-				UUIDGen:          clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
-				ServiceDiscovery: serviceDiscovery,
+				S3Client:              s3Client,
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
+				ServiceDiscovery:      serviceDiscovery,
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			if tt.giveSubtitles != nil {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).WithArgs(validVideoID).WillReturnRows(tt.giveSubtitles)
 			}
 
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
 			r := router.NewRouter(config.Config{
-				UserAuth: givenUsername,
-				PwdAuth:  givenUserPwd,
-			}, &routerClients, &router.DAOs{})
+				UserAuth:          givenUsername,
+				PwdAuth:           givenUserPwd,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
+			}, &routerClients, &router.DAOs{SubtitlesDAO: *subtitlesDAO})
 
 			w := httptest.NewRecorder()
 
@@ -142,9 +344,354 @@ func TestVideoStream(t *testing.T) { //nolint:cyclop
 
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+
+	}
+
+}
+
+func TestVideoStreamHTTPSemantics(t *testing.T) {
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	segment := "segment content"
+	etag := `"5d41402abc4b2a76b9719d911017c592"`
+	lastModified := time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)
+	master := "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480\nv0/segment_index.m3u8\n"
+	subtitlesColumns := []string{"id", "video_id", "language", "label", "is_default", "forced", "path", "created_at", "updated_at"}
+
+	getObjectFull := func(path string) (*s3.GetObjectOutput, error) {
+		content := ""
+		switch path {
+		case validVideoID + "/master.m3u8":
+			content = master
+		case validVideoID + "/v0/segment_index.m3u8", validVideoID + "/v0/segment_1.m4s", validVideoID + "/subtitles.vtt":
+			content = segment
+		default:
+			return nil, errors.New("Not found")
+		}
+		return &s3.GetObjectOutput{
+			Body:          io.NopCloser(strings.NewReader(content)),
+			ContentLength: int64(len(content)),
+			ETag:          aws.String(etag),
+			LastModified:  aws.Time(lastModified),
+		}, nil
+	}
+
+	cases := []struct {
+		name                 string
+		giveMethod           string
+		giveRequest          string
+		giveHeaders          map[string]string
+		giveSubtitles        bool
+		expectedHTTPCode     int
+		expectedContentType  string
+		expectedCacheControl string
+		expectedBody         string
+		// Transformed segments have no validators, their content is generated on GET
+		expectedTransformed bool
+	}{
+		{
+			name:                 "GET segment",
+			giveMethod:           http.MethodGet,
+			giveRequest:          "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID),
+			expectedHTTPCode:     200,
+			expectedContentType:  "video/iso.segment",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+			expectedBody:         segment,
+		},
+		{
+			name:                 "HEAD segment",
+			giveMethod:           http.MethodHead,
+			giveRequest:          "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID),
+			expectedHTTPCode:     200,
+			expectedContentType:  "video/iso.segment",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			// The transformer is not reachable, the segment is not transformed for a HEAD request
+			name:                 "HEAD filtered segment",
+			giveMethod:           http.MethodHead,
+			giveRequest:          "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID) + "&filter=gray",
+			expectedHTTPCode:     200,
+			expectedContentType:  "video/iso.segment",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+			expectedTransformed:  true,
+		},
+		{
+			name:             "HEAD fails with missing filtered segment",
+			giveMethod:       http.MethodHead,
+			giveRequest:      "/videos/" + validVideoID + "/streams/v0/segment_2.m4s" + streamTokenQuery(validVideoID) + "&filter=gray",
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with empty filter",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID) + "&filter=",
+			expectedHTTPCode: 400,
+			expectedBody:     "unknown filter \"\"\n",
+		},
+		{
+			name:             "GET fails with unknown filter",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID) + "&filter=sepia",
+			expectedHTTPCode: 400,
+			expectedBody:     "unknown filter \"sepia\"\n",
+		},
+		{
+			name:             "GET fails to serve master with unknown filter",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/streams/master.m3u8" + streamTokenQuery(validVideoID) + "&filter=sepia",
+			expectedHTTPCode: 400,
+			expectedBody:     "unknown filter \"sepia\"\n",
+		},
+		{
+			name:             "GET segment not modified with matching ETag",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID),
+			giveHeaders:      map[string]string{"If-None-Match": `"other", ` + etag},
+			expectedHTTPCode: 304,
+		},
+		{
+			name:                 "GET segment with other ETag",
+			giveMethod:           http.MethodGet,
+			giveRequest:          "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID),
+			giveHeaders:          map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			expectedHTTPCode:     200,
+			expectedContentType:  "video/iso.segment",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+			expectedBody:         segment,
+		},
+		{
+			name:             "GET segment not modified since",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID),
+			giveHeaders:      map[string]string{"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat)},
+			expectedHTTPCode: 304,
+		},
+		{
+			name:                 "GET segment modified since",
+			giveMethod:           http.MethodGet,
+			giveRequest:          "/videos/" + validVideoID + "/streams/v0/segment_1.m4s" + streamTokenQuery(validVideoID),
+			giveHeaders:          map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)},
+			expectedHTTPCode:     200,
+			expectedContentType:  "video/iso.segment",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+			expectedBody:         segment,
+		},
+		{
+			name:                 "GET variant playlist",
+			giveMethod:           http.MethodGet,
+			giveRequest:          "/videos/" + validVideoID + "/streams/v0/segment_index.m3u8" + streamTokenQuery(validVideoID),
+			expectedHTTPCode:     200,
+			expectedContentType:  "application/vnd.apple.mpegurl",
+			expectedCacheControl: "public, max-age=60",
+			expectedBody:         segment + streamTokenQuery(validVideoID),
+		},
+		{
+			name:                 "GET master",
+			giveMethod:           http.MethodGet,
+			giveRequest:          "/videos/" + validVideoID + "/streams/master.m3u8" + streamTokenQuery(validVideoID),
+			giveSubtitles:        true,
+			expectedHTTPCode:     200,
+			expectedContentType:  "application/vnd.apple.mpegurl",
+			expectedCacheControl: "public, max-age=60",
+			expectedBody:         strings.Replace(master, "segment_index.m3u8", "segment_index.m3u8"+streamTokenQuery(validVideoID), 1),
+		},
+		{
+			name:                 "HEAD master",
+			giveMethod:           http.MethodHead,
+			giveRequest:          "/videos/" + validVideoID + "/streams/master.m3u8" + streamTokenQuery(validVideoID),
+			giveSubtitles:        true,
+			expectedHTTPCode:     200,
+			expectedContentType:  "application/vnd.apple.mpegurl",
+			expectedCacheControl: "public, max-age=60",
+		},
+		{
+			name:             "GET master not modified since",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/streams/master.m3u8" + streamTokenQuery(validVideoID),
+			giveHeaders:      map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			giveSubtitles:    true,
+			expectedHTTPCode: 304,
+		},
+		{
+			name:                 "GET subtitles",
+			giveMethod:           http.MethodGet,
+			giveRequest:          "/videos/" + validVideoID + "/subtitles/subtitles.vtt" + streamTokenQuery(validVideoID),
+			expectedHTTPCode:     200,
+			expectedContentType:  "text/vtt; charset=utf-8",
+			expectedCacheControl: "public, max-age=60",
+			expectedBody:         segment,
+		},
+		{
+			name:             "GET fails with missing subtitles",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/videos/" + validVideoID + "/subtitles/missing.vtt" + streamTokenQuery(validVideoID),
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			routerClients := router.Clients{
+				S3Client: clients.NewS3ClientFullDummy(getObjectFull),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			if tt.giveSubtitles {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).WithArgs(validVideoID).WillReturnRows(sqlmock.NewRows(subtitlesColumns))
+			}
+
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r := router.NewRouter(config.Config{StreamTokenSecret: givenStreamTokenSecret, StreamTokenTTL: time.Hour}, &routerClients, &router.DAOs{SubtitlesDAO: *subtitlesDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, nil)
+			for header, value := range tt.giveHeaders {
+				req.Header.Set(header, value)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedHTTPCode == 200 {
+				require.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+				require.Equal(t, tt.expectedCacheControl, w.Header().Get("Cache-Control"))
+			}
+			if tt.expectedHTTPCode == 200 && !tt.expectedTransformed {
+				require.NotEmpty(t, w.Header().Get("ETag"))
+				require.Equal(t, lastModified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+				require.NotEmpty(t, w.Header().Get("Content-Length"))
+			}
 
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
 		})
+	}
+}
+
+func TestVideoEditData(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
 
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	vtt := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n"
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	t1 := time.Now()
+
+	videoRow := func(id, title string) *sqlmock.Rows {
+		return sqlmock.NewRows(videosColumns).AddRow(id, title, 4, t1, t1, t1, id+"/source.mp4", "", nil, nil, "public")
 	}
 
+	cases := []struct {
+		name             string
+		giveTitle        string
+		giveSubtitles    bool
+		expectQueries    func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+	}{
+		{
+			name:      "POST renames video",
+			giveTitle: "new title",
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(videoID).WillReturnRows(videoRow(videoID, "title"))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs("new title").WillReturnRows(sqlmock.NewRows(videosColumns))
+				mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoTitle])).WithArgs("new title", videoID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:      "POST fails with title of another video",
+			giveTitle: "other title",
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(videoID).WillReturnRows(videoRow(videoID, "title"))
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle])).WithArgs("other title").WillReturnRows(videoRow(otherVideoID, "other title"))
+			},
+			expectedHTTPCode: 409,
+		},
+		{
+			// Nothing is stored for an unknown video
+			name:          "POST fails with unknown video",
+			giveTitle:     "title",
+			giveSubtitles: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(videoID).WillReturnError(sql.ErrNoRows)
+			},
+			expectedHTTPCode: 404,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			uploads := []string{}
+			putObject := func(f io.Reader, path string) error { uploads = append(uploads, path); return nil }
+
+			routerClients := router.Clients{
+				S3Client:              clients.NewS3ClientDummy(nil, nil, putObject, nil, nil),
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(func(string, []byte) error { return nil }, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			tt.expectQueries(mock)
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &router.DAOs{VideosDAO: *videosDAO, SubtitlesDAO: *subtitlesDAO})
+
+			// Dummy multipart body creation
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			require.NoError(t, writer.WriteField("title", tt.giveTitle))
+			if tt.giveSubtitles {
+				fileWriter, err := writer.CreateFormFile("subs", "subs.vtt")
+				require.NoError(t, err)
+				_, err = fileWriter.Write([]byte(vtt))
+				require.NoError(t, err)
+			}
+			writer.Close()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/"+videoID+"/edit", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			if tt.expectedHTTPCode == 404 {
+				require.Empty(t, uploads)
+			}
+
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
 }
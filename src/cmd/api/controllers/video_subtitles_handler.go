@@ -0,0 +1,688 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/subtitles"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// GROUP-ID of the subtitles renditions in the HLS master
+const subtitlesGroupID = "subs"
+
+var (
+	errUnsupportedSubtitlesFormat = errors.New("unsupported subtitles format")
+	errInvalidSubtitles           = errors.New("invalid subtitles")
+	errInvalidSubtitleMetadata    = errors.New("invalid subtitles metadata")
+)
+
+type SubtitleResponse struct {
+	Subtitle jsonDTO.SubtitleJson        `json:"subtitle"`
+	Links    map[string]jsonDTO.LinkJson `json:"_links"`
+}
+
+type SubtitlesListResponse struct {
+	Subtitles []SubtitleResponse `json:"subtitles"`
+}
+
+type VideoSubtitlesListHandler struct {
+	VideosDAO    *dao.VideosDAO
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitlesListHandler godoc
+// @Summary List video subtitles
+// @Description List the subtitles tracks of a video
+// @Tags video, subtitles
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SubtitlesListResponse "Subtitles and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles [get]
+func (v VideoSubtitlesListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoSubtitlesListHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if statusCode, err := checkVideoExists(r.Context(), v.VideosDAO, id); err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	videoSubtitles, err := v.SubtitlesDAO.GetVideoSubtitles(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get subtitles of video "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := SubtitlesListResponse{Subtitles: make([]SubtitleResponse, 0, len(videoSubtitles))}
+	for i := range videoSubtitles {
+		response.Subtitles = append(response.Subtitles, subtitleToResponse(&videoSubtitles[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+type VideoSubtitlesCreateHandler struct {
+	S3Client     clients.IS3Client
+	VideosDAO    *dao.VideosDAO
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitlesCreateHandler godoc
+// @Summary Add subtitles to a video
+// @Description Add a subtitles track to a video. SRT files are converted to WebVTT.
+// @Tags video, subtitles
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param subs formData file true "SRT or WebVTT file"
+// @Param language formData string true "Language tag (BCP 47), e.g. en or fr-CA"
+// @Param label formData string false "Name displayed by the players, the language by default"
+// @Param default formData bool false "Track selected by default"
+// @Param forced formData bool false "Track only containing forced subtitles"
+// @Success 200 {object} SubtitleResponse "Subtitle and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 415 {string} string
+// @Failure 422 {string} string "Invalid subtitles"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles [post]
+func (v VideoSubtitlesCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("POST VideoSubtitlesCreateHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	subtitle := &models.Subtitle{VideoID: id}
+	if err := readSubtitleMetadata(r, subtitle); err != nil {
+		log.Error("Invalid subtitles metadata : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if subtitle.Language == "" {
+		log.Error("Missing subtitles language")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	file, fileHandler, err := r.FormFile("subs")
+	if err != nil {
+		log.Error("Missing subtitles file ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	vtt, err := readSubtitles(file, fileHandler)
+	if err != nil {
+		w.WriteHeader(subtitlesErrorStatus(err))
+		return
+	}
+
+	if statusCode, err := checkVideoExists(r.Context(), v.VideosDAO, id); err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if err := createSubtitle(r.Context(), v.S3Client, v.SubtitlesDAO, v.UUIDGen, subtitle, vtt); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	subtitle, err = v.SubtitlesDAO.GetSubtitle(r.Context(), subtitle.ID)
+	if err != nil {
+		log.Error("Cannot get created subtitle : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, subtitleToResponse(subtitle))
+	log.Infof("Subtitles '%v' added to video %v", subtitle.Label, id)
+}
+
+type VideoSubtitleGetHandler struct {
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitleGetHandler godoc
+// @Summary Get video subtitles
+// @Description Get a subtitles track of a video
+// @Tags video, subtitles
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param subtitleID path string true "Subtitle ID"
+// @Success 200 {object} SubtitleResponse "Subtitle and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{subtitleID} [get]
+func (v VideoSubtitleGetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoSubtitleGetHandler - Parameters: ", vars)
+
+	subtitle, statusCode := getVideoSubtitle(r.Context(), v.SubtitlesDAO, v.UUIDGen, vars["id"], vars["subtitleID"])
+	if subtitle == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	writeJSON(w, subtitleToResponse(subtitle))
+}
+
+type VideoSubtitleUpdateHandler struct {
+	S3Client     clients.IS3Client
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitleUpdateHandler godoc
+// @Summary Update video subtitles
+// @Description Update a subtitles track of a video. Fields which are not given are left unchanged.
+// @Tags video, subtitles
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param subtitleID path string true "Subtitle ID"
+// @Param subs formData file false "SRT or WebVTT file"
+// @Param language formData string false "Language tag (BCP 47), e.g. en or fr-CA"
+// @Param label formData string false "Name displayed by the players"
+// @Param default formData bool false "Track selected by default"
+// @Param forced formData bool false "Track only containing forced subtitles"
+// @Success 200 {object} SubtitleResponse "Subtitle and Links (HATEOAS)"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 415 {string} string
+// @Failure 422 {string} string "Invalid subtitles"
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{subtitleID} [put]
+func (v VideoSubtitleUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("PUT VideoSubtitleUpdateHandler - Parameters: ", vars)
+
+	subtitle, statusCode := getVideoSubtitle(r.Context(), v.SubtitlesDAO, v.UUIDGen, vars["id"], vars["subtitleID"])
+	if subtitle == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if err := readSubtitleMetadata(r, subtitle); err != nil {
+		log.Error("Invalid subtitles metadata : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Replace the file only if a new one is given
+	file, fileHandler, err := r.FormFile("subs")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart) {
+		log.Error("Subtitle file error ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if file != nil {
+		defer file.Close()
+
+		vtt, err := readSubtitles(file, fileHandler)
+		if err != nil {
+			w.WriteHeader(subtitlesErrorStatus(err))
+			return
+		}
+
+		if err := v.S3Client.PutObjectInput(r.Context(), bytes.NewReader(vtt), subtitle.Path); err != nil {
+			log.Error("Cannot upload subtitles : ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := saveSubtitle(r.Context(), v.SubtitlesDAO, subtitle, false); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	subtitle, err = v.SubtitlesDAO.GetSubtitle(r.Context(), subtitle.ID)
+	if err != nil {
+		log.Error("Cannot get updated subtitle : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, subtitleToResponse(subtitle))
+}
+
+type VideoSubtitleDeleteHandler struct {
+	S3Client     clients.IS3Client
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoSubtitleDeleteHandler godoc
+// @Summary Delete video subtitles
+// @Description Delete a subtitles track of a video
+// @Tags video, subtitles
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param subtitleID path string true "Subtitle ID"
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{subtitleID} [delete]
+func (v VideoSubtitleDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE VideoSubtitleDeleteHandler - Parameters: ", vars)
+
+	subtitle, statusCode := getVideoSubtitle(r.Context(), v.SubtitlesDAO, v.UUIDGen, vars["id"], vars["subtitleID"])
+	if subtitle == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	if err := v.SubtitlesDAO.DeleteSubtitle(r.Context(), subtitle.ID); err != nil {
+		log.Error("Cannot delete subtitle "+subtitle.ID+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The track is no longer referenced, a remaining file is only wasted space
+	if err := v.S3Client.RemoveObject(r.Context(), subtitle.Path); err != nil {
+		log.Error("Cannot remove subtitles "+subtitle.Path+" from S3 : ", err)
+	}
+}
+
+type VideoGetSubtitlePlaylistHandler struct {
+	S3Client     clients.IS3Client
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoGetSubtitlePlaylistHandler godoc
+// @Summary Get subtitles playlist
+// @Description HLS media playlist of a subtitles track, referenced by the video master
+// @Tags video, subtitles
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param subtitleID path string true "Subtitle ID"
+// @Success 200 {string} string "HLS subtitles playlist"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{subtitleID}/playlist.m3u8 [get]
+func (v VideoGetSubtitlePlaylistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetSubtitlePlaylistHandler - Parameters: ", vars)
+
+	query, err := playlistQuery(r)
+	if err != nil {
+		log.Error("Invalid filters : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subtitle, statusCode := getVideoSubtitle(r.Context(), v.SubtitlesDAO, v.UUIDGen, vars["id"], vars["subtitleID"])
+	if subtitle == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	object, err := v.S3Client.GetObject(r.Context(), subtitle.Path)
+	if err != nil {
+		log.Error("Failed to get subtitles from S3 : ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// The whole file is a single segment, as long as its last cue
+	cues, err := subtitles.ParseWebVTT(object)
+	if err != nil {
+		log.Error("Cannot read subtitles "+subtitle.Path+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	duration := subtitles.Duration(cues).Seconds()
+
+	playlist := fmt.Sprintf("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:%.3f,\ntrack.vtt\n#EXT-X-ENDLIST\n",
+		int(math.Max(1, math.Ceil(duration))), duration)
+	lastModified := time.Time{}
+	if subtitle.UpdatedAt != nil {
+		lastModified = *subtitle.UpdatedAt
+	}
+	serveGeneratedContent(w, r, addQueryToPlaylist([]byte(playlist), query), "playlist.m3u8", cacheControlRevalidate, lastModified)
+}
+
+type VideoGetSubtitleTrackHandler struct {
+	S3Client     clients.IS3Client
+	SubtitlesDAO *dao.SubtitlesDAO
+	UUIDGen      clients.IUUIDGenerator
+}
+
+// VideoGetSubtitleTrackHandler godoc
+// @Summary Get subtitles file
+// @Description WebVTT file of a subtitles track
+// @Tags video, subtitles
+// @Produce plain
+// @Param id path string true "Video ID"
+// @Param subtitleID path string true "Subtitle ID"
+// @Success 200 {string} string "WebVTT subtitles"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/subtitles/{subtitleID}/track.vtt [get]
+func (v VideoGetSubtitleTrackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetSubtitleTrackHandler - Parameters: ", vars)
+
+	subtitle, statusCode := getVideoSubtitle(r.Context(), v.SubtitlesDAO, v.UUIDGen, vars["id"], vars["subtitleID"])
+	if subtitle == nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	object, err := v.S3Client.GetObjectFull(r.Context(), subtitle.Path)
+	if err != nil {
+		log.Error("Failed to get subtitles from S3 : ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	serveObject(w, r, object, "track.vtt", cacheControlRevalidate)
+}
+
+// addSubtitlesToMaster declares the subtitles renditions in a HLS master and links them to every variant
+func addSubtitlesToMaster(master []byte, videoSubtitles []models.Subtitle) []byte {
+	if len(videoSubtitles) == 0 {
+		return master
+	}
+
+	var output bytes.Buffer
+	mediaWritten := false
+	scanner := bufio.NewScanner(bytes.NewReader(master))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			if !mediaWritten {
+				for _, subtitle := range videoSubtitles {
+					output.WriteString(subtitleMediaTag(&subtitle) + "\n")
+				}
+				mediaWritten = true
+			}
+			if !strings.Contains(line, "SUBTITLES=") {
+				line += `,SUBTITLES="` + subtitlesGroupID + `"`
+			}
+		}
+
+		output.WriteString(line + "\n")
+	}
+
+	return output.Bytes()
+}
+
+func subtitleMediaTag(subtitle *models.Subtitle) string {
+	yesNo := func(b bool) string {
+		if b {
+			return "YES"
+		}
+		return "NO"
+	}
+
+	// AUTOSELECT must be YES when DEFAULT is YES
+	return fmt.Sprintf(`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="%v",NAME="%v",LANGUAGE="%v",DEFAULT=%v,AUTOSELECT=YES,FORCED=%v,URI="../subtitles/%v/playlist.m3u8"`,
+		subtitlesGroupID, subtitle.Label, subtitle.Language, yesNo(subtitle.IsDefault), yesNo(subtitle.Forced), subtitle.ID)
+}
+
+// readSubtitles validates the uploaded subtitles and returns them as WebVTT
+func readSubtitles(file multipart.File, fileHandler *multipart.FileHeader) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(fileHandler.Filename)) {
+	case ".srt":
+		cues, err := subtitles.ParseSRT(file)
+		if err != nil {
+			log.Error("Invalid SRT subtitles : ", err)
+			return nil, fmt.Errorf("%w : %v", errInvalidSubtitles, err)
+		}
+		return subtitles.ToWebVTT(cues), nil
+
+	case ".vtt":
+		vtt, err := io.ReadAll(file)
+		if err != nil {
+			log.Error("Cannot read subtitles : ", err)
+			return nil, err
+		}
+		if _, err := subtitles.ParseWebVTT(bytes.NewReader(vtt)); err != nil {
+			log.Error("Invalid WebVTT subtitles : ", err)
+			return nil, fmt.Errorf("%w : %v", errInvalidSubtitles, err)
+		}
+		return vtt, nil
+
+	default:
+		log.Error("Unsupported subtitles file : ", fileHandler.Filename)
+		return nil, errUnsupportedSubtitlesFormat
+	}
+}
+
+func subtitlesErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errUnsupportedSubtitlesFormat):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errInvalidSubtitles):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// readUploadedSubtitles reads the subtitles sent along a video, described by the optional subsLanguage and subsLabel fields
+func readUploadedSubtitles(language, label string, file multipart.File, fileHandler *multipart.FileHeader) (*models.Subtitle, []byte, error) {
+	subtitle := &models.Subtitle{
+		Language: "und",
+		Label:    "Subtitles",
+	}
+	if language != "" {
+		subtitle.Language = language
+	}
+	if label != "" {
+		subtitle.Label = label
+	}
+	if !subtitles.IsValidLanguage(subtitle.Language) || !subtitles.IsValidLabel(subtitle.Label) {
+		log.Errorf("Invalid subtitles metadata : '%v' '%v'", subtitle.Language, subtitle.Label)
+		return nil, nil, errInvalidSubtitleMetadata
+	}
+
+	vtt, err := readSubtitles(file, fileHandler)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return subtitle, vtt, nil
+}
+
+// readSubtitleMetadata overrides the subtitle fields given in the form
+func readSubtitleMetadata(r *http.Request, subtitle *models.Subtitle) error {
+	if language := r.FormValue("language"); language != "" {
+		if !subtitles.IsValidLanguage(language) {
+			return fmt.Errorf("invalid language '%v'", language)
+		}
+		subtitle.Language = language
+	}
+
+	if label := r.FormValue("label"); label != "" {
+		subtitle.Label = label
+	}
+	if subtitle.Label == "" {
+		subtitle.Label = subtitle.Language
+	}
+	if !subtitles.IsValidLabel(subtitle.Label) {
+		return fmt.Errorf("invalid label '%v'", subtitle.Label)
+	}
+
+	for field, value := range map[string]*bool{"default": &subtitle.IsDefault, "forced": &subtitle.Forced} {
+		if raw := r.FormValue(field); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("invalid %v '%v'", field, raw)
+			}
+			*value = parsed
+		}
+	}
+
+	return nil
+}
+
+// createSubtitle uploads the WebVTT file on S3 and records the track
+func createSubtitle(ctx context.Context, s3Client clients.IS3Client, subtitlesDAO *dao.SubtitlesDAO, uuidGen clients.IUUIDGenerator, subtitle *models.Subtitle, vtt []byte) error {
+	subtitleID, err := uuidGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new subtitle ID : ", err)
+		return err
+	}
+	subtitle.ID = subtitleID
+	subtitle.Path = subtitle.VideoID + "/subtitles/" + subtitleID + ".vtt"
+
+	if err := s3Client.PutObjectInput(ctx, bytes.NewReader(vtt), subtitle.Path); err != nil {
+		log.Error("Cannot upload subtitles : ", err)
+		return err
+	}
+
+	if err := saveSubtitle(ctx, subtitlesDAO, subtitle, true); err != nil {
+		if err := s3Client.RemoveObject(ctx, subtitle.Path); err != nil {
+			log.Error("Unable to remove uploaded subtitles "+subtitle.Path+" : ", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// saveSubtitle creates or updates the subtitle, a video has at most one default track
+func saveSubtitle(ctx context.Context, subtitlesDAO *dao.SubtitlesDAO, subtitle *models.Subtitle, isNew bool) error {
+	tx, err := subtitlesDAO.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Cannot open new database transaction : ", err)
+		return err
+	}
+
+	// Defer a rollback in case anything fails.
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if isNew {
+		err = subtitlesDAO.CreateSubtitleTx(ctx, tx, subtitle)
+	} else {
+		err = subtitlesDAO.UpdateSubtitleTx(ctx, tx, subtitle)
+	}
+	if err != nil {
+		log.Error("Cannot save subtitle "+subtitle.ID+" : ", err)
+		return err
+	}
+
+	if subtitle.IsDefault {
+		if err := subtitlesDAO.ClearDefaultSubtitleTx(ctx, tx, subtitle.VideoID, subtitle.ID); err != nil {
+			log.Error("Cannot clear default subtitles of video "+subtitle.VideoID+" : ", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Cannot commit database transaction")
+		return err
+	}
+
+	return nil
+}
+
+// getVideoSubtitle returns nil and the HTTP status to answer if the subtitle does not belong to the video
+func getVideoSubtitle(ctx context.Context, subtitlesDAO *dao.SubtitlesDAO, uuidGen clients.IUUIDGenerator, videoID, subtitleID string) (*models.Subtitle, int) {
+	if !uuidGen.IsValidUUID(videoID) || !uuidGen.IsValidUUID(subtitleID) {
+		log.Error("Invalid id")
+		return nil, http.StatusBadRequest
+	}
+
+	subtitle, err := subtitlesDAO.GetSubtitle(ctx, subtitleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, http.StatusNotFound
+		}
+		return nil, http.StatusInternalServerError
+	}
+
+	if subtitle.VideoID != videoID {
+		log.Errorf("Subtitle %v does not belong to video %v", subtitleID, videoID)
+		return nil, http.StatusNotFound
+	}
+
+	return subtitle, http.StatusOK
+}
+
+func checkVideoExists(ctx context.Context, videosDAO *dao.VideosDAO, id string) (int, error) {
+	if _, err := videosDAO.GetVideo(ctx, id); err != nil {
+		log.Error("Cannot found video : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func subtitleToResponse(subtitle *models.Subtitle) SubtitleResponse {
+	base := "api/v1/videos/" + subtitle.VideoID + "/subtitles/" + subtitle.ID
+	return SubtitleResponse{
+		Subtitle: jsonDTO.SubtitleToSubtitleJson(subtitle),
+		Links: map[string]jsonDTO.LinkJson{
+			"self":     jsonDTO.LinkToLinkJson(&models.Link{Href: base, Method: "GET"}),
+			"playlist": jsonDTO.LinkToLinkJson(&models.Link{Href: base + "/playlist.m3u8", Method: "GET"}),
+			"track":    jsonDTO.LinkToLinkJson(&models.Link{Href: base + "/track.vtt", Method: "GET"}),
+		},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, response interface{}) {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(payload)
+}
@@ -0,0 +1,326 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoSubtitles(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "0000a0a0-0aa0-0a00-0000-aa0000aa00aa"
+	subtitleID := "2d0f9a40-3a6c-4a3e-a3a4-5b8e2f0c6a51"
+	subtitlePath := videoID + "/subtitles/" + subtitleID + ".vtt"
+	t1 := time.Now()
+
+	srt := "1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,200\r\nWorld\r\n"
+	vtt := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n00:00:03.000 --> 00:00:04.200\nWorld\n"
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	subtitlesColumns := []string{"id", "video_id", "language", "label", "is_default", "forced", "path", "created_at", "updated_at"}
+	subtitleRow := func(owner, label string, isDefault bool) *sqlmock.Rows {
+		return sqlmock.NewRows(subtitlesColumns).AddRow(subtitleID, owner, "fr", label, isDefault, false, subtitlePath, t1, t1)
+	}
+	expectVideo := func(mock sqlmock.Sqlmock, found bool) {
+		rows := sqlmock.NewRows(videosColumns)
+		if found {
+			rows.AddRow(videoID, "title", models.COMPLETE, t1, t1, t1, videoID+"/source.mp4", "", nil, nil, "public")
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])).WithArgs(videoID).WillReturnRows(rows)
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveWithAuth     bool
+		giveFields       map[string]string
+		giveFilename     string
+		giveFile         string
+		expectQueries    func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedUpload   string
+		expectedRemove   string
+		expectedLabel    string
+		expectedBody     string
+	}{
+		{
+			name:         "GET subtitles list",
+			giveMethod:   http.MethodGet,
+			giveRequest:  "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectVideo(mock, true)
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles])).WithArgs(videoID).
+					WillReturnRows(subtitleRow(videoID, "Français", true))
+			},
+			expectedHTTPCode: 200,
+			expectedLabel:    "Français",
+		},
+		{
+			name:             "GET subtitles list fails with unknown video",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth:     true,
+			expectQueries:    func(mock sqlmock.Sqlmock) { expectVideo(mock, false) },
+			expectedHTTPCode: 404,
+		},
+		{
+			name:         "POST SRT subtitles converted to WebVTT",
+			giveMethod:   http.MethodPost,
+			giveRequest:  "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth: true,
+			giveFields:   map[string]string{"language": "fr", "label": "Français", "default": "true"},
+			giveFilename: "subs.srt",
+			giveFile:     srt,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				expectVideo(mock, true)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.CreateSubtitle])).
+					WithArgs(subtitleID, videoID, "fr", "Français", true, false, subtitlePath).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.ClearDefaultSubtitle])).
+					WithArgs(videoID, subtitleID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(videoID, "Français", true))
+			},
+			expectedHTTPCode: 200,
+			expectedUpload:   vtt,
+			expectedLabel:    "Français",
+		},
+		{
+			name:             "POST fails with invalid SRT",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth:     true,
+			giveFields:       map[string]string{"language": "fr"},
+			giveFilename:     "subs.srt",
+			giveFile:         "1\n00:00:01 --> 00:00:02\nHello\n",
+			expectedHTTPCode: 422,
+		},
+		{
+			name:             "POST fails with unsupported format",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth:     true,
+			giveFields:       map[string]string{"language": "fr"},
+			giveFilename:     "subs.txt",
+			giveFile:         srt,
+			expectedHTTPCode: 415,
+		},
+		{
+			name:             "POST fails without language",
+			giveMethod:       http.MethodPost,
+			giveRequest:      "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth:     true,
+			giveFilename:     "subs.vtt",
+			giveFile:         vtt,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:         "PUT subtitles label",
+			giveMethod:   http.MethodPut,
+			giveRequest:  "/api/v1/videos/" + videoID + "/subtitles/" + subtitleID,
+			giveWithAuth: true,
+			giveFields:   map[string]string{"label": "Français (CA)"},
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(videoID, "Français", false))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.UpdateSubtitle])).
+					WithArgs("fr", "Français (CA)", false, false, subtitlePath, subtitleID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(videoID, "Français (CA)", false))
+			},
+			expectedHTTPCode: 200,
+			expectedLabel:    "Français (CA)",
+		},
+		{
+			name:         "DELETE subtitles",
+			giveMethod:   http.MethodDelete,
+			giveRequest:  "/api/v1/videos/" + videoID + "/subtitles/" + subtitleID,
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(videoID, "Français", false))
+				mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.DeleteSubtitle])).WithArgs(subtitleID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+			expectedRemove:   subtitlePath,
+		},
+		{
+			name:         "GET fails with subtitles of another video",
+			giveMethod:   http.MethodGet,
+			giveRequest:  "/api/v1/videos/" + videoID + "/subtitles/" + subtitleID,
+			giveWithAuth: true,
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(otherVideoID, "Français", false))
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:        "GET subtitles playlist",
+			giveMethod:  http.MethodGet,
+			giveRequest: "/videos/" + videoID + "/subtitles/" + subtitleID + "/playlist.m3u8" + streamTokenQuery(videoID),
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(videoID, "Français", false))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:4.200,\ntrack.vtt" + streamTokenQuery(videoID) + "\n#EXT-X-ENDLIST\n",
+		},
+		{
+			name:        "GET subtitles track",
+			giveMethod:  http.MethodGet,
+			giveRequest: "/videos/" + videoID + "/subtitles/" + subtitleID + "/track.vtt" + streamTokenQuery(videoID),
+			expectQueries: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle])).WithArgs(subtitleID).
+					WillReturnRows(subtitleRow(videoID, "Français", false))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     vtt,
+		},
+		{
+			name:             "GET subtitles list fails with no auth",
+			giveMethod:       http.MethodGet,
+			giveRequest:      "/api/v1/videos/" + videoID + "/subtitles",
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			uploaded := ""
+			putObject := func(f io.Reader, path string) error {
+				content, err := io.ReadAll(f)
+				require.Equal(t, subtitlePath, path)
+				uploaded = string(content)
+				return err
+			}
+			removed := ""
+			removeObject := func(path string) error { removed = path; return nil }
+			getObject := func(path string) (io.Reader, error) {
+				require.Equal(t, subtitlePath, path)
+				return strings.NewReader(vtt), nil
+			}
+
+			routerClients := router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, putObject, nil, removeObject),
+				UUIDGen: clients.NewUuidGeneratorDummy(
+					func() (string, error) { return subtitleID, nil },
+					func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectSubtitlesDAOCreation(mock)
+			if tt.expectQueries != nil {
+				tt.expectQueries(mock)
+			}
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:    *videosDAO,
+				SubtitlesDAO: *subtitlesDAO,
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth:          givenUsername,
+				PwdAuth:           givenUserPwd,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
+			}, &routerClients, &routerDAO)
+
+			// Dummy multipart body creation
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			for field, value := range tt.giveFields {
+				require.NoError(t, writer.WriteField(field, value))
+			}
+			if tt.giveFilename != "" {
+				fileWriter, err := writer.CreateFormFile("subs", tt.giveFilename)
+				require.NoError(t, err)
+				_, err = fileWriter.Write([]byte(tt.giveFile))
+				require.NoError(t, err)
+			}
+			writer.Close()
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			require.Equal(t, tt.expectedUpload, uploaded)
+			require.Equal(t, tt.expectedRemove, removed)
+
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+
+			if tt.expectedLabel != "" {
+				var label string
+				if tt.giveMethod == http.MethodGet {
+					var response controllers.SubtitlesListResponse
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					require.Len(t, response.Subtitles, 1)
+					label = response.Subtitles[0].Subtitle.Label
+				} else {
+					var response controllers.SubtitleResponse
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					require.Equal(t, subtitleID, response.Subtitle.ID)
+					label = response.Subtitle.Label
+				}
+				require.Equal(t, tt.expectedLabel, label)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+)
+
+// Files generated by the encoder in the thumbnails folder
+var thumbnailsFilenameRegexp = regexp.MustCompile(`^(sprite_[0-9]+\.jpg|` + regexp.QuoteMeta(ffmpeg.ThumbnailsVTT) + `)$`)
+
+// Sprite references of the thumbnails track, e.g. "sprite_0.jpg#xywh=160,0,160,90"
+var thumbnailsSpriteRegexp = regexp.MustCompile(`(?m)^(sprite_[0-9]+\.jpg)#`)
+
+type VideoGetThumbnailsHandler struct {
+	S3Client clients.IS3Client
+	UUIDGen  clients.IUUIDGenerator
+}
+
+// VideoGetThumbnailsHandler godoc
+// @Summary Get seek-preview thumbnails
+// @Description Get the WebVTT file mapping the timeline to thumbnails (thumbnails.vtt), or a sprite sheet it refers to (sprite_N.jpg)
+// @Tags video
+// @Produce text/vtt,image/jpeg
+// @Param id path string true "Video ID"
+// @Param filename path string true "thumbnails.vtt or sprite_N.jpg"
+// @Success 200 {file} binary "WebVTT thumbnails track or sprite sheet"
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/videos/{id}/thumbnails/{filename} [get]
+func (v VideoGetThumbnailsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET VideoGetThumbnailsHandler - Parameters: ", vars)
+
+	id := vars["id"]
+	if !v.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filename := vars["filename"]
+	if !thumbnailsFilenameRegexp.MatchString(filename) {
+		log.Error("Invalid thumbnails file : ", filename)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	query, err := playlistQuery(r)
+	if err != nil {
+		log.Error("Invalid filters : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	object, err := v.S3Client.GetObjectFull(r.Context(), id+"/"+ffmpeg.ThumbnailsFolder+"/"+filename)
+	if err != nil {
+		log.Error("Failed to get thumbnails "+filename+" : ", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if filename != ffmpeg.ThumbnailsVTT || len(query) == 0 {
+		// Thumbnails are generated once, with the renditions
+		serveObject(w, r, object, filename, cacheControlImmutable)
+		return
+	}
+	defer object.Body.Close()
+
+	// The sprites are requested with the parameters of the track
+	vtt, err := io.ReadAll(object.Body)
+	if err != nil {
+		log.Error("Unable to read thumbnails track", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	vtt = thumbnailsSpriteRegexp.ReplaceAll(vtt, []byte("${1}?"+query.Encode()+"#"))
+
+	lastModified := time.Time{}
+	if object.LastModified != nil {
+		lastModified = *object.LastModified
+	}
+	serveGeneratedContent(w, r, vtt, filename, cacheControlImmutable, lastModified)
+}
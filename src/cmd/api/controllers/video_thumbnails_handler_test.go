@@ -0,0 +1,123 @@
+package controllers_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoThumbnails(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	validVideoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	invalidVideoID := "invalidvideoid"
+
+	thumbnailsVTT := "WEBVTT\n\n00:00.000 --> 00:05.000\nsprite_0.jpg#xywh=0,0,160,90\n"
+
+	getObject := func(path string) (io.Reader, error) {
+		switch path {
+		case validVideoID + "/thumbnails/thumbnails.vtt":
+			return strings.NewReader(thumbnailsVTT), nil
+		case validVideoID + "/thumbnails/sprite_0.jpg":
+			return strings.NewReader(path), nil
+		}
+		return nil, fmt.Errorf("S3 error")
+	}
+
+	cases := []struct {
+		name                string
+		giveRequest         string
+		giveWithAuth        bool
+		expectedHTTPCode    int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "GET thumbnails track",
+			giveRequest:         "/api/v1/videos/" + validVideoID + "/thumbnails/thumbnails.vtt",
+			giveWithAuth:        true,
+			expectedHTTPCode:    200,
+			expectedContentType: "text/vtt; charset=utf-8",
+			expectedBody:        thumbnailsVTT,
+		},
+		{
+			name:                "GET public thumbnails track passes the token to the sprites",
+			giveRequest:         "/videos/" + validVideoID + "/thumbnails/thumbnails.vtt" + streamTokenQuery(validVideoID),
+			expectedHTTPCode:    200,
+			expectedContentType: "text/vtt; charset=utf-8",
+			expectedBody:        "WEBVTT\n\n00:00.000 --> 00:05.000\nsprite_0.jpg" + streamTokenQuery(validVideoID) + "#xywh=0,0,160,90\n",
+		},
+		{
+			name:                "GET public thumbnails sprite",
+			giveRequest:         "/videos/" + validVideoID + "/thumbnails/sprite_0.jpg" + streamTokenQuery(validVideoID),
+			expectedHTTPCode:    200,
+			expectedContentType: "image/jpeg",
+		},
+		{
+			name:             "GET fails with missing sprite",
+			giveRequest:      "/videos/" + validVideoID + "/thumbnails/sprite_1.jpg" + streamTokenQuery(validVideoID),
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with other file of the video",
+			giveRequest:      "/videos/" + validVideoID + "/thumbnails/source.mp4" + streamTokenQuery(validVideoID),
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET fails with invalid video ID",
+			giveRequest:      "/videos/" + invalidVideoID + "/thumbnails/thumbnails.vtt" + streamTokenQuery(invalidVideoID),
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "GET fails with no auth",
+			giveRequest:      "/api/v1/videos/" + validVideoID + "/thumbnails/thumbnails.vtt",
+			giveWithAuth:     false,
+			expectedHTTPCode: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			routerClients := router.Clients{
+				S3Client: clients.NewS3ClientDummy(nil, getObject, nil, nil, nil),
+				UUIDGen:  clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth:          givenUsername,
+				PwdAuth:           givenUserPwd,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
+			}, &routerClients, &router.DAOs{})
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, tt.giveRequest, nil)
+			if tt.giveWithAuth {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			if tt.expectedContentType != "" {
+				require.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			if tt.expectedBody != "" {
+				require.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -10,13 +10,15 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 )
 
 type VideoUnarchiveHandler struct {
-	VideosDAO *dao.VideosDAO
-	UUIDGen   clients.IUUIDGenerator
+	AmqpVideoStatusUpdate clients.AmqpClient
+	VideosDAO             *dao.VideosDAO
+	UUIDGen               clients.IUUIDGenerator
 }
 
 // VideoUnarchiveHandler godoc
@@ -57,6 +59,8 @@ func (v VideoUnarchiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(statusCode)
 		return
 	}
+
+	eventhandler.PublishVideoStatusEvent(v.AmqpVideoStatusUpdate, video, models.ARCHIVE)
 }
 
 func (v VideoUnarchiveHandler) unarchiveVideo(ctx context.Context, video *models.Video) (int, error) {
@@ -120,7 +120,8 @@ func TestVideoUnarchive(t *testing.T) { //nolint:cyclop
 			defer db.Close()
 
 			routerClients := router.Clients{
-				UUIDGen: clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, tt.isValidUUID),
 			}
 
 			dao_test.ExpectVideosDAOCreation(mock)
@@ -134,7 +135,7 @@ func TestVideoUnarchive(t *testing.T) { //nolint:cyclop
 				updateVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				// Define database response according to case
@@ -144,7 +145,7 @@ func TestVideoUnarchive(t *testing.T) { //nolint:cyclop
 				} else if tt.giveRequest == "/api/v1/videos/"+unknownVideoID+"/unarchive" {
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 				} else {
-					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath)
+					videosRows.AddRow(validVideoID, videoTitle, int(tt.status), t1, t1, nil, sourcePath, coverPath, nil, nil, "public")
 					mock.ExpectQuery(getVideoFromIdQuery).WillReturnRows(videosRows)
 
 					if tt.status == models.ARCHIVE {
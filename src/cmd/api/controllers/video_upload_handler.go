@@ -17,23 +17,29 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 	"github.com/Sogilis/Voogle/src/pkg/events"
 
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
-	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
 	protobufDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
 	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
 type VideoUploadHandler struct {
+	Config                config.Config
 	S3Client              clients.IS3Client
 	AmqpClient            clients.AmqpClient
 	AmqpVideoStatusUpdate clients.AmqpClient
 	VideosDAO             *dao.VideosDAO
 	UploadsDAO            *dao.UploadsDAO
+	StorageUsagesDAO      *dao.StorageUsagesDAO
+	SubtitlesDAO          *dao.SubtitlesDAO
 	UUIDGen               clients.IUUIDGenerator
+	VideoProber           clients.IVideoProber
 }
 
 type Response struct {
@@ -48,15 +54,26 @@ type Response struct {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "video"
+// @Param subs formData file false "SRT or WebVTT subtitles"
+// @Param subsLanguage formData string false "Subtitles language tag (BCP 47), und by default"
+// @Param subsLabel formData string false "Subtitles name displayed by the players"
+// @Param visibility formData string false "private (default), unlisted, shared or public"
 // @Success 200 {object} Response "Video and Links (HATEOAS)"
 // @Failure 400 {string} string
 // @Failure 409 {string} string "This title already exists"
+// @Failure 413 {object} UploadLimitError "Upload too large or storage quota exceeded"
 // @Failure 415 {string} string
+// @Failure 422 {object} UploadLimitError "Video duration or resolution exceeds the limits"
 // @Failure 500 {string} string
 // @Router /api/v1/videos/upload [post]
 func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) { //nolint:cyclop
 	log.Debug("POST VideoUploadHandler")
 
+	if violation := v.parseUploadForm(w, r); violation != nil {
+		writeUploadLimitError(w, violation)
+		return
+	}
+
 	// Fetch title
 	title := r.FormValue("title")
 	if title == "" {
@@ -66,6 +83,13 @@ func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Infof("Receive video upload request with title : '%v'", title)
 
+	visibility, err := parseVisibility(r.FormValue("visibility"))
+	if err != nil {
+		log.Error("Invalid visibility : ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Fetch video
 	fileVideo, fileHandler, err := r.FormFile("video")
 	if err != nil {
@@ -104,9 +128,22 @@ func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	var subtitle *models.Subtitle
+	var vtt []byte
 	if subtitles != nil {
 		defer subtitles.Close()
-		//TODO check subtitles are valid
+
+		subtitle, vtt, err = readUploadedSubtitles(r.FormValue("subsLanguage"), r.FormValue("subsLabel"), subtitles, subtitileHandler)
+		if err != nil {
+			w.WriteHeader(subtitlesErrorStatus(err))
+			return
+		}
+	}
+
+	uploader := uploaderName(r)
+	if violation := v.checkUploadLimits(r.Context(), fileVideo, fileHandler.Size, uploader); violation != nil {
+		writeUploadLimitError(w, violation)
+		return
 	}
 
 	// Check if a video with this title already exists
@@ -118,8 +155,16 @@ func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if video != nil {
 		// If a video with the same title already exists, and if its status is failed upload/encode,
 		// try to re-upload/re-encode as needed
-		if video.Status == models.FAIL_UPLOAD || video.Status == models.FAIL_ENCODE {
-			v.resumeVideoUpload(r.Context(), video, fileCover, fileVideo, fileHandlerCover, w)
+		if (video.Status == models.FAIL_UPLOAD || video.Status == models.FAIL_ENCODE) && canManageVideo(UserFromContext(r.Context()), video) {
+			video, err = v.resumeVideoUpload(r.Context(), video, fileCover, fileVideo, fileHandlerCover, fileHandler.Size, uploader, subtitle, vtt)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			// Include video and HATEOAS upload link into response
+			writeHTTPResponse(video, w)
+			log.Infof("Video '%v' successfully uploaded", video.Title)
 			return
 		} else {
 			// Title already exist, video already uploaded and encoded, return error
@@ -145,23 +190,16 @@ func (v VideoUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Upload subtitles (if file exists) on S3
-	// TODO update database?
-	_, err = v.uploadSubtitles(r.Context(), subtitles, videoID, subtitileHandler)
-	if err != nil {
-		log.Error("Cannot upload subtitles : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
 	// Upload video on S3, update database
 	videoPath := videoID + "/" + "source" + filepath.Ext(fileHandler.Filename)
-	videoCreated, err := v.uploadVideo(r.Context(), videoID, title, videoPath, coverPath, fileVideo, nil)
+	videoCreated, err := v.uploadVideo(r.Context(), videoID, title, videoPath, coverPath, fileVideo, fileHandler.Size, nil, visibility)
 	if err != nil {
 		log.Error("Cannot upload video : ", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	v.recordStorageUsage(r.Context(), videoCreated, uploader, fileHandler.Size)
+	v.addUploadedSubtitles(r.Context(), videoCreated, subtitle, vtt)
 
 	if err = v.sendVideoForEncoding(r.Context(), videoCreated); err != nil {
 		log.Error("Cannot send video for encoding : ", err)
@@ -214,7 +252,7 @@ func isSupportedCoverType(input io.ReaderAt) bool {
 	return false
 }
 
-func (v VideoUploadHandler) resumeVideoUpload(ctx context.Context, video *models.Video, fileCover, fileVideo multipart.File, fileHandler *multipart.FileHeader, w http.ResponseWriter) {
+func (v VideoUploadHandler) resumeVideoUpload(ctx context.Context, video *models.Video, fileCover, fileVideo multipart.File, fileHandler *multipart.FileHeader, size int64, uploader string, subtitle *models.Subtitle, vtt []byte) (*models.Video, error) {
 
 	// If the upload failed before the encoding started, then we have to fix the upload before resuming with the encoding.
 	if video.Status == models.FAIL_UPLOAD {
@@ -222,27 +260,25 @@ func (v VideoUploadHandler) resumeVideoUpload(ctx context.Context, video *models
 		coverPath, err := v.uploadCover(ctx, fileCover, video.ID, fileHandler)
 		if err != nil {
 			log.Error("Cannot upload cover image : ", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, err
 		}
 
-		video, err = v.uploadVideo(ctx, video.ID, video.Title, video.SourcePath, coverPath, fileVideo, video)
+		video, err = v.uploadVideo(ctx, video.ID, video.Title, video.SourcePath, coverPath, fileVideo, size, video, video.Visibility)
 		if err != nil {
 			log.Error("Cannot upload video : ", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, err
 		}
+		v.recordStorageUsage(ctx, video, uploader, size)
 	}
+	v.addUploadedSubtitles(ctx, video, subtitle, vtt)
 
 	log.Debug("Try to re-encode failed video")
 	if err := v.sendVideoForEncoding(ctx, video); err != nil {
 		log.Error("Cannot send video for encoding : ", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, err
 	}
-	// Include video and HATEOAS upload link into response
-	writeHTTPResponse(video, w)
-	log.Infof("Video '%v' successfully uploaded", video.Title)
+
+	return video, nil
 }
 
 func (v VideoUploadHandler) uploadCover(ctx context.Context, cover multipart.File, videoID string, fileHandler *multipart.FileHeader) (string, error) {
@@ -257,31 +293,33 @@ func (v VideoUploadHandler) uploadCover(ctx context.Context, cover multipart.Fil
 	return coverPath, nil
 }
 
-func (v VideoUploadHandler) uploadSubtitles(ctx context.Context, cover multipart.File, videoID string, fileHandler *multipart.FileHeader) (string, error) {
-	subtitlesPath := ""
-	if cover != nil {
-		subtitlesPath = videoID + "/" + "subs" + filepath.Ext(fileHandler.Filename)
-		if err := v.S3Client.PutObjectInput(ctx, cover, subtitlesPath); err != nil {
-			log.Error("Cannot upload subtitles : ", err)
-			return "", err
-		}
+// addUploadedSubtitles only logs on failure : the subtitles can be added again once the video is uploaded
+func (v VideoUploadHandler) addUploadedSubtitles(ctx context.Context, video *models.Video, subtitle *models.Subtitle, vtt []byte) {
+	if subtitle == nil {
+		return
+	}
+
+	subtitle.VideoID = video.ID
+	if err := createSubtitle(ctx, v.S3Client, v.SubtitlesDAO, v.UUIDGen, subtitle, vtt); err != nil {
+		log.Errorf("Unable to add subtitles to video %v : %v", video.ID, err)
 	}
-	return subtitlesPath, nil
 }
 
-func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, videoPath, coverPath string, file multipart.File, video *models.Video) (*models.Video, error) {
+// uploadVideo creates the video owned by the user of ctx, unless it exists already, then stores its source of the given size
+func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, videoPath, coverPath string, file multipart.File, size int64, video *models.Video, visibility models.Visibility) (*models.Video, error) {
 	metrics.CounterVideoUploadRequest.Inc()
 
 	// video not nil means that the video already exists. So we are in case of recover after error
 	if video == nil {
 		var err error
-		video, err = v.VideosDAO.CreateVideo(ctx, videoID, title, int(models.UPLOADING), videoPath, coverPath)
+		video, err = v.VideosDAO.CreateVideo(ctx, videoID, title, int(models.UPLOADING), videoPath, coverPath, ownerIDOf(UserFromContext(ctx)), visibility)
 		if err != nil {
 			metrics.CounterVideoUploadFail.Inc()
 			log.Error("Cannot generate new uploadID : ", err)
 
 			return nil, err
 		}
+		v.publishStatus(video)
 	}
 
 	uploadID, err := v.UUIDGen.GenerateUuid()
@@ -303,7 +341,7 @@ func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, vid
 	}
 
 	// Upload video on S3
-	err = v.S3Client.PutObjectInput(ctx, file, video.SourcePath)
+	err = v.S3Client.PutObjectInput(ctx, v.newUploadProgressReader(file, size, video), video.SourcePath)
 	if err != nil {
 		metrics.CounterVideoUploadFail.Inc()
 		log.Error("Unable to put object input on S3 ", err)
@@ -366,10 +404,21 @@ func (v VideoUploadHandler) uploadVideo(ctx context.Context, videoID, title, vid
 	return video, nil
 }
 
+// recordStorageUsage only logs on failure : the video is already stored, refusing it now would be worse than a lax quota
+func (v VideoUploadHandler) recordStorageUsage(ctx context.Context, video *models.Video, uploader string, size int64) {
+	if err := v.StorageUsagesDAO.CreateStorageUsage(ctx, video.ID, uploader, size); err != nil {
+		log.Errorf("Unable to record storage usage of video %v : %v", video.ID, err)
+	}
+}
+
 func (v VideoUploadHandler) sendVideoForEncoding(ctx context.Context, video *models.Video) error {
+	return v.sendVideoProtoForEncoding(ctx, video, protobufDTO.VideoToVideoProtobuf(video))
+}
+
+// sendVideoProtoForEncoding publishes the video to the encoder, along with the instructions of its protobuf (e.g. the clip to cut)
+func (v VideoUploadHandler) sendVideoProtoForEncoding(ctx context.Context, video *models.Video, videoProto *contracts.Video) error {
 	metrics.CounterVideoEncodeRequest.Inc()
 
-	videoProto := protobufDTO.VideoToVideoProtobuf(video)
 	videoData, err := proto.Marshal(videoProto)
 	if err != nil {
 		metrics.CounterVideoEncodeFail.Inc()
@@ -447,16 +496,18 @@ func (v VideoUploadHandler) videoAndUploadFailed(ctx context.Context, video *mod
 	return nil
 }
 
-func writeHTTPResponse(video *models.Video, w http.ResponseWriter) {
-	// Include videoCreated and status link into response (HATEOAS)
-	links := map[string]jsonDTO.LinkJson{
+func uploadedVideoLinks(video *models.Video) map[string]jsonDTO.LinkJson {
+	return map[string]jsonDTO.LinkJson{
 		"status": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + video.ID + "/status", Method: "GET"}),
 		"stream": jsonDTO.LinkToLinkJson(&models.Link{Href: "api/v1/videos/" + video.ID + "/streams/master.m3u8", Method: "GET"}),
 	}
+}
 
+func writeHTTPResponse(video *models.Video, w http.ResponseWriter) {
+	// Include videoCreated and status link into response (HATEOAS)
 	response := Response{
 		Video: jsonDTO.VideoToVideoJson(video),
-		Links: links,
+		Links: uploadedVideoLinks(video),
 	}
 
 	payload, err := json.Marshal(response)
@@ -468,14 +519,41 @@ func writeHTTPResponse(video *models.Video, w http.ResponseWriter) {
 	_, _ = w.Write(payload)
 }
 
-func (v VideoUploadHandler) publishStatus(video *models.Video) {
-	msg, err := proto.Marshal(protobuf.VideoToVideoProtobuf(video))
-	if err != nil {
-		log.Error("Failed to Marshal status", err)
-		return
+// Period between two upload progress events of a video
+const uploadProgressInterval = time.Second
+
+// uploadProgressReader publishes the share of the source read, at most every uploadProgressInterval
+type uploadProgressReader struct {
+	reader        io.Reader
+	size          int64
+	read          int64
+	lastPublished time.Time
+	publish       func(progress float64)
+}
+
+func (v VideoUploadHandler) newUploadProgressReader(file io.Reader, size int64, video *models.Video) *uploadProgressReader {
+	return &uploadProgressReader{
+		reader:        file,
+		size:          size,
+		lastPublished: time.Now(),
+		publish: func(progress float64) {
+			eventhandler.PublishVideoEvent(v.AmqpVideoStatusUpdate, models.UPLOAD_PROGRESS_EVENT, video, progress)
+		},
 	}
+}
+
+func (u *uploadProgressReader) Read(p []byte) (int, error) {
+	n, err := u.reader.Read(p)
+	u.read += int64(n)
 
-	if err := v.AmqpVideoStatusUpdate.Publish(video.Title, msg); err != nil {
-		log.Error("Unable to publish status update", err)
+	// The end of the upload is told by the uploaded status
+	if u.size > 0 && u.read < u.size && time.Since(u.lastPublished) >= uploadProgressInterval {
+		u.lastPublished = time.Now()
+		u.publish(float64(u.read) / float64(u.size))
 	}
+	return n, err
+}
+
+func (v VideoUploadHandler) publishStatus(video *models.Video) {
+	eventhandler.PublishVideoEvent(v.AmqpVideoStatusUpdate, models.STATUS_EVENT, video, 0)
 }
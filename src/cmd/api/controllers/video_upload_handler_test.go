@@ -50,6 +50,7 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 		giveTitle               string
 		giveFieldVideo          string
 		giveCover               string
+		giveVisibility          string
 		giveFieldCover          string
 		giveEmptyBody           bool
 		giveWrongMagic          bool
@@ -119,6 +120,15 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			putObject:         func(f io.Reader, s string) error { _, err := io.ReadAll(f); return err },
 			amqpClientPublish: func(string, []byte) error { return nil },
 		},
+		{
+			name:             "POST upload video fails with unknown visibility",
+			giveRequest:      "/api/v1/videos/upload",
+			giveWithAuth:     true,
+			giveTitle:        "title-of-video",
+			giveFieldVideo:   "video",
+			giveVisibility:   "secret",
+			expectedHTTPCode: 400,
+		},
 		{
 			name:              "POST upload video unsupported cover image",
 			giveRequest:       "/api/v1/videos/upload",
@@ -369,9 +379,10 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 			dao_test.ExpectVideosDAOCreation(mock)
 			dao_test.ExpectUploadsDAOCreation(mock)
+			dao_test.ExpectStorageUsagesDAOCreation(mock)
 
 			if tt.giveTitle == "" || tt.giveEmptyBody || tt.giveFieldVideo == "NOT-video" ||
-				tt.giveWrongMagic || !tt.giveWithAuth || tt.giveCover == "cover.gif" {
+				tt.giveWrongMagic || !tt.giveWithAuth || tt.giveCover == "cover.gif" || tt.giveVisibility == "secret" {
 				// All these cases will stop before modifying the database : Nothing to do
 
 			} else {
@@ -386,8 +397,10 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 				updateUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.UpdateUpload])
 				getUploadQuery := regexp.QuoteMeta(dao.UploadsRequests[dao.GetUpload])
 
+				createStorageUsageQuery := regexp.QuoteMeta(dao.StorageUsagesRequests[dao.CreateStorageUsage])
+
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
 				uploadsColumns := []string{"id", "video_id", "upload_status", "uploaded_at", "created_at", "updated_at"}
 				videosRows := sqlmock.NewRows(videosColumns)
 				uploadRows := sqlmock.NewRows(uploadsColumns)
@@ -404,7 +417,7 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 				}
 
 				if tt.titleAlreadyExists {
-					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath)
+					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath, nil, nil, "public")
 					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(res)
 
 				} else if tt.uploadVideoOnS3fail {
@@ -413,10 +426,10 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 					if tt.giveCover == "" {
 						// Create Video
 						mock.ExpectExec(createVideoQuery).
-							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath).
+							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath, nil, models.PRIVATE).
 							WillReturnResult(sqlmock.NewResult(1, 1))
 
-						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath)
+						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath, nil, nil, "public")
 						mock.ExpectQuery(getVideoFromIdQuery).WithArgs(VideoID).WillReturnRows(res)
 
 						// Create Upload
@@ -451,11 +464,11 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 					// Create Video (fail)
 					mock.ExpectExec(createVideoQuery).
-						WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath).
+						WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath, nil, models.PRIVATE).
 						WillReturnError(fmt.Errorf("Error while creating new video"))
 
 				} else if tt.lastEncodeFailed {
-					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_ENCODE, nil, t1, t1, sourcePath, coverPath)
+					res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_ENCODE, nil, t1, t1, sourcePath, coverPath, nil, nil, "public")
 					mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(res)
 
 					// Update video status : ENCODING
@@ -465,7 +478,7 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 				} else {
 					if tt.lastUploadFailed {
-						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_UPLOAD, nil, t1, t1, sourcePath, coverPath)
+						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.FAIL_UPLOAD, nil, t1, t1, sourcePath, coverPath, nil, nil, "public")
 						mock.ExpectQuery(getVideoFromTitleQuery).WithArgs(tt.giveTitle).WillReturnRows(res)
 
 					} else {
@@ -473,10 +486,10 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 
 						// Create Video
 						mock.ExpectExec(createVideoQuery).
-							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath).
+							WithArgs(VideoID, tt.giveTitle, models.UPLOADING, sourcePath, coverPath, nil, models.PRIVATE).
 							WillReturnResult(sqlmock.NewResult(1, 1))
 
-						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath)
+						res := sqlmock.NewRows(videosColumns).AddRow(VideoID, tt.giveTitle, models.UPLOADING, nil, t1, t1, sourcePath, coverPath, nil, nil, "public")
 						mock.ExpectQuery(getVideoFromIdQuery).WithArgs(VideoID).WillReturnRows(res)
 					}
 
@@ -550,6 +563,11 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 									WithArgs(VideoID, models.DONE, AnyTime{}, UploadID).
 									WillReturnResult(sqlmock.NewResult(0, 1))
 
+								// Record storage usage of the uploader
+								mock.ExpectExec(createStorageUsageQuery).
+									WithArgs(VideoID, givenUsername, sqlmock.AnyArg()).
+									WillReturnResult(sqlmock.NewResult(1, 1))
+
 								if tt.publishToEncoderFail {
 									// Update video status : ENCODING
 									mock.ExpectExec(updateVideoQuery).
@@ -572,6 +590,10 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			writer := multipart.NewWriter(body)
 			err = writer.WriteField("title", tt.giveTitle)
 			require.NoError(t, err)
+			if tt.giveVisibility != "" {
+				err = writer.WriteField("visibility", tt.giveVisibility)
+				require.NoError(t, err)
+			}
 
 			if !tt.giveEmptyBody {
 				fileWriter, _ := writer.CreateFormFile(tt.giveFieldVideo, "4K.mp4")
@@ -646,9 +668,13 @@ func TestVideoUploadHandler(t *testing.T) { //nolint:cyclop
 			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
 			require.NoError(t, err)
 
+			storageUsagesDAO, err := dao.CreateStorageUsagesDAO(context.Background(), db)
+			require.NoError(t, err)
+
 			routerDAO := router.DAOs{
-				VideosDAO:  *videosDAO,
-				UploadsDAO: *uploadsDAO,
+				VideosDAO:        *videosDAO,
+				UploadsDAO:       *uploadsDAO,
+				StorageUsagesDAO: *storageUsagesDAO,
 			}
 
 			r := router.NewRouter(config.Config{
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Same in-memory limit as the one used by r.FormFile
+const uploadMaxMemory = 32 << 20
+
+type UploadLimitErrorCode string
+
+const (
+	FILE_TOO_LARGE      UploadLimitErrorCode = "file_too_large"
+	QUOTA_EXCEEDED      UploadLimitErrorCode = "quota_exceeded"
+	DURATION_EXCEEDED   UploadLimitErrorCode = "duration_exceeded"
+	RESOLUTION_EXCEEDED UploadLimitErrorCode = "resolution_exceeded"
+	UNREADABLE_VIDEO    UploadLimitErrorCode = "unreadable_video"
+)
+
+// UploadLimitError is the body returned when an upload violates one of the configured limits
+type UploadLimitError struct {
+	Error   UploadLimitErrorCode `json:"error" example:"file_too_large"`
+	Message string               `json:"message" example:"Upload exceeds the maximum size of 1073741824 bytes"`
+	Limit   string               `json:"limit,omitempty" example:"1073741824"`
+}
+
+// uploadLimitViolation is returned by the upload checks, Body is nil for internal errors
+type uploadLimitViolation struct {
+	Code int
+	Body *UploadLimitError
+}
+
+func writeUploadLimitError(w http.ResponseWriter, violation *uploadLimitViolation) {
+	if violation.Body == nil {
+		w.WriteHeader(violation.Code)
+		return
+	}
+
+	payload, err := json.Marshal(violation.Body)
+	if err != nil {
+		log.Error("Unable to parse data struct in json ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(violation.Code)
+	_, _ = w.Write(payload)
+}
+
+func (v VideoUploadHandler) fileTooLarge() *uploadLimitViolation {
+	return &uploadLimitViolation{
+		Code: http.StatusRequestEntityTooLarge,
+		Body: &UploadLimitError{
+			Error:   FILE_TOO_LARGE,
+			Message: fmt.Sprintf("Upload exceeds the maximum size of %v bytes", v.Config.MaxUploadBytes),
+			Limit:   fmt.Sprint(v.Config.MaxUploadBytes),
+		},
+	}
+}
+
+// parseUploadForm bounds the request body to the configured size before parsing the multipart form
+func (v VideoUploadHandler) parseUploadForm(w http.ResponseWriter, r *http.Request) *uploadLimitViolation {
+	if v.Config.MaxUploadBytes > 0 {
+		// Fail fast when the client announces the size of the body
+		if r.ContentLength > v.Config.MaxUploadBytes {
+			log.Errorf("Upload of %v bytes refused", r.ContentLength)
+			return v.fileTooLarge()
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, v.Config.MaxUploadBytes)
+	}
+
+	if err := r.ParseMultipartForm(uploadMaxMemory); err != nil {
+		// http.MaxBytesError is not available with go 1.18
+		if strings.Contains(err.Error(), "request body too large") {
+			log.Error("Upload refused : ", err)
+			return v.fileTooLarge()
+		}
+
+		log.Error("Cannot parse multipart form : ", err)
+		return &uploadLimitViolation{Code: http.StatusBadRequest}
+	}
+
+	return nil
+}
+
+// checkUploadLimits checks the duration, resolution and the storage quota of uploader before accepting a video
+func (v VideoUploadHandler) checkUploadLimits(ctx context.Context, video multipart.File, size int64, uploader string) *uploadLimitViolation {
+	if violation := v.checkVideoLimits(ctx, video); violation != nil {
+		return violation
+	}
+
+	if v.Config.UserStorageQuota <= 0 {
+		return nil
+	}
+
+	used, err := v.StorageUsagesDAO.GetUserStorageUsage(ctx, uploader)
+	if err != nil {
+		log.Error("Cannot get storage usage of "+uploader+" : ", err)
+		return &uploadLimitViolation{Code: http.StatusInternalServerError}
+	}
+
+	if used+size > v.Config.UserStorageQuota {
+		log.Errorf("Storage quota exceeded for %v : %v bytes used, %v bytes uploaded", uploader, used, size)
+		return &uploadLimitViolation{
+			Code: http.StatusRequestEntityTooLarge,
+			Body: &UploadLimitError{
+				Error:   QUOTA_EXCEEDED,
+				Message: fmt.Sprintf("Storage quota of %v bytes exceeded, %v bytes already used", v.Config.UserStorageQuota, used),
+				Limit:   fmt.Sprint(v.Config.UserStorageQuota),
+			},
+		}
+	}
+
+	return nil
+}
+
+func (v VideoUploadHandler) checkVideoLimits(ctx context.Context, video multipart.File) *uploadLimitViolation {
+	checkDuration := v.Config.MaxVideoDuration > 0
+	checkResolution := v.Config.MaxVideoWidth > 0 && v.Config.MaxVideoHeight > 0
+	if !checkDuration && !checkResolution {
+		return nil
+	}
+
+	probe, err := v.VideoProber.Probe(ctx, video)
+	if err != nil {
+		log.Error("Cannot probe video : ", err)
+		return &uploadLimitViolation{
+			Code: http.StatusUnprocessableEntity,
+			Body: &UploadLimitError{
+				Error:   UNREADABLE_VIDEO,
+				Message: "Video duration and resolution cannot be read",
+			},
+		}
+	}
+
+	// A limit cannot be enforced on a missing duration or resolution
+	if (checkDuration && probe.Duration <= 0) || (checkResolution && (probe.Width == 0 || probe.Height == 0)) {
+		log.Errorf("Video without duration or resolution refused : %v, %vx%v", probe.Duration, probe.Width, probe.Height)
+		return &uploadLimitViolation{
+			Code: http.StatusUnprocessableEntity,
+			Body: &UploadLimitError{
+				Error:   UNREADABLE_VIDEO,
+				Message: "Video duration and resolution cannot be read",
+			},
+		}
+	}
+
+	duration := time.Duration(probe.Duration * float64(time.Second))
+	if checkDuration && duration > v.Config.MaxVideoDuration {
+		log.Errorf("Video of %v refused", duration)
+		return &uploadLimitViolation{
+			Code: http.StatusUnprocessableEntity,
+			Body: &UploadLimitError{
+				Error:   DURATION_EXCEEDED,
+				Message: fmt.Sprintf("Video lasts %v, the maximum is %v", duration.Round(time.Second), v.Config.MaxVideoDuration),
+				Limit:   v.Config.MaxVideoDuration.String(),
+			},
+		}
+	}
+
+	// Compare long and short sides, so that portrait videos get the same limit as landscape ones
+	longSide, shortSide := sortedSides(probe.Width, probe.Height)
+	maxLongSide, maxShortSide := sortedSides(v.Config.MaxVideoWidth, v.Config.MaxVideoHeight)
+	if checkResolution && (longSide > maxLongSide || shortSide > maxShortSide) {
+		log.Errorf("Video of %vx%v refused", probe.Width, probe.Height)
+		return &uploadLimitViolation{
+			Code: http.StatusUnprocessableEntity,
+			Body: &UploadLimitError{
+				Error:   RESOLUTION_EXCEEDED,
+				Message: fmt.Sprintf("Video resolution %vx%v exceeds the maximum of %vx%v", probe.Width, probe.Height, v.Config.MaxVideoWidth, v.Config.MaxVideoHeight),
+				Limit:   fmt.Sprintf("%vx%v", v.Config.MaxVideoWidth, v.Config.MaxVideoHeight),
+			},
+		}
+	}
+
+	return nil
+}
+
+func sortedSides(x, y uint64) (uint64, uint64) {
+	if x < y {
+		return y, x
+	}
+	return x, y
+}
+
+// uploaderName identifies the owner of the uploaded videos for storage quotas
+func uploaderName(r *http.Request) string {
+	if user := UserFromContext(r.Context()); user != nil {
+		return user.Username
+	}
+	username, _, _ := r.BasicAuth()
+	return username
+}
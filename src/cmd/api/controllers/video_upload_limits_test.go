@@ -0,0 +1,178 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+)
+
+func TestVideoUploadLimits(t *testing.T) {
+	givenUsername := "dev"
+	givenUserPwd := "test"
+	videoSize := int64(262)
+
+	cases := []struct {
+		name             string
+		giveConfig       config.Config
+		giveProbe        ffmpeg.VideoProbe
+		giveProbeErr     error
+		giveStorageUsed  int64
+		expectedHTTPCode int
+		expectedError    controllers.UploadLimitErrorCode
+	}{
+		{
+			name:             "POST fails with upload too large",
+			giveConfig:       config.Config{MaxUploadBytes: 100},
+			expectedHTTPCode: 413,
+			expectedError:    controllers.FILE_TOO_LARGE,
+		},
+		{
+			name:             "POST fails with video too long",
+			giveConfig:       config.Config{MaxVideoDuration: time.Hour},
+			giveProbe:        ffmpeg.VideoProbe{Width: 1280, Height: 720, Duration: 3 * 3600},
+			expectedHTTPCode: 422,
+			expectedError:    controllers.DURATION_EXCEEDED,
+		},
+		{
+			name:             "POST fails with resolution too high",
+			giveConfig:       config.Config{MaxVideoWidth: 1920, MaxVideoHeight: 1080},
+			giveProbe:        ffmpeg.VideoProbe{Width: 3840, Height: 2160, Duration: 60},
+			expectedHTTPCode: 422,
+			expectedError:    controllers.RESOLUTION_EXCEEDED,
+		},
+		{
+			name:             "POST fails with portrait resolution too high",
+			giveConfig:       config.Config{MaxVideoWidth: 1920, MaxVideoHeight: 1080},
+			giveProbe:        ffmpeg.VideoProbe{Width: 1440, Height: 2560, Duration: 60},
+			expectedHTTPCode: 422,
+			expectedError:    controllers.RESOLUTION_EXCEEDED,
+		},
+		{
+			name:             "POST fails with unreadable video",
+			giveConfig:       config.Config{MaxVideoDuration: time.Hour},
+			giveProbeErr:     errors.New("Invalid data found when processing input"),
+			expectedHTTPCode: 422,
+			expectedError:    controllers.UNREADABLE_VIDEO,
+		},
+		{
+			name:             "POST fails with video without duration",
+			giveConfig:       config.Config{MaxVideoDuration: time.Hour},
+			giveProbe:        ffmpeg.VideoProbe{Width: 1280, Height: 720},
+			expectedHTTPCode: 422,
+			expectedError:    controllers.UNREADABLE_VIDEO,
+		},
+		{
+			name:             "POST fails with video without resolution",
+			giveConfig:       config.Config{MaxVideoWidth: 1920, MaxVideoHeight: 1080},
+			giveProbe:        ffmpeg.VideoProbe{Duration: 60},
+			expectedHTTPCode: 422,
+			expectedError:    controllers.UNREADABLE_VIDEO,
+		},
+		{
+			name:             "POST fails with storage quota exceeded",
+			giveConfig:       config.Config{UserStorageQuota: 1000},
+			giveStorageUsed:  900,
+			expectedHTTPCode: 413,
+			expectedError:    controllers.QUOTA_EXCEEDED,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := func(io.Reader) (ffmpeg.VideoProbe, error) { return tt.giveProbe, tt.giveProbeErr }
+
+			routerClients := router.Clients{
+				S3Client:              clients.NewS3ClientDummy(nil, nil, nil, nil, nil),
+				AmqpClient:            clients.NewAmqpClientDummy(nil, nil, nil),
+				AmqpVideoStatusUpdate: clients.NewAmqpClientDummy(nil, nil, nil),
+				UUIDGen:               clients.NewUuidGeneratorDummy(nil, nil),
+				VideoProber:           clients.NewVideoProberDummy(probe),
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectUploadsDAOCreation(mock)
+			dao_test.ExpectStorageUsagesDAOCreation(mock)
+
+			if tt.giveConfig.UserStorageQuota > 0 {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.StorageUsagesRequests[dao.GetUserStorageUsage])).
+					WithArgs(givenUsername).
+					WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.giveStorageUsed))
+			}
+
+			// Dummy multipart body creation
+			body := new(bytes.Buffer)
+			writer := multipart.NewWriter(body)
+			require.NoError(t, writer.WriteField("title", "title"))
+			fileWriter, err := writer.CreateFormFile("video", "video.mp4")
+			require.NoError(t, err)
+			content := make([]byte, videoSize)
+			copy(content, webmHeader)
+			_, err = fileWriter.Write(content)
+			require.NoError(t, err)
+			writer.Close()
+
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			storageUsagesDAO, err := dao.CreateStorageUsagesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerDAO := router.DAOs{
+				VideosDAO:        *videosDAO,
+				UploadsDAO:       *uploadsDAO,
+				StorageUsagesDAO: *storageUsagesDAO,
+			}
+
+			cfg := tt.giveConfig
+			cfg.UserAuth = givenUsername
+			cfg.PwdAuth = givenUserPwd
+			r := router.NewRouter(cfg, &routerClients, &routerDAO)
+
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/videos/upload", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.SetBasicAuth(givenUsername, givenUserPwd)
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+
+			var response controllers.UploadLimitError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			require.Equal(t, tt.expectedError, response.Error)
+			require.NotEmpty(t, response.Message)
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
@@ -14,12 +14,18 @@ import (
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/pkg/streamtoken"
 )
 
+// PreviewTokenBinding binds the stream tokens of the videos lists to the cover and the preview routes,
+// a canonical watermark query cannot take this value
+const PreviewTokenBinding = "preview"
+
 type VideoInfo struct {
-	Id        string           `json:"id" example:"1"`
-	Title     string           `json:"title" example:"my title"`
-	CoverLink jsonDTO.LinkJson `json:"coverlink"`
+	Id          string           `json:"id" example:"1"`
+	Title       string           `json:"title" example:"my title"`
+	CoverLink   jsonDTO.LinkJson `json:"coverlink"`
+	PreviewLink jsonDTO.LinkJson `json:"previewLink"`
 }
 
 type VideoListResponse struct {
@@ -29,7 +35,8 @@ type VideoListResponse struct {
 }
 
 type VideosListHandler struct {
-	VideosDAO *dao.VideosDAO
+	VideosDAO    *dao.VideosDAO
+	StreamSigner *streamtoken.Signer
 }
 
 // VideosListHandler godoc
@@ -67,8 +74,15 @@ func (v VideosListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	//Initialize the response
 	response := VideoListResponse{}
 
+	// Users only list the public videos, their own and the ones shared with them. Admins list all the videos.
+	user := UserFromContext(r.Context())
+	userID, seeAll := "", false
+	if user != nil {
+		userID, seeAll = user.ID, user.Role.Includes(models.ADMIN)
+	}
+
 	//Get videos to be returned
-	videos, err := v.VideosDAO.GetVideos(r.Context(), attribute, order, page, limit, int(status), title)
+	videos, err := v.VideosDAO.GetVideos(r.Context(), attribute, order, page, limit, int(status), title, userID, seeAll)
 	if err != nil {
 		log.Error("Unable to list objects from database: ", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -76,16 +90,21 @@ func (v VideosListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//Add videos to response
+	// Covers and previews are displayed without authentication, their links carry a stream token.
+	// It is bound to them: the videos listed cannot be streamed with it, even archived ones.
+	clientIP := streamtoken.ClientIP(r)
 	for _, video := range videos {
+		token, _ := v.StreamSigner.Sign(video.ID, clientIP, PreviewTokenBinding)
 		response.Videos = append(response.Videos, VideoInfo{
-			Id:        video.ID,
-			Title:     video.Title,
-			CoverLink: jsonDTO.LinkToLinkJson(models.CreateLink("videos/"+video.ID+"/cover", "GET")),
+			Id:          video.ID,
+			Title:       video.Title,
+			CoverLink:   signedLink(video.ID, "/cover", token, ""),
+			PreviewLink: signedLink(video.ID, "/preview", token, ""),
 		})
 	}
 
 	//Add total number of page to the response
-	totalvideos, err := v.VideosDAO.GetTotalVideos(r.Context(), int(models.COMPLETE), "%"+title+"%")
+	totalvideos, err := v.VideosDAO.GetTotalVideos(r.Context(), int(models.COMPLETE), "%"+title+"%", userID, seeAll)
 	if err != nil {
 		log.Error("Unable to get number of videos: ", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -2,6 +2,7 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http/httptest"
 	"regexp"
@@ -14,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
@@ -168,27 +170,23 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 
 			} else {
 
-				direction := "DESC"
-				if tt.ascending == "true" {
-					direction = "ASC"
-				}
 				pagenum, _ := strconv.Atoi(tt.page)
 				limitnum, _ := strconv.Atoi(tt.limit)
 				// Queries
-				getVideoListQuery := regexp.QuoteMeta(fmt.Sprintf("SELECT * FROM videos WHERE video_status = ? ORDER BY %v %v LIMIT ?,?", tt.videoAttribute, direction))
+				getVideoListQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosTitleAsc])
 				getVideoTotal := regexp.QuoteMeta(dao.VideosRequests[dao.GetTotalVideos])
 
 				// Tables
-				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path"}
+				videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
 				videosRows := sqlmock.NewRows(videosColumns)
 
 				if tt.databaseHasError {
-					mock.ExpectQuery(getVideoListQuery).WithArgs(int(tt.status), (pagenum-1)*limitnum, limitnum).WillReturnError(fmt.Errorf("Server Error"))
+					mock.ExpectQuery(getVideoListQuery).WithArgs(int(tt.status), "%%", true, "", "", (pagenum-1)*limitnum, limitnum).WillReturnError(fmt.Errorf("Server Error"))
 				} else {
 					sourcePathVideo := validVideoId + "/" + "source.mp4"
 					coverPath := validVideoId + "/" + "cover.png"
-					videosRows.AddRow(validVideoId, "title", int(models.ENCODING), t1, t1, nil, sourcePathVideo, coverPath)
-					mock.ExpectQuery(getVideoListQuery).WithArgs(int(tt.status), (pagenum-1)*limitnum, limitnum).WillReturnRows(videosRows)
+					videosRows.AddRow(validVideoId, "title", int(models.ENCODING), t1, t1, nil, sourcePathVideo, coverPath, nil, nil, "public")
+					mock.ExpectQuery(getVideoListQuery).WithArgs(int(tt.status), "%%", true, "", "", (pagenum-1)*limitnum, limitnum).WillReturnRows(videosRows)
 					mock.ExpectQuery(getVideoTotal).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
 				}
 			}
@@ -201,8 +199,10 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 			}
 
 			r := router.NewRouter(config.Config{
-				UserAuth: givenUsername,
-				PwdAuth:  givenPassword,
+				UserAuth:          givenUsername,
+				PwdAuth:           givenPassword,
+				StreamTokenSecret: givenStreamTokenSecret,
+				StreamTokenTTL:    time.Hour,
 			}, &routerClients, &routerDAO)
 
 			w := httptest.NewRecorder()
@@ -215,6 +215,14 @@ func TestVideosList(t *testing.T) { //nolint:cyclop
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedHTTPCode, w.Code)
 
+			if tt.expectedHTTPCode == 200 {
+				var response controllers.VideoListResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Len(t, response.Videos, 1)
+				require.Equal(t, "videos/"+validVideoId+"/cover"+previewTokenQuery(validVideoId), response.Videos[0].CoverLink.Href)
+				require.Equal(t, "videos/"+validVideoId+"/preview"+previewTokenQuery(validVideoId), response.Videos[0].PreviewLink.Href)
+			}
+
 			// we make sure that all expectations were met
 			err = mock.ExpectationsWereMet()
 			require.NoError(t, err)
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	maxWebhookURLLength    = 2048
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 128
+
+	defaultWebhookDeliveriesLimit = 50
+	maxWebhookDeliveriesLimit     = 200
+)
+
+type WebhookCreateRequest struct {
+	URL string `json:"url" example:"https://cms.example.org/hooks/voogle"`
+	// Key of the HMAC-SHA256 signature of the notifications, never returned
+	Secret string   `json:"secret" example:"2f6c1c0b8e0e4a7d9a3f"`
+	Events []string `json:"events" example:"video.complete,video.fail_encode"`
+}
+
+type WebhooksListResponse struct {
+	Webhooks []jsonDTO.WebhookJson `json:"webhooks"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []jsonDTO.WebhookDeliveryJson `json:"deliveries"`
+}
+
+type WebhooksListHandler struct {
+	WebhooksDAO *dao.WebhooksDAO
+}
+
+// WebhooksListHandler godoc
+// @Summary List webhooks
+// @Description List the webhooks, the most recent first
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} WebhooksListResponse
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/webhooks [get]
+func (wh WebhooksListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GET WebhooksListHandler")
+
+	webhooks, err := wh.WebhooksDAO.GetWebhooks(r.Context())
+	if err != nil {
+		log.Error("Cannot get webhooks : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := WebhooksListResponse{Webhooks: make([]jsonDTO.WebhookJson, 0, len(webhooks))}
+	for i := range webhooks {
+		response.Webhooks = append(response.Webhooks, jsonDTO.WebhookToWebhookJson(&webhooks[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+type WebhookCreateHandler struct {
+	WebhooksDAO *dao.WebhooksDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// WebhookCreateHandler godoc
+// @Summary Create a webhook
+// @Description Notify an URL of the events of the videos among video.complete and video.fail_encode. The notifications are POST requests of a JSON body, signed in the X-Voogle-Signature header by "sha256=" followed by the hex encoded HMAC-SHA256 of the body with the secret.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body WebhookCreateRequest true "URL, secret and events"
+// @Success 200 {object} jsonDTO.WebhookJson
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/webhooks [post]
+func (wh WebhookCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("POST WebhookCreateHandler")
+
+	var request WebhookCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("Cannot decode webhook request : ", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !isValidWebhookURL(request.URL) {
+		log.Error("Invalid webhook url ", request.URL)
+		http.Error(w, "url must be an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+	if len(request.Secret) < minWebhookSecretLength || len(request.Secret) > maxWebhookSecretLength {
+		log.Error("Invalid webhook secret length ", len(request.Secret))
+		http.Error(w, "secret must be 16 to 128 characters long", http.StatusBadRequest)
+		return
+	}
+	if len(request.Events) == 0 {
+		log.Error("Webhook without events")
+		http.Error(w, "events must not be empty", http.StatusBadRequest)
+		return
+	}
+	events := make([]models.WebhookEvent, 0, len(request.Events))
+	seen := map[models.WebhookEvent]bool{}
+	for _, name := range request.Events {
+		event, err := models.StringToWebhookEvent(name)
+		if err != nil {
+			log.Error("Invalid webhook event : ", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	webhookID, err := wh.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	webhook, err := wh.WebhooksDAO.CreateWebhook(r.Context(), &models.Webhook{
+		ID:     webhookID,
+		URL:    request.URL,
+		Secret: request.Secret,
+		Events: events,
+	})
+	if err != nil {
+		log.Error("Cannot create webhook "+request.URL+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, jsonDTO.WebhookToWebhookJson(webhook))
+	log.Infof("Webhook %v created for %v", webhook.ID, webhook.URL)
+}
+
+type WebhookDeleteHandler struct {
+	WebhooksDAO *dao.WebhooksDAO
+	UUIDGen     clients.IUUIDGenerator
+}
+
+// WebhookDeleteHandler godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook and its delivery log
+// @Tags webhooks
+// @Produce plain
+// @Param webhookID path string true "Webhook ID"
+// @Success 200 {string} string "OK"
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/webhooks/{webhookID} [delete]
+func (wh WebhookDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("DELETE WebhookDeleteHandler - Parameters: ", vars)
+
+	id := vars["webhookID"]
+	if !wh.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid webhook id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := wh.WebhooksDAO.GetWebhook(r.Context(), id)
+	if err != nil {
+		log.Error("Cannot get webhook "+id+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := wh.WebhooksDAO.DeleteWebhook(r.Context(), id); err != nil {
+		log.Error("Cannot delete webhook "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Webhook %v deleted, %v will not be notified anymore", id, webhook.URL)
+}
+
+type WebhookDeliveriesHandler struct {
+	WebhooksDAO          *dao.WebhooksDAO
+	WebhookDeliveriesDAO *dao.WebhookDeliveriesDAO
+	UUIDGen              clients.IUUIDGenerator
+}
+
+// WebhookDeliveriesHandler godoc
+// @Summary Get the delivery log of a webhook
+// @Description List the last attempts to notify the webhook, the most recent first. The attempts of a notification share their deliveryId, sent in the X-Voogle-Delivery header.
+// @Tags webhooks
+// @Produce json
+// @Param webhookID path string true "Webhook ID"
+// @Param limit query int false "Number of attempts, 50 by default, at most 200"
+// @Success 200 {object} WebhookDeliveriesResponse
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 500 {string} string
+// @Router /api/v1/webhooks/{webhookID}/deliveries [get]
+func (wh WebhookDeliveriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	log.Debug("GET WebhookDeliveriesHandler - Parameters: ", vars)
+
+	id := vars["webhookID"]
+	if !wh.UUIDGen.IsValidUUID(id) {
+		log.Error("Invalid webhook id")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultWebhookDeliveriesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxWebhookDeliveriesLimit {
+			log.Error("Invalid deliveries limit ", value)
+			http.Error(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := wh.WebhooksDAO.GetWebhook(r.Context(), id); err != nil {
+		log.Error("Cannot get webhook "+id+" : ", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	deliveries, err := wh.WebhookDeliveriesDAO.GetWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		log.Error("Cannot get deliveries of webhook "+id+" : ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := WebhookDeliveriesResponse{Deliveries: make([]jsonDTO.WebhookDeliveryJson, 0, len(deliveries))}
+	for i := range deliveries {
+		response.Deliveries = append(response.Deliveries, jsonDTO.WebhookDeliveryToWebhookDeliveryJson(&deliveries[i]))
+	}
+
+	writeJSON(w, response)
+}
+
+func isValidWebhookURL(rawURL string) bool {
+	if len(rawURL) > maxWebhookURLLength {
+		return false
+	}
+	webhookURL, err := url.Parse(rawURL)
+	return err == nil && (webhookURL.Scheme == "http" || webhookURL.Scheme == "https") && webhookURL.Host != ""
+}
@@ -0,0 +1,254 @@
+package controllers_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/cmd/api/router"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+)
+
+func TestWebhooks(t *testing.T) { //nolint:cyclop
+	givenUsername := "dev"
+	givenUserPwd := "test"
+
+	webhookID := "5a1e6f3b-2c4d-4e8f-9a0b-1c2d3e4f5a6b"
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	token := "q1Xv-session-token"
+	secret := "a-secret-of-32-characters-long!!"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+	t1 := time.Now()
+
+	webhooksColumns := []string{"id", "url", "secret", "events", "created_at"}
+	deliveriesColumns := []string{"id", "webhook_id", "delivery_id", "event", "video_id", "attempt", "status_code", "error", "created_at"}
+	getWebhookQuery := regexp.QuoteMeta(dao.WebhooksRequests[dao.GetWebhook])
+	webhookRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows(webhooksColumns).AddRow(webhookID, "https://cms.example.org/hooks", secret, "video.complete,video.fail_encode", t1)
+	}
+
+	cases := []struct {
+		name             string
+		giveMethod       string
+		giveRequest      string
+		giveBody         string
+		giveSession      bool
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedHTTPCode int
+		expectedBody     []string
+	}{
+		{
+			name:        "POST webhook",
+			giveMethod:  "POST",
+			giveRequest: "/api/v1/webhooks",
+			giveBody:    `{"url": "https://cms.example.org/hooks", "secret": "` + secret + `", "events": ["video.complete", "VIDEO.FAIL_ENCODE", "video.complete"]}`,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(dao.WebhooksRequests[dao.CreateWebhook])).
+					WithArgs(webhookID, "https://cms.example.org/hooks", secret, "video.complete,video.fail_encode").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(getWebhookQuery).WithArgs(webhookID).WillReturnRows(webhookRow())
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"id":"` + webhookID + `"`, `"events":["video.complete","video.fail_encode"]`},
+		},
+		{
+			name:             "POST webhook fails with invalid url",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": "ftp://cms.example.org/hooks", "secret": "` + secret + `", "events": ["video.complete"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST webhook fails with relative url",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": "/hooks", "secret": "` + secret + `", "events": ["video.complete"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST webhook fails with short secret",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": "https://cms.example.org/hooks", "secret": "short", "events": ["video.complete"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST webhook fails without events",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": "https://cms.example.org/hooks", "secret": "` + secret + `", "events": []}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST webhook fails with unknown event",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": "https://cms.example.org/hooks", "secret": "` + secret + `", "events": ["video.uploaded"]}`,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST webhook fails with invalid body",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": `,
+			expectedHTTPCode: 400,
+		},
+		{
+			name:             "POST webhook fails for an uploader",
+			giveMethod:       "POST",
+			giveRequest:      "/api/v1/webhooks",
+			giveBody:         `{"url": "https://cms.example.org/hooks", "secret": "` + secret + `", "events": ["video.complete"]}`,
+			giveSession:      true,
+			expectedHTTPCode: 403,
+		},
+		{
+			name:        "GET webhooks without secrets",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/webhooks",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.WebhooksRequests[dao.GetWebhooks])).WillReturnRows(webhookRow())
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"webhooks":[{"id":"` + webhookID + `","url":"https://cms.example.org/hooks"`},
+		},
+		{
+			name:        "GET webhooks fails with database error",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/webhooks",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.WebhooksRequests[dao.GetWebhooks])).WillReturnError(fmt.Errorf("database error"))
+			},
+			expectedHTTPCode: 500,
+		},
+		{
+			name:        "DELETE webhook",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/webhooks/" + webhookID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getWebhookQuery).WithArgs(webhookID).WillReturnRows(webhookRow())
+				mock.ExpectExec(regexp.QuoteMeta(dao.WebhooksRequests[dao.DeleteWebhook])).WithArgs(webhookID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedHTTPCode: 200,
+		},
+		{
+			name:        "DELETE unknown webhook",
+			giveMethod:  "DELETE",
+			giveRequest: "/api/v1/webhooks/" + webhookID,
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getWebhookQuery).WithArgs(webhookID).WillReturnError(sql.ErrNoRows)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "DELETE webhook fails with invalid id",
+			giveMethod:       "DELETE",
+			giveRequest:      "/api/v1/webhooks/invalid",
+			expectedHTTPCode: 400,
+		},
+		{
+			name:        "GET webhook deliveries",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/webhooks/" + webhookID + "/deliveries?limit=2",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getWebhookQuery).WithArgs(webhookID).WillReturnRows(webhookRow())
+				mock.ExpectQuery(regexp.QuoteMeta(dao.WebhookDeliveriesRequests[dao.GetWebhookDeliveries])).WithArgs(webhookID, 2).
+					WillReturnRows(sqlmock.NewRows(deliveriesColumns).
+						AddRow(uuid.NewString(), webhookID, "delivery-1", "video.complete", videoID, 2, 200, nil, t1).
+						AddRow(uuid.NewString(), webhookID, "delivery-1", "video.complete", videoID, 1, 503, "unexpected status 503", t1))
+			},
+			expectedHTTPCode: 200,
+			expectedBody:     []string{`"attempt":2,"statusCode":200`, `"attempt":1,"statusCode":503,"error":"unexpected status 503"`},
+		},
+		{
+			name:        "GET deliveries of unknown webhook",
+			giveMethod:  "GET",
+			giveRequest: "/api/v1/webhooks/" + webhookID + "/deliveries",
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getWebhookQuery).WithArgs(webhookID).WillReturnError(sql.ErrNoRows)
+			},
+			expectedHTTPCode: 404,
+		},
+		{
+			name:             "GET webhook deliveries fails with invalid limit",
+			giveMethod:       "GET",
+			giveRequest:      "/api/v1/webhooks/" + webhookID + "/deliveries?limit=1000",
+			expectedHTTPCode: 400,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			dao_test.ExpectWebhooksDAOCreation(mock)
+			dao_test.ExpectWebhookDeliveriesDAOCreation(mock)
+
+			if tt.giveSession {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser])).
+					WithArgs(controllers.HashSessionToken(token), AnyTime{}).
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(models.UPLOADER), t1, t1, nil))
+			}
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+			webhooksDAO, err := dao.CreateWebhooksDAO(context.Background(), db)
+			require.NoError(t, err)
+			webhookDeliveriesDAO, err := dao.CreateWebhookDeliveriesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			routerClients := router.Clients{
+				UUIDGen: clients.NewUuidGeneratorDummy(func() (string, error) { return webhookID, nil }, UUIDValidFunc),
+			}
+
+			r := router.NewRouter(config.Config{
+				UserAuth: givenUsername,
+				PwdAuth:  givenUserPwd,
+			}, &routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO, WebhooksDAO: *webhooksDAO, WebhookDeliveriesDAO: *webhookDeliveriesDAO})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.giveMethod, tt.giveRequest, strings.NewReader(tt.giveBody))
+			if tt.giveSession {
+				req.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				req.SetBasicAuth(givenUsername, givenUserPwd)
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedHTTPCode, w.Code)
+			for _, expected := range tt.expectedBody {
+				require.Contains(t, w.Body.String(), expected)
+			}
+			require.NotContains(t, w.Body.String(), secret)
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
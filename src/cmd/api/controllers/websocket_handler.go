@@ -1,64 +1,98 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
-	"google.golang.org/protobuf/proto"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
-	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 
-	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
-	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
+// Version of the messages exchanged on the websocket, sent in the welcome message
+const WSProtocolVersion = "v1"
+
+const (
+	WSSubscribe   = "subscribe"
+	WSUnsubscribe = "unsubscribe"
+
+	WSWelcome = "welcome"
+	WSAck     = "ack"
+	WSError   = "error"
+	WSEvent   = "event"
+)
+
+// Time given to the client to answer a ping
+const wsPongTimeout = 5 * time.Second
+
+// WSRequest is a message sent by the client
+type WSRequest struct {
+	Type string `json:"type" enums:"subscribe,unsubscribe" example:"subscribe"`
+	// Optional, echoed in the reply
+	ID string `json:"id,omitempty" example:"1"`
+	// ID of a video, or "all" for every video the user can list
+	VideoID string `json:"videoId" example:"all"`
+}
+
+// WSMessage is a message sent by the server: the welcome message, the reply to a request or an event
+type WSMessage struct {
+	Type string `json:"type" enums:"welcome,ack,error,event" example:"event"`
+	// Protocol version, only in the welcome message
+	Version string `json:"version,omitempty" example:"v1"`
+	// ID of the request replied to
+	ID string `json:"id,omitempty" example:"1"`
+	// Type of the request replied to
+	Action  string                  `json:"action,omitempty" example:"subscribe"`
+	VideoID string                  `json:"videoId,omitempty" example:"all"`
+	Error   string                  `json:"error,omitempty" example:"video not found"`
+	Event   *jsonDTO.VideoEventJson `json:"event,omitempty"`
+}
+
 type WSHandler struct {
-	Config                config.Config
-	AmqpVideoStatusUpdate clients.AmqpClient
+	// Origins of the browsers allowed to open a websocket, see checkOrigin
+	AllowedOrigins []string
+	VideoEvents    *eventhandler.Hub
+	VideosDAO      *dao.VideosDAO
+	VideoSharesDAO *dao.VideoSharesDAO
+	UUIDGen        clients.IUUIDGenerator
 }
 
 // wshandler godoc
-// @Summary Send Update to Front
-// @Description Send Update to Front
+// @Summary Receive the events of the videos
+// @Description Websocket of the JSON protocol v1. The server first sends a welcome message with the protocol version. The client subscribes to the events of a video, or of every video it can list with the video ID "all", and unsubscribes the same way. Each request is answered by an ack or an error message, echoing the optional request ID. The events (status, upload_progress, encode_progress, deleted, renamed) of the subscribed videos the user can see are then sent as event messages.
 // @Tags websocket
-// @Accept plain
-// @Produce plain
-// @Param Cookie header string true "Authentication cookie"
-// @Success 101 {string} string
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer token or basic auth, browsers use the access_token parameter or the cookie instead"
+// @Param access_token query string false "Bearer token"
+// @Param Cookie header string false "Authorization cookie of the webapp"
+// @Param request body WSRequest false "Messages sent by the client once connected"
+// @Success 101 {object} WSMessage "Messages sent by the server once connected"
 // @Failure 400 {string} string
 // @Failure 401 {string} string
+// @Failure 403 {string} string "Origin not allowed"
 // @Failure 500 {string} string
 // @Router /ws [get]
 func (wsh WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug("WS WSHandler new connection", r.Host)
 
-	upgrader := websocket.Upgrader{}
-
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-
-		decodedData, err := decodeAuthorization(r)
-		if err != nil {
-			log.Error("Could not decode data", err)
-			return false
-		}
-
-		givenUser, givenPass := extractCredentials(decodedData)
-
-		// We check the data match our expectation.
-		if givenUser == wsh.Config.UserAuth &&
-			givenPass == wsh.Config.PwdAuth {
-			return true
-		}
-		return false
+	// The request is already authenticated by the middleware of the route
+	user := UserFromContext(r.Context())
+	upgrader := websocket.Upgrader{
+		CheckOrigin: checkOrigin(wsh.AllowedOrigins),
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -68,144 +102,220 @@ func (wsh WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	err = conn.WriteMessage(websocket.TextMessage, []byte("Connection is a success."))
-	if err != nil {
-		log.Error("Cannot send message : ", err)
-		return
-	}
-	randomQueueName := wsh.AmqpVideoStatusUpdate.GetRandomQueueName()
-	HandleMessage(context.Background(), &wsh, randomQueueName, conn)
+	HandleMessage(context.Background(), &wsh, user, conn)
 }
 
-var HandleMessage = func(ctx context.Context, wsh *WSHandler, randomQueueName string, conn *websocket.Conn) {
+var HandleMessage = func(ctx context.Context, wsh *WSHandler, user *models.User, conn *websocket.Conn) {
 
 	ctx, clear := context.WithCancel(ctx)
+	defer clear()
 
-	conn.SetCloseHandler(func(code int, text string) error {
-		log.Debugf("Connection closed with code %v : %v", code, text)
-		clear()
-		return nil
-	})
+	// Subscribe before reading the requests, so that no event is missed after an ack
+	videoEvents, unsubscribe := wsh.VideoEvents.Subscribe()
+	defer unsubscribe()
+
+	session := &wsSession{conn: conn, user: user, videoSubscriptions: newVideoSubscriptions()}
+	if err := session.send(WSMessage{Type: WSWelcome, Version: WSProtocolVersion}); err != nil {
+		log.Error("Cannot send message : ", err)
+		return
+	}
 
 	// Read message from client
-	go wsh.handleClientMessage(ctx, clear, randomQueueName, conn)
+	go wsh.handleClientMessage(ctx, clear, session)
 
-	// Transfer message from queue to client
-	go wsh.handleUpdateMessage(ctx, randomQueueName, conn)
+	// Transfer events to client
+	go wsh.handleVideoEvents(ctx, clear, session, videoEvents)
 
 	// Ping Client to ensure connection is still needed
-	wsh.pingClient(ctx, clear, conn, time.Duration(5)*time.Second)
+	wsh.pingClient(ctx, clear, session, wsPongTimeout)
 
 	conn.Close()
 }
 
-func decodeAuthorization(r *http.Request) (decodedData []byte, err error) {
+// wsSession is the state of a websocket connection
+type wsSession struct {
+	conn *websocket.Conn
+	user *models.User
+
+	// Gorilla websockets support one concurrent writer
+	writeMutex sync.Mutex
+
+	*videoSubscriptions
+}
+
+func (s *wsSession) send(msg WSMessage) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.conn.WriteJSON(msg)
+}
+
+func (s *wsSession) ping(deadline time.Time) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.conn.WriteControl(websocket.PingMessage, []byte("pingClient"), deadline)
+}
+
+// WebsocketAuthorization returns the Authorization value of the websocket request. Browsers cannot set headers
+// on websockets nor event sources, so it is also read from the access_token parameter, then from the cookie of
+// the webapp.
+// A missing or undecodable cookie gives an empty value.
+func WebsocketAuthorization(r *http.Request) string {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		return authorization
+	}
+	if token := r.URL.Query().Get("access_token"); token != "" {
+		return "Bearer " + token
+	}
+
 	authCookie, err := r.Cookie("Authorization")
 	if err != nil {
-		log.Error("No such cookie", err)
+		return ""
 	}
-	strCookie := authCookie.String()
-	auth := strCookie[len("Authorization="):]
-	decodedData, err = base64.StdEncoding.DecodeString(auth[len("Basic%20"):])
+	authorization, err := url.QueryUnescape(authCookie.Value)
 	if err != nil {
-		return nil, err
+		log.Error("Cannot decode authorization cookie : ", err)
+		return ""
 	}
+	return authorization
+}
 
-	return decodedData, nil
+// checkOrigin accepts the requests without Origin header, which are not sent by a browser, and those of the
+// allowed origins ("*" allows any of them). Without allowed origins, only the pages served from the host name
+// of the API are accepted, whatever their port.
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		if len(allowedOrigins) == 0 {
+			originURL, err := url.Parse(origin)
+			if err == nil && strings.EqualFold(originURL.Hostname(), hostname(r.Host)) {
+				return true
+			}
+		}
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+				return true
+			}
+		}
+
+		log.Error("Websocket origin not allowed : ", origin)
+		return false
+	}
 }
 
-func extractCredentials(data []byte) (username string, password string) {
-	creds := bytes.SplitN(data, []byte(":"), 2)
-	givenUser := string(creds[0])
-	givenPass := string(creds[1])
-	return givenUser, givenPass
+// hostname returns the host of a "host:port" value
+func hostname(host string) string {
+	return (&url.URL{Host: host}).Hostname()
 }
 
-func (wsh *WSHandler) handleClientMessage(ctx context.Context, clear context.CancelFunc, randomQueueName string, conn *websocket.Conn) {
+func (wsh *WSHandler) handleClientMessage(ctx context.Context, clear context.CancelFunc, session *wsSession) {
+	defer clear()
+
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		default: // Read message from browser
-			_, msg, err := conn.ReadMessage()
-			if err != nil {
-				if _, ok := err.(*websocket.CloseError); ok {
-					log.Debug("Close message received.")
-					clear()
-				} else {
-					log.Error("Could not read message : ", err)
-				}
+		_, msg, err := session.conn.ReadMessage()
+		if err != nil {
+			if _, ok := err.(*websocket.CloseError); ok {
+				log.Debug("Close message received.")
+			} else if ctx.Err() == nil {
+				log.Error("Could not read message : ", err)
 			}
-			err = wsh.AmqpVideoStatusUpdate.QueueBind(randomQueueName, string(msg))
-			if err != nil {
-				log.Error("Could not bind queue : ", err)
+			return
+		}
+
+		var request WSRequest
+		if err := json.Unmarshal(msg, &request); err != nil {
+			log.Error("Cannot decode websocket request : ", err)
+			if err := session.send(WSMessage{Type: WSError, Error: "invalid JSON message"}); err != nil {
+				log.Error("Cannot send message : ", err)
+				return
 			}
+			continue
+		}
+
+		if err := session.send(wsh.handleRequest(ctx, session, &request)); err != nil {
+			log.Error("Cannot send message : ", err)
+			return
 		}
 	}
 }
 
-func (wsh *WSHandler) handleUpdateMessage(ctx context.Context, randomQueueName string, conn *websocket.Conn) {
-	session := wsh.AmqpVideoStatusUpdate.WithRedial()
+// handleRequest applies the request and returns the reply
+func (wsh *WSHandler) handleRequest(ctx context.Context, session *wsSession, request *WSRequest) WSMessage {
+	reply := WSMessage{Type: WSAck, ID: request.ID, Action: request.Type, VideoID: request.VideoID}
+	fail := func(message string) WSMessage {
+		reply.Type = WSError
+		reply.Error = message
+		return reply
+	}
+
+	switch request.Type {
+	case WSSubscribe:
+		if statusCode, err := checkVideoSubscription(ctx, wsh.VideosDAO, wsh.VideoSharesDAO, wsh.UUIDGen, session.user, request.VideoID); err != nil {
+			return fail(videoSubscriptionError(statusCode))
+		}
+		session.subscribe(request.VideoID)
+		return reply
+
+	case WSUnsubscribe:
+		if request.VideoID != AllVideos && !wsh.UUIDGen.IsValidUUID(request.VideoID) {
+			return fail(videoSubscriptionError(http.StatusBadRequest))
+		}
+		session.unsubscribe(request.VideoID)
+		return reply
 
+	default:
+		return fail("unknown message type " + request.Type)
+	}
+}
+
+func (wsh *WSHandler) handleVideoEvents(ctx context.Context, clear context.CancelFunc, session *wsSession, videoEvents <-chan *models.VideoEvent) {
 	for {
-		var client clients.AmqpClient
 		select {
 		case <-ctx.Done():
 			return
-		case client = <-session:
-			msgs, err := client.Consume(randomQueueName)
-			if err != nil {
-				log.Error("Failed to consume RabbitMQ client: ", err)
+		case event := <-videoEvents:
+			if !isEventSent(ctx, wsh.VideoSharesDAO, session.user, session.videoSubscriptions, event) {
 				continue
 			}
-			for d := range msgs {
-				videoProto := &contracts.Video{}
-				if err := proto.Unmarshal([]byte(d.Body), videoProto); err != nil {
-					log.Error("Fail to unmarshal video event : ", err)
-					continue
-				}
-				video := protobuf.VideoProtobufToVideo(videoProto)
-				video.Title = d.RoutingKey
-				msg, err := json.Marshal(jsonDTO.VideoToStatusJson(video))
-				if err != nil {
-					log.Error("Failed to marshall response to front :", err)
-				}
-				err = conn.WriteMessage(websocket.TextMessage, []byte(msg))
-				if err != nil {
-					log.Error("Cannot send message : ", err)
-					return
-				}
-				if err := d.Acknowledger.Ack(d.DeliveryTag, false); err != nil {
-					log.Error("Failed to Ack message ", video.ID, " - ", err)
-					continue
-				}
+			eventJson := jsonDTO.VideoEventToVideoEventJson(event)
+			if err := session.send(WSMessage{Type: WSEvent, Event: &eventJson}); err != nil {
+				log.Error("Cannot send message : ", err)
+				clear()
+				return
+			}
+			if event.Kind == models.DELETED_EVENT {
+				session.unsubscribe(event.Video.ID)
 			}
 		}
-		client.Close()
 	}
 }
 
-func (wsh *WSHandler) pingClient(ctx context.Context, clear context.CancelFunc, conn *websocket.Conn, timeout time.Duration) {
-	lastCheck := time.Now()
-	conn.SetPongHandler(func(appData string) error {
-		lastCheck = time.Now()
+func (wsh *WSHandler) pingClient(ctx context.Context, clear context.CancelFunc, session *wsSession, timeout time.Duration) {
+	// The pong handler runs in the goroutine reading the messages
+	lastCheck := time.Now().UnixNano()
+	session.conn.SetPongHandler(func(appData string) error {
+		atomic.StoreInt64(&lastCheck, time.Now().UnixNano())
 		return nil
 	})
+
+	ticker := time.NewTicker(timeout * 9 / 10)
+	defer ticker.Stop()
 	for {
+		if time.Now().After(time.Unix(0, atomic.LoadInt64(&lastCheck)).Add(timeout)) {
+			clear()
+			return
+		}
+		if err := session.ping(time.Now().Add(timeout)); err != nil {
+			log.Error("Could not ping the client : ", err)
+		}
+
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			if time.Now().After(lastCheck.Add(timeout)) {
-				clear()
-				return
-			} else {
-				err := conn.WriteMessage(websocket.PingMessage, []byte("pingClient"))
-				if err != nil {
-					log.Error("Could not ping the client : ", err)
-				}
-			}
+		case <-ticker.C:
 		}
-		time.Sleep(timeout * 9 / 10)
 	}
 }
@@ -2,67 +2,144 @@ package controllers_test
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	hijack "github.com/getlantern/httptest"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/oidc"
+	"github.com/Sogilis/Voogle/src/pkg/oidc/oidctest"
 	"github.com/stretchr/testify/require"
 )
 
 func TestWebsocket(t *testing.T) { //nolint:cyclop
 	requiredUsername := "valid"
 	requiredPassword := "valid"
+	basicCookie := func(credentials string) string {
+		return "Basic%20" + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
 
 	cases := []struct {
-		name             string
-		givenUsername    string
-		givenPassword    string
-		expectedResponse int
+		name               string
+		givenCookie        string
+		givenOrigin        string
+		givenAllowedOrigin []string
+		expectedResponse   int
 	}{
 		{
 			name:             "Authentication Succeed",
-			givenUsername:    "valid",
-			givenPassword:    "valid",
+			givenCookie:      basicCookie("valid:valid"),
 			expectedResponse: 200,
 		},
 		{
 			name:             "Authentication Fail with invalid Username",
-			givenUsername:    "invalid",
-			givenPassword:    "valid",
-			expectedResponse: 403,
+			givenCookie:      basicCookie("invalid:valid"),
+			expectedResponse: 401,
 		},
 		{
 			name:             "Authentication Fail with invalid Password",
-			givenUsername:    "valid",
-			givenPassword:    "invalid",
+			givenCookie:      basicCookie("valid:invalid"),
+			expectedResponse: 401,
+		},
+		{
+			name:             "Authentication Fail without cookie",
+			expectedResponse: 401,
+		},
+		{
+			name:             "Authentication Fail with cookie not url encoded",
+			givenCookie:      "Basic%zz",
+			expectedResponse: 401,
+		},
+		{
+			name:             "Authentication Fail with credentials without colon",
+			givenCookie:      basicCookie("validvalid"),
+			expectedResponse: 401,
+		},
+		{
+			name:             "Authentication Fail with credentials not base64 encoded",
+			givenCookie:      "Basic%20not-base64!",
+			expectedResponse: 401,
+		},
+		{
+			name:             "Authentication Fail with unknown scheme",
+			givenCookie:      "Digest%20" + base64.StdEncoding.EncodeToString([]byte("valid:valid")),
+			expectedResponse: 401,
+		},
+		{
+			name:             "Origin of the API host name accepted",
+			givenCookie:      basicCookie("valid:valid"),
+			givenOrigin:      "http://example.com:8080",
+			expectedResponse: 200,
+		},
+		{
+			name:             "Origin of another host name refused",
+			givenCookie:      basicCookie("valid:valid"),
+			givenOrigin:      "http://evil.com",
 			expectedResponse: 403,
 		},
+		{
+			name:               "Allowed origin accepted",
+			givenCookie:        basicCookie("valid:valid"),
+			givenOrigin:        "https://voogle.example.org",
+			givenAllowedOrigin: []string{"https://other.example.org", "https://voogle.example.org/"},
+			expectedResponse:   200,
+		},
+		{
+			name:               "Origin not allowed refused",
+			givenCookie:        basicCookie("valid:valid"),
+			givenOrigin:        "http://example.com",
+			givenAllowedOrigin: []string{"https://voogle.example.org"},
+			expectedResponse:   403,
+		},
+		{
+			name:               "Any origin accepted",
+			givenCookie:        basicCookie("valid:valid"),
+			givenOrigin:        "http://evil.com",
+			givenAllowedOrigin: []string{"*"},
+			expectedResponse:   200,
+		},
+		{
+			name:             "Authentication checked before origin",
+			givenOrigin:      "http://evil.com",
+			expectedResponse: 401,
+		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 
-			controllers.HandleMessage = func(ctx context.Context, wsh *controllers.WSHandler, randomQueueName string, conn *websocket.Conn) {
+			handleMessage := controllers.HandleMessage
+			defer func() { controllers.HandleMessage = handleMessage }()
+			controllers.HandleMessage = func(ctx context.Context, wsh *controllers.WSHandler, user *models.User, conn *websocket.Conn) {
 			}
 
-			encodedAuth := "Basic%20" + base64.StdEncoding.EncodeToString([]byte(tt.givenUsername+":"+tt.givenPassword))
-			authCookie := http.Cookie{Name: "Authorization", Value: encodedAuth}
-
 			givenRequest := "/ws"
 
 			amqpDummy := clients.NewAmqpClientDummy(nil, nil, nil)
 
 			r := router.NewRouter(config.Config{
-				UserAuth: requiredUsername,
-				PwdAuth:  requiredPassword,
+				UserAuth:         requiredUsername,
+				PwdAuth:          requiredPassword,
+				WSAllowedOrigins: tt.givenAllowedOrigin,
 			}, &router.Clients{AmqpVideoStatusUpdate: amqpDummy}, &router.DAOs{})
 
 			w := hijack.NewRecorder(nil)
@@ -73,12 +150,336 @@ func TestWebsocket(t *testing.T) { //nolint:cyclop
 			req.Header.Set("Sec-WebSocket-Version", "13")
 			req.Header.Set("Sec-WebSocket-Key", "42")
 			req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate")
-			req.AddCookie(&authCookie)
+			if tt.givenOrigin != "" {
+				req.Header.Set("Origin", tt.givenOrigin)
+			}
+			if tt.givenCookie != "" {
+				req.AddCookie(&http.Cookie{Name: "Authorization", Value: tt.givenCookie})
+			}
+
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedResponse, w.Code())
+
+		})
+	}
+
+}
+
+func TestWebsocketAccessToken(t *testing.T) {
+	subject := "248289761001"
+	audience := "voogle"
+	t1 := time.Now()
+
+	issuer, err := oidctest.NewIssuer()
+	require.NoError(t, err)
+	defer issuer.Close()
+
+	verifier, err := oidc.NewVerifier(context.Background(), issuer.URL(), audience, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name             string
+		giveTTL          time.Duration
+		expectedResponse int
+	}{
+		{
+			name:             "Authentication Succeed with OIDC token",
+			giveTTL:          time.Hour,
+			expectedResponse: 200,
+		},
+		{
+			name:             "Authentication Fail with expired OIDC token",
+			giveTTL:          -time.Hour,
+			expectedResponse: 401,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+
+			handleMessage := controllers.HandleMessage
+			defer func() { controllers.HandleMessage = handleMessage }()
+			controllers.HandleMessage = func(ctx context.Context, wsh *controllers.WSHandler, user *models.User, conn *websocket.Conn) {
+			}
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			if tt.expectedResponse == 200 {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromOIDCSubject])).
+					WithArgs(subject).
+					WillReturnRows(sqlmock.NewRows(usersColumns).AddRow("2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11", "alice", "", "viewer", t1, t1, subject))
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			token, err := issuer.Token(subject, audience, tt.giveTTL, nil)
+			require.NoError(t, err)
+
+			amqpDummy := clients.NewAmqpClientDummy(nil, nil, nil)
+
+			r := router.NewRouter(config.Config{OIDCGroupsClaim: "groups"},
+				&router.Clients{AmqpVideoStatusUpdate: amqpDummy, OIDCVerifier: verifier},
+				&router.DAOs{UsersDAO: *usersDAO})
+
+			w := hijack.NewRecorder(nil)
+
+			req := httptest.NewRequest("GET", "/ws?access_token="+token, nil)
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", "42")
 
 			r.ServeHTTP(w, req)
 			require.Equal(t, tt.expectedResponse, w.Code())
 
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
 		})
 	}
+}
+
+func TestWebsocketProtocol(t *testing.T) { //nolint:cyclop
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	otherVideoID := "7f3c2d1e-8b4a-4c6d-9e0f-1a2b3c4d5e6f"
+	ownerID := "9d6f2b7e-3c1a-4f0e-8a52-7b3e1d0c4f21"
+	userID := "2c4ba3b6-6a6b-4c6e-8f1c-0c3b1e0f2a11"
+	token := "q1Xv-session-token"
+	t1 := time.Now()
+
+	videosColumns := []string{"id", "title", "video_status", "uploaded_at", "created_at", "updated_at", "source_path", "cover_path", "loudness", "owner_id", "visibility"}
+	getVideoQuery := regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo])
+	getVideoShareQuery := regexp.QuoteMeta(dao.VideoSharesRequests[dao.GetVideoShare])
+	videoRow := func(owner string, visibility models.Visibility) *sqlmock.Rows {
+		return sqlmock.NewRows(videosColumns).
+			AddRow(videoID, "title", int(models.COMPLETE), t1, t1, t1, videoID+"/source.mp4", "", nil, owner, string(visibility))
+	}
+
+	videoEvent := func(kind models.VideoEventKind, id, owner string, visibility models.Visibility, progress float64) *models.VideoEvent {
+		return &models.VideoEvent{
+			Kind:     kind,
+			Video:    models.Video{ID: id, Title: "title " + id[:4], Status: models.ENCODING, OwnerID: &owner, Visibility: visibility},
+			Progress: progress,
+		}
+	}
+	eventMessage := func(event *models.VideoEvent) controllers.WSMessage {
+		eventJson := jsonDTO.VideoEventToVideoEventJson(event)
+		return controllers.WSMessage{Type: controllers.WSEvent, Event: &eventJson}
+	}
+
+	publicEvent := videoEvent(models.STATUS_EVENT, otherVideoID, ownerID, models.PUBLIC, 0)
+	ownProgressEvent := videoEvent(models.UPLOAD_PROGRESS_EVENT, videoID, userID, models.PRIVATE, 0.5)
+	ownRenamedEvent := videoEvent(models.RENAMED_EVENT, videoID, userID, models.PRIVATE, 0)
+	ownDeletedEvent := videoEvent(models.DELETED_EVENT, videoID, userID, models.PRIVATE, 0)
+	unlistedEvent := videoEvent(models.ENCODE_PROGRESS_EVENT, videoID, ownerID, models.UNLISTED, 0.7)
+	sharedEvent := videoEvent(models.STATUS_EVENT, videoID, ownerID, models.SHARED, 0)
+
+	cases := []struct {
+		name             string
+		giveRequests     []string
+		giveEvents       []*models.VideoEvent
+		expectDb         func(mock sqlmock.Sqlmock)
+		expectedMessages []controllers.WSMessage
+	}{
+		{
+			name:         "Subscribe to all videos receives the events of the listed videos",
+			giveRequests: []string{`{"type":"subscribe","id":"1","videoId":"all"}`},
+			giveEvents: []*models.VideoEvent{
+				videoEvent(models.STATUS_EVENT, otherVideoID, ownerID, models.PRIVATE, 0),
+				videoEvent(models.STATUS_EVENT, otherVideoID, ownerID, models.UNLISTED, 0),
+				publicEvent,
+				ownProgressEvent,
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSAck, ID: "1", Action: controllers.WSSubscribe, VideoID: controllers.AllVideos},
+				eventMessage(publicEvent),
+				eventMessage(ownProgressEvent),
+			},
+		},
+		{
+			name:         "Subscribe to all videos receives the events of the shared videos",
+			giveRequests: []string{`{"type":"subscribe","videoId":"all"}`},
+			giveEvents:   []*models.VideoEvent{sharedEvent, sharedEvent},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoShareQuery).WithArgs(videoID, userID).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+				mock.ExpectQuery(getVideoShareQuery).WithArgs(videoID, userID).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSAck, Action: controllers.WSSubscribe, VideoID: controllers.AllVideos},
+				eventMessage(sharedEvent),
+			},
+		},
+		{
+			name:         "Subscribe to a video receives its events until deleted",
+			giveRequests: []string{`{"type":"subscribe","id":"2","videoId":"` + videoID + `"}`},
+			giveEvents:   []*models.VideoEvent{publicEvent, ownRenamedEvent, ownDeletedEvent},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSAck, ID: "2", Action: controllers.WSSubscribe, VideoID: videoID},
+				eventMessage(ownRenamedEvent),
+				eventMessage(ownDeletedEvent),
+			},
+		},
+		{
+			name:         "Subscribe to an unlisted video of another user",
+			giveRequests: []string{`{"type":"subscribe","videoId":"` + videoID + `"}`},
+			giveEvents:   []*models.VideoEvent{unlistedEvent},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.UNLISTED))
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSAck, Action: controllers.WSSubscribe, VideoID: videoID},
+				eventMessage(unlistedEvent),
+			},
+		},
+		{
+			name:         "Subscribe fails for a private video of another user",
+			giveRequests: []string{`{"type":"subscribe","id":"3","videoId":"` + videoID + `"}`},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(ownerID, models.PRIVATE))
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSError, ID: "3", Action: controllers.WSSubscribe, VideoID: videoID, Error: "video not found"},
+			},
+		},
+		{
+			name:         "Subscribe fails for an unknown video",
+			giveRequests: []string{`{"type":"subscribe","videoId":"` + videoID + `"}`},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnError(sql.ErrNoRows)
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSError, Action: controllers.WSSubscribe, VideoID: videoID, Error: "video not found"},
+			},
+		},
+		{
+			name:         "Subscribe fails with database error",
+			giveRequests: []string{`{"type":"subscribe","videoId":"` + videoID + `"}`},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnError(fmt.Errorf("database error"))
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSError, Action: controllers.WSSubscribe, VideoID: videoID, Error: "internal error"},
+			},
+		},
+		{
+			name:         "Subscribe fails with invalid video ID",
+			giveRequests: []string{`{"type":"subscribe","videoId":"invalid"}`},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSError, Action: controllers.WSSubscribe, VideoID: "invalid", Error: `videoId must be a video ID or "all"`},
+			},
+		},
+		{
+			name: "Unsubscribe from all videos keeps the other subscriptions",
+			giveRequests: []string{
+				`{"type":"subscribe","videoId":"all"}`,
+				`{"type":"subscribe","videoId":"` + videoID + `"}`,
+				`{"type":"unsubscribe","id":"4","videoId":"all"}`,
+			},
+			giveEvents: []*models.VideoEvent{publicEvent, ownProgressEvent},
+			expectDb: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(getVideoQuery).WithArgs(videoID).WillReturnRows(videoRow(userID, models.PRIVATE))
+			},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSAck, Action: controllers.WSSubscribe, VideoID: controllers.AllVideos},
+				{Type: controllers.WSAck, Action: controllers.WSSubscribe, VideoID: videoID},
+				{Type: controllers.WSAck, ID: "4", Action: controllers.WSUnsubscribe, VideoID: controllers.AllVideos},
+				eventMessage(ownProgressEvent),
+			},
+		},
+		{
+			name:         "Invalid JSON replies an error",
+			giveRequests: []string{`{"type":`},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSError, Error: "invalid JSON message"},
+			},
+		},
+		{
+			name:         "Unknown message type replies an error",
+			giveRequests: []string{`{"type":"publish","id":"5","videoId":"all"}`},
+			expectedMessages: []controllers.WSMessage{
+				{Type: controllers.WSError, ID: "5", Action: "publish", VideoID: controllers.AllVideos, Error: "unknown message type publish"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectUsersDAOCreation(mock)
+			dao_test.ExpectSessionsDAOCreation(mock)
+			dao_test.ExpectVideosDAOCreation(mock)
+			dao_test.ExpectVideoSharesDAOCreation(mock)
+
+			mock.ExpectQuery(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser])).
+				WithArgs(controllers.HashSessionToken(token), AnyTime{}).
+				WillReturnRows(sqlmock.NewRows(usersColumns).AddRow(userID, "alice", "hash", string(models.UPLOADER), t1, t1, nil))
+			if tt.expectDb != nil {
+				tt.expectDb(mock)
+			}
+
+			usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+			require.NoError(t, err)
+			sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+			require.NoError(t, err)
+			videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
+			require.NoError(t, err)
+			videoSharesDAO, err := dao.CreateVideoSharesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			videoEvents := eventhandler.NewHub(clients.NewAmqpClientDummy(nil, nil, nil))
+			routerClients := router.Clients{
+				UUIDGen:     clients.NewUuidGeneratorDummy(nil, func(u string) bool { _, err := uuid.Parse(u); return err == nil }),
+				VideoEvents: videoEvents,
+			}
+
+			server := httptest.NewServer(router.NewRouter(config.Config{},
+				&routerClients, &router.DAOs{UsersDAO: *usersDAO, SessionsDAO: *sessionsDAO, VideosDAO: *videosDAO, VideoSharesDAO: *videoSharesDAO}))
+			defer server.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws",
+				http.Header{"Authorization": []string{"Bearer " + token}})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			readMessage := func() controllers.WSMessage {
+				var msg controllers.WSMessage
+				require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+				require.NoError(t, conn.ReadJSON(&msg))
+				return msg
+			}
 
+			require.Equal(t, controllers.WSMessage{Type: controllers.WSWelcome, Version: controllers.WSProtocolVersion}, readMessage())
+
+			// Each request is replied before the events are sent
+			expectedMessages := tt.expectedMessages
+			for _, request := range tt.giveRequests {
+				require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(request)))
+				require.Equal(t, expectedMessages[0], readMessage())
+				expectedMessages = expectedMessages[1:]
+			}
+
+			for _, event := range tt.giveEvents {
+				videoEvents.Dispatch(event)
+			}
+			for _, expected := range expectedMessages {
+				require.Equal(t, expected, readMessage())
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
 }
@@ -0,0 +1,271 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type ApiKeysRequestName int
+
+const (
+	CreateTableApiKeysReq ApiKeysRequestName = iota
+	CreateApiKey
+	GetApiKey
+	GetApiKeyFromHash
+	GetApiKeys
+	GetUserApiKeys
+	UpdateApiKeyLastUsed
+	DeleteApiKey
+)
+
+var ApiKeysRequests = map[ApiKeysRequestName]string{
+	CreateTableApiKeysReq: `CREATE TABLE IF NOT EXISTS api_keys (
+			id              VARCHAR(36) NOT NULL,
+			user_id         VARCHAR(36),
+			name            VARCHAR(64) NOT NULL,
+			prefix          VARCHAR(16) NOT NULL,
+			key_hash        CHAR(64) NOT NULL,
+			scope           VARCHAR(16) NOT NULL,
+			expires_at      DATETIME,
+			last_used_at    DATETIME,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT unique_key_hash UNIQUE (key_hash),
+			CONSTRAINT fk_ak_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		);`,
+
+	CreateApiKey:         "INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scope, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+	GetApiKey:            "SELECT * FROM api_keys WHERE id = ?",
+	GetApiKeyFromHash:    "SELECT * FROM api_keys WHERE key_hash = ? AND (expires_at IS NULL OR expires_at > ?)",
+	GetApiKeys:           "SELECT * FROM api_keys ORDER BY created_at DESC",
+	GetUserApiKeys:       "SELECT * FROM api_keys WHERE user_id = ? ORDER BY created_at DESC",
+	UpdateApiKeyLastUsed: "UPDATE api_keys SET last_used_at = ? WHERE id = ?",
+	DeleteApiKey:         "DELETE FROM api_keys WHERE id = ?",
+}
+
+type ApiKeysDAO struct {
+	DB                 *sql.DB
+	stmtCreate         *sql.Stmt
+	stmtGetApiKey      *sql.Stmt
+	stmtGetFromHash    *sql.Stmt
+	stmtGetApiKeys     *sql.Stmt
+	stmtGetUserApiKeys *sql.Stmt
+	stmtUpdateLastUsed *sql.Stmt
+	stmtDelete         *sql.Stmt
+}
+
+func prepareApiKeyStmts(ctx context.Context, db *sql.DB) (*ApiKeysDAO, error) {
+	stmts := ApiKeysDAO{}
+
+	// CreateApiKey
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, ApiKeysRequests[CreateApiKey])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetApiKey
+	stmts.stmtGetApiKey, err = db.PrepareContext(ctx, ApiKeysRequests[GetApiKey])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetApiKeyFromHash
+	stmts.stmtGetFromHash, err = db.PrepareContext(ctx, ApiKeysRequests[GetApiKeyFromHash])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetApiKeys
+	stmts.stmtGetApiKeys, err = db.PrepareContext(ctx, ApiKeysRequests[GetApiKeys])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUserApiKeys
+	stmts.stmtGetUserApiKeys, err = db.PrepareContext(ctx, ApiKeysRequests[GetUserApiKeys])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateApiKeyLastUsed
+	stmts.stmtUpdateLastUsed, err = db.PrepareContext(ctx, ApiKeysRequests[UpdateApiKeyLastUsed])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteApiKey
+	stmts.stmtDelete, err = db.PrepareContext(ctx, ApiKeysRequests[DeleteApiKey])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableApiKeys(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, ApiKeysRequests[CreateTableApiKeysReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table api_keys created (or existed already)")
+	return nil
+}
+
+func CreateApiKeysDAO(ctx context.Context, db *sql.DB) (*ApiKeysDAO, error) {
+	if err := createTableApiKeys(ctx, db); err != nil {
+		log.Error("Cannot create table api_keys : ", err)
+		return nil, err
+	}
+
+	apiKeyDAO, err := prepareApiKeyStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare api_keys statements : ", err)
+		return nil, err
+	}
+
+	apiKeyDAO.DB = db
+
+	return apiKeyDAO, nil
+}
+
+func (a ApiKeysDAO) CreateApiKey(ctx context.Context, apiKey *models.ApiKey) (*models.ApiKey, error) {
+	res, err := a.stmtCreate.ExecContext(ctx, apiKey.ID, apiKey.UserID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scope, apiKey.ExpiresAt)
+	if err != nil {
+		log.Error("Error while insert into api_keys : ", err)
+		return nil, err
+	}
+
+	if err := checkOneRowAffected(res, "creating api key id : "+apiKey.ID); err != nil {
+		return nil, err
+	}
+
+	return a.GetApiKey(ctx, apiKey.ID)
+}
+
+func (a ApiKeysDAO) GetApiKey(ctx context.Context, ID string) (*models.ApiKey, error) {
+	apiKey, err := scanApiKey(a.stmtGetApiKey.QueryRowContext(ctx, ID))
+	if err != nil {
+		log.Error("Error, api key not found : ", err)
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// GetApiKeyFromHash returns the key of the hash, sql.ErrNoRows if it is unknown or expired
+func (a ApiKeysDAO) GetApiKeyFromHash(ctx context.Context, keyHash string) (*models.ApiKey, error) {
+	apiKey, err := scanApiKey(a.stmtGetFromHash.QueryRowContext(ctx, keyHash, time.Now()))
+	if err != nil {
+		log.Error("Error, api key not found : ", err)
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// GetApiKeys returns every key, the most recent first
+func (a ApiKeysDAO) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	rows, err := a.stmtGetApiKeys.QueryContext(ctx)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	return scanApiKeys(rows)
+}
+
+// GetUserApiKeys returns the keys created by the user, the most recent first
+func (a ApiKeysDAO) GetUserApiKeys(ctx context.Context, userID string) ([]models.ApiKey, error) {
+	rows, err := a.stmtGetUserApiKeys.QueryContext(ctx, userID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	return scanApiKeys(rows)
+}
+
+func (a ApiKeysDAO) UpdateApiKeyLastUsed(ctx context.Context, ID string, lastUsedAt time.Time) error {
+	res, err := a.stmtUpdateLastUsed.ExecContext(ctx, lastUsedAt, ID)
+	if err != nil {
+		log.Error("Error while update api key last use : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "updating last use of api key id : "+ID)
+}
+
+func (a ApiKeysDAO) DeleteApiKey(ctx context.Context, ID string) error {
+	res, err := a.stmtDelete.ExecContext(ctx, ID)
+	if err != nil {
+		log.Error("Error while delete from api_keys : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "deleting api key id : "+ID)
+}
+
+func (a ApiKeysDAO) Close() {
+	_ = a.stmtCreate.Close()
+	_ = a.stmtGetApiKey.Close()
+	_ = a.stmtGetFromHash.Close()
+	_ = a.stmtGetApiKeys.Close()
+	_ = a.stmtGetUserApiKeys.Close()
+	_ = a.stmtUpdateLastUsed.Close()
+	_ = a.stmtDelete.Close()
+}
+
+func scanApiKeys(rows *sql.Rows) ([]models.ApiKey, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	apiKeys := []models.ApiKey{}
+	for rows.Next() {
+		apiKey, err := scanApiKey(rows)
+		if err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		apiKeys = append(apiKeys, *apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+func scanApiKey(row rowScanner) (*models.ApiKey, error) {
+	var apiKey models.ApiKey
+	if err := row.Scan(
+		&apiKey.ID,
+		&apiKey.UserID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		&apiKey.Scope,
+		&apiKey.ExpiresAt,
+		&apiKey.LastUsedAt,
+		&apiKey.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
@@ -0,0 +1,156 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type ClipsRequestName int
+
+const (
+	CreateTableClipsReq ClipsRequestName = iota
+	CreateClip
+	GetVideoClips
+)
+
+var ClipsRequests = map[ClipsRequestName]string{
+	// A clip is a video on its own, it outlives its parent
+	CreateTableClipsReq: `CREATE TABLE IF NOT EXISTS clips (
+			video_id        VARCHAR(36) NOT NULL,
+			parent_id       VARCHAR(36),
+			start_seconds   DOUBLE NOT NULL,
+			end_seconds     DOUBLE NOT NULL,
+			accurate        BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (video_id),
+			CONSTRAINT fk_c_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			CONSTRAINT fk_c_p_id FOREIGN KEY (parent_id) REFERENCES videos (id) ON DELETE SET NULL
+		);`,
+
+	CreateClip:    "INSERT INTO clips (video_id, parent_id, start_seconds, end_seconds, accurate) VALUES (?, ?, ?, ?, ?)",
+	GetVideoClips: "SELECT clips.* FROM clips JOIN videos ON videos.id = clips.video_id WHERE clips.parent_id = ? AND (? OR videos.visibility = 'public' OR videos.owner_id = ? OR (videos.visibility = 'shared' AND videos.id IN (SELECT video_id FROM video_shares WHERE user_id = ?))) ORDER BY clips.created_at ASC",
+}
+
+type ClipsDAO struct {
+	DB                *sql.DB
+	stmtCreate        *sql.Stmt
+	stmtGetVideoClips *sql.Stmt
+}
+
+func prepareClipStmts(ctx context.Context, db *sql.DB) (*ClipsDAO, error) {
+	stmts := ClipsDAO{}
+
+	// CreateClip
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, ClipsRequests[CreateClip])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoClips
+	stmts.stmtGetVideoClips, err = db.PrepareContext(ctx, ClipsRequests[GetVideoClips])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableClips(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, ClipsRequests[CreateTableClipsReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table clips created (or existed already)")
+	return nil
+}
+
+func CreateClipsDAO(ctx context.Context, db *sql.DB) (*ClipsDAO, error) {
+	if err := createTableClips(ctx, db); err != nil {
+		log.Error("Cannot create table clips : ", err)
+		return nil, err
+	}
+
+	clipDAO, err := prepareClipStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare clips statements : ", err)
+		return nil, err
+	}
+
+	clipDAO.DB = db
+
+	return clipDAO, nil
+}
+
+func (c ClipsDAO) CreateClip(ctx context.Context, clip *models.Clip) error {
+	res, err := c.stmtCreate.ExecContext(ctx, clip.VideoID, clip.ParentID, clip.Start, clip.End, clip.Accurate)
+	if err != nil {
+		log.Error("Error while insert into clips : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating clip of video id : %v", nbRowAff, clip.VideoID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetVideoClips returns the clips cut from the video, oldest first. Like the videos list, users only get the public
+// clips, their own and the ones shared with them, unless seeAll.
+func (c ClipsDAO) GetVideoClips(ctx context.Context, parentID, userID string, seeAll bool) ([]models.Clip, error) {
+	rows, err := c.stmtGetVideoClips.QueryContext(ctx, parentID, seeAll, userID, userID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	clips := []models.Clip{}
+	for rows.Next() {
+		var row models.Clip
+		if err := rows.Scan(
+			&row.VideoID,
+			&row.ParentID,
+			&row.Start,
+			&row.End,
+			&row.Accurate,
+			&row.CreatedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		clips = append(clips, row)
+	}
+
+	return clips, nil
+}
+
+func (c ClipsDAO) Close() {
+	_ = c.stmtCreate.Close()
+	_ = c.stmtGetVideoClips.Close()
+}
@@ -0,0 +1,158 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type RendersRequestName int
+
+const (
+	CreateTableRendersReq RendersRequestName = iota
+	CreateRender
+	GetVideoRenders
+)
+
+var RendersRequests = map[RendersRequestName]string{
+	// A render is a video on its own, it outlives its parent
+	CreateTableRendersReq: `CREATE TABLE IF NOT EXISTS renders (
+			video_id        VARCHAR(36) NOT NULL,
+			parent_id       VARCHAR(36),
+			filters         TEXT NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (video_id),
+			CONSTRAINT fk_r_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			CONSTRAINT fk_r_p_id FOREIGN KEY (parent_id) REFERENCES videos (id) ON DELETE SET NULL
+		);`,
+
+	CreateRender:    "INSERT INTO renders (video_id, parent_id, filters) VALUES (?, ?, ?)",
+	GetVideoRenders: "SELECT renders.* FROM renders JOIN videos ON videos.id = renders.video_id WHERE renders.parent_id = ? AND (? OR videos.visibility = 'public' OR videos.owner_id = ? OR (videos.visibility = 'shared' AND videos.id IN (SELECT video_id FROM video_shares WHERE user_id = ?))) ORDER BY renders.created_at ASC",
+}
+
+// Separator of the filters in the filters column, transformer names never contain it
+const renderFiltersSeparator = ","
+
+type RendersDAO struct {
+	DB                  *sql.DB
+	stmtCreate          *sql.Stmt
+	stmtGetVideoRenders *sql.Stmt
+}
+
+func prepareRenderStmts(ctx context.Context, db *sql.DB) (*RendersDAO, error) {
+	stmts := RendersDAO{}
+
+	// CreateRender
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, RendersRequests[CreateRender])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoRenders
+	stmts.stmtGetVideoRenders, err = db.PrepareContext(ctx, RendersRequests[GetVideoRenders])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableRenders(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, RendersRequests[CreateTableRendersReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table renders created (or existed already)")
+	return nil
+}
+
+func CreateRendersDAO(ctx context.Context, db *sql.DB) (*RendersDAO, error) {
+	if err := createTableRenders(ctx, db); err != nil {
+		log.Error("Cannot create table renders : ", err)
+		return nil, err
+	}
+
+	renderDAO, err := prepareRenderStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare renders statements : ", err)
+		return nil, err
+	}
+
+	renderDAO.DB = db
+
+	return renderDAO, nil
+}
+
+func (r RendersDAO) CreateRender(ctx context.Context, render *models.Render) error {
+	res, err := r.stmtCreate.ExecContext(ctx, render.VideoID, render.ParentID, strings.Join(render.Filters, renderFiltersSeparator))
+	if err != nil {
+		log.Error("Error while insert into renders : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating render of video id : %v", nbRowAff, render.VideoID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetVideoRenders returns the renders of the video, oldest first. Like the videos list, users only get the public
+// renders, their own and the ones shared with them, unless seeAll.
+func (r RendersDAO) GetVideoRenders(ctx context.Context, parentID, userID string, seeAll bool) ([]models.Render, error) {
+	rows, err := r.stmtGetVideoRenders.QueryContext(ctx, parentID, seeAll, userID, userID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	renders := []models.Render{}
+	for rows.Next() {
+		var row models.Render
+		var filters string
+		if err := rows.Scan(
+			&row.VideoID,
+			&row.ParentID,
+			&filters,
+			&row.CreatedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		row.Filters = strings.Split(filters, renderFiltersSeparator)
+		renders = append(renders, row)
+	}
+
+	return renders, nil
+}
+
+func (r RendersDAO) Close() {
+	_ = r.stmtCreate.Close()
+	_ = r.stmtGetVideoRenders.Close()
+}
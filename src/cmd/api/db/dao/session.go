@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type SessionsRequestName int
+
+const (
+	CreateTableSessionsReq SessionsRequestName = iota
+	CreateSession
+	GetSessionUser
+	DeleteSession
+	DeleteUserSessions
+	DeleteExpiredSessions
+)
+
+var SessionsRequests = map[SessionsRequestName]string{
+	CreateTableSessionsReq: `CREATE TABLE IF NOT EXISTS sessions (
+			token_hash      CHAR(64) NOT NULL,
+			user_id         VARCHAR(36) NOT NULL,
+			expires_at      DATETIME NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (token_hash),
+			CONSTRAINT fk_s_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		);`,
+
+	CreateSession:         "INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+	GetSessionUser:        "SELECT users.* FROM sessions INNER JOIN users ON users.id = sessions.user_id WHERE sessions.token_hash = ? AND sessions.expires_at > ?",
+	DeleteSession:         "DELETE FROM sessions WHERE token_hash = ?",
+	DeleteUserSessions:    "DELETE FROM sessions WHERE user_id = ?",
+	DeleteExpiredSessions: "DELETE FROM sessions WHERE expires_at <= ?",
+}
+
+type SessionsDAO struct {
+	DB                        *sql.DB
+	stmtCreate                *sql.Stmt
+	stmtGetSessionUser        *sql.Stmt
+	stmtDeleteSession         *sql.Stmt
+	stmtDeleteUserSessions    *sql.Stmt
+	stmtDeleteExpiredSessions *sql.Stmt
+}
+
+func prepareSessionStmts(ctx context.Context, db *sql.DB) (*SessionsDAO, error) {
+	stmts := SessionsDAO{}
+
+	// CreateSession
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, SessionsRequests[CreateSession])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetSessionUser
+	stmts.stmtGetSessionUser, err = db.PrepareContext(ctx, SessionsRequests[GetSessionUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteSession
+	stmts.stmtDeleteSession, err = db.PrepareContext(ctx, SessionsRequests[DeleteSession])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteUserSessions
+	stmts.stmtDeleteUserSessions, err = db.PrepareContext(ctx, SessionsRequests[DeleteUserSessions])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteExpiredSessions
+	stmts.stmtDeleteExpiredSessions, err = db.PrepareContext(ctx, SessionsRequests[DeleteExpiredSessions])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableSessions(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, SessionsRequests[CreateTableSessionsReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table sessions created (or existed already)")
+	return nil
+}
+
+func CreateSessionsDAO(ctx context.Context, db *sql.DB) (*SessionsDAO, error) {
+	if err := createTableSessions(ctx, db); err != nil {
+		log.Error("Cannot create table sessions : ", err)
+		return nil, err
+	}
+
+	sessionDAO, err := prepareSessionStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare sessions statements : ", err)
+		return nil, err
+	}
+
+	sessionDAO.DB = db
+
+	return sessionDAO, nil
+}
+
+func (s SessionsDAO) CreateSession(ctx context.Context, session *models.Session) error {
+	res, err := s.stmtCreate.ExecContext(ctx, session.TokenHash, session.UserID, session.ExpiresAt)
+	if err != nil {
+		log.Error("Error while insert into sessions : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "creating session of user id : "+session.UserID)
+}
+
+// GetSessionUser returns the user logged in by the session, sql.ErrNoRows if it is unknown or expired
+func (s SessionsDAO) GetSessionUser(ctx context.Context, tokenHash string) (*models.User, error) {
+	user, err := scanUser(s.stmtGetSessionUser.QueryRowContext(ctx, tokenHash, time.Now()))
+	if err != nil {
+		log.Error("Error, session not found : ", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s SessionsDAO) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := s.stmtDeleteSession.ExecContext(ctx, tokenHash); err != nil {
+		log.Error("Error while delete from sessions : ", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserSessions logs the user out of all its sessions
+func (s SessionsDAO) DeleteUserSessions(ctx context.Context, userID string) error {
+	if _, err := s.stmtDeleteUserSessions.ExecContext(ctx, userID); err != nil {
+		log.Error("Error while delete from sessions : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SessionsDAO) DeleteExpiredSessions(ctx context.Context) error {
+	if _, err := s.stmtDeleteExpiredSessions.ExecContext(ctx, time.Now()); err != nil {
+		log.Error("Error while delete from sessions : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SessionsDAO) Close() {
+	_ = s.stmtCreate.Close()
+	_ = s.stmtGetSessionUser.Close()
+	_ = s.stmtDeleteSession.Close()
+	_ = s.stmtDeleteUserSessions.Close()
+	_ = s.stmtDeleteExpiredSessions.Close()
+}
@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+)
+
+type StorageUsagesRequestName int
+
+const (
+	CreateTableStorageUsagesReq StorageUsagesRequestName = iota
+	CreateStorageUsage
+	GetUserStorageUsage
+)
+
+var StorageUsagesRequests = map[StorageUsagesRequestName]string{
+	// Rows are removed with their video, so the usage of a user is always the sum of its remaining videos
+	CreateTableStorageUsagesReq: `CREATE TABLE IF NOT EXISTS storage_usages (
+			video_id        VARCHAR(36) NOT NULL,
+			username        VARCHAR(64) NOT NULL,
+			size_bytes      BIGINT NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (video_id),
+			CONSTRAINT fk_su_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+		);`,
+
+	CreateStorageUsage:  "INSERT INTO storage_usages (video_id, username, size_bytes) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE username = VALUES(username), size_bytes = VALUES(size_bytes)",
+	GetUserStorageUsage: "SELECT COALESCE(SUM(size_bytes), 0) FROM storage_usages WHERE username = ?",
+}
+
+type StorageUsagesDAO struct {
+	DB                      *sql.DB
+	stmtCreate              *sql.Stmt
+	stmtGetUserStorageUsage *sql.Stmt
+}
+
+func prepareStorageUsageStmts(ctx context.Context, db *sql.DB) (*StorageUsagesDAO, error) {
+	stmts := StorageUsagesDAO{}
+
+	// CreateStorageUsage
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, StorageUsagesRequests[CreateStorageUsage])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUserStorageUsage
+	stmts.stmtGetUserStorageUsage, err = db.PrepareContext(ctx, StorageUsagesRequests[GetUserStorageUsage])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableStorageUsages(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, StorageUsagesRequests[CreateTableStorageUsagesReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table storage_usages created (or existed already)")
+	return nil
+}
+
+func CreateStorageUsagesDAO(ctx context.Context, db *sql.DB) (*StorageUsagesDAO, error) {
+	if err := createTableStorageUsages(ctx, db); err != nil {
+		log.Error("Cannot create table storage_usages : ", err)
+		return nil, err
+	}
+
+	storageUsageDAO, err := prepareStorageUsageStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare storage_usages statements : ", err)
+		return nil, err
+	}
+
+	storageUsageDAO.DB = db
+
+	return storageUsageDAO, nil
+}
+
+// CreateStorageUsage records (or replaces) the size of the video source uploaded by username
+func (s StorageUsagesDAO) CreateStorageUsage(ctx context.Context, videoID, username string, sizeBytes int64) error {
+	if _, err := s.stmtCreate.ExecContext(ctx, videoID, username, sizeBytes); err != nil {
+		log.Error("Error while insert into storage_usages : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s StorageUsagesDAO) GetUserStorageUsage(ctx context.Context, username string) (int64, error) {
+	var total int64
+	err := s.stmtGetUserStorageUsage.QueryRowContext(ctx, username).Scan(&total)
+	if err != nil {
+		log.Error("Cannot read rows : ", err)
+		return -1, err
+	}
+	return total, nil
+}
+
+func (s StorageUsagesDAO) Close() {
+	_ = s.stmtCreate.Close()
+	_ = s.stmtGetUserStorageUsage.Close()
+}
@@ -0,0 +1,305 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type SubtitlesRequestName int
+
+const (
+	CreateTableSubtitlesReq SubtitlesRequestName = iota
+	CreateSubtitle
+	UpdateSubtitle
+	GetSubtitle
+	GetVideoSubtitles
+	DeleteSubtitle
+	ClearDefaultSubtitle
+)
+
+var SubtitlesRequests = map[SubtitlesRequestName]string{
+	CreateTableSubtitlesReq: `CREATE TABLE IF NOT EXISTS subtitles (
+			id              VARCHAR(36) NOT NULL,
+			video_id        VARCHAR(36) NOT NULL,
+			language        VARCHAR(35) NOT NULL,
+			label           VARCHAR(64) NOT NULL,
+			is_default      BOOLEAN NOT NULL DEFAULT FALSE,
+			forced          BOOLEAN NOT NULL DEFAULT FALSE,
+			path            VARCHAR(256) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT fk_s_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE
+		);`,
+
+	CreateSubtitle:       "INSERT INTO subtitles (id, video_id, language, label, is_default, forced, path) VALUES (?, ?, ?, ?, ?, ?, ?)",
+	UpdateSubtitle:       "UPDATE subtitles SET language = ?, label = ?, is_default = ?, forced = ?, path = ? WHERE id = ?",
+	GetSubtitle:          "SELECT id, video_id, language, label, is_default, forced, path, created_at, updated_at FROM subtitles WHERE id = ?",
+	GetVideoSubtitles:    "SELECT id, video_id, language, label, is_default, forced, path, created_at, updated_at FROM subtitles WHERE video_id = ? ORDER BY created_at, id",
+	DeleteSubtitle:       "DELETE FROM subtitles WHERE id = ?",
+	ClearDefaultSubtitle: "UPDATE subtitles SET is_default = FALSE WHERE video_id = ? AND id <> ?",
+}
+
+type SubtitlesDAO struct {
+	DB                       *sql.DB
+	stmtCreate               *sql.Stmt
+	stmtUpdate               *sql.Stmt
+	stmtGetSubtitle          *sql.Stmt
+	stmtGetVideoSubtitles    *sql.Stmt
+	stmtDelete               *sql.Stmt
+	stmtClearDefaultSubtitle *sql.Stmt
+}
+
+func prepareSubtitleStmts(ctx context.Context, db *sql.DB) (*SubtitlesDAO, error) {
+	stmts := SubtitlesDAO{}
+
+	// CreateSubtitle
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, SubtitlesRequests[CreateSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateSubtitle
+	stmts.stmtUpdate, err = db.PrepareContext(ctx, SubtitlesRequests[UpdateSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetSubtitle
+	stmts.stmtGetSubtitle, err = db.PrepareContext(ctx, SubtitlesRequests[GetSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoSubtitles
+	stmts.stmtGetVideoSubtitles, err = db.PrepareContext(ctx, SubtitlesRequests[GetVideoSubtitles])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteSubtitle
+	stmts.stmtDelete, err = db.PrepareContext(ctx, SubtitlesRequests[DeleteSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// ClearDefaultSubtitle
+	stmts.stmtClearDefaultSubtitle, err = db.PrepareContext(ctx, SubtitlesRequests[ClearDefaultSubtitle])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableSubtitles(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, SubtitlesRequests[CreateTableSubtitlesReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table subtitles created (or existed already)")
+	return nil
+}
+
+func CreateSubtitlesDAO(ctx context.Context, db *sql.DB) (*SubtitlesDAO, error) {
+	if err := createTableSubtitles(ctx, db); err != nil {
+		log.Error("Cannot create table subtitles : ", err)
+		return nil, err
+	}
+
+	subtitleDAO, err := prepareSubtitleStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare subtitles statements : ", err)
+		return nil, err
+	}
+
+	subtitleDAO.DB = db
+
+	return subtitleDAO, nil
+}
+
+func (s SubtitlesDAO) CreateSubtitle(ctx context.Context, subtitle *models.Subtitle) error {
+	res, err := s.stmtCreate.ExecContext(ctx, subtitle.ID, subtitle.VideoID, subtitle.Language, subtitle.Label, subtitle.IsDefault, subtitle.Forced, subtitle.Path)
+	if err != nil {
+		log.Error("Error while insert into subtitles : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating subtitle id : %v", nbRowAff, subtitle.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SubtitlesDAO) CreateSubtitleTx(ctx context.Context, tx *sql.Tx, subtitle *models.Subtitle) error {
+	stmt := tx.StmtContext(ctx, s.stmtCreate)
+	res, err := stmt.ExecContext(ctx, subtitle.ID, subtitle.VideoID, subtitle.Language, subtitle.Label, subtitle.IsDefault, subtitle.Forced, subtitle.Path)
+	if err != nil {
+		log.Error("Error while insert into subtitles : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while creating subtitle id : %v", nbRowAff, subtitle.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SubtitlesDAO) UpdateSubtitleTx(ctx context.Context, tx *sql.Tx, subtitle *models.Subtitle) error {
+	stmt := tx.StmtContext(ctx, s.stmtUpdate)
+	res, err := stmt.ExecContext(ctx, subtitle.Language, subtitle.Label, subtitle.IsDefault, subtitle.Forced, subtitle.Path, subtitle.ID)
+	if err != nil {
+		log.Error("Error while update subtitle : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// A row is not affected when nothing changed, so only check that we did not update several rows
+	if nbRowAff > 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while update id : %v in table subtitles", nbRowAff, subtitle.ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// ClearDefaultSubtitleTx removes the default flag from every subtitle of the video but the given one
+func (s SubtitlesDAO) ClearDefaultSubtitleTx(ctx context.Context, tx *sql.Tx, videoID, keptID string) error {
+	stmt := tx.StmtContext(ctx, s.stmtClearDefaultSubtitle)
+	if _, err := stmt.ExecContext(ctx, videoID, keptID); err != nil {
+		log.Error("Error while update subtitles : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SubtitlesDAO) DeleteSubtitle(ctx context.Context, ID string) error {
+	res, err := s.stmtDelete.ExecContext(ctx, ID)
+	if err != nil {
+		log.Error("Error while delete from subtitles : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// Check if one and only one rows has been affected
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while deleting subtitle id : %v", nbRowAff, ID)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s SubtitlesDAO) GetSubtitle(ctx context.Context, ID string) (*models.Subtitle, error) {
+	var subtitle models.Subtitle
+	err := s.stmtGetSubtitle.QueryRowContext(ctx, ID).Scan(
+		&subtitle.ID,
+		&subtitle.VideoID,
+		&subtitle.Language,
+		&subtitle.Label,
+		&subtitle.IsDefault,
+		&subtitle.Forced,
+		&subtitle.Path,
+		&subtitle.CreatedAt,
+		&subtitle.UpdatedAt,
+	)
+	if err != nil {
+		log.Error("Error, subtitle not found : ", err)
+		return nil, err
+	}
+
+	return &subtitle, nil
+}
+
+func (s SubtitlesDAO) GetVideoSubtitles(ctx context.Context, videoID string) ([]models.Subtitle, error) {
+	rows, err := s.stmtGetVideoSubtitles.QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	subtitles := []models.Subtitle{}
+	for rows.Next() {
+		var row models.Subtitle
+		if err := rows.Scan(
+			&row.ID,
+			&row.VideoID,
+			&row.Language,
+			&row.Label,
+			&row.IsDefault,
+			&row.Forced,
+			&row.Path,
+			&row.CreatedAt,
+			&row.UpdatedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		subtitles = append(subtitles, row)
+	}
+
+	return subtitles, nil
+}
+
+func (s SubtitlesDAO) Close() {
+	_ = s.stmtCreate.Close()
+	_ = s.stmtUpdate.Close()
+	_ = s.stmtGetSubtitle.Close()
+	_ = s.stmtGetVideoSubtitles.Close()
+	_ = s.stmtDelete.Close()
+	_ = s.stmtClearDefaultSubtitle.Close()
+}
@@ -0,0 +1,338 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type UsersRequestName int
+
+const (
+	CreateTableUsersReq UsersRequestName = iota
+	AddColumnUsersOIDCSubjectReq
+	CreateUser
+	CreateOIDCUser
+	GetUser
+	GetUserFromUsername
+	GetUserFromOIDCSubject
+	GetUsers
+	UpdateUserRole
+	UpdateUserPassword
+	DeleteUser
+)
+
+var UsersRequests = map[UsersRequestName]string{
+	CreateTableUsersReq: `CREATE TABLE IF NOT EXISTS users (
+			id              VARCHAR(36) NOT NULL,
+			username        VARCHAR(64) NOT NULL,
+			password_hash   VARCHAR(255) NOT NULL,
+			role            VARCHAR(16) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			oidc_subject    VARCHAR(255),
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT unique_username UNIQUE (username),
+			CONSTRAINT unique_oidc_subject UNIQUE (oidc_subject)
+		);`,
+
+	// Tables created before the single sign-on
+	AddColumnUsersOIDCSubjectReq: "ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255) UNIQUE",
+
+	CreateUser:             "INSERT INTO users (id, username, password_hash, role) VALUES (?, ?, ?, ?)",
+	CreateOIDCUser:         "INSERT INTO users (id, username, password_hash, role, oidc_subject) VALUES (?, ?, '', ?, ?)",
+	GetUser:                "SELECT * FROM users WHERE id = ?",
+	GetUserFromUsername:    "SELECT * FROM users WHERE username = ?",
+	GetUserFromOIDCSubject: "SELECT * FROM users WHERE oidc_subject = ?",
+	GetUsers:               "SELECT * FROM users ORDER BY username ASC",
+	UpdateUserRole:         "UPDATE users SET role = ? WHERE id = ?",
+	UpdateUserPassword:     "UPDATE users SET password_hash = ? WHERE id = ?",
+	DeleteUser:             "DELETE FROM users WHERE id = ?",
+}
+
+type UsersDAO struct {
+	DB                         *sql.DB
+	stmtCreate                 *sql.Stmt
+	stmtCreateOIDC             *sql.Stmt
+	stmtGetUser                *sql.Stmt
+	stmtGetUserFromUsername    *sql.Stmt
+	stmtGetUserFromOIDCSubject *sql.Stmt
+	stmtGetUsers               *sql.Stmt
+	stmtUpdateRole             *sql.Stmt
+	stmtUpdatePassword         *sql.Stmt
+	stmtDelete                 *sql.Stmt
+}
+
+func prepareUserStmts(ctx context.Context, db *sql.DB) (*UsersDAO, error) {
+	stmts := UsersDAO{}
+
+	// CreateUser
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, UsersRequests[CreateUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// CreateOIDCUser
+	stmts.stmtCreateOIDC, err = db.PrepareContext(ctx, UsersRequests[CreateOIDCUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUser
+	stmts.stmtGetUser, err = db.PrepareContext(ctx, UsersRequests[GetUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUserFromUsername
+	stmts.stmtGetUserFromUsername, err = db.PrepareContext(ctx, UsersRequests[GetUserFromUsername])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUserFromOIDCSubject
+	stmts.stmtGetUserFromOIDCSubject, err = db.PrepareContext(ctx, UsersRequests[GetUserFromOIDCSubject])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetUsers
+	stmts.stmtGetUsers, err = db.PrepareContext(ctx, UsersRequests[GetUsers])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateUserRole
+	stmts.stmtUpdateRole, err = db.PrepareContext(ctx, UsersRequests[UpdateUserRole])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateUserPassword
+	stmts.stmtUpdatePassword, err = db.PrepareContext(ctx, UsersRequests[UpdateUserPassword])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteUser
+	stmts.stmtDelete, err = db.PrepareContext(ctx, UsersRequests[DeleteUser])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableUsers(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, UsersRequests[CreateTableUsersReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, UsersRequests[AddColumnUsersOIDCSubjectReq]); err != nil {
+		log.Error("Cannot add column oidc_subject : ", err)
+		return err
+	}
+
+	log.Debug("Table users created (or existed already)")
+	return nil
+}
+
+func CreateUsersDAO(ctx context.Context, db *sql.DB) (*UsersDAO, error) {
+	if err := createTableUsers(ctx, db); err != nil {
+		log.Error("Cannot create table users : ", err)
+		return nil, err
+	}
+
+	userDAO, err := prepareUserStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare users statements : ", err)
+		return nil, err
+	}
+
+	userDAO.DB = db
+
+	return userDAO, nil
+}
+
+func (u UsersDAO) CreateUser(ctx context.Context, ID, username, passwordHash string, role models.Role) (*models.User, error) {
+	res, err := u.stmtCreate.ExecContext(ctx, ID, username, passwordHash, role)
+	if err != nil {
+		log.Error("Error while insert into users : ", err)
+		return nil, err
+	}
+
+	if err := checkOneRowAffected(res, "creating user id : "+ID); err != nil {
+		return nil, err
+	}
+
+	return u.GetUser(ctx, ID)
+}
+
+// CreateOIDCUser creates a user authenticated by the OpenID Connect provider, without password
+func (u UsersDAO) CreateOIDCUser(ctx context.Context, ID, username string, role models.Role, subject string) (*models.User, error) {
+	res, err := u.stmtCreateOIDC.ExecContext(ctx, ID, username, role, subject)
+	if err != nil {
+		log.Error("Error while insert into users : ", err)
+		return nil, err
+	}
+
+	if err := checkOneRowAffected(res, "creating user id : "+ID); err != nil {
+		return nil, err
+	}
+
+	return u.GetUser(ctx, ID)
+}
+
+func (u UsersDAO) GetUser(ctx context.Context, ID string) (*models.User, error) {
+	user, err := scanUser(u.stmtGetUser.QueryRowContext(ctx, ID))
+	if err != nil {
+		log.Error("Error, user not found : ", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u UsersDAO) GetUserFromUsername(ctx context.Context, username string) (*models.User, error) {
+	user, err := scanUser(u.stmtGetUserFromUsername.QueryRowContext(ctx, username))
+	if err != nil {
+		log.Error("Error, user not found : ", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u UsersDAO) GetUserFromOIDCSubject(ctx context.Context, subject string) (*models.User, error) {
+	user, err := scanUser(u.stmtGetUserFromOIDCSubject.QueryRowContext(ctx, subject))
+	if err != nil {
+		log.Error("Error, user not found : ", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUsers returns every user, sorted by username
+func (u UsersDAO) GetUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := u.stmtGetUsers.QueryContext(ctx)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
+
+func (u UsersDAO) UpdateUserRole(ctx context.Context, ID string, role models.Role) error {
+	res, err := u.stmtUpdateRole.ExecContext(ctx, role, ID)
+	if err != nil {
+		log.Error("Error while update user role : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "updating role of user id : "+ID)
+}
+
+func (u UsersDAO) UpdateUserPassword(ctx context.Context, ID, passwordHash string) error {
+	res, err := u.stmtUpdatePassword.ExecContext(ctx, passwordHash, ID)
+	if err != nil {
+		log.Error("Error while update user password : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "updating password of user id : "+ID)
+}
+
+func (u UsersDAO) DeleteUser(ctx context.Context, ID string) error {
+	res, err := u.stmtDelete.ExecContext(ctx, ID)
+	if err != nil {
+		log.Error("Error while delete from users : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "deleting user id : "+ID)
+}
+
+func (u UsersDAO) Close() {
+	_ = u.stmtCreate.Close()
+	_ = u.stmtCreateOIDC.Close()
+	_ = u.stmtGetUser.Close()
+	_ = u.stmtGetUserFromUsername.Close()
+	_ = u.stmtGetUserFromOIDCSubject.Close()
+	_ = u.stmtGetUsers.Close()
+	_ = u.stmtUpdateRole.Close()
+	_ = u.stmtUpdatePassword.Close()
+	_ = u.stmtDelete.Close()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.OIDCSubject,
+	); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// checkOneRowAffected checks that the statement changed one and only one row
+func checkOneRowAffected(res sql.Result, action string) error {
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	if nbRowAff != 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while %v", nbRowAff, action)
+		log.Error(err)
+		return err
+	}
+
+	return nil
+}
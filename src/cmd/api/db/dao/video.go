@@ -16,10 +16,15 @@ type VideosRequestName int
 
 const (
 	CreateTableVideosReq VideosRequestName = iota
+	AddColumnVideosLoudnessReq
+	AddColumnVideosOwnerReq
+	AddColumnVideosVisibilityReq
 	CreateVideo
 	UpdateVideo
 	UpdateVideoTitle
 	UpdateVideoCover
+	UpdateVideoLoudness
+	UpdateVideoVisibility
 	GetVideo
 	GetVideoFromTitle
 	GetVideosTitleAsc
@@ -40,22 +45,32 @@ var VideosRequests = map[VideosRequestName]string{
 			updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			source_path     VARCHAR(64) NOT NULL,
 			cover_path      VARCHAR(64),
+			loudness        DOUBLE,
+			owner_id        VARCHAR(36),
+			visibility      VARCHAR(16) NOT NULL DEFAULT 'public',
 
 			CONSTRAINT pk PRIMARY KEY (id),
 			CONSTRAINT unique_title UNIQUE (title)
 		);`,
+	// Tables created before the loudness measure
+	AddColumnVideosLoudnessReq: "ALTER TABLE videos ADD COLUMN IF NOT EXISTS loudness DOUBLE",
+	// Tables created before the user accounts, their videos stay visible to everyone
+	AddColumnVideosOwnerReq:      "ALTER TABLE videos ADD COLUMN IF NOT EXISTS owner_id VARCHAR(36)",
+	AddColumnVideosVisibilityReq: "ALTER TABLE videos ADD COLUMN IF NOT EXISTS visibility VARCHAR(16) NOT NULL DEFAULT 'public'",
 
-	CreateVideo:             "INSERT INTO videos (id, title, video_status, source_path, cover_path) VALUES (?, ? , ?, ?, ?)",
+	CreateVideo:             "INSERT INTO videos (id, title, video_status, source_path, cover_path, owner_id, visibility) VALUES (?, ? , ?, ?, ?, ?, ?)",
 	UpdateVideo:             "UPDATE videos SET title = ?, video_status = ?, uploaded_at = ?, source_path = ?, cover_path = ? WHERE id = ?",
 	UpdateVideoTitle:        "UPDATE videos SET title = ? WHERE id = ?",
 	UpdateVideoCover:        "UPDATE videos SET cover_path = ? WHERE id = ?",
+	UpdateVideoLoudness:     "UPDATE videos SET loudness = ? WHERE id = ?",
+	UpdateVideoVisibility:   "UPDATE videos SET visibility = ? WHERE id = ?",
 	GetVideo:                "SELECT * FROM videos WHERE id = ?",
 	GetVideoFromTitle:       "SELECT * FROM videos WHERE title = ?",
-	GetVideosTitleAsc:       "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? ORDER BY title ASC LIMIT ?,?",
-	GetVideosTitleDesc:      "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? ORDER BY title DESC LIMIT ?,?",
-	GetVideosUploadedAtAsc:  "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? ORDER BY uploaded_at ASC LIMIT ?,?",
-	GetVideosUploadedAtDesc: "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? ORDER BY uploaded_at DESC LIMIT ?,?",
-	GetTotalVideos:          "SELECT COUNT(*) FROM videos WHERE video_status = ? and LOWER(title) like ?",
+	GetVideosTitleAsc:       "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? AND (? OR visibility = 'public' OR owner_id = ? OR (visibility = 'shared' AND id IN (SELECT video_id FROM video_shares WHERE user_id = ?))) ORDER BY title ASC LIMIT ?,?",
+	GetVideosTitleDesc:      "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? AND (? OR visibility = 'public' OR owner_id = ? OR (visibility = 'shared' AND id IN (SELECT video_id FROM video_shares WHERE user_id = ?))) ORDER BY title DESC LIMIT ?,?",
+	GetVideosUploadedAtAsc:  "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? AND (? OR visibility = 'public' OR owner_id = ? OR (visibility = 'shared' AND id IN (SELECT video_id FROM video_shares WHERE user_id = ?))) ORDER BY uploaded_at ASC LIMIT ?,?",
+	GetVideosUploadedAtDesc: "SELECT * FROM videos WHERE video_status = ? AND LOWER(title) like ? AND (? OR visibility = 'public' OR owner_id = ? OR (visibility = 'shared' AND id IN (SELECT video_id FROM video_shares WHERE user_id = ?))) ORDER BY uploaded_at DESC LIMIT ?,?",
+	GetTotalVideos:          "SELECT COUNT(*) FROM videos WHERE video_status = ? and LOWER(title) like ? AND (? OR visibility = 'public' OR owner_id = ? OR (visibility = 'shared' AND id IN (SELECT video_id FROM video_shares WHERE user_id = ?)))",
 	DeleteVideo:             "DELETE FROM videos WHERE id = ?",
 }
 
@@ -65,6 +80,8 @@ type VideosDAO struct {
 	stmtUpdate                  *sql.Stmt
 	stmtUpdateTitle             *sql.Stmt
 	stmtUpdateCover             *sql.Stmt
+	stmtUpdateLoudness          *sql.Stmt
+	stmtUpdateVisibility        *sql.Stmt
 	stmtGetVideo                *sql.Stmt
 	stmtGetVideoFromTitle       *sql.Stmt
 	stmtGetVideosTitleAsc       *sql.Stmt
@@ -107,6 +124,20 @@ func prepareVideoStmts(ctx context.Context, db *sql.DB) (*VideosDAO, error) {
 		return nil, err
 	}
 
+	// UpdateVideoLoudness
+	stmts.stmtUpdateLoudness, err = db.PrepareContext(ctx, VideosRequests[UpdateVideoLoudness])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// UpdateVideoVisibility
+	stmts.stmtUpdateVisibility, err = db.PrepareContext(ctx, VideosRequests[UpdateVideoVisibility])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
 	// GetVideo
 	stmts.stmtGetVideo, err = db.PrepareContext(ctx, VideosRequests[GetVideo])
 	if err != nil {
@@ -172,6 +203,26 @@ func createTableVideos(ctx context.Context, db *sql.DB) error {
 		return err
 	}
 
+	if _, err := db.ExecContext(ctx, VideosRequests[AddColumnVideosLoudnessReq]); err != nil {
+		log.Error("Cannot add column loudness : ", err)
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, VideosRequests[AddColumnVideosOwnerReq]); err != nil {
+		log.Error("Cannot add column owner_id : ", err)
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, VideosRequests[AddColumnVideosVisibilityReq]); err != nil {
+		log.Error("Cannot add column visibility : ", err)
+		return err
+	}
+
+	// The list statements look for the shares of the videos
+	if err := createTableVideoShares(ctx, db); err != nil {
+		return err
+	}
+
 	log.Debug("Table videos created (or existed already)")
 	return nil
 }
@@ -193,8 +244,8 @@ func CreateVideosDAO(ctx context.Context, db *sql.DB) (*VideosDAO, error) {
 	return videoDAO, nil
 }
 
-func (v VideosDAO) CreateVideo(ctx context.Context, ID, title string, status int, sourcePath string, coverPath string) (*models.Video, error) {
-	res, err := v.stmtCreate.ExecContext(ctx, ID, title, status, sourcePath, coverPath)
+func (v VideosDAO) CreateVideo(ctx context.Context, ID, title string, status int, sourcePath string, coverPath string, ownerID *string, visibility models.Visibility) (*models.Video, error) {
+	res, err := v.stmtCreate.ExecContext(ctx, ID, title, status, sourcePath, coverPath, ownerID, visibility)
 	if err != nil {
 		log.Error("Error while insert into videos : ", err)
 		return nil, err
@@ -329,6 +380,28 @@ func (v VideosDAO) UpdateVideoCover(ctx context.Context, ID, coverPath string) e
 	return nil
 }
 
+func (v VideosDAO) UpdateVideoLoudness(ctx context.Context, ID string, loudness float64) error {
+	res, err := v.stmtUpdateLoudness.ExecContext(ctx, loudness, ID)
+	if err != nil {
+		log.Error("Error while update video : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+
+	// A redelivered event sets the same value, no row is changed then
+	if nbRowAff > 1 {
+		err := fmt.Errorf("wrong number of row affected (%d) while update id : %v in table videos", nbRowAff, ID)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
 func (v VideosDAO) UpdateVideoTx(ctx context.Context, tx *sql.Tx, video *models.Video) error {
 	stmt := tx.StmtContext(ctx, v.stmtUpdate)
 	res, err := stmt.ExecContext(ctx, video.Title, video.Status, video.UploadedAt, video.SourcePath, video.CoverPath, video.ID)
@@ -354,46 +427,28 @@ func (v VideosDAO) UpdateVideoTx(ctx context.Context, tx *sql.Tx, video *models.
 }
 
 func (v VideosDAO) GetVideo(ctx context.Context, ID string) (*models.Video, error) {
-	var video models.Video
-	err := v.stmtGetVideo.QueryRowContext(ctx, ID).Scan(
-		&video.ID,
-		&video.Title,
-		&video.Status,
-		&video.UploadedAt,
-		&video.CreatedAt,
-		&video.UpdatedAt,
-		&video.SourcePath,
-		&video.CoverPath,
-	)
+	video, err := scanVideo(v.stmtGetVideo.QueryRowContext(ctx, ID))
 	if err != nil {
 		log.Error("Error, video not found : ", err)
 		return nil, err
 	}
 
-	return &video, nil
+	return video, nil
 }
 
 func (v VideosDAO) GetVideoFromTitle(ctx context.Context, title string) (*models.Video, error) {
-	var video models.Video
-	err := v.stmtGetVideoFromTitle.QueryRowContext(ctx, title).Scan(
-		&video.ID,
-		&video.Title,
-		&video.Status,
-		&video.UploadedAt,
-		&video.CreatedAt,
-		&video.UpdatedAt,
-		&video.SourcePath,
-		&video.CoverPath,
-	)
+	video, err := scanVideo(v.stmtGetVideoFromTitle.QueryRowContext(ctx, title))
 	if err != nil {
 		log.Error("Error, video not found : ", err)
 		return nil, err
 	}
 
-	return &video, nil
+	return video, nil
 }
 
-func (v VideosDAO) GetVideos(ctx context.Context, attribute interface{}, ascending bool, page, limit, status int, title string) ([]models.Video, error) {
+// GetVideos returns a page of the videos listed to the user: the public ones, the ones it owns or that are shared with it.
+// All the videos are listed when seeAll is set.
+func (v VideosDAO) GetVideos(ctx context.Context, attribute interface{}, ascending bool, page, limit, status int, title, userID string, seeAll bool) ([]models.Video, error) {
 
 	var stmt *sql.Stmt
 	switch attribute {
@@ -424,7 +479,7 @@ func (v VideosDAO) GetVideos(ctx context.Context, attribute interface{}, ascendi
 		return nil, err
 	}
 
-	rows, err := stmt.QueryContext(ctx, status, "%"+strings.ToLower(title)+"%", (page-1)*limit, limit)
+	rows, err := stmt.QueryContext(ctx, status, "%"+strings.ToLower(title)+"%", seeAll, userID, userID, (page-1)*limit, limit)
 	if err != nil {
 		log.Error("Error, cannot query database : ", err)
 		return nil, err
@@ -437,29 +492,20 @@ func (v VideosDAO) GetVideos(ctx context.Context, attribute interface{}, ascendi
 
 	var videos []models.Video
 	for rows.Next() {
-		var row models.Video
-		if err := rows.Scan(
-			&row.ID,
-			&row.Title,
-			&row.Status,
-			&row.UploadedAt,
-			&row.CreatedAt,
-			&row.UpdatedAt,
-			&row.SourcePath,
-			&row.CoverPath,
-		); err != nil {
+		row, err := scanVideo(rows)
+		if err != nil {
 			log.Error("Cannot read rows : ", err)
 			return nil, err
 		}
-		videos = append(videos, row)
+		videos = append(videos, *row)
 	}
 
 	return videos, nil
 }
 
-func (v VideosDAO) GetTotalVideos(ctx context.Context, status int, query string) (int, error) {
+func (v VideosDAO) GetTotalVideos(ctx context.Context, status int, query, userID string, seeAll bool) (int, error) {
 	var total int
-	err := v.stmtGetTotalVideos.QueryRowContext(ctx, status, query).Scan(&total)
+	err := v.stmtGetTotalVideos.QueryRowContext(ctx, status, query, seeAll, userID, userID).Scan(&total)
 	if err != nil {
 		log.Error("Cannot read rows : ", err)
 		return -1, err
@@ -467,9 +513,22 @@ func (v VideosDAO) GetTotalVideos(ctx context.Context, status int, query string)
 	return total, nil
 }
 
+func (v VideosDAO) UpdateVideoVisibility(ctx context.Context, ID string, visibility models.Visibility) error {
+	res, err := v.stmtUpdateVisibility.ExecContext(ctx, visibility, ID)
+	if err != nil {
+		log.Error("Error while update video visibility : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "updating visibility of video id : "+ID)
+}
+
 func (v VideosDAO) Close() {
 	_ = v.stmtCreate.Close()
 	_ = v.stmtUpdate.Close()
+	_ = v.stmtUpdateTitle.Close()
+	_ = v.stmtUpdateCover.Close()
+	_ = v.stmtUpdateLoudness.Close()
 	_ = v.stmtGetVideo.Close()
 	_ = v.stmtGetVideoFromTitle.Close()
 	_ = v.stmtDeleteVideo.Close()
@@ -478,4 +537,26 @@ func (v VideosDAO) Close() {
 	_ = v.stmtGetVideosTitleDesc.Close()
 	_ = v.stmtGetVideosUploadedAtAsc.Close()
 	_ = v.stmtGetVideosUploadedAtDesc.Close()
+	_ = v.stmtUpdateVisibility.Close()
+}
+
+func scanVideo(row rowScanner) (*models.Video, error) {
+	var video models.Video
+	if err := row.Scan(
+		&video.ID,
+		&video.Title,
+		&video.Status,
+		&video.UploadedAt,
+		&video.CreatedAt,
+		&video.UpdatedAt,
+		&video.SourcePath,
+		&video.CoverPath,
+		&video.Loudness,
+		&video.OwnerID,
+		&video.Visibility,
+	); err != nil {
+		return nil, err
+	}
+
+	return &video, nil
 }
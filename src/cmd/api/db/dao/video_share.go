@@ -0,0 +1,179 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type VideoSharesRequestName int
+
+const (
+	CreateTableVideoSharesReq VideoSharesRequestName = iota
+	CreateVideoShare
+	GetVideoShare
+	GetVideoSharesUsers
+	DeleteVideoShare
+)
+
+var VideoSharesRequests = map[VideoSharesRequestName]string{
+	CreateTableVideoSharesReq: `CREATE TABLE IF NOT EXISTS video_shares (
+			video_id        VARCHAR(36) NOT NULL,
+			user_id         VARCHAR(36) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (video_id, user_id),
+			CONSTRAINT fk_vs_v_id FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE,
+			CONSTRAINT fk_vs_u_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		);`,
+
+	CreateVideoShare:    "INSERT IGNORE INTO video_shares (video_id, user_id) VALUES (?, ?)",
+	GetVideoShare:       "SELECT COUNT(*) FROM video_shares WHERE video_id = ? AND user_id = ?",
+	GetVideoSharesUsers: "SELECT users.* FROM video_shares INNER JOIN users ON users.id = video_shares.user_id WHERE video_shares.video_id = ? ORDER BY users.username ASC",
+	DeleteVideoShare:    "DELETE FROM video_shares WHERE video_id = ? AND user_id = ?",
+}
+
+type VideoSharesDAO struct {
+	DB                      *sql.DB
+	stmtCreate              *sql.Stmt
+	stmtGetVideoShare       *sql.Stmt
+	stmtGetVideoSharesUsers *sql.Stmt
+	stmtDelete              *sql.Stmt
+}
+
+func prepareVideoShareStmts(ctx context.Context, db *sql.DB) (*VideoSharesDAO, error) {
+	stmts := VideoSharesDAO{}
+
+	// CreateVideoShare
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, VideoSharesRequests[CreateVideoShare])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoShare
+	stmts.stmtGetVideoShare, err = db.PrepareContext(ctx, VideoSharesRequests[GetVideoShare])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetVideoSharesUsers
+	stmts.stmtGetVideoSharesUsers, err = db.PrepareContext(ctx, VideoSharesRequests[GetVideoSharesUsers])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteVideoShare
+	stmts.stmtDelete, err = db.PrepareContext(ctx, VideoSharesRequests[DeleteVideoShare])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+// createTableVideoShares is also called when creating the videos table, whose list statements join the shares
+func createTableVideoShares(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, VideoSharesRequests[CreateTableVideoSharesReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table video_shares created (or existed already)")
+	return nil
+}
+
+// CreateVideoSharesDAO must be called after the creation of the videos and users tables
+func CreateVideoSharesDAO(ctx context.Context, db *sql.DB) (*VideoSharesDAO, error) {
+	videoShareDAO, err := prepareVideoShareStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare video shares statements : ", err)
+		return nil, err
+	}
+
+	videoShareDAO.DB = db
+
+	return videoShareDAO, nil
+}
+
+// CreateVideoShare lets the user see the video when its visibility is shared, sharing twice does nothing
+func (v VideoSharesDAO) CreateVideoShare(ctx context.Context, videoID, userID string) error {
+	if _, err := v.stmtCreate.ExecContext(ctx, videoID, userID); err != nil {
+		log.Error("Error while insert into video_shares : ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (v VideoSharesDAO) IsVideoSharedWith(ctx context.Context, videoID, userID string) (bool, error) {
+	var count int
+	if err := v.stmtGetVideoShare.QueryRowContext(ctx, videoID, userID).Scan(&count); err != nil {
+		log.Error("Cannot read rows : ", err)
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetVideoSharesUsers returns the users the video is shared with, sorted by username
+func (v VideoSharesDAO) GetVideoSharesUsers(ctx context.Context, videoID string) ([]models.User, error) {
+	rows, err := v.stmtGetVideoSharesUsers.QueryContext(ctx, videoID)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
+
+// DeleteVideoShare returns sql.ErrNoRows when the video was not shared with the user
+func (v VideoSharesDAO) DeleteVideoShare(ctx context.Context, videoID, userID string) error {
+	res, err := v.stmtDelete.ExecContext(ctx, videoID, userID)
+	if err != nil {
+		log.Error("Error while delete from video_shares : ", err)
+		return err
+	}
+
+	nbRowAff, err := res.RowsAffected()
+	if err != nil {
+		log.Error("Error, can't know how many rows affected : ", err)
+		return err
+	}
+	if nbRowAff == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (v VideoSharesDAO) Close() {
+	_ = v.stmtCreate.Close()
+	_ = v.stmtGetVideoShare.Close()
+	_ = v.stmtGetVideoSharesUsers.Close()
+	_ = v.stmtDelete.Close()
+}
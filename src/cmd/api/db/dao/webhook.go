@@ -0,0 +1,211 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type WebhooksRequestName int
+
+const (
+	CreateTableWebhooksReq WebhooksRequestName = iota
+	CreateWebhook
+	GetWebhook
+	GetWebhooks
+	DeleteWebhook
+)
+
+var WebhooksRequests = map[WebhooksRequestName]string{
+	CreateTableWebhooksReq: `CREATE TABLE IF NOT EXISTS webhooks (
+			id              VARCHAR(36) NOT NULL,
+			url             VARCHAR(2048) NOT NULL,
+			secret          VARCHAR(128) NOT NULL,
+			events          VARCHAR(255) NOT NULL,
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id)
+		);`,
+
+	CreateWebhook: "INSERT INTO webhooks (id, url, secret, events) VALUES (?, ?, ?, ?)",
+	GetWebhook:    "SELECT * FROM webhooks WHERE id = ?",
+	GetWebhooks:   "SELECT * FROM webhooks ORDER BY created_at DESC",
+	DeleteWebhook: "DELETE FROM webhooks WHERE id = ?",
+}
+
+// Separator of the events in their column
+const webhookEventsSeparator = ","
+
+type WebhooksDAO struct {
+	DB             *sql.DB
+	stmtCreate     *sql.Stmt
+	stmtGetWebhook *sql.Stmt
+	stmtGetAll     *sql.Stmt
+	stmtDelete     *sql.Stmt
+}
+
+func prepareWebhookStmts(ctx context.Context, db *sql.DB) (*WebhooksDAO, error) {
+	stmts := WebhooksDAO{}
+
+	// CreateWebhook
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, WebhooksRequests[CreateWebhook])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetWebhook
+	stmts.stmtGetWebhook, err = db.PrepareContext(ctx, WebhooksRequests[GetWebhook])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetWebhooks
+	stmts.stmtGetAll, err = db.PrepareContext(ctx, WebhooksRequests[GetWebhooks])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// DeleteWebhook
+	stmts.stmtDelete, err = db.PrepareContext(ctx, WebhooksRequests[DeleteWebhook])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableWebhooks(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, WebhooksRequests[CreateTableWebhooksReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table webhooks created (or existed already)")
+	return nil
+}
+
+func CreateWebhooksDAO(ctx context.Context, db *sql.DB) (*WebhooksDAO, error) {
+	if err := createTableWebhooks(ctx, db); err != nil {
+		log.Error("Cannot create table webhooks : ", err)
+		return nil, err
+	}
+
+	webhookDAO, err := prepareWebhookStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare webhooks statements : ", err)
+		return nil, err
+	}
+
+	webhookDAO.DB = db
+
+	return webhookDAO, nil
+}
+
+func (w WebhooksDAO) CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	res, err := w.stmtCreate.ExecContext(ctx, webhook.ID, webhook.URL, webhook.Secret, joinWebhookEvents(webhook.Events))
+	if err != nil {
+		log.Error("Error while insert into webhooks : ", err)
+		return nil, err
+	}
+
+	if err := checkOneRowAffected(res, "creating webhook id : "+webhook.ID); err != nil {
+		return nil, err
+	}
+
+	return w.GetWebhook(ctx, webhook.ID)
+}
+
+func (w WebhooksDAO) GetWebhook(ctx context.Context, ID string) (*models.Webhook, error) {
+	webhook, err := scanWebhook(w.stmtGetWebhook.QueryRowContext(ctx, ID))
+	if err != nil {
+		log.Error("Error, webhook not found : ", err)
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks returns every webhook, the most recent first
+func (w WebhooksDAO) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := w.stmtGetAll.QueryContext(ctx)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook also deletes its deliveries
+func (w WebhooksDAO) DeleteWebhook(ctx context.Context, ID string) error {
+	res, err := w.stmtDelete.ExecContext(ctx, ID)
+	if err != nil {
+		log.Error("Error while delete from webhooks : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "deleting webhook id : "+ID)
+}
+
+func (w WebhooksDAO) Close() {
+	_ = w.stmtCreate.Close()
+	_ = w.stmtGetWebhook.Close()
+	_ = w.stmtGetAll.Close()
+	_ = w.stmtDelete.Close()
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	if err := row.Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Secret,
+		&events,
+		&webhook.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	for _, event := range strings.Split(events, webhookEventsSeparator) {
+		if event != "" {
+			webhook.Events = append(webhook.Events, models.WebhookEvent(event))
+		}
+	}
+
+	return &webhook, nil
+}
+
+func joinWebhookEvents(events []models.WebhookEvent) string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return strings.Join(names, webhookEventsSeparator)
+}
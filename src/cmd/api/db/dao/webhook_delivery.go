@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+type WebhookDeliveriesRequestName int
+
+const (
+	CreateTableWebhookDeliveriesReq WebhookDeliveriesRequestName = iota
+	CreateWebhookDelivery
+	GetWebhookDeliveries
+)
+
+var WebhookDeliveriesRequests = map[WebhookDeliveriesRequestName]string{
+	CreateTableWebhookDeliveriesReq: `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id              VARCHAR(36) NOT NULL,
+			webhook_id      VARCHAR(36) NOT NULL,
+			delivery_id     VARCHAR(36) NOT NULL,
+			event           VARCHAR(32) NOT NULL,
+			video_id        VARCHAR(36) NOT NULL,
+			attempt         INT NOT NULL,
+			status_code     INT,
+			error           VARCHAR(512),
+			created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+			CONSTRAINT pk PRIMARY KEY (id),
+			CONSTRAINT fk_wd_w_id FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+		);`,
+
+	CreateWebhookDelivery: "INSERT INTO webhook_deliveries (id, webhook_id, delivery_id, event, video_id, attempt, status_code, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+	GetWebhookDeliveries:  "SELECT * FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC, attempt DESC LIMIT ?",
+}
+
+// Length of the error column, the longer errors are truncated
+const webhookDeliveryErrorLength = 512
+
+type WebhookDeliveriesDAO struct {
+	DB                *sql.DB
+	stmtCreate        *sql.Stmt
+	stmtGetDeliveries *sql.Stmt
+}
+
+func prepareWebhookDeliveryStmts(ctx context.Context, db *sql.DB) (*WebhookDeliveriesDAO, error) {
+	stmts := WebhookDeliveriesDAO{}
+
+	// CreateWebhookDelivery
+	var err error
+	stmts.stmtCreate, err = db.PrepareContext(ctx, WebhookDeliveriesRequests[CreateWebhookDelivery])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	// GetWebhookDeliveries
+	stmts.stmtGetDeliveries, err = db.PrepareContext(ctx, WebhookDeliveriesRequests[GetWebhookDeliveries])
+	if err != nil {
+		log.Error("Cannot prepare statement : ", err)
+		return nil, err
+	}
+
+	return &stmts, nil
+}
+
+func createTableWebhookDeliveries(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, WebhookDeliveriesRequests[CreateTableWebhookDeliveriesReq]); err != nil {
+		log.Error("Cannot create table : ", err)
+		return err
+	}
+
+	log.Debug("Table webhook_deliveries created (or existed already)")
+	return nil
+}
+
+// CreateWebhookDeliveriesDAO must be called after the creation of the webhooks table
+func CreateWebhookDeliveriesDAO(ctx context.Context, db *sql.DB) (*WebhookDeliveriesDAO, error) {
+	if err := createTableWebhookDeliveries(ctx, db); err != nil {
+		log.Error("Cannot create table webhook_deliveries : ", err)
+		return nil, err
+	}
+
+	webhookDeliveryDAO, err := prepareWebhookDeliveryStmts(ctx, db)
+	if err != nil {
+		log.Error("Cannot prepare webhook deliveries statements : ", err)
+		return nil, err
+	}
+
+	webhookDeliveryDAO.DB = db
+
+	return webhookDeliveryDAO, nil
+}
+
+func (w WebhookDeliveriesDAO) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	deliveryError := delivery.Error
+	if deliveryError != nil && len(*deliveryError) > webhookDeliveryErrorLength {
+		truncated := (*deliveryError)[:webhookDeliveryErrorLength]
+		deliveryError = &truncated
+	}
+
+	res, err := w.stmtCreate.ExecContext(ctx, delivery.ID, delivery.WebhookID, delivery.DeliveryID, delivery.Event, delivery.VideoID, delivery.Attempt, delivery.StatusCode, deliveryError)
+	if err != nil {
+		log.Error("Error while insert into webhook_deliveries : ", err)
+		return err
+	}
+
+	return checkOneRowAffected(res, "creating webhook delivery id : "+delivery.ID)
+}
+
+// GetWebhookDeliveries returns the last attempts to notify the webhook, the most recent first
+func (w WebhookDeliveriesDAO) GetWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := w.stmtGetDeliveries.QueryContext(ctx, webhookID, limit)
+	if err != nil {
+		log.Error("Error, cannot query database : ", err)
+		return nil, err
+	}
+
+	defer func() {
+		if err = rows.Close(); err != nil {
+			log.Error("Error while closing database Rows", err)
+		}
+	}()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.DeliveryID,
+			&delivery.Event,
+			&delivery.VideoID,
+			&delivery.Attempt,
+			&delivery.StatusCode,
+			&delivery.Error,
+			&delivery.CreatedAt,
+		); err != nil {
+			log.Error("Cannot read rows : ", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (w WebhookDeliveriesDAO) Close() {
+	_ = w.stmtCreate.Close()
+	_ = w.stmtGetDeliveries.Close()
+}
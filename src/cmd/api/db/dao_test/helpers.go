@@ -9,8 +9,16 @@ import (
 
 func ExpectVideosDAOCreation(mock sqlmock.Sqlmock) {
 	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.CreateTableVideosReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.AddColumnVideosLoudnessReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.AddColumnVideosOwnerReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideosRequests[dao.AddColumnVideosVisibilityReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.VideoSharesRequests[dao.CreateTableVideoSharesReq])).WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.CreateVideo]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideo]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoTitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoCover]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoLoudness]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.UpdateVideoVisibility]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideo]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideoFromTitle]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideosRequests[dao.GetVideosTitleAsc]))
@@ -29,3 +37,86 @@ func ExpectUploadsDAOCreation(mock sqlmock.Sqlmock) {
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.GetUploads]))
 	mock.ExpectPrepare(regexp.QuoteMeta(dao.UploadsRequests[dao.DeleteUpload]))
 }
+
+func ExpectStorageUsagesDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.StorageUsagesRequests[dao.CreateTableStorageUsagesReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.StorageUsagesRequests[dao.CreateStorageUsage]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.StorageUsagesRequests[dao.GetUserStorageUsage]))
+}
+
+func ExpectSubtitlesDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.SubtitlesRequests[dao.CreateTableSubtitlesReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.CreateSubtitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.UpdateSubtitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetSubtitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.GetVideoSubtitles]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.DeleteSubtitle]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SubtitlesRequests[dao.ClearDefaultSubtitle]))
+}
+
+func ExpectClipsDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.ClipsRequests[dao.CreateTableClipsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ClipsRequests[dao.CreateClip]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ClipsRequests[dao.GetVideoClips]))
+}
+
+func ExpectRendersDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.RendersRequests[dao.CreateTableRendersReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.RendersRequests[dao.CreateRender]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.RendersRequests[dao.GetVideoRenders]))
+}
+
+func ExpectUsersDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.CreateTableUsersReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(dao.UsersRequests[dao.AddColumnUsersOIDCSubjectReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.CreateUser]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.CreateOIDCUser]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.GetUser]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromUsername]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.GetUserFromOIDCSubject]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.GetUsers]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.UpdateUserRole]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.UpdateUserPassword]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.UsersRequests[dao.DeleteUser]))
+}
+
+func ExpectSessionsDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.SessionsRequests[dao.CreateTableSessionsReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SessionsRequests[dao.CreateSession]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SessionsRequests[dao.GetSessionUser]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SessionsRequests[dao.DeleteSession]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SessionsRequests[dao.DeleteUserSessions]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.SessionsRequests[dao.DeleteExpiredSessions]))
+}
+
+func ExpectVideoSharesDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideoSharesRequests[dao.CreateVideoShare]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideoSharesRequests[dao.GetVideoShare]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideoSharesRequests[dao.GetVideoSharesUsers]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.VideoSharesRequests[dao.DeleteVideoShare]))
+}
+
+func ExpectApiKeysDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateTableApiKeysReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.CreateApiKey]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKey]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeyFromHash]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetApiKeys]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.GetUserApiKeys]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.UpdateApiKeyLastUsed]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.ApiKeysRequests[dao.DeleteApiKey]))
+}
+
+func ExpectWebhooksDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.WebhooksRequests[dao.CreateTableWebhooksReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WebhooksRequests[dao.CreateWebhook]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WebhooksRequests[dao.GetWebhook]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WebhooksRequests[dao.GetWebhooks]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WebhooksRequests[dao.DeleteWebhook]))
+}
+
+func ExpectWebhookDeliveriesDAOCreation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(dao.WebhookDeliveriesRequests[dao.CreateTableWebhookDeliveriesReq])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WebhookDeliveriesRequests[dao.CreateWebhookDelivery]))
+	mock.ExpectPrepare(regexp.QuoteMeta(dao.WebhookDeliveriesRequests[dao.GetWebhookDeliveries]))
+}
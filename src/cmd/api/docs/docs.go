@@ -16,62 +16,25 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/videos/list/{attribute}/{order}/{page}/{limit}/{status}": {
+        "/api/v1/apikeys": {
             "get": {
-                "description": "Get list of all videos",
+                "description": "List the API keys of the user, or all of them for an admin, the most recent first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "video"
-                ],
-                "summary": "Get list of all videos",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Sort attribute",
-                        "name": "attribute",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Sort order",
-                        "name": "order",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Video per page",
-                        "name": "limit",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Video status ",
-                        "name": "status",
-                        "in": "path",
-                        "required": true
-                    }
+                    "apikeys"
                 ],
+                "summary": "List API keys",
                 "responses": {
                     "200": {
-                        "description": "Video list and Hateoas links",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.VideoListResponse"
+                            "$ref": "#/definitions/controllers.ApiKeysListResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -83,55 +46,35 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/transformer": {
-            "get": {
-                "description": "Get list of existing services",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "services"
-                ],
-                "summary": "Get list of existing services",
-                "responses": {
-                    "200": {
-                        "description": "Service list",
-                        "schema": {
-                            "$ref": "#/definitions/controllers.TransformerServiceListResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/videos/upload": {
+            },
             "post": {
-                "description": "Upload video file",
+                "description": "Create a key acting as the user, with at most the rights of its scope among read, upload and admin. The scope cannot exceed the role of the user. The key is only returned by this request, send it as \"Authorization: Bearer \u003ckey\u003e\".",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "apikeys"
                 ],
-                "summary": "Upload video file",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "video",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Name, scope and optional expiry",
+                        "name": "apiKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ApiKeyCreateRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Video and Links (HATEOAS)",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.Response"
+                            "$ref": "#/definitions/controllers.ApiKeyCreateResponse"
                         }
                     },
                     "400": {
@@ -140,14 +83,14 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "409": {
-                        "description": "This title already exists",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "415": {
-                        "description": "Unsupported Media Type",
+                    "403": {
+                        "description": "The scope exceeds the role of the user, or the request is authenticated by an API key",
                         "schema": {
                             "type": "string"
                         }
@@ -161,21 +104,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/archive": {
-            "put": {
-                "description": "Archive video",
+        "/api/v1/apikeys/{keyID}": {
+            "delete": {
+                "description": "Delete an API key of the user, or any of them for an admin",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "video"
+                    "apikeys"
                 ],
-                "summary": "Archive video",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
+                        "description": "API key ID",
+                        "name": "keyID",
                         "in": "path",
                         "required": true
                     }
@@ -193,6 +136,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "The request is authenticated by an API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -208,33 +163,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/cover": {
+        "/api/v1/events": {
             "get": {
-                "description": "Get video cover image in base64",
-                "consumes": [
-                    "text/plain"
-                ],
+                "description": "Server-sent events fallback of the websocket, for the clients or proxies without websocket support. Each event has the ID to resume from, its kind (status, upload_progress, encode_progress, deleted, renamed) as name and the same JSON data as the websocket events. A client reconnecting with the Last-Event-ID header first receives the events it missed, among the last ones kept by the API instance.",
                 "produces": [
-                    "text/plain"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "video"
+                    "events"
                 ],
-                "summary": "Get video cover image in base64",
+                "summary": "Stream the events of the videos",
                 "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Video ID, or \\",
+                        "name": "video",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Bearer token or basic auth, browsers use the access_token parameter or the cookie instead",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "access_token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received, to resume the stream",
+                        "name": "Last-Event-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "video cover image in base64",
+                        "description": "Data of the events",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/json.VideoEventJson"
                         }
                     },
                     "400": {
@@ -243,6 +216,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -258,30 +237,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/delete": {
-            "delete": {
-                "description": "Delete video",
+        "/api/v1/login": {
+            "post": {
+                "description": "Check the user credentials and open a session. The token must then be sent as \"Authorization: Bearer \u003ctoken\u003e\".",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "auth"
                 ],
-                "summary": "Delete video",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Username and password",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Session token, its expiration and the user",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controllers.LoginResponse"
                         }
                     },
                     "400": {
@@ -290,8 +274,8 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -305,40 +289,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/info": {
-            "get": {
-                "description": "Get video informations",
+        "/api/v1/logout": {
+            "post": {
+                "description": "Close the session of the bearer token. Nothing is done for the basic auth service account.",
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "video"
-                ],
-                "summary": "Get video informations",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "Log out",
                 "responses": {
-                    "200": {
-                        "description": "Video Informations",
-                        "schema": {
-                            "$ref": "#/definitions/json.VideoInfo"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -352,40 +321,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/status": {
+        "/api/v1/users": {
             "get": {
-                "description": "Get video status",
+                "description": "List the users, sorted by username. Admin only.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "video"
-                ],
-                "summary": "Get video status",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "users"
                 ],
+                "summary": "List users",
                 "responses": {
                     "200": {
-                        "description": "Describe video status",
-                        "schema": {
-                            "$ref": "#/definitions/json.VideoStatus"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controllers.UsersListResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -397,32 +351,35 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/{id}/streams/master.m3u8": {
-            "get": {
-                "description": "Get video master",
+            },
+            "post": {
+                "description": "Create a user with a role among viewer, uploader and admin. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "users"
                 ],
-                "summary": "Get video master",
+                "summary": "Create a user",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Username, password and role",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserCreateRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "HLS video master",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/json.UserJson"
                         }
                     },
                     "400": {
@@ -431,8 +388,14 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This username already exists",
                         "schema": {
                             "type": "string"
                         }
@@ -446,53 +409,119 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/videos/{id}/streams/{quality}/{filename}": {
+        "/api/v1/users/me": {
             "get": {
-                "description": "Get sub part stream video",
+                "description": "Get the user of the session, or the service account when using basic auth",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "video"
+                    "users"
                 ],
-                "summary": "Get sub part stream video",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                "summary": "Get the authenticated user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/json.UserJson"
+                        }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{userID}": {
+            "get": {
+                "description": "Get a user. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a user",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Video quality",
-                        "name": "quality",
+                        "description": "User ID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/json.UserJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Change the role and/or the password of a user. A new password closes all the sessions of the user. Admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update a user",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Video sub part name",
-                        "name": "filename",
+                        "description": "User ID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "description": "List of required filters",
-                        "name": "filter",
-                        "in": "query"
+                        "description": "New role and/or password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UserUpdateRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Video sub part (.ts)",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/json.UserJson"
                         }
                     },
                     "400": {
@@ -501,6 +530,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -514,23 +549,21 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/videos/{id}/unarchive": {
-            "put": {
-                "description": "Unarchive video",
+            },
+            "delete": {
+                "description": "Delete a user and close its sessions. Admins cannot delete themselves. Admin only.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "video"
+                    "users"
                 ],
-                "summary": "Unarchive video",
+                "summary": "Delete a user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Video ID",
-                        "name": "id",
+                        "description": "User ID",
+                        "name": "userID",
                         "in": "path",
                         "required": true
                     }
@@ -548,12 +581,24 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Cannot delete yourself",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -563,19 +608,68 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/api/v1/videos/list/{attribute}/{order}/{page}/{limit}/{status}": {
             "get": {
-                "description": "Get component health",
+                "description": "Get list of all videos",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "video"
+                ],
+                "summary": "Get list of all videos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sort attribute",
+                        "name": "attribute",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video per page",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video status ",
+                        "name": "status",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get component health",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Video list and Hateoas links",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VideoListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "string"
                         }
@@ -583,33 +677,77 @@ const docTemplate = `{
                 }
             }
         },
-        "/ws": {
+        "/api/v1/videos/transformer/list": {
             "get": {
-                "description": "Send Update to Front",
+                "description": "Get list of existing services",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "services"
+                ],
+                "summary": "Get list of existing services",
+                "responses": {
+                    "200": {
+                        "description": "Service list",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.TransformerServiceListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/upload": {
+            "post": {
+                "description": "Upload video file",
                 "consumes": [
-                    "text/plain"
+                    "multipart/form-data"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "websocket"
+                    "video"
                 ],
-                "summary": "Send Update to Front",
+                "summary": "Upload video file",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Authentication cookie",
-                        "name": "Cookie",
-                        "in": "header",
+                        "type": "file",
+                        "description": "video",
+                        "name": "file",
+                        "in": "formData",
                         "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "SRT or WebVTT subtitles",
+                        "name": "subs",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitles language tag (BCP 47), und by default",
+                        "name": "subsLanguage",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitles name displayed by the players",
+                        "name": "subsLabel",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "private (default), unlisted, shared or public",
+                        "name": "visibility",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "101": {
-                        "description": "Switching Protocols",
+                    "200": {
+                        "description": "Video and Links (HATEOAS)",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/controllers.Response"
                         }
                     },
                     "400": {
@@ -618,12 +756,30 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "409": {
+                        "description": "This title already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload too large or storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UploadLimitError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "422": {
+                        "description": "Video duration or resolution exceeds the limits",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UploadLimitError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -632,31 +788,2568 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.Response": {
-            "type": "object",
-            "properties": {
-                "_links": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/json.LinkJson"
-                    }
-                },
-                "video": {
-                    "$ref": "#/definitions/json.VideoJson"
-                }
-            }
         },
-        "controllers.TransformerServiceListResponse": {
-            "type": "object",
-            "properties": {
-                "services": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/json.TransformerServiceJson"
-                    }
+        "/api/v1/videos/upload/batch": {
+            "post": {
+                "description": "Upload several video files in a single request. The n-th \"video\" part is paired with the n-th \"title\" part.\nCover and subtitles are optional and given for the n-th video as \"cover[n]\" and \"subs[n]\" (zero based),\nthe subtitles being described by \"subsLanguage[n]\" and \"subsLabel[n]\".\nEach video is processed independently : a failing item does not roll back the others.\nThe upload size limit applies to the whole request, the other limits are checked for each video.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Upload several video files",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "videos",
+                        "name": "video",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "titles, in the same order as the videos",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "private (default), unlisted, shared or public, for all the videos",
+                        "name": "visibility",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One result per video, in the same order as the videos",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.BatchUploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload too large",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UploadLimitError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/archive": {
+            "put": {
+                "description": "Archive video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Archive video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/clips": {
+            "get": {
+                "description": "List the clips cut from a video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "clips"
+                ],
+                "summary": "List video clips",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Clips and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ClipsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new video from the [start, end] part of the video source, then encode it.\nTimestamps are seconds or [HH:]MM:SS[.mmm]. The cut is keyframe-accurate, unless accurate is set: the clip is then re-encoded to start on the exact frame.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "clips"
+                ],
+                "summary": "Cut a clip from a video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Parent video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Clip title and boundaries",
+                        "name": "clip",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ClipRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Clip video and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ClipResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This title already exists, or the parent source is not uploaded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/cover": {
+            "get": {
+                "description": "Get video cover image. Without size, the original cover is returned.\nThe cover is the uploaded one, or a frame of the video generated by the encoder.",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video cover image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "small, medium or large",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "jpeg or webp, negotiated with the Accept header by default",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "video cover image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/delete": {
+            "delete": {
+                "description": "Delete video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Delete video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/edit": {
+            "post": {
+                "description": "Edit the title and the cover of a video, and add subtitles to it",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Edit video data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video title",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Cover image",
+                        "name": "cover",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "SRT or WebVTT subtitles",
+                        "name": "subs",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitles language tag (BCP 47), und by default",
+                        "name": "subsLanguage",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitles name displayed by the players",
+                        "name": "subsLabel",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid subtitles",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/info": {
+            "get": {
+                "description": "Get video informations",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video informations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video Informations",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/playback": {
+            "get": {
+                "description": "Mint a signed and time-limited token giving access to the public playback routes of the video, and the links carrying it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video playback links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path on S3 of the image stamped on the segments, under watermarks/, bound to the token",
+                        "name": "watermarkImage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text stamped on the segments, bound to the token",
+                        "name": "watermarkText",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "top-left, top-right, bottom-left, bottom-right (default) or center",
+                        "name": "watermarkPosition",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Opacity of the watermark, from 0 to 1 (0.5 by default)",
+                        "name": "watermarkOpacity",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token and playback links",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.PlaybackResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or watermark",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Archived video",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Video not encoded yet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/preview": {
+            "get": {
+                "description": "Get the short muted MP4 clip sampled across the video, shown when hovering it in the catalogue",
+                "produces": [
+                    "video/mp4"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video preview clip",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preview clip",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Requested range of the preview clip",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/render": {
+            "post": {
+                "description": "Create a new video from the video source with the transformer filters applied, then encode it.\nThe filters are the ones of the streams filter parameter, applied in order. The new video plays without transformation at runtime.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "renders"
+                ],
+                "summary": "Burn transformations into a new video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Parent video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Render title and filters",
+                        "name": "render",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.RenderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rendered video and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.RenderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "This title already exists, or the parent source is not uploaded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/renders": {
+            "get": {
+                "description": "List the videos rendered from a video, with their filters",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "renders"
+                ],
+                "summary": "List video renders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Renders and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.RendersListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/shares": {
+            "get": {
+                "description": "The shares are only used when the visibility of the video is shared. Owner or admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "shares"
+                ],
+                "summary": "List the users a video is shared with",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.UsersListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/shares/{userID}": {
+            "put": {
+                "description": "The user can see the video while its visibility is shared. Owner or admin only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "shares"
+                ],
+                "summary": "Share a video with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/json.UserJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Owner or admin only.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video",
+                    "shares"
+                ],
+                "summary": "Stop sharing a video with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "The video is not shared with this user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/status": {
+            "get": {
+                "description": "Get video status",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Describe video status",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/manifest.mpd": {
+            "get": {
+                "description": "Get the MPEG-DASH manifest of the video, referencing the fMP4 segments of the HLS renditions.\nIts URLs carry the filters and the stream token. Supports HEAD and conditional requests",
+                "produces": [
+                    "application/dash+xml"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video DASH manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "List of required filters: gray, flip or watermark",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "DASH video manifest",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/master.m3u8": {
+            "get": {
+                "description": "Get video master. Its URIs carry the filters and the stream token, so that the whole stream is transformed.\nSupports HEAD and conditional requests (If-None-Match, If-Modified-Since)",
+                "produces": [
+                    "application/vnd.apple.mpegurl"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video master",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "List of required filters: gray, flip or watermark",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Highest rendition of the master",
+                        "name": "maxHeight",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "Video codecs supported by the client (h264, hevc, av1 or RFC 6381 sample entries)",
+                        "name": "codecs",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HLS video master",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/source.mp4": {
+            "get": {
+                "description": "Get video master",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get video master",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "MP4 video source",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/streams/{quality}/{filename}": {
+            "get": {
+                "description": "Get a variant playlist or a segment of the video. The URIs of the playlists carry the filters and the stream token.\nSegments are immutable and cached for a year. Supports HEAD and conditional requests",
+                "produces": [
+                    "application/vnd.apple.mpegurl",
+                    "video/iso.segment",
+                    "video/mp2t"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get sub part stream video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video quality",
+                        "name": "quality",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Video sub part name",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "description": "List of required filters: gray, flip or watermark",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path on S3 of the image stamped by the watermark filter, under watermarks/",
+                        "name": "watermarkImage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text stamped by the watermark filter",
+                        "name": "watermarkText",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "top-left, top-right, bottom-left, bottom-right (default) or center",
+                        "name": "watermarkPosition",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Opacity of the watermark, from 0 to 1 (0.5 by default)",
+                        "name": "watermarkOpacity",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video sub part",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles": {
+            "get": {
+                "description": "List the subtitles tracks of a video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "List video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subtitles and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SubtitlesListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a subtitles track to a video. SRT files are converted to WebVTT.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Add subtitles to a video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "SRT or WebVTT file",
+                        "name": "subs",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language tag (BCP 47), e.g. en or fr-CA",
+                        "name": "language",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name displayed by the players, the language by default",
+                        "name": "label",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Track selected by default",
+                        "name": "default",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Track only containing forced subtitles",
+                        "name": "forced",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subtitle and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SubtitleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid subtitles",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles/{filename}": {
+            "get": {
+                "description": "Get subtitles for video. Supports HEAD and conditional requests",
+                "produces": [
+                    "text/vtt"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Get subtitles for video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitles file nams",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Video subtitles",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles/{subtitleID}": {
+            "get": {
+                "description": "Get a subtitles track of a video",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Get video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitleID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subtitle and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SubtitleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a subtitles track of a video. Fields which are not given are left unchanged.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Update video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitleID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "SRT or WebVTT file",
+                        "name": "subs",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language tag (BCP 47), e.g. en or fr-CA",
+                        "name": "language",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name displayed by the players",
+                        "name": "label",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Track selected by default",
+                        "name": "default",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Track only containing forced subtitles",
+                        "name": "forced",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subtitle and Links (HATEOAS)",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SubtitleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid subtitles",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a subtitles track of a video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Delete video subtitles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitleID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles/{subtitleID}/playlist.m3u8": {
+            "get": {
+                "description": "HLS media playlist of a subtitles track, referenced by the video master",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Get subtitles playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitleID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HLS subtitles playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/subtitles/{subtitleID}/track.vtt": {
+            "get": {
+                "description": "WebVTT file of a subtitles track",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video",
+                    "subtitles"
+                ],
+                "summary": "Get subtitles file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitleID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebVTT subtitles",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/thumbnails/{filename}": {
+            "get": {
+                "description": "Get the WebVTT file mapping the timeline to thumbnails (thumbnails.vtt), or a sprite sheet it refers to (sprite_N.jpg)",
+                "produces": [
+                    "text/vtt",
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Get seek-preview thumbnails",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "thumbnails.vtt or sprite_N.jpg",
+                        "name": "filename",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "WebVTT thumbnails track or sprite sheet",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/unarchive": {
+            "put": {
+                "description": "Unarchive video",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "video"
+                ],
+                "summary": "Unarchive video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/videos/{id}/visibility": {
+            "put": {
+                "description": "Visibility is private (owner only), unlisted (anyone with the ID), shared (users chosen by the owner) or public. Owner or admin only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "video",
+                    "shares"
+                ],
+                "summary": "Change the visibility of a video",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Video ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New visibility",
+                        "name": "visibility",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.VisibilityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/json.VideoJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "description": "List the webhooks, the most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhooksListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Notify an URL of the events of the videos among video.complete and video.fail_encode. The notifications are POST requests of a JSON body, signed in the X-Voogle-Signature header by \"sha256=\" followed by the hex encoded HMAC-SHA256 of the body with the secret.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "description": "URL, secret and events",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhookCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/json.WebhookJson"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{webhookID}": {
+            "delete": {
+                "description": "Delete a webhook and its delivery log",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{webhookID}/deliveries": {
+            "get": {
+                "description": "List the last attempts to notify the webhook, the most recent first. The attempts of a notification share their deliveryId, sent in the X-Voogle-Delivery header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get the delivery log of a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of attempts, 50 by default, at most 200",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Get component health",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get component health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ws": {
+            "get": {
+                "description": "Websocket of the JSON protocol v1. The server first sends a welcome message with the protocol version. The client subscribes to the events of a video, or of every video it can list with the video ID \"all\", and unsubscribes the same way. Each request is answered by an ack or an error message, echoing the optional request ID. The events (status, upload_progress, encode_progress, deleted, renamed) of the subscribed videos the user can see are then sent as event messages.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "websocket"
+                ],
+                "summary": "Receive the events of the videos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token or basic auth, browsers use the access_token parameter or the cookie instead",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "access_token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization cookie of the webapp",
+                        "name": "Cookie",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Messages sent by the client once connected",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WSRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Messages sent by the server once connected",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.WSMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Origin not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.ApiKeyCreateRequest": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2023-04-15T12:59:52Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci-uploader"
+                },
+                "scope": {
+                    "type": "string",
+                    "example": "upload"
+                }
+            }
+        },
+        "controllers.ApiKeyCreateResponse": {
+            "type": "object",
+            "properties": {
+                "apiKey": {
+                    "$ref": "#/definitions/json.ApiKeyJson"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "vgl_Xk3v9aQe..."
+                }
+            }
+        },
+        "controllers.ApiKeysListResponse": {
+            "type": "object",
+            "properties": {
+                "apiKeys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.ApiKeyJson"
+                    }
+                }
+            }
+        },
+        "controllers.BatchUploadItemResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "error": {
+                    "$ref": "#/definitions/controllers.UploadLimitError"
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "result": {
+                    "type": "string",
+                    "example": "created"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "A Title"
+                },
+                "video": {
+                    "$ref": "#/definitions/json.VideoJson"
+                }
+            }
+        },
+        "controllers.BatchUploadResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.BatchUploadItemResponse"
+                    }
+                }
+            }
+        },
+        "controllers.ClipRequest": {
+            "type": "object",
+            "properties": {
+                "accurate": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "end": {
+                    "type": "string",
+                    "example": "90"
+                },
+                "start": {
+                    "type": "string",
+                    "example": "00:01:12.5"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Best goal"
+                }
+            }
+        },
+        "controllers.ClipResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "clip": {
+                    "$ref": "#/definitions/json.ClipJson"
+                },
+                "video": {
+                    "$ref": "#/definitions/json.VideoJson"
+                }
+            }
+        },
+        "controllers.ClipsListResponse": {
+            "type": "object",
+            "properties": {
+                "clips": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.ClipResponse"
+                    }
+                }
+            }
+        },
+        "controllers.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "correct horse battery staple"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "controllers.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2022-04-16T12:59:52Z"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "q1Xv..."
+                },
+                "user": {
+                    "$ref": "#/definitions/json.UserJson"
+                }
+            }
+        },
+        "controllers.PlaybackResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2022-05-10T12:00:57Z"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "19052.1652180457.0.mJ4wAa..."
+                }
+            }
+        },
+        "controllers.RenderRequest": {
+            "type": "object",
+            "properties": {
+                "filters": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gray",
+                        "flip"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Gray version"
+                }
+            }
+        },
+        "controllers.RenderResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "render": {
+                    "$ref": "#/definitions/json.RenderJson"
+                },
+                "video": {
+                    "$ref": "#/definitions/json.VideoJson"
+                }
+            }
+        },
+        "controllers.RendersListResponse": {
+            "type": "object",
+            "properties": {
+                "renders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.RenderResponse"
+                    }
+                }
+            }
+        },
+        "controllers.Response": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "video": {
+                    "$ref": "#/definitions/json.VideoJson"
+                }
+            }
+        },
+        "controllers.SubtitleResponse": {
+            "type": "object",
+            "properties": {
+                "_links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/json.LinkJson"
+                    }
+                },
+                "subtitle": {
+                    "$ref": "#/definitions/json.SubtitleJson"
+                }
+            }
+        },
+        "controllers.SubtitlesListResponse": {
+            "type": "object",
+            "properties": {
+                "subtitles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/controllers.SubtitleResponse"
+                    }
+                }
+            }
+        },
+        "controllers.TransformerServiceListResponse": {
+            "type": "object",
+            "properties": {
+                "services": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.TransformerServiceJson"
+                    }
+                }
+            }
+        },
+        "controllers.UploadLimitError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "file_too_large"
+                },
+                "limit": {
+                    "type": "string",
+                    "example": "1073741824"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Upload exceeds the maximum size of 1073741824 bytes"
+                }
+            }
+        },
+        "controllers.UserCreateRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "correct horse battery staple"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "uploader"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "controllers.UserUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "correct horse battery staple"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "controllers.UsersListResponse": {
+            "type": "object",
+            "properties": {
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.UserJson"
+                    }
                 }
             }
         },
@@ -670,6 +3363,9 @@ const docTemplate = `{
                     "type": "string",
                     "example": "1"
                 },
+                "previewLink": {
+                    "$ref": "#/definitions/json.LinkJson"
+                },
                 "title": {
                     "type": "string",
                     "example": "my title"
@@ -696,6 +3392,191 @@ const docTemplate = `{
                 }
             }
         },
+        "controllers.VisibilityRequest": {
+            "type": "object",
+            "properties": {
+                "visibility": {
+                    "type": "string",
+                    "example": "shared"
+                }
+            }
+        },
+        "controllers.WSMessage": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Type of the request replied to",
+                    "type": "string",
+                    "example": "subscribe"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "video not found"
+                },
+                "event": {
+                    "$ref": "#/definitions/json.VideoEventJson"
+                },
+                "id": {
+                    "description": "ID of the request replied to",
+                    "type": "string",
+                    "example": "1"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "welcome",
+                        "ack",
+                        "error",
+                        "event"
+                    ],
+                    "example": "event"
+                },
+                "version": {
+                    "description": "Protocol version, only in the welcome message",
+                    "type": "string",
+                    "example": "v1"
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "all"
+                }
+            }
+        },
+        "controllers.WSRequest": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "Optional, echoed in the reply",
+                    "type": "string",
+                    "example": "1"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "subscribe",
+                        "unsubscribe"
+                    ],
+                    "example": "subscribe"
+                },
+                "videoId": {
+                    "description": "ID of a video, or \"all\" for every video the user can list",
+                    "type": "string",
+                    "example": "all"
+                }
+            }
+        },
+        "controllers.WebhookCreateRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "video.complete",
+                        "video.fail_encode"
+                    ]
+                },
+                "secret": {
+                    "description": "Key of the HMAC-SHA256 signature of the notifications, never returned",
+                    "type": "string",
+                    "example": "2f6c1c0b8e0e4a7d9a3f"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://cms.example.org/hooks/voogle"
+                }
+            }
+        },
+        "controllers.WebhookDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.WebhookDeliveryJson"
+                    }
+                }
+            }
+        },
+        "controllers.WebhooksListResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/json.WebhookJson"
+                    }
+                }
+            }
+        },
+        "json.ApiKeyJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2023-04-15T12:59:52Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "lastUsedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci-uploader"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "vgl_Xk3v9aQe"
+                },
+                "scope": {
+                    "type": "string",
+                    "example": "upload"
+                },
+                "userId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                }
+            }
+        },
+        "json.ClipJson": {
+            "type": "object",
+            "properties": {
+                "accurate": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "end": {
+                    "type": "number",
+                    "example": 42
+                },
+                "parentId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "start": {
+                    "type": "number",
+                    "example": 12.5
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                }
+            }
+        },
         "json.LinkJson": {
             "type": "object",
             "properties": {
@@ -707,6 +3588,66 @@ const docTemplate = `{
                 }
             }
         },
+        "json.RenderJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "filters": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gray",
+                        "flip"
+                    ]
+                },
+                "parentId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                }
+            }
+        },
+        "json.SubtitleJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "default": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "forced": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "label": {
+                    "type": "string",
+                    "example": "English"
+                },
+                "language": {
+                    "type": "string",
+                    "example": "en"
+                },
+                "updatedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                }
+            }
+        },
         "json.TransformerServiceJson": {
             "type": "object",
             "properties": {
@@ -716,9 +3657,71 @@ const docTemplate = `{
                 }
             }
         },
+        "json.UserJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "role": {
+                    "type": "string",
+                    "example": "uploader"
+                },
+                "updatedAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "json.VideoEventJson": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "status",
+                        "upload_progress",
+                        "encode_progress",
+                        "deleted",
+                        "renamed"
+                    ],
+                    "example": "status"
+                },
+                "progress": {
+                    "description": "Share of the upload or of the encoding done, from 0 to 1, only for the progress events",
+                    "type": "number",
+                    "example": 0.42
+                },
+                "status": {
+                    "type": "string",
+                    "example": "Encoding"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "A Title"
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                }
+            }
+        },
         "json.VideoInfo": {
             "type": "object",
             "properties": {
+                "loudness": {
+                    "type": "number",
+                    "example": -23.4
+                },
                 "title": {
                     "type": "string",
                     "example": "amazingtitle"
@@ -740,6 +3743,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "aaaa-b56b-..."
                 },
+                "ownerId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
                 "status": {
                     "type": "string",
                     "example": "VIDEO_STATUS_ENCODING"
@@ -755,6 +3762,10 @@ const docTemplate = `{
                 "uploadedAt": {
                     "type": "string",
                     "example": "2022-04-15T12:59:52Z"
+                },
+                "visibility": {
+                    "type": "string",
+                    "example": "private"
                 }
             }
         },
@@ -770,6 +3781,70 @@ const docTemplate = `{
                     "example": "AmazingTitle"
                 }
             }
+        },
+        "json.WebhookDeliveryJson": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "deliveryId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "error": {
+                    "type": "string",
+                    "example": "unexpected status 503"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "video.complete"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "statusCode": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "videoId": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                }
+            }
+        },
+        "json.WebhookJson": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-04-15T12:59:52Z"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "video.complete",
+                        "video.fail_encode"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aaaa-b56b-..."
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://cms.example.org/hooks/voogle"
+                }
+            }
         }
     }
 }`
@@ -14,6 +14,8 @@ type VideoJson struct {
 	UploadedAt *time.Time `json:"uploadedAt" example:"2022-04-15T12:59:52Z"`
 	CreatedAt  *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
 	UpdatedAt  *time.Time `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+	OwnerID    *string    `json:"ownerId,omitempty" example:"aaaa-b56b-..."`
+	Visibility string     `json:"visibility" example:"private"`
 }
 
 func VideoToVideoJson(video *models.Video) VideoJson {
@@ -24,6 +26,8 @@ func VideoToVideoJson(video *models.Video) VideoJson {
 		CreatedAt:  video.CreatedAt,
 		UploadedAt: video.UploadedAt,
 		UpdatedAt:  video.UpdatedAt,
+		OwnerID:    video.OwnerID,
+		Visibility: string(video.Visibility),
 	}
 
 	return videoJson
@@ -47,19 +51,91 @@ func VideoToStatusJson(video *models.Video) VideoStatus {
 // VideoInfo DTO
 
 type VideoInfo struct {
-	Title          string `json:"title" example:"amazingtitle"`
-	UploadDateUnix int64  `json:"uploadDateUnix" example:"1652173257"`
+	Title          string   `json:"title" example:"amazingtitle"`
+	UploadDateUnix int64    `json:"uploadDateUnix" example:"1652173257"`
+	Loudness       *float64 `json:"loudness,omitempty" example:"-23.4"`
 }
 
 func VideoToInfoJson(video *models.Video) VideoInfo {
 	videoInfo := VideoInfo{
 		Title:          video.Title,
 		UploadDateUnix: video.UploadedAt.Unix(),
+		Loudness:       video.Loudness,
 	}
 
 	return videoInfo
 }
 
+// SubtitleJson DTO
+
+type SubtitleJson struct {
+	ID        string     `json:"id" example:"aaaa-b56b-..."`
+	Language  string     `json:"language" example:"en"`
+	Label     string     `json:"label" example:"English"`
+	Default   bool       `json:"default" example:"true"`
+	Forced    bool       `json:"forced" example:"false"`
+	CreatedAt *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+	UpdatedAt *time.Time `json:"updatedAt" example:"2022-04-15T12:59:52Z"`
+}
+
+func SubtitleToSubtitleJson(subtitle *models.Subtitle) SubtitleJson {
+	subtitleJson := SubtitleJson{
+		ID:        subtitle.ID,
+		Language:  subtitle.Language,
+		Label:     subtitle.Label,
+		Default:   subtitle.IsDefault,
+		Forced:    subtitle.Forced,
+		CreatedAt: subtitle.CreatedAt,
+		UpdatedAt: subtitle.UpdatedAt,
+	}
+
+	return subtitleJson
+}
+
+// ClipJson DTO
+
+type ClipJson struct {
+	VideoID   string     `json:"videoId" example:"aaaa-b56b-..."`
+	ParentID  *string    `json:"parentId,omitempty" example:"aaaa-b56b-..."`
+	Start     float64    `json:"start" example:"12.5"`
+	End       float64    `json:"end" example:"42"`
+	Accurate  bool       `json:"accurate" example:"false"`
+	CreatedAt *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+}
+
+func ClipToClipJson(clip *models.Clip) ClipJson {
+	clipJson := ClipJson{
+		VideoID:   clip.VideoID,
+		ParentID:  clip.ParentID,
+		Start:     clip.Start,
+		End:       clip.End,
+		Accurate:  clip.Accurate,
+		CreatedAt: clip.CreatedAt,
+	}
+
+	return clipJson
+}
+
+// RenderJson DTO
+
+type RenderJson struct {
+	VideoID   string     `json:"videoId" example:"aaaa-b56b-..."`
+	ParentID  *string    `json:"parentId,omitempty" example:"aaaa-b56b-..."`
+	Filters   []string   `json:"filters" example:"gray,flip"`
+	CreatedAt *time.Time `json:"createdAt" example:"2022-04-15T12:59:52Z"`
+}
+
+func RenderToRenderJson(render *models.Render) RenderJson {
+	renderJson := RenderJson{
+		VideoID:   render.VideoID,
+		ParentID:  render.ParentID,
+		Filters:   render.Filters,
+		CreatedAt: render.CreatedAt,
+	}
+
+	return renderJson
+}
+
 // LinkJson DTO
 
 type LinkJson struct {
@@ -89,3 +165,145 @@ func TransformerServiceToTransformerServiceJson(transformerService models.Transf
 
 	return transformerServiceJson
 }
+
+// UserJson DTO, without the password hash
+
+type UserJson struct {
+	ID        string     `json:"id" example:"aaaa-b56b-..."`
+	Username  string     `json:"username" example:"alice"`
+	Role      string     `json:"role" example:"uploader"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" example:"2022-04-15T12:59:52Z"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" example:"2022-04-15T12:59:52Z"`
+}
+
+func UserToUserJson(user *models.User) UserJson {
+	userJson := UserJson{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      string(user.Role),
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+
+	return userJson
+}
+
+// ApiKeyJson DTO, without the key hash
+
+type ApiKeyJson struct {
+	ID         string     `json:"id" example:"aaaa-b56b-..."`
+	UserID     *string    `json:"userId,omitempty" example:"aaaa-b56b-..."`
+	Name       string     `json:"name" example:"ci-uploader"`
+	Prefix     string     `json:"prefix" example:"vgl_Xk3v9aQe"`
+	Scope      string     `json:"scope" example:"upload"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" example:"2023-04-15T12:59:52Z"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" example:"2022-04-15T12:59:52Z"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty" example:"2022-04-15T12:59:52Z"`
+}
+
+func ApiKeyToApiKeyJson(apiKey *models.ApiKey) ApiKeyJson {
+	apiKeyJson := ApiKeyJson{
+		ID:         apiKey.ID,
+		UserID:     apiKey.UserID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scope:      string(apiKey.Scope),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+
+	return apiKeyJson
+}
+
+// VideoEventJson DTO
+
+type VideoEventJson struct {
+	Kind    string `json:"kind" enums:"status,upload_progress,encode_progress,deleted,renamed" example:"status"`
+	VideoID string `json:"videoId" example:"aaaa-b56b-..."`
+	Title   string `json:"title" example:"A Title"`
+	Status  string `json:"status" example:"Encoding"`
+	// Share of the upload or of the encoding done, from 0 to 1, only for the progress events
+	Progress *float64 `json:"progress,omitempty" example:"0.42"`
+}
+
+func VideoEventToVideoEventJson(event *models.VideoEvent) VideoEventJson {
+	videoEventJson := VideoEventJson{
+		Kind:    string(event.Kind),
+		VideoID: event.Video.ID,
+		Title:   event.Video.Title,
+		Status:  event.Video.Status.String(),
+	}
+	if event.Kind == models.UPLOAD_PROGRESS_EVENT || event.Kind == models.ENCODE_PROGRESS_EVENT {
+		progress := event.Progress
+		videoEventJson.Progress = &progress
+	}
+
+	return videoEventJson
+}
+
+// WebhookJson DTO, without the secret
+
+type WebhookJson struct {
+	ID        string     `json:"id" example:"aaaa-b56b-..."`
+	URL       string     `json:"url" example:"https://cms.example.org/hooks/voogle"`
+	Events    []string   `json:"events" example:"video.complete,video.fail_encode"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" example:"2022-04-15T12:59:52Z"`
+}
+
+func WebhookToWebhookJson(webhook *models.Webhook) WebhookJson {
+	webhookJson := WebhookJson{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    make([]string, 0, len(webhook.Events)),
+		CreatedAt: webhook.CreatedAt,
+	}
+	for _, event := range webhook.Events {
+		webhookJson.Events = append(webhookJson.Events, string(event))
+	}
+
+	return webhookJson
+}
+
+// WebhookDeliveryJson DTO
+
+type WebhookDeliveryJson struct {
+	ID         string     `json:"id" example:"aaaa-b56b-..."`
+	DeliveryID string     `json:"deliveryId" example:"aaaa-b56b-..."`
+	Event      string     `json:"event" example:"video.complete"`
+	VideoID    string     `json:"videoId" example:"aaaa-b56b-..."`
+	Attempt    int        `json:"attempt" example:"1"`
+	StatusCode *int       `json:"statusCode,omitempty" example:"200"`
+	Error      *string    `json:"error,omitempty" example:"unexpected status 503"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty" example:"2022-04-15T12:59:52Z"`
+}
+
+func WebhookDeliveryToWebhookDeliveryJson(delivery *models.WebhookDelivery) WebhookDeliveryJson {
+	webhookDeliveryJson := WebhookDeliveryJson{
+		ID:         delivery.ID,
+		DeliveryID: delivery.DeliveryID,
+		Event:      string(delivery.Event),
+		VideoID:    delivery.VideoID,
+		Attempt:    delivery.Attempt,
+		StatusCode: delivery.StatusCode,
+		Error:      delivery.Error,
+		CreatedAt:  delivery.CreatedAt,
+	}
+
+	return webhookDeliveryJson
+}
+
+// WebhookPayloadJson DTO, the body of the notifications
+
+type WebhookPayloadJson struct {
+	DeliveryID string           `json:"deliveryId" example:"aaaa-b56b-..."`
+	Event      string           `json:"event" example:"video.complete"`
+	OccurredAt time.Time        `json:"occurredAt" example:"2022-04-15T12:59:52Z"`
+	Video      WebhookVideoJson `json:"video"`
+}
+
+type WebhookVideoJson struct {
+	ID     string `json:"id" example:"aaaa-b56b-..."`
+	Title  string `json:"title" example:"A Title"`
+	Status string `json:"status" example:"Complete"`
+}
@@ -17,6 +17,7 @@ var protoToModelStatus = []models.VideoStatus{
 	contracts.Video_VIDEO_STATUS_UNKNOWN:     models.UNKNOWN,
 	contracts.Video_VIDEO_STATUS_FAIL_UPLOAD: models.FAIL_UPLOAD,
 	contracts.Video_VIDEO_STATUS_FAIL_ENCODE: models.FAIL_ENCODE,
+	contracts.Video_VIDEO_STATUS_ARCHIVE:     models.ARCHIVE,
 }
 
 var modelToProtoStatus = []contracts.Video_VideoStatus{
@@ -28,6 +29,23 @@ var modelToProtoStatus = []contracts.Video_VideoStatus{
 	models.UNKNOWN:     contracts.Video_VIDEO_STATUS_UNKNOWN,
 	models.FAIL_UPLOAD: contracts.Video_VIDEO_STATUS_FAIL_UPLOAD,
 	models.FAIL_ENCODE: contracts.Video_VIDEO_STATUS_FAIL_ENCODE,
+	models.ARCHIVE:     contracts.Video_VIDEO_STATUS_ARCHIVE,
+}
+
+var protoToModelEventKind = map[contracts.VideoEvent_Kind]models.VideoEventKind{
+	contracts.VideoEvent_KIND_STATUS:          models.STATUS_EVENT,
+	contracts.VideoEvent_KIND_UPLOAD_PROGRESS: models.UPLOAD_PROGRESS_EVENT,
+	contracts.VideoEvent_KIND_ENCODE_PROGRESS: models.ENCODE_PROGRESS_EVENT,
+	contracts.VideoEvent_KIND_DELETED:         models.DELETED_EVENT,
+	contracts.VideoEvent_KIND_RENAMED:         models.RENAMED_EVENT,
+}
+
+var modelToProtoEventKind = map[models.VideoEventKind]contracts.VideoEvent_Kind{
+	models.STATUS_EVENT:          contracts.VideoEvent_KIND_STATUS,
+	models.UPLOAD_PROGRESS_EVENT: contracts.VideoEvent_KIND_UPLOAD_PROGRESS,
+	models.ENCODE_PROGRESS_EVENT: contracts.VideoEvent_KIND_ENCODE_PROGRESS,
+	models.DELETED_EVENT:         contracts.VideoEvent_KIND_DELETED,
+	models.RENAMED_EVENT:         contracts.VideoEvent_KIND_RENAMED,
 }
 
 func VideoProtobufToVideo(videoProto *contracts.Video) *models.Video {
@@ -41,6 +59,7 @@ func VideoProtobufToVideo(videoProto *contracts.Video) *models.Video {
 		Status:     protoToModelStatus[videoProto.Status],
 		SourcePath: videoProto.Source,
 		CoverPath:  videoProto.CoverPath,
+		Loudness:   videoProto.Loudness,
 	}
 
 	return &video
@@ -61,3 +80,97 @@ func VideoToVideoProtobuf(video *models.Video) *contracts.Video {
 
 	return videoData
 }
+
+func VideoEventProtobufToVideoEvent(eventProto *contracts.VideoEvent) *models.VideoEvent {
+	if eventProto == nil || eventProto.Video == nil {
+		log.Error("Cannot convert protobuf video event to video event, video event nil")
+		return nil
+	}
+
+	event := models.VideoEvent{
+		Kind: protoToModelEventKind[eventProto.Kind],
+		Video: models.Video{
+			ID:         eventProto.Video.Id,
+			Title:      eventProto.Title,
+			Status:     protoToModelStatus[eventProto.Video.Status],
+			OwnerID:    eventProto.OwnerId,
+			Visibility: models.Visibility(eventProto.Visibility),
+		},
+		PreviousStatus: protoToModelStatus[eventProto.PreviousStatus],
+		Progress:       eventProto.Progress,
+	}
+
+	return &event
+}
+
+func VideoEventToVideoEventProtobuf(event *models.VideoEvent) *contracts.VideoEvent {
+	if event == nil {
+		log.Error("Cannot convert video event to protobuf video event, video event nil")
+		return nil
+	}
+
+	eventData := &contracts.VideoEvent{
+		Kind: modelToProtoEventKind[event.Kind],
+		Video: &contracts.Video{
+			Id:     event.Video.ID,
+			Status: modelToProtoStatus[event.Video.Status],
+		},
+		Title:          event.Video.Title,
+		OwnerId:        event.Video.OwnerID,
+		Visibility:     string(event.Video.Visibility),
+		Progress:       event.Progress,
+		PreviousStatus: modelToProtoStatus[event.PreviousStatus],
+	}
+
+	return eventData
+}
+
+// ClipToClipProtobuf returns the instructions of the encoder to cut the clip from the parent source
+func ClipToClipProtobuf(clip *models.Clip, parentSource string) *contracts.Clip {
+	if clip == nil {
+		log.Error("Cannot convert clip to protobuf clip, clip nil")
+		return nil
+	}
+
+	clipData := &contracts.Clip{
+		ParentSource: parentSource,
+		Start:        clip.Start,
+		End:          clip.End,
+		Accurate:     clip.Accurate,
+	}
+
+	return clipData
+}
+
+// RenderToRenderProtobuf returns the instructions of the encoder to burn the filters into the parent source
+func RenderToRenderProtobuf(render *models.Render, parentSource string) *contracts.Render {
+	if render == nil {
+		log.Error("Cannot convert render to protobuf render, render nil")
+		return nil
+	}
+
+	renderData := &contracts.Render{
+		ParentSource: parentSource,
+		Filters:      render.Filters,
+	}
+
+	return renderData
+}
+
+func SubtitleProtobufToSubtitle(videoID string, subtitleProto *contracts.Subtitle) *models.Subtitle {
+	if subtitleProto == nil {
+		log.Error("Cannot convert protobuf subtitle to subtitle, subtitle nil")
+		return nil
+	}
+
+	subtitle := models.Subtitle{
+		VideoID:   videoID,
+		Language:  subtitleProto.Language,
+		Label:     subtitleProto.Label,
+		IsDefault: subtitleProto.Default,
+		Forced:    subtitleProto.Forced,
+		Path:      subtitleProto.Path,
+	}
+
+	return &subtitle
+}
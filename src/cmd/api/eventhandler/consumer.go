@@ -2,6 +2,8 @@ package eventhandler
 
 import (
 	"context"
+	"strings"
+	"unicode/utf8"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/protobuf/proto"
@@ -9,6 +11,7 @@ import (
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 	"github.com/Sogilis/Voogle/src/pkg/events"
+	"github.com/Sogilis/Voogle/src/pkg/subtitles"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
@@ -17,7 +20,7 @@ import (
 	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
-func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient, videosDAO *dao.VideosDAO) {
+func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient, videosDAO *dao.VideosDAO, subtitlesDAO *dao.SubtitlesDAO, uuidGen clients.IUUIDGenerator) {
 	// amqpClient for encoded video (encoder->api)
 	amqpClientVideoEncode, err := clients.NewAmqpClient(cfg.RabbitmqUser, cfg.RabbitmqPwd, cfg.RabbitmqAddr)
 	if err != nil {
@@ -45,6 +48,15 @@ func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient,
 			log.Debug("New message received: ", videoProto)
 			video := protobuf.VideoProtobufToVideo(videoProto)
 
+			// Progress of the encoding, the video stays in the encoding status
+			if video.Status == models.ENCODING && videoProto.Progress != nil {
+				publishEncodeProgress(amqpVideoStatusUpdate, videosDAO, video.ID, videoProto.GetProgress())
+				if err := msg.Acknowledger.Ack(msg.DeliveryTag, false); err != nil {
+					log.Error("Failed to Ack message ", video.ID, " - ", err)
+				}
+				continue
+			}
+
 			// Update videos status : COMPLETE or FAIL_ENCODE
 			videoDb, err := videosDAO.GetVideo(context.Background(), video.ID)
 			if err != nil {
@@ -52,6 +64,7 @@ func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient,
 				continue
 			}
 
+			previousStatus := videoDb.Status
 			videoDb.Status = video.Status
 			videoDb.CoverPath = video.CoverPath
 			if err := videosDAO.UpdateVideo(context.Background(), videoDb); err != nil {
@@ -59,11 +72,17 @@ func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient,
 			}
 			if video.Status == models.COMPLETE {
 				metrics.CounterVideoEncodeSuccess.Inc()
+				registerSubtitles(context.Background(), subtitlesDAO, uuidGen, video.ID, videoProto.GetSubtitles())
+				if video.Loudness != nil {
+					if err := videosDAO.UpdateVideoLoudness(context.Background(), video.ID, *video.Loudness); err != nil {
+						log.Errorf("Unable to update loudness of video %v: %v", video.ID, err)
+					}
+				}
 			} else if video.Status == models.FAIL_ENCODE {
 				metrics.CounterVideoEncodeFail.Inc()
 			}
 
-			publishStatus(amqpVideoStatusUpdate, videoDb)
+			PublishVideoStatusEvent(amqpVideoStatusUpdate, videoDb, previousStatus)
 
 			if err := msg.Acknowledger.Ack(msg.DeliveryTag, false); err != nil {
 				log.Error("Failed to Ack message ", video.ID, " - ", err)
@@ -75,12 +94,85 @@ func ConsumeEvents(cfg config.Config, amqpVideoStatusUpdate clients.AmqpClient,
 	}
 }
 
-func publishStatus(amqpVideoStatus clients.AmqpClient, video *models.Video) {
-	msg, err := proto.Marshal(protobuf.VideoToVideoProtobuf(video))
+// registerSubtitles records the subtitles extracted by the encoder, a redelivered event must not duplicate them
+func registerSubtitles(ctx context.Context, subtitlesDAO *dao.SubtitlesDAO, uuidGen clients.IUUIDGenerator, videoID string, extracted []*contracts.Subtitle) {
+	if len(extracted) == 0 {
+		return
+	}
+
+	existing, err := subtitlesDAO.GetVideoSubtitles(ctx, videoID)
 	if err != nil {
-		log.Error("Failed to Marshal status", err)
+		log.Errorf("Cannot get subtitles of video %v : %v", videoID, err)
+		return
+	}
+
+	knownPaths := map[string]bool{}
+	hasDefault := false
+	for _, subtitle := range existing {
+		knownPaths[subtitle.Path] = true
+		hasDefault = hasDefault || subtitle.IsDefault
+	}
+
+	for _, subtitleProto := range extracted {
+		subtitle := protobuf.SubtitleProtobufToSubtitle(videoID, subtitleProto)
+		if subtitle == nil || knownPaths[subtitle.Path] {
+			continue
+		}
+		if !strings.HasPrefix(subtitle.Path, videoID+"/") {
+			log.Errorf("Subtitles %v are not stored with video %v", subtitle.Path, videoID)
+			continue
+		}
+
+		if !subtitles.IsValidLanguage(subtitle.Language) {
+			subtitle.Language = "und"
+		}
+		subtitle.Label = sanitizeLabel(subtitle.Label, subtitle.Language)
+
+		// The tracks added by the user keep the default flag
+		subtitle.IsDefault = subtitle.IsDefault && !hasDefault
+		hasDefault = hasDefault || subtitle.IsDefault
+
+		subtitle.ID, err = uuidGen.GenerateUuid()
+		if err != nil {
+			log.Error("Cannot generate new subtitle ID : ", err)
+			return
+		}
+
+		if err := subtitlesDAO.CreateSubtitle(ctx, subtitle); err != nil {
+			log.Errorf("Cannot register subtitles %v of video %v : %v", subtitle.Path, videoID, err)
+			continue
+		}
+		knownPaths[subtitle.Path] = true
+		log.Infof("Embedded subtitles '%v' added to video %v", subtitle.Label, videoID)
+	}
+}
+
+// sanitizeLabel makes the stream title usable as a track label
+func sanitizeLabel(label, fallback string) string {
+	label = strings.Map(func(r rune) rune {
+		if r == '"' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(label))
+
+	for len(label) > subtitles.MaxLabelLength {
+		_, size := utf8.DecodeLastRuneInString(label)
+		label = label[:len(label)-size]
+	}
+
+	if label == "" {
+		return fallback
 	}
-	if err := amqpVideoStatus.Publish(video.Title, msg); err != nil {
-		log.Error("Unable to publish status update", err)
+	return label
+}
+
+// publishEncodeProgress sends the progress with the video of the database, the receivers need its title and visibility
+func publishEncodeProgress(amqpVideoStatusUpdate clients.AmqpClient, videosDAO *dao.VideosDAO, videoID string, progress float64) {
+	video, err := videosDAO.GetVideo(context.Background(), videoID)
+	if err != nil {
+		log.Errorf("Failed to get video %v from database : %v ", videoID, err)
+		return
 	}
+	PublishVideoEvent(amqpVideoStatusUpdate, models.ENCODE_PROGRESS_EVENT, video, progress)
 }
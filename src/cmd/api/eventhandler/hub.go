@@ -0,0 +1,131 @@
+package eventhandler
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+	"github.com/Sogilis/Voogle/src/pkg/events"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// Events kept for a subscriber not reading them, the next ones are dropped
+const subscriberBufferSize = 64
+
+// Last events kept for the subscribers resuming after a disconnection
+const replayBufferSize = 256
+
+// Hub receives the video events published by every API instance on a single queue, and dispatches them
+// to the subscribers of this instance (the websocket connections).
+type Hub struct {
+	amqpVideoStatusUpdate clients.AmqpClient
+	start                 sync.Once
+
+	mutex       sync.Mutex
+	subscribers map[chan *models.VideoEvent]struct{}
+	// Sequence of the last event, and the last events in the order of their sequence
+	sequence uint64
+	replay   []*models.VideoEvent
+}
+
+func NewHub(amqpVideoStatusUpdate clients.AmqpClient) *Hub {
+	return &Hub{
+		amqpVideoStatusUpdate: amqpVideoStatusUpdate,
+		subscribers:           map[chan *models.VideoEvent]struct{}{},
+	}
+}
+
+// Subscribe returns a channel receiving every event, and the function to call once done with it.
+// The events are consumed from the first subscription on.
+func (h *Hub) Subscribe() (<-chan *models.VideoEvent, func()) {
+	_, subscriber, unsubscribe := h.SubscribeAfter(0)
+	return subscriber, unsubscribe
+}
+
+// SubscribeAfter also returns the events kept since the given sequence, followed without gap by the channel.
+// Nothing is replayed for the sequence 0, or for a sequence unknown to this instance: the events are numbered
+// by each API instance from its start.
+func (h *Hub) SubscribeAfter(sequence uint64) ([]*models.VideoEvent, <-chan *models.VideoEvent, func()) {
+	h.start.Do(func() { go h.consume() })
+
+	subscriber := make(chan *models.VideoEvent, subscriberBufferSize)
+	h.mutex.Lock()
+	h.subscribers[subscriber] = struct{}{}
+	var replay []*models.VideoEvent
+	if sequence > 0 && sequence < h.sequence {
+		for _, event := range h.replay {
+			if event.Sequence > sequence {
+				replay = append(replay, event)
+			}
+		}
+	}
+	h.mutex.Unlock()
+
+	unsubscribe := func() {
+		h.mutex.Lock()
+		delete(h.subscribers, subscriber)
+		h.mutex.Unlock()
+	}
+	return replay, subscriber, unsubscribe
+}
+
+// Dispatch numbers the event and sends it to the current subscribers, without waiting for the slow ones
+func (h *Hub) Dispatch(videoEvent *models.VideoEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.sequence++
+	event := *videoEvent
+	event.Sequence = h.sequence
+	if len(h.replay) == replayBufferSize {
+		h.replay = h.replay[1:]
+	}
+	h.replay = append(h.replay, &event)
+
+	for subscriber := range h.subscribers {
+		select {
+		case subscriber <- &event:
+		default:
+			log.Errorf("Subscriber too slow, %v event of video %v dropped", event.Kind, event.Video.ID)
+		}
+	}
+}
+
+func (h *Hub) consume() {
+	queueName := h.amqpVideoStatusUpdate.GetRandomQueueName()
+	session := h.amqpVideoStatusUpdate.WithRedial()
+
+	for client := range session {
+		msgs, err := client.Consume(queueName)
+		if err != nil {
+			log.Error("Failed to consume RabbitMQ client: ", err)
+			client.Close()
+			continue
+		}
+		if err := client.QueueBind(queueName, events.VideoEventKey); err != nil {
+			log.Error("Could not bind queue : ", err)
+			client.Close()
+			continue
+		}
+
+		for d := range msgs {
+			eventProto := &contracts.VideoEvent{}
+			if err := proto.Unmarshal(d.Body, eventProto); err != nil {
+				log.Error("Fail to unmarshal video event : ", err)
+			} else if event := protobuf.VideoEventProtobufToVideoEvent(eventProto); event != nil {
+				h.Dispatch(event)
+			}
+
+			if err := d.Acknowledger.Ack(d.DeliveryTag, false); err != nil {
+				log.Error("Failed to Ack video event : ", err)
+			}
+		}
+		// We close the client to let another take his place.
+		client.Close()
+	}
+}
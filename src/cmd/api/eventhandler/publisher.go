@@ -0,0 +1,36 @@
+package eventhandler
+
+import (
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/events"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+// PublishVideoEvent sends the event to the websockets of every API instance. It only logs on failure:
+// the events are notifications, the change of the video is already done.
+func PublishVideoEvent(amqpVideoStatusUpdate clients.AmqpClient, kind models.VideoEventKind, video *models.Video, progress float64) {
+	publishEvent(amqpVideoStatusUpdate, &models.VideoEvent{Kind: kind, Video: *video, Progress: progress})
+}
+
+// PublishVideoStatusEvent sends the change of status of the video from previousStatus. The webhooks are only
+// notified of the end of the encodings, the status the video had tells them apart from an unarchiving.
+func PublishVideoStatusEvent(amqpVideoStatusUpdate clients.AmqpClient, video *models.Video, previousStatus models.VideoStatus) {
+	publishEvent(amqpVideoStatusUpdate, &models.VideoEvent{Kind: models.STATUS_EVENT, Video: *video, PreviousStatus: previousStatus})
+}
+
+func publishEvent(amqpVideoStatusUpdate clients.AmqpClient, event *models.VideoEvent) {
+	msg, err := proto.Marshal(protobuf.VideoEventToVideoEventProtobuf(event))
+	if err != nil {
+		log.Error("Failed to marshal video event : ", err)
+		return
+	}
+
+	if err := amqpVideoStatusUpdate.Publish(events.VideoEventKey, msg); err != nil {
+		log.Errorf("Unable to publish %v event of video %v : %v", event.Kind, event.Video.ID, err)
+	}
+}
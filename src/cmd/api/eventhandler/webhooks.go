@@ -0,0 +1,215 @@
+package eventhandler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+	"github.com/Sogilis/Voogle/src/pkg/events"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+)
+
+const (
+	WebhookEventHeader     = "X-Voogle-Event"
+	WebhookDeliveryHeader  = "X-Voogle-Delivery"
+	WebhookSignatureHeader = "X-Voogle-Signature"
+)
+
+// Part of the answer of the webhooks read, so that the connection can be reused
+const webhookResponseLimit = 64 * 1024
+
+// WebhookDispatcher notifies the webhooks of the events of the videos, and logs each attempt
+type WebhookDispatcher struct {
+	WebhooksDAO          *dao.WebhooksDAO
+	WebhookDeliveriesDAO *dao.WebhookDeliveriesDAO
+	UUIDGen              clients.IUUIDGenerator
+	HTTPClient           *http.Client
+	MaxAttempts          int
+	// Delay before the first retry, doubled after each attempt
+	RetryDelay time.Duration
+}
+
+// ConsumeEvents receives the events on the queue shared by the API instances. The notifications are sent in
+// the background, those still retried are lost when the instance stops.
+func (d *WebhookDispatcher) ConsumeEvents(amqpVideoStatusUpdate clients.AmqpClient) {
+	session := amqpVideoStatusUpdate.WithRedial()
+
+	for client := range session {
+		msgs, err := client.Consume(events.WebhooksQueue)
+		if err != nil {
+			log.Error("Failed to consume RabbitMQ client: ", err)
+			client.Close()
+			continue
+		}
+		if err := client.QueueBind(events.WebhooksQueue, events.VideoEventKey); err != nil {
+			log.Error("Could not bind queue : ", err)
+			client.Close()
+			continue
+		}
+
+		for msg := range msgs {
+			eventProto := &contracts.VideoEvent{}
+			if err := proto.Unmarshal(msg.Body, eventProto); err != nil {
+				log.Error("Fail to unmarshal video event : ", err)
+			} else if event := protobuf.VideoEventProtobufToVideoEvent(eventProto); event != nil {
+				go d.Notify(context.Background(), event)
+			}
+
+			if err := msg.Acknowledger.Ack(msg.DeliveryTag, false); err != nil {
+				log.Error("Failed to Ack video event : ", err)
+			}
+		}
+		// We close the client to let another take his place.
+		client.Close()
+	}
+}
+
+// Notify sends the event to the webhooks subscribed to it, and returns once they are all notified or given up
+func (d *WebhookDispatcher) Notify(ctx context.Context, event *models.VideoEvent) {
+	webhookEvent, ok := models.WebhookEventOf(event)
+	if !ok {
+		return
+	}
+
+	webhooks, err := d.WebhooksDAO.GetWebhooks(ctx)
+	if err != nil {
+		log.Errorf("Cannot get webhooks to notify %v of video %v : %v", webhookEvent, event.Video.ID, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range webhooks {
+		if !webhooks[i].Subscribed(webhookEvent) {
+			continue
+		}
+		wg.Add(1)
+		go func(webhook *models.Webhook) {
+			defer wg.Done()
+			d.deliver(ctx, webhook, webhookEvent, &event.Video)
+		}(&webhooks[i])
+	}
+	wg.Wait()
+}
+
+// deliver posts the notification until the webhook accepts it, it is refused, or the attempts are exhausted
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook *models.Webhook, webhookEvent models.WebhookEvent, video *models.Video) {
+	deliveryID, err := d.UUIDGen.GenerateUuid()
+	if err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		return
+	}
+
+	body, err := json.Marshal(jsonDTO.WebhookPayloadJson{
+		DeliveryID: deliveryID,
+		Event:      string(webhookEvent),
+		OccurredAt: time.Now().UTC(),
+		Video:      jsonDTO.WebhookVideoJson{ID: video.ID, Title: video.Title, Status: video.Status.String()},
+	})
+	if err != nil {
+		log.Error("Cannot marshal webhook payload : ", err)
+		return
+	}
+
+	delay := d.RetryDelay
+	for attempt := 1; ; attempt++ {
+		statusCode, err := d.post(ctx, webhook, webhookEvent, deliveryID, body)
+		d.logDelivery(ctx, &models.WebhookDelivery{
+			WebhookID:  webhook.ID,
+			DeliveryID: deliveryID,
+			Event:      webhookEvent,
+			VideoID:    video.ID,
+			Attempt:    attempt,
+		}, statusCode, err)
+		if err == nil {
+			return
+		}
+
+		if !isWebhookRetryable(statusCode) || attempt >= d.MaxAttempts {
+			log.Errorf("Webhook %v not notified of %v of video %v after %v attempts : %v", webhook.ID, webhookEvent, video.ID, attempt, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post returns the status code of the answer, 0 when there is none
+func (d *WebhookDispatcher) post(ctx context.Context, webhook *models.Webhook, webhookEvent models.WebhookEvent, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Voogle-Webhook")
+	req.Header.Set(WebhookEventHeader, string(webhookEvent))
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, body))
+
+	res, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, webhookResponseLimit))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status %v", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// logDelivery records the attempt, the notification goes on when it cannot
+func (d *WebhookDispatcher) logDelivery(ctx context.Context, delivery *models.WebhookDelivery, statusCode int, deliveryErr error) {
+	var err error
+	if delivery.ID, err = d.UUIDGen.GenerateUuid(); err != nil {
+		log.Error("Cannot generate new UUID : ", err)
+		return
+	}
+	if statusCode != 0 {
+		delivery.StatusCode = &statusCode
+	}
+	if deliveryErr != nil {
+		message := deliveryErr.Error()
+		delivery.Error = &message
+	}
+
+	if err := d.WebhookDeliveriesDAO.CreateWebhookDelivery(ctx, delivery); err != nil {
+		log.Errorf("Cannot log delivery %v to webhook %v : %v", delivery.DeliveryID, delivery.WebhookID, err)
+	}
+}
+
+// isWebhookRetryable returns whether a failed notification may succeed later: the webhook did not answer,
+// failed, or asked to slow down. The other refusals are final.
+func isWebhookRetryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// SignWebhookPayload returns the signature of the body, sent in the X-Voogle-Signature header: the hex encoded
+// HMAC-SHA256 of the body with the secret of the webhook, prefixed by "sha256="
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,215 @@
+package eventhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
+	"github.com/Sogilis/Voogle/src/cmd/api/db/dao_test"
+	jsonDTO "github.com/Sogilis/Voogle/src/cmd/api/dto/json"
+	"github.com/Sogilis/Voogle/src/cmd/api/dto/protobuf"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+)
+
+func TestWebhookDispatcher(t *testing.T) { //nolint:cyclop
+	webhookID := "5a1e6f3b-2c4d-4e8f-9a0b-1c2d3e4f5a6b"
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	secret := "a-secret-of-32-characters-long!!"
+	UUIDValidFunc := func(u string) bool { _, err := uuid.Parse(u); return err == nil }
+
+	webhooksColumns := []string{"id", "url", "secret", "events", "created_at"}
+	createDeliveryQuery := regexp.QuoteMeta(dao.WebhookDeliveriesRequests[dao.CreateWebhookDelivery])
+
+	cases := []struct {
+		name           string
+		givePrevious   models.VideoStatus
+		giveStatus     models.VideoStatus
+		giveKind       models.VideoEventKind
+		giveEvents     string
+		giveResponses  []int
+		expectAttempts int
+		expectEvent    string
+	}{
+		{
+			name:           "Notify completed video",
+			givePrevious:   models.ENCODING,
+			giveStatus:     models.COMPLETE,
+			giveKind:       models.STATUS_EVENT,
+			giveEvents:     "video.complete,video.fail_encode",
+			giveResponses:  []int{http.StatusNoContent},
+			expectAttempts: 1,
+			expectEvent:    "video.complete",
+		},
+		{
+			name:           "Notify failed encoding after retries",
+			givePrevious:   models.ENCODING,
+			giveStatus:     models.FAIL_ENCODE,
+			giveKind:       models.STATUS_EVENT,
+			giveEvents:     "video.fail_encode",
+			giveResponses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			expectAttempts: 3,
+			expectEvent:    "video.fail_encode",
+		},
+		{
+			name:           "Notify gives up after the last attempt",
+			givePrevious:   models.ENCODING,
+			giveStatus:     models.COMPLETE,
+			giveKind:       models.STATUS_EVENT,
+			giveEvents:     "video.complete",
+			giveResponses:  []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusInternalServerError},
+			expectAttempts: 3,
+			expectEvent:    "video.complete",
+		},
+		{
+			name:           "Notify does not retry a refused notification",
+			givePrevious:   models.ENCODING,
+			giveStatus:     models.COMPLETE,
+			giveKind:       models.STATUS_EVENT,
+			giveEvents:     "video.complete",
+			giveResponses:  []int{http.StatusBadRequest},
+			expectAttempts: 1,
+			expectEvent:    "video.complete",
+		},
+		{
+			name:           "Notify skips webhooks not subscribed to the event",
+			givePrevious:   models.ENCODING,
+			giveStatus:     models.FAIL_ENCODE,
+			giveKind:       models.STATUS_EVENT,
+			giveEvents:     "video.complete",
+			expectAttempts: 0,
+		},
+		{
+			name:         "Notify ignores the unarchiving",
+			givePrevious: models.ARCHIVE,
+			giveStatus:   models.COMPLETE,
+			giveKind:     models.STATUS_EVENT,
+			giveEvents:   "video.complete",
+		},
+		{
+			name:         "Notify ignores the other statuses",
+			givePrevious: models.UPLOADED,
+			giveStatus:   models.ENCODING,
+			giveKind:     models.STATUS_EVENT,
+			giveEvents:   "video.complete",
+		},
+		{
+			name:         "Notify ignores the other events",
+			givePrevious: models.ENCODING,
+			giveStatus:   models.COMPLETE,
+			giveKind:     models.RENAMED_EVENT,
+			giveEvents:   "video.complete",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			deliveryIDs := make(chan string, len(tt.giveResponses))
+
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.Equal(t, eventhandler.SignWebhookPayload(secret, body), r.Header.Get(eventhandler.WebhookSignatureHeader))
+				require.Equal(t, tt.expectEvent, r.Header.Get(eventhandler.WebhookEventHeader))
+
+				payload := jsonDTO.WebhookPayloadJson{}
+				require.NoError(t, json.Unmarshal(body, &payload))
+				require.Equal(t, tt.expectEvent, payload.Event)
+				require.Equal(t, videoID, payload.Video.ID)
+				require.Equal(t, r.Header.Get(eventhandler.WebhookDeliveryHeader), payload.DeliveryID)
+				deliveryIDs <- payload.DeliveryID
+
+				w.WriteHeader(tt.giveResponses[attempt-1])
+			}))
+			defer receiver.Close()
+
+			// Mock database
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			dao_test.ExpectWebhooksDAOCreation(mock)
+			dao_test.ExpectWebhookDeliveriesDAOCreation(mock)
+
+			if _, ok := models.WebhookEventOf(&models.VideoEvent{Kind: tt.giveKind, Video: models.Video{Status: tt.giveStatus}, PreviousStatus: tt.givePrevious}); ok {
+				mock.ExpectQuery(regexp.QuoteMeta(dao.WebhooksRequests[dao.GetWebhooks])).
+					WillReturnRows(sqlmock.NewRows(webhooksColumns).AddRow(webhookID, receiver.URL, secret, tt.giveEvents, time.Now()))
+			}
+			for i := 0; i < tt.expectAttempts; i++ {
+				var expectError interface{}
+				if status := tt.giveResponses[i]; status >= 300 {
+					expectError = fmt.Sprintf("unexpected status %v", status)
+				}
+				mock.ExpectExec(createDeliveryQuery).
+					WithArgs(sqlmock.AnyArg(), webhookID, sqlmock.AnyArg(), tt.expectEvent, videoID, i+1, tt.giveResponses[i], expectError).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			webhooksDAO, err := dao.CreateWebhooksDAO(context.Background(), db)
+			require.NoError(t, err)
+			webhookDeliveriesDAO, err := dao.CreateWebhookDeliveriesDAO(context.Background(), db)
+			require.NoError(t, err)
+
+			dispatcher := eventhandler.WebhookDispatcher{
+				WebhooksDAO:          webhooksDAO,
+				WebhookDeliveriesDAO: webhookDeliveriesDAO,
+				UUIDGen:              clients.NewUuidGeneratorDummy(func() (string, error) { return uuid.NewString(), nil }, UUIDValidFunc),
+				HTTPClient:           receiver.Client(),
+				MaxAttempts:          3,
+				RetryDelay:           time.Millisecond,
+			}
+
+			// The event is received as published by the API instances
+			var event *models.VideoEvent
+			publish := func(key string, message []byte) error {
+				eventProto := &contracts.VideoEvent{}
+				require.NoError(t, proto.Unmarshal(message, eventProto))
+				event = protobuf.VideoEventProtobufToVideoEvent(eventProto)
+				return nil
+			}
+			video := &models.Video{ID: videoID, Title: "title", Status: tt.giveStatus}
+			if tt.giveKind == models.STATUS_EVENT {
+				eventhandler.PublishVideoStatusEvent(clients.NewAmqpClientDummy(publish, nil, nil), video, tt.givePrevious)
+			} else {
+				eventhandler.PublishVideoEvent(clients.NewAmqpClientDummy(publish, nil, nil), tt.giveKind, video, 0)
+			}
+			require.NotNil(t, event)
+
+			dispatcher.Notify(context.Background(), event)
+
+			require.Equal(t, int32(tt.expectAttempts), atomic.LoadInt32(&attempts))
+
+			// The attempts of a notification share their delivery id
+			close(deliveryIDs)
+			var deliveryID string
+			for id := range deliveryIDs {
+				if deliveryID == "" {
+					deliveryID = id
+				}
+				require.Equal(t, deliveryID, id)
+			}
+
+			// we make sure that all expectations were met
+			err = mock.ExpectationsWereMet()
+			require.NoError(t, err)
+		})
+	}
+}
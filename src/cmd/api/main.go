@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,15 +15,19 @@ import (
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	"github.com/Sogilis/Voogle/src/pkg/events"
+	"github.com/Sogilis/Voogle/src/pkg/oidc"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
+	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
 	"github.com/Sogilis/Voogle/src/cmd/api/router"
 )
 
 const GORILLA_MUX_SHUTDOWN_TIMEOUT time.Duration = time.Second * 2
 const GOROUTINE_FLUSH_TIMEOUT time.Duration = time.Millisecond * 100
+const OIDC_HTTP_TIMEOUT time.Duration = time.Second * 10
 
 func main() {
 	log.Info("Starting Voogle API")
@@ -36,11 +42,38 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	if cfg.StreamTokenSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal("Failed to generate stream token secret : ", err)
+		}
+		cfg.StreamTokenSecret = string(secret)
+		log.Warn("STREAM_TOKEN_SECRET is not set, stream tokens will only be valid on this instance until it restarts")
+	}
+
 	// Create routers
 	routerClients, routerDAOs := createRouters(cfg)
 	defer routerDAOs.Db.Close()
 	defer routerDAOs.VideosDAO.Close()
 	defer routerDAOs.UploadsDAO.Close()
+	defer routerDAOs.StorageUsagesDAO.Close()
+	defer routerDAOs.SubtitlesDAO.Close()
+	defer routerDAOs.ClipsDAO.Close()
+	defer routerDAOs.RendersDAO.Close()
+	defer routerDAOs.UsersDAO.Close()
+	defer routerDAOs.SessionsDAO.Close()
+	defer routerDAOs.VideoSharesDAO.Close()
+	defer routerDAOs.ApiKeysDAO.Close()
+	defer routerDAOs.WebhooksDAO.Close()
+	defer routerDAOs.WebhookDeliveriesDAO.Close()
+
+	if cfg.UserAuth != "" && cfg.PwdAuth != "" {
+		if err := ensureAdminUser(context.Background(), cfg, &routerDAOs.UsersDAO, routerClients.UUIDGen); err != nil {
+			log.Fatal("Failed to create admin user : ", err)
+		}
+	} else {
+		log.Warn("USER_AUTH or PWD_AUTH is not set, the basic auth service account is disabled")
+	}
 
 	// Start service discovery
 	go func() {
@@ -68,7 +101,22 @@ func main() {
 	}()
 
 	// Start encoder event listener
-	go eventhandler.ConsumeEvents(cfg, routerClients.AmqpVideoStatusUpdate, &routerDAOs.VideosDAO)
+	go eventhandler.ConsumeEvents(cfg, routerClients.AmqpVideoStatusUpdate, &routerDAOs.VideosDAO, &routerDAOs.SubtitlesDAO, routerClients.UUIDGen)
+
+	// Start webhook notifications
+	webhookDispatcher := &eventhandler.WebhookDispatcher{
+		WebhooksDAO:          &routerDAOs.WebhooksDAO,
+		WebhookDeliveriesDAO: &routerDAOs.WebhookDeliveriesDAO,
+		UUIDGen:              routerClients.UUIDGen,
+		HTTPClient: &http.Client{
+			Timeout: cfg.WebhookTimeout,
+			// A redirection is an answer of the webhook, the notification is not sent elsewhere
+			CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		RetryDelay:  cfg.WebhookRetryDelay,
+	}
+	go webhookDispatcher.ConsumeEvents(routerClients.AmqpVideoStatusUpdate)
 
 	// Wait for SIGINT.
 	sig := make(chan os.Signal, 1)
@@ -117,16 +165,67 @@ func createRouters(cfg config.Config) (*router.Clients, *router.DAOs) {
 		log.Fatal("Failed to open connection with database: ", err)
 	}
 
+	// The shares of the videos reference the users
+	usersDAO, err := dao.CreateUsersDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create users DAO : ", err)
+	}
+
 	videosDAO, err := dao.CreateVideosDAO(context.Background(), db)
 	if err != nil {
 		log.Fatal("Failed to create videos DAO : ", err)
 	}
 
+	videoSharesDAO, err := dao.CreateVideoSharesDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create video shares DAO : ", err)
+	}
+
 	uploadsDAO, err := dao.CreateUploadsDAO(context.Background(), db)
 	if err != nil {
 		log.Fatal("Failed to create uploads DAO : ", err)
 	}
 
+	storageUsagesDAO, err := dao.CreateStorageUsagesDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create storage usages DAO : ", err)
+	}
+
+	subtitlesDAO, err := dao.CreateSubtitlesDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create subtitles DAO : ", err)
+	}
+
+	clipsDAO, err := dao.CreateClipsDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create clips DAO : ", err)
+	}
+
+	rendersDAO, err := dao.CreateRendersDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create renders DAO : ", err)
+	}
+
+	sessionsDAO, err := dao.CreateSessionsDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create sessions DAO : ", err)
+	}
+
+	apiKeysDAO, err := dao.CreateApiKeysDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create api keys DAO : ", err)
+	}
+
+	webhooksDAO, err := dao.CreateWebhooksDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create webhooks DAO : ", err)
+	}
+
+	webhookDeliveriesDAO, err := dao.CreateWebhookDeliveriesDAO(context.Background(), db)
+	if err != nil {
+		log.Fatal("Failed to create webhook deliveries DAO : ", err)
+	}
+
 	discoveryClient, err := clients.NewServiceDiscovery(cfg.ConsulHost)
 	if err != nil {
 		log.Fatal("Cannot create consul client : ", err)
@@ -138,13 +237,64 @@ func createRouters(cfg config.Config) (*router.Clients, *router.DAOs) {
 		AmqpVideoStatusUpdate: amqpVideoStatusUpdate,
 		ServiceDiscovery:      discoveryClient,
 		UUIDGen:               clients.NewUuidGenerator(),
+		VideoProber:           clients.NewVideoProber(),
+		VideoEvents:           eventhandler.NewHub(amqpVideoStatusUpdate),
+	}
+
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCAudience == "" {
+			log.Fatal("OIDC_AUDIENCE is required with OIDC_ISSUER")
+		}
+		verifier, err := oidc.NewVerifier(context.Background(), cfg.OIDCIssuer, cfg.OIDCAudience, &http.Client{Timeout: OIDC_HTTP_TIMEOUT})
+		if err != nil {
+			log.Fatal("Failed to create OIDC verifier : ", err)
+		}
+		routerClients.OIDCVerifier = verifier
+		log.Info("Single sign-on enabled with issuer ", cfg.OIDCIssuer)
 	}
 
 	routerDAOs := &router.DAOs{
-		Db:         db,
-		VideosDAO:  *videosDAO,
-		UploadsDAO: *uploadsDAO,
+		Db:                   db,
+		VideosDAO:            *videosDAO,
+		UploadsDAO:           *uploadsDAO,
+		StorageUsagesDAO:     *storageUsagesDAO,
+		SubtitlesDAO:         *subtitlesDAO,
+		ClipsDAO:             *clipsDAO,
+		RendersDAO:           *rendersDAO,
+		UsersDAO:             *usersDAO,
+		SessionsDAO:          *sessionsDAO,
+		VideoSharesDAO:       *videoSharesDAO,
+		ApiKeysDAO:           *apiKeysDAO,
+		WebhooksDAO:          *webhooksDAO,
+		WebhookDeliveriesDAO: *webhookDeliveriesDAO,
 	}
 
 	return routerClients, routerDAOs
 }
+
+// ensureAdminUser creates the first admin user from the service account credentials, so that it can log in the webapp
+func ensureAdminUser(ctx context.Context, cfg config.Config, usersDAO *dao.UsersDAO, uuidGen clients.IUUIDGenerator) error {
+	_, err := usersDAO.GetUserFromUsername(ctx, cfg.UserAuth)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	passwordHash, err := controllers.HashPassword(cfg.PwdAuth)
+	if err != nil {
+		return err
+	}
+
+	userID, err := uuidGen.GenerateUuid()
+	if err != nil {
+		return err
+	}
+
+	if _, err := usersDAO.CreateUser(ctx, userID, cfg.UserAuth, passwordHash, models.ADMIN); err != nil {
+		return err
+	}
+
+	log.Info("Admin user " + cfg.UserAuth + " created")
+	return nil
+}
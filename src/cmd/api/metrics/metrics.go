@@ -95,6 +95,13 @@ var (
 	})
 )
 
+var (
+	CounterVideoTransformWatermark = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "api_watermark_transformation_request",
+		Help: "The total number of watermark transformation request",
+	})
+)
+
 func StoreTranformationTime(start time.Time, transformers []string) {
 	elapsed := time.Since(start)
 	if len(transformers) == 1 {
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ApiKeyScope limits the rights of an API key to those of a role, and to the routes opened to the scope
+type ApiKeyScope string
+
+// Each scope includes the routes of the scopes below it
+const (
+	// The read routes
+	READ_SCOPE ApiKeyScope = "read"
+	// The read routes and the uploads, the videos cannot be edited nor deleted
+	UPLOAD_SCOPE ApiKeyScope = "upload"
+	// Every route the user is allowed to, but the management of the API keys
+	ADMIN_SCOPE ApiKeyScope = "admin"
+)
+
+var scopeRoles = map[ApiKeyScope]Role{READ_SCOPE: VIEWER, UPLOAD_SCOPE: UPLOADER, ADMIN_SCOPE: ADMIN}
+
+var scopeRanks = map[ApiKeyScope]int{READ_SCOPE: 1, UPLOAD_SCOPE: 2, ADMIN_SCOPE: 3}
+
+func StringToApiKeyScope(s string) (ApiKeyScope, error) {
+	scope := ApiKeyScope(strings.ToLower(s))
+	if _, ok := scopeRoles[scope]; !ok {
+		return "", fmt.Errorf("unknown scope %v", s)
+	}
+	return scope, nil
+}
+
+// Role returns the role whose rights are given by the scope
+func (s ApiKeyScope) Role() Role {
+	return scopeRoles[s]
+}
+
+// Includes tells whether the scope opens the routes of the other one
+func (s ApiKeyScope) Includes(other ApiKeyScope) bool {
+	return scopeRanks[s] >= scopeRanks[other] && scopeRanks[s] > 0
+}
+
+// ApiKey authenticates a machine client as the user who created it, with at most the rights of its scope.
+// Only the hash of the key is stored, its prefix is kept to recognize it.
+type ApiKey struct {
+	ID         string
+	UserID     *string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scope      ApiKeyScope
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  *time.Time
+}
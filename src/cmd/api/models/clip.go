@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Clip is a video cut from another one, its parent
+type Clip struct {
+	VideoID   string
+	ParentID  *string // Unset once the parent is deleted
+	Start     float64 // Seconds
+	End       float64 // Seconds
+	Accurate  bool    // Frame-accurate cut, otherwise cut on keyframes
+	CreatedAt *time.Time
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Render is a video whose transformation filters are burnt into the encoded renditions of another one, its parent
+type Render struct {
+	VideoID   string
+	ParentID  *string  // Unset once the parent is deleted
+	Filters   []string // Transformers, applied in order
+	CreatedAt *time.Time
+}
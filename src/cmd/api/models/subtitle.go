@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type Subtitle struct {
+	ID        string
+	VideoID   string
+	Language  string // BCP 47 language tag, e.g. "en" or "fr-CA"
+	Label     string
+	IsDefault bool
+	Forced    bool
+	Path      string // S3 path of the WebVTT file
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
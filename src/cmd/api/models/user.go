@@ -0,0 +1,58 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Role string
+
+// Each role includes the rights of the roles below it
+const (
+	VIEWER   Role = "viewer"
+	UPLOADER Role = "uploader"
+	ADMIN    Role = "admin"
+)
+
+var roleRanks = map[Role]int{VIEWER: 1, UPLOADER: 2, ADMIN: 3}
+
+func StringToRole(r string) (Role, error) {
+	role := Role(strings.ToLower(r))
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %v", r)
+	}
+	return role, nil
+}
+
+// Includes tells whether the role has the rights of the other one
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other] && roleRanks[r] > 0
+}
+
+// Lowest returns the role having the fewest rights among the two
+func (r Role) Lowest(other Role) Role {
+	if roleRanks[other] < roleRanks[r] {
+		return other
+	}
+	return r
+}
+
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	Role         Role
+	CreatedAt    *time.Time
+	UpdatedAt    *time.Time
+	// Subject of the user at the OpenID Connect provider, nil for local users
+	OIDCSubject *string
+}
+
+// Session is a login of a user. Only the hash of its token is stored.
+type Session struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+	CreatedAt *time.Time
+}
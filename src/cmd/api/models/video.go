@@ -79,4 +79,35 @@ type Video struct {
 	UpdatedAt  *time.Time
 	SourcePath string
 	CoverPath  string
+	Loudness   *float64 // Integrated loudness of the default audio track, in LUFS
+	OwnerID    *string  // Nil for the videos uploaded by the service account or before the accounts
+	Visibility Visibility
+}
+
+// IsOwnedBy tells whether the user uploaded the video
+func (v Video) IsOwnedBy(user *User) bool {
+	return user != nil && user.ID != "" && v.OwnerID != nil && *v.OwnerID == user.ID
+}
+
+type Visibility string
+
+const (
+	// Only the owner (and the admins) can see the video
+	PRIVATE Visibility = "private"
+	// Anyone knowing the video ID can see it, but it is not listed
+	UNLISTED Visibility = "unlisted"
+	// The owner chooses the users who can see the video
+	SHARED Visibility = "shared"
+	// Everyone can see and list the video
+	PUBLIC Visibility = "public"
+)
+
+func StringToVisibility(v string) (Visibility, error) {
+	visibility := Visibility(strings.ToLower(v))
+	switch visibility {
+	case PRIVATE, UNLISTED, SHARED, PUBLIC:
+		return visibility, nil
+	default:
+		return "", errors.New("No cast for " + v + " to Visibility")
+	}
 }
@@ -0,0 +1,27 @@
+package models
+
+type VideoEventKind string
+
+const (
+	// The status of the video changed
+	STATUS_EVENT VideoEventKind = "status"
+	// Part of the source is stored, while the status is uploading
+	UPLOAD_PROGRESS_EVENT VideoEventKind = "upload_progress"
+	// Part of the video is processed by the encoder, while the status is encoding
+	ENCODE_PROGRESS_EVENT VideoEventKind = "encode_progress"
+	DELETED_EVENT         VideoEventKind = "deleted"
+	RENAMED_EVENT         VideoEventKind = "renamed"
+)
+
+// VideoEvent is a change of a video, sent to the websocket clients allowed to see the video
+type VideoEvent struct {
+	Kind VideoEventKind
+	// ID, title, status, owner and visibility of the video after the change
+	Video Video
+	// Status before the change for the status events, UNSPECIFIED when it is unknown
+	PreviousStatus VideoStatus
+	// Share of the upload or of the encoding done, from 0 to 1, for the progress events
+	Progress float64
+	// Number of the event in the API instance, set when dispatched to its subscribers
+	Sequence uint64
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebhookEvent is a step of the life of a video notified to the webhooks
+type WebhookEvent string
+
+const (
+	VIDEO_COMPLETE_EVENT    WebhookEvent = "video.complete"
+	VIDEO_FAIL_ENCODE_EVENT WebhookEvent = "video.fail_encode"
+)
+
+var webhookEventStatuses = map[WebhookEvent]VideoStatus{VIDEO_COMPLETE_EVENT: COMPLETE, VIDEO_FAIL_ENCODE_EVENT: FAIL_ENCODE}
+
+func StringToWebhookEvent(s string) (WebhookEvent, error) {
+	event := WebhookEvent(strings.ToLower(s))
+	if _, ok := webhookEventStatuses[event]; !ok {
+		return "", fmt.Errorf("unknown webhook event %v", s)
+	}
+	return event, nil
+}
+
+// WebhookEventOf returns the webhook event of a change of a video, false when it is not notified.
+// Only the ends of the encodings are notified, not the unarchiving of a complete video.
+func WebhookEventOf(event *VideoEvent) (WebhookEvent, bool) {
+	if event.Kind != STATUS_EVENT || event.PreviousStatus != ENCODING {
+		return "", false
+	}
+	for webhookEvent, status := range webhookEventStatuses {
+		if event.Video.Status == status {
+			return webhookEvent, true
+		}
+	}
+	return "", false
+}
+
+// Webhook is an URL notified of the events it subscribed to. The secret signs the notifications.
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    string
+	Events    []WebhookEvent
+	CreatedAt *time.Time
+}
+
+// Subscribed returns whether the webhook is notified of the event
+func (w *Webhook) Subscribed(event WebhookEvent) bool {
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an attempt to notify a webhook. The attempts of a notification share their DeliveryID.
+type WebhookDelivery struct {
+	ID         string
+	WebhookID  string
+	DeliveryID string
+	Event      WebhookEvent
+	VideoID    string
+	Attempt    int
+	// Nil when the webhook did not answer
+	StatusCode *int
+	Error      *string
+	CreatedAt  *time.Time
+}
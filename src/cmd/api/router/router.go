@@ -9,20 +9,24 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/goji/httpauth"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/oidc"
+	"github.com/Sogilis/Voogle/src/pkg/streamtoken"
 
 	"github.com/Sogilis/Voogle/src/cmd/api/config"
 	"github.com/Sogilis/Voogle/src/cmd/api/controllers"
 	"github.com/Sogilis/Voogle/src/cmd/api/db/dao"
 	_ "github.com/Sogilis/Voogle/src/cmd/api/docs"
+	"github.com/Sogilis/Voogle/src/cmd/api/eventhandler"
 	"github.com/Sogilis/Voogle/src/cmd/api/metrics"
+	"github.com/Sogilis/Voogle/src/cmd/api/models"
 )
 
 type Clients struct {
@@ -31,11 +35,26 @@ type Clients struct {
 	AmqpVideoStatusUpdate clients.AmqpClient
 	ServiceDiscovery      clients.ServiceDiscovery
 	UUIDGen               clients.IUUIDGenerator
+	VideoProber           clients.IVideoProber
+	// Events of the videos, received from every API instance
+	VideoEvents *eventhandler.Hub
+	// Nil when the single sign-on is disabled
+	OIDCVerifier *oidc.Verifier
 }
 type DAOs struct {
-	Db         *sql.DB
-	VideosDAO  dao.VideosDAO
-	UploadsDAO dao.UploadsDAO
+	Db                   *sql.DB
+	VideosDAO            dao.VideosDAO
+	UploadsDAO           dao.UploadsDAO
+	StorageUsagesDAO     dao.StorageUsagesDAO
+	SubtitlesDAO         dao.SubtitlesDAO
+	ClipsDAO             dao.ClipsDAO
+	RendersDAO           dao.RendersDAO
+	UsersDAO             dao.UsersDAO
+	SessionsDAO          dao.SessionsDAO
+	VideoSharesDAO       dao.VideoSharesDAO
+	ApiKeysDAO           dao.ApiKeysDAO
+	WebhooksDAO          dao.WebhooksDAO
+	WebhookDeliveriesDAO dao.WebhookDeliveriesDAO
 }
 
 type responseWriter struct {
@@ -57,32 +76,107 @@ func NewRouter(config config.Config, clients *Clients, DAOs *DAOs) http.Handler
 	r := mux.NewRouter()
 	r.Use(prometheusMiddleware)
 
-	r.PathPrefix("/ws").Handler(controllers.WSHandler{Config: config, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate}).Methods("GET")
+	authenticator := controllers.Authenticator{
+		Config:       config,
+		SessionsDAO:  &DAOs.SessionsDAO,
+		UsersDAO:     &DAOs.UsersDAO,
+		ApiKeysDAO:   &DAOs.ApiKeysDAO,
+		UUIDGen:      clients.UUIDGen,
+		OIDCVerifier: clients.OIDCVerifier,
+	}
+
+	// Authenticated before the upgrade, so that a failure is answered with a status code
+	wsAuth := authMiddleware(authenticator, controllers.WebsocketAuthorization)
+	r.PathPrefix("/ws").Handler(wsAuth(controllers.WSHandler{AllowedOrigins: config.WSAllowedOrigins, VideoEvents: clients.VideoEvents, VideosDAO: &DAOs.VideosDAO, VideoSharesDAO: &DAOs.VideoSharesDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
 
 	r.PathPrefix("/metrics").Handler(promhttp.Handler()).Methods("GET", "POST")
 	r.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 
 	r.PathPrefix("/health").Handler(controllers.HealthComponentHandler{}).Methods("GET")
-	r.PathPrefix("/videos/{id}/streams/master.m3u8").Handler(controllers.VideoGetMasterHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}).Methods("GET")
-	r.PathPrefix("/videos/{id}/streams/source.mp4").Handler(controllers.VideoGetSourceHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
-	r.PathPrefix("/videos/{id}/streams/{quality}/{filename}").Handler(controllers.VideoGetSubPartHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery}).Methods("GET")
-	r.PathPrefix("/videos/{id}/subtitles/{filename}").Handler(controllers.VideoGetSubtitlesHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery}).Methods("GET")
-	r.PathPrefix("/videos/{id}/cover").Handler(controllers.VideoCoverHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET", "HEAD")
+	// Playback routes used without authentication (players, img tags, CDN) require a stream token
+	streamSigner := streamtoken.NewSigner([]byte(config.StreamTokenSecret), config.StreamTokenTTL, config.StreamTokenKeyRotation, config.StreamTokenBindIP)
+	public := r.PathPrefix("/videos/{id}").Subrouter()
+	// The tokens of the videos lists only open the cover and the preview
+	streamToken := streamTokenMiddleware(streamSigner, false)
+	previewToken := streamTokenMiddleware(streamSigner, true)
+
+	public.PathPrefix("/streams/master.m3u8").Handler(streamToken(controllers.VideoGetMasterHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+	public.Path("/streams/manifest.mpd").Handler(streamToken(controllers.VideoGetManifestHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+	public.PathPrefix("/streams/source.mp4").Handler(streamToken(controllers.VideoGetSourceHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	public.PathPrefix("/streams/{quality}/{filename}").Handler(streamToken(controllers.VideoGetSubPartHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery})).Methods("GET", "HEAD")
+	public.Path("/subtitles/{subtitleID}/playlist.m3u8").Handler(streamToken(controllers.VideoGetSubtitlePlaylistHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+	public.Path("/subtitles/{subtitleID}/track.vtt").Handler(streamToken(controllers.VideoGetSubtitleTrackHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+	public.PathPrefix("/subtitles/{filename}").Handler(streamToken(controllers.VideoGetSubtitlesHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery})).Methods("GET", "HEAD")
+	public.Path("/thumbnails/{filename}").Handler(streamToken(controllers.VideoGetThumbnailsHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+	public.Path("/preview").Handler(previewToken(controllers.VideoGetPreviewHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+	public.PathPrefix("/cover").Handler(previewToken(controllers.VideoCoverHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen})).Methods("GET", "HEAD")
+
+	// Registered before the v1 subrouter, browsers cannot set headers on event sources either
+	r.Path("/api/v1/events").Handler(wsAuth(viewer(controllers.EventsHandler{VideoEvents: clients.VideoEvents, VideosDAO: &DAOs.VideosDAO, VideoSharesDAO: &DAOs.VideoSharesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+
+	// Registered before the v1 subrouter to be reachable without authentication
+	r.Path("/api/v1/login").Handler(controllers.LoginHandler{Config: config, UsersDAO: &DAOs.UsersDAO, SessionsDAO: &DAOs.SessionsDAO}).Methods("POST")
 
 	v1 := r.PathPrefix("/api/v1").Subrouter()
-	v1.Use(httpauth.SimpleBasicAuth(config.UserAuth, config.PwdAuth))
-
-	v1.PathPrefix("/videos/{id}/streams/master.m3u8").Handler(controllers.VideoGetMasterHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}).Methods("GET")
-	v1.PathPrefix("/videos/{id}/streams/{quality}/{filename}").Handler(controllers.VideoGetSubPartHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery}).Methods("GET")
-	v1.PathPrefix("/videos/{id}/edit").Handler(controllers.VideoEditDataHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery, VideosDAO: &DAOs.VideosDAO}).Methods("POST")
-	v1.PathPrefix("/videos/transformer/list").Handler(controllers.VideoTransformerListHandler{ServiceDiscovery: clients.ServiceDiscovery}).Methods("GET")
-	v1.PathPrefix("/videos/list/{attribute}/{order}/{page}/{limit}/{status}").Handler(controllers.VideosListHandler{VideosDAO: &DAOs.VideosDAO}).Methods("GET")
-	v1.PathPrefix("/videos/{id}/delete").Handler(controllers.VideoDeleteHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen}).Methods("DELETE")
-	v1.PathPrefix("/videos/{id}/archive").Handler(controllers.VideoArchiveHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("PUT")
-	v1.PathPrefix("/videos/{id}/unarchive").Handler(controllers.VideoUnarchiveHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("PUT")
-	v1.PathPrefix("/videos/{id}/info").Handler(controllers.VideoGetInfoHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
-	v1.PathPrefix("/videos/upload").Handler(controllers.VideoUploadHandler{S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen}).Methods("POST")
-	v1.PathPrefix("/videos/{id}/status").Handler(controllers.VideoGetStatusHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}).Methods("GET")
+	v1.Use(authMiddleware(authenticator, headerAuthorization))
+
+	viewVideo := func(handler http.Handler) http.Handler {
+		return controllers.RequireVideoAccess(controllers.VIEW_VIDEO, &DAOs.VideosDAO, &DAOs.VideoSharesDAO, clients.UUIDGen, handler)
+	}
+	manageVideo := func(handler http.Handler) http.Handler {
+		return controllers.RequireVideoAccess(controllers.MANAGE_VIDEO, &DAOs.VideosDAO, &DAOs.VideoSharesDAO, clients.UUIDGen, handler)
+	}
+	deriveVideo := func(handler http.Handler) http.Handler {
+		return controllers.RequireVideoAccess(controllers.DERIVE_VIDEO, &DAOs.VideosDAO, &DAOs.VideoSharesDAO, clients.UUIDGen, handler)
+	}
+
+	v1.Path("/logout").Handler(controllers.LogoutHandler{SessionsDAO: &DAOs.SessionsDAO}).Methods("POST")
+	v1.Path("/users/me").Handler(viewer(controllers.UserGetMeHandler{})).Methods("GET")
+	v1.Path("/users").Handler(admin(controllers.UsersListHandler{UsersDAO: &DAOs.UsersDAO})).Methods("GET")
+	v1.Path("/users").Handler(admin(controllers.UserCreateHandler{UsersDAO: &DAOs.UsersDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/users/{userID}").Handler(admin(controllers.UserGetHandler{UsersDAO: &DAOs.UsersDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+	v1.Path("/users/{userID}").Handler(admin(controllers.UserUpdateHandler{UsersDAO: &DAOs.UsersDAO, SessionsDAO: &DAOs.SessionsDAO, UUIDGen: clients.UUIDGen})).Methods("PUT")
+	v1.Path("/users/{userID}").Handler(admin(controllers.UserDeleteHandler{UsersDAO: &DAOs.UsersDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.Path("/apikeys").Handler(viewer(controllers.ApiKeysListHandler{ApiKeysDAO: &DAOs.ApiKeysDAO})).Methods("GET")
+	v1.Path("/apikeys").Handler(viewer(controllers.ApiKeyCreateHandler{ApiKeysDAO: &DAOs.ApiKeysDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/apikeys/{keyID}").Handler(viewer(controllers.ApiKeyDeleteHandler{ApiKeysDAO: &DAOs.ApiKeysDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.Path("/webhooks").Handler(admin(controllers.WebhooksListHandler{WebhooksDAO: &DAOs.WebhooksDAO})).Methods("GET")
+	v1.Path("/webhooks").Handler(admin(controllers.WebhookCreateHandler{WebhooksDAO: &DAOs.WebhooksDAO, UUIDGen: clients.UUIDGen})).Methods("POST")
+	v1.Path("/webhooks/{webhookID}").Handler(admin(controllers.WebhookDeleteHandler{WebhooksDAO: &DAOs.WebhooksDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.Path("/webhooks/{webhookID}/deliveries").Handler(admin(controllers.WebhookDeliveriesHandler{WebhooksDAO: &DAOs.WebhooksDAO, WebhookDeliveriesDAO: &DAOs.WebhookDeliveriesDAO, UUIDGen: clients.UUIDGen})).Methods("GET")
+
+	v1.PathPrefix("/videos/{id}/streams/master.m3u8").Handler(viewer(viewVideo(controllers.VideoGetMasterHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.Path("/videos/{id}/streams/manifest.mpd").Handler(viewer(viewVideo(controllers.VideoGetManifestHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.PathPrefix("/videos/{id}/streams/{quality}/{filename}").Handler(viewer(viewVideo(controllers.VideoGetSubPartHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery}))).Methods("GET", "HEAD")
+	v1.PathPrefix("/videos/{id}/edit").Handler(uploader(manageVideo(controllers.VideoEditDataHandler{S3Client: clients.S3Client, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, UUIDGen: clients.UUIDGen, ServiceDiscovery: clients.ServiceDiscovery, VideosDAO: &DAOs.VideosDAO, SubtitlesDAO: &DAOs.SubtitlesDAO}))).Methods("POST")
+	v1.Path("/videos/{id}/subtitles/{subtitleID}/playlist.m3u8").Handler(viewer(viewVideo(controllers.VideoGetSubtitlePlaylistHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.Path("/videos/{id}/subtitles/{subtitleID}/track.vtt").Handler(viewer(viewVideo(controllers.VideoGetSubtitleTrackHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.Path("/videos/{id}/subtitles/{subtitleID}").Handler(viewer(viewVideo(controllers.VideoSubtitleGetHandler{SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+	v1.Path("/videos/{id}/subtitles/{subtitleID}").Handler(uploader(manageVideo(controllers.VideoSubtitleUpdateHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("PUT")
+	v1.Path("/videos/{id}/subtitles/{subtitleID}").Handler(uploader(manageVideo(controllers.VideoSubtitleDeleteHandler{S3Client: clients.S3Client, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("DELETE")
+	v1.Path("/videos/{id}/subtitles").Handler(viewer(viewVideo(controllers.VideoSubtitlesListHandler{VideosDAO: &DAOs.VideosDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+	v1.Path("/videos/{id}/subtitles").Handler(uploader(manageVideo(controllers.VideoSubtitlesCreateHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen}))).Methods("POST")
+	v1.Path("/videos/{id}/clips").Handler(viewer(viewVideo(controllers.VideoClipsListHandler{VideosDAO: &DAOs.VideosDAO, ClipsDAO: &DAOs.ClipsDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+	v1.Path("/videos/{id}/clips").Handler(uploader(deriveVideo(controllers.VideoClipsCreateHandler{AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, ClipsDAO: &DAOs.ClipsDAO, UUIDGen: clients.UUIDGen}))).Methods("POST")
+	v1.Path("/videos/{id}/renders").Handler(viewer(viewVideo(controllers.VideoRendersListHandler{VideosDAO: &DAOs.VideosDAO, RendersDAO: &DAOs.RendersDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+	v1.Path("/videos/{id}/render").Handler(uploader(deriveVideo(controllers.VideoRenderCreateHandler{AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, RendersDAO: &DAOs.RendersDAO, UUIDGen: clients.UUIDGen}))).Methods("POST")
+	v1.Path("/videos/{id}/visibility").Handler(uploader(manageVideo(controllers.VideoVisibilityHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}))).Methods("PUT")
+	v1.Path("/videos/{id}/shares").Handler(uploader(manageVideo(controllers.VideoSharesListHandler{VideoSharesDAO: &DAOs.VideoSharesDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+	v1.Path("/videos/{id}/shares/{userID}").Handler(uploader(manageVideo(controllers.VideoShareCreateHandler{UsersDAO: &DAOs.UsersDAO, VideoSharesDAO: &DAOs.VideoSharesDAO, UUIDGen: clients.UUIDGen}))).Methods("PUT")
+	v1.Path("/videos/{id}/shares/{userID}").Handler(uploader(manageVideo(controllers.VideoShareDeleteHandler{VideoSharesDAO: &DAOs.VideoSharesDAO, UUIDGen: clients.UUIDGen}))).Methods("DELETE")
+	v1.PathPrefix("/videos/transformer/list").Handler(viewer(controllers.VideoTransformerListHandler{ServiceDiscovery: clients.ServiceDiscovery})).Methods("GET")
+	v1.PathPrefix("/videos/list/{attribute}/{order}/{page}/{limit}/{status}").Handler(viewer(controllers.VideosListHandler{VideosDAO: &DAOs.VideosDAO, StreamSigner: streamSigner})).Methods("GET")
+	v1.PathPrefix("/videos/{id}/delete").Handler(admin(controllers.VideoDeleteHandler{S3Client: clients.S3Client, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, UUIDGen: clients.UUIDGen})).Methods("DELETE")
+	v1.PathPrefix("/videos/{id}/archive").Handler(uploader(manageVideo(controllers.VideoArchiveHandler{AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}))).Methods("PUT")
+	v1.PathPrefix("/videos/{id}/unarchive").Handler(uploader(manageVideo(controllers.VideoUnarchiveHandler{AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}))).Methods("PUT")
+	v1.Path("/videos/{id}/thumbnails/{filename}").Handler(viewer(viewVideo(controllers.VideoGetThumbnailsHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.Path("/videos/{id}/preview").Handler(viewer(viewVideo(controllers.VideoGetPreviewHandler{S3Client: clients.S3Client, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.PathPrefix("/videos/{id}/cover").Handler(viewer(viewVideo(controllers.VideoCoverHandler{S3Client: clients.S3Client, VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}))).Methods("GET", "HEAD")
+	v1.Path("/videos/{id}/playback").Handler(viewer(viewVideo(controllers.VideoGetPlaybackHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen, StreamSigner: streamSigner}))).Methods("GET")
+	v1.PathPrefix("/videos/{id}/info").Handler(viewer(viewVideo(controllers.VideoGetInfoHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
+	v1.PathPrefix("/videos/upload/batch").Handler(upload(controllers.VideoBatchUploadHandler{Config: config, S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, StorageUsagesDAO: &DAOs.StorageUsagesDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen, VideoProber: clients.VideoProber})).Methods("POST")
+	v1.PathPrefix("/videos/upload").Handler(upload(controllers.VideoUploadHandler{Config: config, S3Client: clients.S3Client, AmqpClient: clients.AmqpClient, AmqpVideoStatusUpdate: clients.AmqpVideoStatusUpdate, VideosDAO: &DAOs.VideosDAO, UploadsDAO: &DAOs.UploadsDAO, StorageUsagesDAO: &DAOs.StorageUsagesDAO, SubtitlesDAO: &DAOs.SubtitlesDAO, UUIDGen: clients.UUIDGen, VideoProber: clients.VideoProber})).Methods("POST")
+	v1.PathPrefix("/videos/{id}/status").Handler(viewer(viewVideo(controllers.VideoGetStatusHandler{VideosDAO: &DAOs.VideosDAO, UUIDGen: clients.UUIDGen}))).Methods("GET")
 
 	return handlers.CORS(getCORS())(r)
 }
@@ -90,7 +184,7 @@ func NewRouter(config config.Config, clients *Clients, DAOs *DAOs) http.Handler
 func getCORS() (handlers.CORSOption, handlers.CORSOption, handlers.CORSOption, handlers.CORSOption) {
 	corsObj := handlers.AllowedOrigins([]string{"*"})
 	methods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "OPTIONS", "DELETE", "HEAD"})
-	headers := handlers.AllowedHeaders([]string{"Authorization"})
+	headers := handlers.AllowedHeaders([]string{"Authorization", "Content-Type"})
 	credentials := handlers.AllowCredentials()
 
 	return corsObj, methods, headers, credentials
@@ -106,6 +200,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush lets the handlers stream their response, as the server-sent events
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	h, ok := rw.ResponseWriter.(http.Hijacker)
 	if !ok {
@@ -114,6 +215,83 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return h.Hijack()
 }
 
+// authMiddleware only lets through the requests of an authenticated user, and puts it in their context.
+// The credentials are read by authorization.
+func authMiddleware(authenticator controllers.Authenticator, authorization func(r *http.Request) string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, apiKey, err := authenticator.Authenticate(r.Context(), authorization(r))
+			if err != nil {
+				log.Error("Cannot authenticate request : ", err)
+				statusCode := controllers.AuthenticationStatusCode(err)
+				if statusCode == http.StatusUnauthorized {
+					w.Header().Add("WWW-Authenticate", `Bearer realm="Restricted"`)
+					w.Header().Add("WWW-Authenticate", `Basic realm="Restricted"`)
+				}
+				w.WriteHeader(statusCode)
+				return
+			}
+
+			ctx := controllers.ContextWithUser(r.Context(), user)
+			if apiKey != nil {
+				ctx = controllers.ContextWithApiKey(ctx, apiKey)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func headerAuthorization(r *http.Request) string {
+	return r.Header.Get("Authorization")
+}
+
+// The API keys of every scope reach the read routes
+func viewer(handler http.Handler) http.Handler {
+	return controllers.RequireRole(models.VIEWER, handler)
+}
+
+// upload routes are opened to the API keys of the upload scope, the other uploader routes are not
+func upload(handler http.Handler) http.Handler {
+	return controllers.RequireRole(models.UPLOADER, controllers.RequireScope(models.UPLOAD_SCOPE, handler))
+}
+
+func uploader(handler http.Handler) http.Handler {
+	return controllers.RequireRole(models.UPLOADER, controllers.RequireScope(models.ADMIN_SCOPE, handler))
+}
+
+func admin(handler http.Handler) http.Handler {
+	return controllers.RequireRole(models.ADMIN, controllers.RequireScope(models.ADMIN_SCOPE, handler))
+}
+
+// streamTokenMiddleware only lets through the requests carrying a valid stream token for their video.
+// The tokens bound to the previews, given by the videos lists, are only accepted with previewRoute.
+func streamTokenMiddleware(signer *streamtoken.Signer, previewRoute bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get(streamtoken.QueryParam)
+			if token == "" {
+				log.Error("Missing stream token")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			// The watermark the token was given with cannot be removed nor changed
+			watermark := controllers.WatermarkQuery(r.URL.Query())
+			err := signer.Verify(token, mux.Vars(r)["id"], streamtoken.ClientIP(r), watermark)
+			if err != nil && previewRoute {
+				err = signer.Verify(token, mux.Vars(r)["id"], streamtoken.ClientIP(r), controllers.PreviewTokenBinding)
+			}
+			if err != nil {
+				log.Error("Invalid stream token : ", err)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func prometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		route := mux.CurrentRoute(r)
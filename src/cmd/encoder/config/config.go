@@ -1,7 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/caarlos0/env/v6"
+
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
 )
 
 type Config struct {
@@ -16,6 +21,42 @@ type Config struct {
 	RabbitmqAddr string `env:"RABBITMQ_ADDR,required"`
 	RabbitmqUser string `env:"RABBITMQ_USER,required"`
 	RabbitmqPwd  string `env:"RABBITMQ_PWD,required"`
+
+	// Time between two seek-preview thumbnails, 0 disables them
+	ThumbnailsInterval time.Duration `env:"THUMBNAILS_INTERVAL" envDefault:"5s"`
+
+	// Codecs of the lower renditions (h264, hevc, av1), H.264 is always encoded
+	EncodingCodecs []string `env:"ENCODING_CODECS" envSeparator:"," envDefault:"h264"`
+
+	// Audio stage: copy or aac, downmix to stereo and EBU R128 loudness normalization (LUFS target)
+	AudioCodec     string  `env:"AUDIO_CODEC" envDefault:"copy"`
+	AudioBitrate   string  `env:"AUDIO_BITRATE" envDefault:"128k"`
+	AudioDownmix   bool    `env:"AUDIO_DOWNMIX" envDefault:"false"`
+	AudioLoudnorm  bool    `env:"AUDIO_LOUDNORM" envDefault:"false"`
+	LoudnessTarget float64 `env:"LOUDNESS_TARGET" envDefault:"-23"`
+}
+
+// EncodingProfile returns the encoding profile described by the configuration
+func (c Config) EncodingProfile() (ffmpeg.EncodingProfile, error) {
+	codecs, err := ffmpeg.ParseEncodingCodecs(c.EncodingCodecs)
+	if err != nil {
+		return ffmpeg.EncodingProfile{}, err
+	}
+
+	if c.AudioCodec != "copy" && c.AudioCodec != "aac" {
+		return ffmpeg.EncodingProfile{}, fmt.Errorf("Unknown audio codec %v, expected copy or aac", c.AudioCodec)
+	}
+
+	return ffmpeg.EncodingProfile{
+		Codecs: codecs,
+		Audio: ffmpeg.AudioProfile{
+			Transcode:      c.AudioCodec == "aac",
+			Bitrate:        c.AudioBitrate,
+			Downmix:        c.AudioDownmix,
+			Loudnorm:       c.AudioLoudnorm,
+			TargetLoudness: c.LoudnessTarget,
+		},
+	}, nil
 }
 
 func NewConfig() (Config, error) {
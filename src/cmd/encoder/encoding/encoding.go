@@ -2,20 +2,25 @@ package encoding
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
 	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+
+	"github.com/Sogilis/Voogle/src/cmd/encoder/config"
 )
 
-// Process input video into a HLS video
-func Process(s3Client clients.IS3Client, videoData *contracts.Video) error {
+// Process input video into a HLS video. progress is called with the share of the processing done, between 0
+// and 1, at the end of each step.
+func Process(cfg config.Config, s3Client clients.IS3Client, videoData *contracts.Video, progress func(float64)) error {
 	// Going to the working directory
 	processingFolder := filepath.Join(os.TempDir(), "/encoder-processing-dir")
 	if err := os.MkdirAll(processingFolder, os.ModePerm); err != nil {
@@ -30,20 +35,50 @@ func Process(s3Client clients.IS3Client, videoData *contracts.Video) error {
 		_ = os.RemoveAll(processingFolder)
 	}()
 
-	// Download and write the source file on the filesystem
-	err := fetchVideoSource(s3Client, videoData)
+	// Download and write the source file on the filesystem, clips and rendered videos derive it from their parent source
+	var err error
+	switch {
+	case videoData.GetClip() != nil:
+		err = cutClipSource(s3Client, videoData)
+	case videoData.GetRender() != nil:
+		err = renderSource(s3Client, videoData)
+	default:
+		err = fetchVideoSource(s3Client, videoData)
+	}
 	if err != nil {
 		log.Error("Failed to fetch video source")
 		return err
 	}
+	progress(0.1)
 
 	// Video processing
 	// Some video doesn't contains audio and HLS can't handle it, so we add an empty track
-	err = encode(videoData)
+	// Embedded text subtitles are extracted as WebVTT
+	err = encode(videoData, cfg)
 	if err != nil {
 		log.Error("Failed to encode video")
 		return err
 	}
+	progress(0.7)
+
+	// Cover, seek previews and preview clip are not mandatory, the video is still playable without them
+	probe, err := ffmpeg.ProbeVideo(context.Background(), filepath.Base(videoData.GetSource()), nil)
+	if err != nil {
+		log.Error("Failed to probe video : ", err)
+	}
+
+	if err := generateCover(s3Client, videoData, probe); err != nil {
+		log.Error("Failed to generate video cover : ", err)
+	}
+
+	if err := generateThumbnails(videoData, probe, cfg.ThumbnailsInterval); err != nil {
+		log.Error("Failed to generate thumbnails : ", err)
+	}
+
+	if err := generatePreview(videoData, probe); err != nil {
+		log.Error("Failed to generate preview clip : ", err)
+	}
+	progress(0.9)
 
 	log.Info("Processing of video ", videoData.GetId(), "done - Uploading to S3")
 	// Uploading files to the S3
@@ -57,11 +92,16 @@ func Process(s3Client clients.IS3Client, videoData *contracts.Video) error {
 }
 
 func fetchVideoSource(s3Client clients.IS3Client, videoData *contracts.Video) error {
-	source, err := s3Client.GetObject(context.Background(), videoData.GetSource())
+	return fetchFile(s3Client, videoData.GetSource(), filepath.Base(videoData.GetSource()))
+}
+
+// fetchFile downloads an S3 object into the processing folder
+func fetchFile(s3Client clients.IS3Client, key, filename string) error {
+	source, err := s3Client.GetObject(context.Background(), key)
 	if err != nil {
 		return err
 	}
-	f, err := os.Create(filepath.Base(videoData.GetSource()))
+	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
@@ -71,28 +111,146 @@ func fetchVideoSource(s3Client clients.IS3Client, videoData *contracts.Video) er
 	return f.Close()
 }
 
-func encode(data *contracts.Video) error {
+// cutClipSource cuts the source of a clip from its parent source
+func cutClipSource(s3Client clients.IS3Client, videoData *contracts.Video) error {
+	clip := videoData.GetClip()
+	return deriveSource(s3Client, videoData, clip.GetParentSource(), func(parentfile, sourcefile string) error {
+		return ffmpeg.CutClip(parentfile, sourcefile, clip.GetStart(), clip.GetEnd(), clip.GetAccurate())
+	})
+}
+
+// renderSource burns the transformers in a copy of the parent source
+func renderSource(s3Client clients.IS3Client, videoData *contracts.Video) error {
+	render := videoData.GetRender()
+	return deriveSource(s3Client, videoData, render.GetParentSource(), func(parentfile, sourcefile string) error {
+		return ffmpeg.RenderTransformations(parentfile, sourcefile, render.GetFilters())
+	})
+}
+
+// deriveSource builds the source of the video from its parent source. It is uploaded as
+// the video source, so that the video does not depend on its parent once encoded.
+func deriveSource(s3Client clients.IS3Client, videoData *contracts.Video, parentSource string, derive func(parentfile, sourcefile string) error) error {
+	parentfile := "parent" + filepath.Ext(parentSource)
+	if err := fetchFile(s3Client, parentSource, parentfile); err != nil {
+		return err
+	}
+
+	sourcefile := filepath.Base(videoData.GetSource())
+	if err := derive(parentfile, sourcefile); err != nil {
+		return err
+	}
+	if err := os.Remove(parentfile); err != nil {
+		log.Error("Failed to remove parent source : ", err)
+	}
+
+	f, err := os.Open(sourcefile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s3Client.PutObjectInput(context.Background(), f, videoData.GetSource())
+}
+
+func encode(data *contracts.Video, cfg config.Config) error {
+	profile, err := cfg.EncodingProfile()
+	if err != nil {
+		return err
+	}
+
 	sourcefile := filepath.Base(data.GetSource())
 
-	//withSound, err := ffmpeg.CheckContainsSound(sourcefile)
-	withSound := true
-	//if err != nil {
-	//	return err
-	//}
-	if !withSound {
+	streams, err := ffmpeg.ProbeStreams(sourcefile)
+	if err != nil {
+		return err
+	}
+
+	// Subtitles are extracted first as adding an audio track drops the other streams
+	data.Subtitles = extractSubtitles(sourcefile, data.GetId(), ffmpeg.FilterStreams(streams, ffmpeg.SubtitleStream))
+
+	audio := ffmpeg.FilterStreams(streams, ffmpeg.AudioStream)
+	if len(audio) == 0 {
 		if err := ffmpeg.AddEmptyAudioTrack(sourcefile); err != nil {
 			return err
 		}
+		audio = []ffmpeg.MediaStream{{CodecType: ffmpeg.AudioStream}}
 	}
 
+	measureLoudness(data, sourcefile, audio, profile.Audio)
+
 	res, err := ffmpeg.ExtractResolution(sourcefile)
 	if err != nil {
 		return err
 	}
-	if err = ffmpeg.ConvertToHLS(sourcefile, res); err != nil {
+	if err = ffmpeg.ConvertToHLS(sourcefile, res, audio, profile); err != nil {
 		return err
 	}
-	return nil
+
+	// The stream copy keeps the source codec, players need the actual ones to pick a variant
+	if err := ffmpeg.WriteMasterCodecs("."); err != nil {
+		log.Error("Failed to write the codecs of the master : ", err)
+	}
+
+	// The DASH manifest shares the fMP4 segments of the HLS renditions
+	return ffmpeg.GenerateDASHManifest(".")
+}
+
+// measureLoudness measures the audio tracks, for the loudness normalization, and reports the loudness of the default one.
+// A track that cannot be measured, like the silent one added to videos without audio, is not normalized.
+func measureLoudness(data *contracts.Video, sourcefile string, audio []ffmpeg.MediaStream, profile ffmpeg.AudioProfile) {
+	for i := range audio {
+		loudness, err := ffmpeg.MeasureLoudness(sourcefile, i, profile)
+		if err != nil {
+			log.Info("Cannot measure the loudness of audio track ", i, " : ", err)
+			continue
+		}
+		audio[i].Loudness = &loudness
+	}
+
+	if len(audio) > 0 {
+		if loudness := audio[ffmpeg.DefaultAudioTrack(audio)].Loudness; loudness != nil {
+			data.Loudness = &loudness.Integrated
+		}
+	}
+}
+
+// extractSubtitles converts the text subtitle streams into WebVTT files, uploaded along the HLS files.
+// A track that cannot be extracted is skipped, it must not fail the encoding.
+func extractSubtitles(sourcefile, videoID string, streams []ffmpeg.MediaStream) []*contracts.Subtitle {
+	extracted := []*contracts.Subtitle{}
+	for i, stream := range streams {
+		if !stream.IsTextSubtitle() {
+			log.Info("Skipping subtitle stream ", stream.Index, " : ", stream.CodecName, " can not be converted to WebVTT")
+			continue
+		}
+
+		if err := os.MkdirAll("subtitles", os.ModePerm); err != nil {
+			log.Error("Cannot create subtitles folder : ", err)
+			return extracted
+		}
+
+		path := fmt.Sprintf("subtitles/embedded_%d.vtt", i)
+		if err := ffmpeg.ExtractSubtitles(sourcefile, stream, path); err != nil {
+			log.Error("Cannot extract subtitle stream ", stream.Index, " : ", err)
+			continue
+		}
+
+		label := stream.Title
+		if label == "" {
+			label = stream.Language
+		}
+		if label == "" {
+			label = fmt.Sprintf("Subtitles %d", i+1)
+		}
+
+		extracted = append(extracted, &contracts.Subtitle{
+			Path:     videoID + "/" + path,
+			Language: stream.Language,
+			Label:    label,
+			Default:  stream.Default,
+			Forced:   stream.Forced,
+		})
+	}
+	return extracted
 }
 
 func fetchCoverSource(s3Client clients.IS3Client, videoData *contracts.Video) (isFileFetch bool, err error) {
@@ -115,13 +273,58 @@ func fetchCoverSource(s3Client clients.IS3Client, videoData *contracts.Video) (i
 	return true, f.Close()
 }
 
+// generateCover resizes the uploaded cover, or a frame of the video when there is none
+func generateCover(s3Client clients.IS3Client, videoData *contracts.Video, probe ffmpeg.VideoProbe) error {
+	isFileFetch, err := fetchCoverSource(s3Client, videoData)
+	if err != nil {
+		log.Error("Failed to fetch cover source : ", err)
+		isFileFetch = false
+	}
+
+	cover := filepath.Base(videoData.GetCoverPath())
+	if !isFileFetch {
+		cover = "cover.jpeg"
+		if err := ffmpeg.ExtractCover(filepath.Base(videoData.GetSource()), probe.Duration, cover); err != nil {
+			return err
+		}
+		videoData.CoverPath = videoData.GetId() + "/" + cover
+	}
+
+	return ffmpeg.GenerateCoverVariants(cover)
+}
+
+// generateThumbnails creates the seek-preview sprite sheets and their WebVTT track
+func generateThumbnails(videoData *contracts.Video, probe ffmpeg.VideoProbe, interval time.Duration) error {
+	if interval <= 0 {
+		log.Debug("Thumbnails disabled")
+		return nil
+	}
+
+	sprites, err := ffmpeg.NewThumbnailsSprites(interval, probe)
+	if err != nil {
+		return err
+	}
+
+	return sprites.Generate(filepath.Base(videoData.GetSource()))
+}
+
+// generatePreview creates the muted clip shown when hovering the video in the catalogue
+func generatePreview(videoData *contracts.Video, probe ffmpeg.VideoProbe) error {
+	// Without duration, the whole video would be encoded again
+	if probe.Duration <= 0 {
+		return fmt.Errorf("unknown duration of video %v", videoData.GetId())
+	}
+
+	return ffmpeg.GeneratePreview(filepath.Base(videoData.GetSource()), probe.Duration, ffmpeg.PreviewFile)
+}
+
 func uploadFiles(s3Client clients.IS3Client, data *contracts.Video) error {
 	err := filepath.WalkDir(".",
 		func(path string, info os.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
-			if path == "." || (!strings.HasSuffix(path, ".ts") && !strings.HasSuffix(path, ".m3u8") && !strings.HasSuffix(path, ".m4s") && !strings.HasSuffix(path, ".mp4") && !strings.HasSuffix(path, ".jpeg")) {
+			if path == "." || (!strings.HasSuffix(path, ".ts") && !strings.HasSuffix(path, ".m3u8") && !strings.HasSuffix(path, ".mpd") && !strings.HasSuffix(path, ".m4s") && !strings.HasSuffix(path, ".mp4") && !strings.HasSuffix(path, ".jpeg") && !strings.HasSuffix(path, ".jpg") && !strings.HasSuffix(path, ".vtt") && !strings.HasSuffix(path, ".webp")) {
 				log.Debug("Skipping ", path)
 				return nil
 			}
@@ -136,13 +339,5 @@ func uploadFiles(s3Client clients.IS3Client, data *contracts.Video) error {
 		return err
 	}
 
-	// Remove cover image on S3 if needed
-	if _, err = os.Stat("cover.jpeg"); err == nil {
-		err = s3Client.RemoveObject(context.Background(), data.GetCoverPath())
-		if err != nil {
-			return err
-		}
-	}
-
 	return nil
 }
@@ -10,10 +10,11 @@ import (
 	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
 	"github.com/Sogilis/Voogle/src/pkg/events"
 
+	"github.com/Sogilis/Voogle/src/cmd/encoder/config"
 	"github.com/Sogilis/Voogle/src/cmd/encoder/encoding"
 )
 
-func ConsumeEvents(amqpClientVideoUpload clients.AmqpClient, s3Client clients.IS3Client) {
+func ConsumeEvents(cfg config.Config, amqpClientVideoUpload clients.AmqpClient, s3Client clients.IS3Client) {
 	session := amqpClientVideoUpload.WithRedial()
 	failedToAck := make(map[string]interface{})
 	for {
@@ -45,7 +46,9 @@ func ConsumeEvents(amqpClientVideoUpload clients.AmqpClient, s3Client clients.IS
 				err := s3Client.HeadObject(context.Background(), video.Id+"/master.m3u8")
 				if err == nil {
 					log.Info("Video already exists!")
-				} else if err := encoding.Process(s3Client, video); err != nil {
+				} else if err := encoding.Process(cfg, s3Client, video, func(progress float64) {
+					sendEncodingProgress(video.Id, progress, client)
+				}); err != nil {
 					log.Error("Failed to processing video ", video.Id, " - ", err)
 
 					if err = msg.Acknowledger.Nack(msg.DeliveryTag, false, false); err != nil {
@@ -75,9 +78,7 @@ func ConsumeEvents(amqpClientVideoUpload clients.AmqpClient, s3Client clients.IS
 			}
 
 			// Send updates
-			// Update video status to COMPLETE
-			videoEncoded.Status = contracts.Video_VIDEO_STATUS_COMPLETE
-			if err := sendUpdatedVideoStatus(videoEncoded, client); err != nil {
+			if err := sendCompleteStatus(videoEncoded, video, client); err != nil {
 				log.Error("Error while sending new video status : ", err)
 				continue
 			}
@@ -87,6 +88,28 @@ func ConsumeEvents(amqpClientVideoUpload clients.AmqpClient, s3Client clients.IS
 	}
 }
 
+// sendCompleteStatus updates the video status to COMPLETE, with what the encoding produced for the API to register:
+// the generated cover, the extracted subtitles and the loudness of the default audio track
+func sendCompleteStatus(videoEncoded, video *contracts.Video, amqpC clients.AmqpClient) error {
+	videoEncoded.Status = contracts.Video_VIDEO_STATUS_COMPLETE
+	videoEncoded.Subtitles = video.Subtitles
+	videoEncoded.CoverPath = video.CoverPath
+	videoEncoded.Loudness = video.Loudness
+	return sendUpdatedVideoStatus(videoEncoded, amqpC)
+}
+
+// sendEncodingProgress only logs on failure, the encoding goes on without the progress updates
+func sendEncodingProgress(videoID string, progress float64, amqpC clients.AmqpClient) {
+	videoProgress := &contracts.Video{
+		Id:       videoID,
+		Status:   contracts.Video_VIDEO_STATUS_ENCODING,
+		Progress: &progress,
+	}
+	if err := sendUpdatedVideoStatus(videoProgress, amqpC); err != nil {
+		log.Error("Error while sending encoding progress : ", err)
+	}
+}
+
 func sendUpdatedVideoStatus(video *contracts.Video, amqpC clients.AmqpClient) error {
 	videoData, err := proto.Marshal(video)
 	if err != nil {
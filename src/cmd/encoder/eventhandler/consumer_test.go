@@ -0,0 +1,46 @@
+package eventhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	contracts "github.com/Sogilis/Voogle/src/pkg/contracts/v1"
+	"github.com/Sogilis/Voogle/src/pkg/events"
+)
+
+func Test_sendCompleteStatus(t *testing.T) {
+	loudness := -18.5
+	givenVideo := &contracts.Video{
+		Id:        "video-id",
+		Status:    contracts.Video_VIDEO_STATUS_ENCODING,
+		Source:    "video-id/source.mp4",
+		CoverPath: "video-id/cover.jpg",
+		Subtitles: []*contracts.Subtitle{{Path: "video-id/subtitles/eng.vtt", Language: "eng"}},
+		Loudness:  &loudness,
+	}
+	givenVideoEncoded := &contracts.Video{
+		Id:     givenVideo.Id,
+		Status: contracts.Video_VIDEO_STATUS_ENCODING,
+		Source: givenVideo.Source,
+	}
+
+	var published []byte
+	amqpClient := clients.NewAmqpClientDummy(func(queue string, body []byte) error {
+		require.Equal(t, events.VideoEncoded, queue)
+		published = body
+		return nil
+	}, nil, nil)
+
+	require.NoError(t, sendCompleteStatus(givenVideoEncoded, givenVideo, amqpClient))
+
+	message := &contracts.Video{}
+	require.NoError(t, proto.Unmarshal(published, message))
+	require.Equal(t, contracts.Video_VIDEO_STATUS_COMPLETE, message.Status)
+	require.Equal(t, givenVideo.CoverPath, message.CoverPath)
+	require.Len(t, message.Subtitles, 1)
+	require.NotNil(t, message.Loudness)
+	require.Equal(t, loudness, *message.Loudness)
+}
@@ -4,6 +4,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
 
 	"github.com/Sogilis/Voogle/src/cmd/encoder/config"
 	"github.com/Sogilis/Voogle/src/cmd/encoder/eventhandler"
@@ -19,6 +20,14 @@ func main() {
 	if cfg.DevMode {
 		log.SetLevel(log.DebugLevel)
 	}
+	profile, err := cfg.EncodingProfile()
+	if err != nil {
+		log.Fatal("Invalid encoding profile ", err)
+	}
+	// The codecs of the profile must be supported by the ffmpeg build
+	if err := ffmpeg.CheckEncoders(profile.Codecs); err != nil {
+		log.Fatal("Unsupported encoding profile ", err)
+	}
 
 	// S3 client to access the videos
 	s3Client, err := clients.NewS3Client(cfg.S3Host, cfg.S3Region, cfg.S3Bucket, cfg.S3AuthKey, cfg.S3AuthPwd)
@@ -29,5 +38,5 @@ func main() {
 	amqpClientVideoUpload, _ := clients.NewAmqpClient(cfg.RabbitmqUser, cfg.RabbitmqPwd, cfg.RabbitmqAddr)
 
 	// Listen, consume and publish on amqpClientVideoUpload
-	eventhandler.ConsumeEvents(amqpClientVideoUpload, s3Client)
+	eventhandler.ConsumeEvents(cfg, amqpClientVideoUpload, s3Client)
 }
@@ -55,7 +55,7 @@ func main() {
 		if err != nil {
 			log.Fatal("Fail to get resolution ", err)
 		}
-		targetRes := ffmpeg.Resolution{854, 480, 800000}
+		targetRes := ffmpeg.Resolution{X: 854, Y: 480, Bitrate: 800000}
 		log.Info("Converting ", vid, "...")
 		err = ffmpeg.ConvertToHLSWithDownsample(source, res, targetRes)
 		if err != nil {
@@ -10,7 +10,6 @@ require (
 	github.com/caarlos0/env/v6 v6.10.0
 	github.com/gabriel-vasile/mimetype v1.4.1
 	github.com/getlantern/httptest v0.0.0-20161025015934-4b40f4c7e590
-	github.com/goji/httpauth v0.0.0-20160601135302-2da839ab0f4d
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/websocket v1.5.0
@@ -18,6 +17,7 @@ require (
 	github.com/sirupsen/logrus v1.9.0
 	github.com/stretchr/testify v1.8.0
 	github.com/swaggo/swag v1.8.5
+	golang.org/x/crypto v0.0.0-20220722155217-630584e8d5aa
 	google.golang.org/grpc v1.49.0
 	google.golang.org/protobuf v1.28.1
 )
@@ -23,6 +23,11 @@ func NewS3ClientDummy(listObjects func() ([]string, error), getObject func(strin
 	return s3ClientDummy{listObjects, getObject, nil, putObjectInput, nil, createBucket, removeObject}
 }
 
+// NewS3ClientFullDummy gives objects with their metadata (ETag, LastModified, ...)
+func NewS3ClientFullDummy(getObjectFull func(string) (*s3.GetObjectOutput, error)) IS3Client {
+	return s3ClientDummy{getObjectFull: getObjectFull}
+}
+
 func (s s3ClientDummy) ListObjects(ctx context.Context) ([]string, error) {
 	return s.listObjects()
 }
@@ -35,10 +40,23 @@ func (s s3ClientDummy) GetObjectRange(ctx context.Context, id string, rangeBytes
 }
 
 func (s s3ClientDummy) GetObjectFull(ctx context.Context, id string) (*s3.GetObjectOutput, error) {
+	if s.getObjectFull == nil {
+		// Without metadata, the full object is the one given by getObject
+		object, err := s.getObject(id)
+		if err != nil {
+			return nil, err
+		}
+		return &s3.GetObjectOutput{Body: io.NopCloser(object)}, nil
+	}
 	return s.getObjectFull(id)
 }
 
 func (s s3ClientDummy) HeadObject(ctx context.Context, key string) error {
+	// The full dummy knows its objects
+	if s.headObject == nil && s.getObjectFull != nil {
+		_, err := s.getObjectFull(key)
+		return err
+	}
 	return s.headObject(key)
 }
 
@@ -0,0 +1,34 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+)
+
+type IVideoProber interface {
+	Probe(ctx context.Context, video io.Reader) (ffmpeg.VideoProbe, error)
+}
+
+var _ IVideoProber = &videoProber{}
+
+type videoProber struct{}
+
+func NewVideoProber() IVideoProber {
+	return &videoProber{}
+}
+
+func (p *videoProber) Probe(ctx context.Context, video io.Reader) (ffmpeg.VideoProbe, error) {
+	// Large uploads are already spooled on disk, let ffprobe seek into the file
+	if file, ok := video.(*os.File); ok {
+		return ffmpeg.ProbeVideo(ctx, file.Name(), nil)
+	}
+	// Read through ReadAt when possible, so the caller can still read the video from the beginning
+	if readerAt, ok := video.(io.ReaderAt); ok {
+		video = io.NewSectionReader(readerAt, 0, math.MaxInt64)
+	}
+	return ffmpeg.ProbeVideo(ctx, "", video)
+}
@@ -0,0 +1,25 @@
+package clients
+
+import (
+	"context"
+	"io"
+
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+)
+
+var _ IVideoProber = &videoProberDummy{}
+
+type videoProberDummy struct {
+	probe func(io.Reader) (ffmpeg.VideoProbe, error)
+}
+
+func NewVideoProberDummy(probe func(io.Reader) (ffmpeg.VideoProbe, error)) IVideoProber {
+	return &videoProberDummy{probe}
+}
+
+func (p *videoProberDummy) Probe(ctx context.Context, video io.Reader) (ffmpeg.VideoProbe, error) {
+	if p.probe != nil {
+		return p.probe(video)
+	}
+	return ffmpeg.VideoProbe{}, nil
+}
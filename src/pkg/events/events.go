@@ -4,4 +4,11 @@ const (
 	VideoUploaded string = "video_uploaded_on_S3"
 	VideoEncoded  string = "video_encoded_on_S3"
 	VideoUpdated  string = "video_updated"
+
+	// Routing key of the events on the VideoUpdated exchange, each API instance receives all of them
+	VideoEventKey string = "video_event"
+
+	// Queue of the webhook dispatchers on the VideoUpdated exchange, shared by the API instances so that each
+	// event is notified once
+	WebhooksQueue string = "video_updated_webhooks"
 )
@@ -0,0 +1,44 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CutClip extracts the [start, end] part of the source, in seconds. The stream copy starts on
+// the keyframe preceding start, an accurate cut re-encodes the video to start on the exact frame.
+func CutClip(source, output string, start, end float64, accurate bool) error {
+	args, err := generateClipArgs(source, output, start, end, accurate)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+	}
+	return err
+}
+
+func generateClipArgs(source, output string, start, end float64, accurate bool) ([]string, error) {
+	// ffmpeg -y -ss <start> -i <source> -t <duration> -map 0:V -map 0:a? -map 0:s? -c copy -avoid_negative_ts make_zero <output>
+	// ffmpeg -y -ss <start> -i <source> -t <duration> -map 0:V -map 0:a? -map 0:s? -c:v libx264 -crf 18 -preset fast -c:a aac -b:a 192k -c:s copy <output>
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("Invalid clip boundaries [%.3f, %.3f]", start, end)
+	}
+
+	// Seeking before the input resets the timestamps, the duration is then used rather than the end
+	args := []string{"-y", "-ss", fmt.Sprintf("%.3f", start), "-i", source, "-t", fmt.Sprintf("%.3f", end-start),
+		"-map", "0:V", "-map", "0:a?", "-map", "0:s?"}
+	if accurate {
+		// The clip is a new source, it is encoded with a high quality
+		args = append(args, "-c:v", "libx264", "-crf", "18", "-preset", "fast", "-c:a", "aac", "-b:a", "192k", "-c:s", "copy")
+	} else {
+		args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero")
+	}
+	return append(args, output), nil
+}
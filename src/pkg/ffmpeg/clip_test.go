@@ -0,0 +1,57 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateClipArgs(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenStart    float64
+		GivenEnd      float64
+		GivenAccurate bool
+		ExpectArgs    string
+		ExpectError   bool
+	}{
+		{
+			Name:       "Keyframe-accurate cut",
+			GivenStart: 12.5,
+			GivenEnd:   42,
+			ExpectArgs: "-y -ss 12.500 -i parent.mp4 -t 29.500 -map 0:V -map 0:a? -map 0:s? -c copy -avoid_negative_ts make_zero source.mp4",
+		},
+		{
+			Name:          "Frame-accurate cut",
+			GivenStart:    0,
+			GivenEnd:      3.25,
+			GivenAccurate: true,
+			ExpectArgs:    "-y -ss 0.000 -i parent.mp4 -t 3.250 -map 0:V -map 0:a? -map 0:s? -c:v libx264 -crf 18 -preset fast -c:a aac -b:a 192k -c:s copy source.mp4",
+		},
+		{
+			Name:        "End before start",
+			GivenStart:  10,
+			GivenEnd:    5,
+			ExpectError: true,
+		},
+		{
+			Name:        "Negative start",
+			GivenStart:  -1,
+			GivenEnd:    5,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			args, err := generateClipArgs("parent.mp4", "source.mp4", tt.GivenStart, tt.GivenEnd, tt.GivenAccurate)
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectArgs, strings.Join(args, " "))
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VideoCodec is a codec the renditions can be encoded with
+type VideoCodec string
+
+const (
+	H264 VideoCodec = "h264"
+	HEVC VideoCodec = "hevc"
+	AV1  VideoCodec = "av1"
+)
+
+// Names accepted for each codec: ours, the usual aliases and the RFC 6381 sample entries
+var videoCodecNames = map[string]VideoCodec{
+	"h264": H264, "avc": H264, "avc1": H264, "avc3": H264,
+	"hevc": HEVC, "h265": HEVC, "hvc1": HEVC, "hev1": HEVC,
+	"av1": AV1, "av01": AV1,
+}
+
+// Video sample entries of the RFC 6381 codec strings, the other ones are audio or unknown
+var videoSampleEntries = []string{"avc1", "avc3", "hvc1", "hev1", "av01", "vp09", "dvh1", "dvhe"}
+
+var hlsCodecsAttributeRegexp = regexp.MustCompile(`CODECS="[^"]*"`)
+
+// ParseVideoCodec returns the codec designated by name, e.g. "hevc", "h265" or "hvc1.1.6.L93.B0"
+func ParseVideoCodec(name string) (VideoCodec, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if codec, ok := videoCodecNames[strings.SplitN(name, ".", 2)[0]]; ok {
+		return codec, nil
+	}
+	return "", fmt.Errorf("Unknown video codec %v", name)
+}
+
+// ParseEncodingCodecs returns the codecs of the encoding profile. H.264 is always
+// encoded first, so that every device can play the video.
+func ParseEncodingCodecs(names []string) ([]VideoCodec, error) {
+	codecs := []VideoCodec{H264}
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		codec, err := ParseVideoCodec(name)
+		if err != nil {
+			return nil, err
+		}
+		if !containsCodec(codecs, codec) {
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs, nil
+}
+
+// CodecFamily returns the video codec of a RFC 6381 codec string, or "" for audio and unknown codecs
+func CodecFamily(codec string) VideoCodec {
+	family, err := ParseVideoCodec(codec)
+	if err != nil {
+		return ""
+	}
+	return family
+}
+
+// IsVideoCodec tells whether a RFC 6381 codec string is a video one
+func IsVideoCodec(codec string) bool {
+	for _, prefix := range videoSampleEntries {
+		if strings.HasPrefix(codec, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCodec(codecs []VideoCodec, codec VideoCodec) bool {
+	for _, c := range codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Encoder returns the name of the ffmpeg encoder of the codec
+func (c VideoCodec) Encoder() string {
+	switch c {
+	case HEVC:
+		return "libx265"
+	case AV1:
+		return "libsvtav1"
+	default:
+		return "libx264"
+	}
+}
+
+// encoderArgs returns the ffmpeg arguments encoding the output video stream i with the codec
+func (c VideoCodec) encoderArgs(i int) []string {
+	switch c {
+	case HEVC:
+		// Apple players only accept the hvc1 sample entry
+		return []string{fmt.Sprintf("-c:v:%d", i), c.Encoder(), fmt.Sprintf("-crf:v:%d", i), "28", fmt.Sprintf("-tag:v:%d", i), "hvc1"}
+	case AV1:
+		// SVT-AV1 presets are numbers, 8 is a fast one
+		return []string{fmt.Sprintf("-c:v:%d", i), c.Encoder(), fmt.Sprintf("-crf:v:%d", i), "35", fmt.Sprintf("-preset:v:%d", i), "8"}
+	default:
+		return []string{fmt.Sprintf("-c:v:%d", i), c.Encoder(), fmt.Sprintf("-crf:v:%d", i), "23"}
+	}
+}
+
+// CheckEncoders returns an error when the ffmpeg build lacks the encoder of one of the codecs
+func CheckEncoders(codecs []VideoCodec) error {
+	rawOutput, err := exec.Command("ffmpeg", "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return fmt.Errorf("Unable to list the ffmpeg encoders : %w", err)
+	}
+
+	if missing := missingEncoders(string(rawOutput), codecs); len(missing) > 0 {
+		return fmt.Errorf("ffmpeg lacks the encoders %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingEncoders returns the encoders of the codecs absent from the output of ffmpeg -encoders,
+// whose lines below the "------" separator start with the capabilities then the name of an encoder
+func missingEncoders(output string, codecs []VideoCodec) []string {
+	available := map[string]bool{}
+	listed := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !listed {
+			listed = strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 {
+			available[fields[1]] = true
+		}
+	}
+
+	missing := []string{}
+	for _, codec := range codecs {
+		if !available[codec.Encoder()] {
+			missing = append(missing, codec.Encoder())
+		}
+	}
+	return missing
+}
+
+type probedStream struct {
+	CodecType string `json:"codec_type"`
+	CodecName string `json:"codec_name"`
+	CodecTag  string `json:"codec_tag_string"`
+	Profile   string `json:"profile"`
+	Level     int    `json:"level"`
+	PixFmt    string `json:"pix_fmt"`
+}
+
+type ffprobeCodecsOutput struct {
+	Streams []probedStream `json:"streams"`
+}
+
+// ProbeCodecs returns the RFC 6381 codec strings of the streams of a HLS rendition,
+// read from its initialization segment followed by its first media segment
+func ProbeCodecs(files ...string) ([]string, error) {
+	// ffprobe -v error -show_entries stream=codec_type,codec_name,codec_tag_string,profile,level,pix_fmt -of json concat:<init>|<segment>
+	source := strings.Join(files, "|")
+	if len(files) > 1 {
+		source = "concat:" + source
+	}
+	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=codec_type,codec_name,codec_tag_string,profile,level,pix_fmt", "-of", "json", source).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseCodecsOutput(rawOutput)
+}
+
+func parseCodecsOutput(rawOutput []byte) ([]string, error) {
+	var output ffprobeCodecsOutput
+	if err := json.Unmarshal(rawOutput, &output); err != nil {
+		return nil, err
+	}
+
+	codecs := []string{}
+	for _, stream := range output.Streams {
+		if codec := stream.rfc6381(); codec != "" {
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs, nil
+}
+
+// rfc6381 returns the codec string of the stream as expected by the CODECS attribute of HLS
+func (s probedStream) rfc6381() string {
+	switch s.CodecName {
+	case "h264":
+		return s.avcCodec()
+	case "hevc":
+		return s.hevcCodec()
+	case "av1":
+		return s.av1Codec()
+	case "aac":
+		switch s.Profile {
+		case "HE-AAC":
+			return "mp4a.40.5"
+		case "HE-AACv2":
+			return "mp4a.40.29"
+		}
+		return "mp4a.40.2"
+	case "mp3":
+		return "mp4a.40.34"
+	case "ac3":
+		return "ac-3"
+	case "eac3":
+		return "ec-3"
+	case "opus":
+		return "Opus"
+	case "flac":
+		return "fLaC"
+	}
+	return ""
+}
+
+func (s probedStream) avcCodec() string {
+	profiles := map[string][2]int{
+		"Baseline":              {66, 0x00},
+		"Constrained Baseline":  {66, 0x40},
+		"Main":                  {77, 0x00},
+		"Extended":              {88, 0x00},
+		"High":                  {100, 0x00},
+		"High 10":               {110, 0x00},
+		"High 4:2:2":            {122, 0x00},
+		"High 4:4:4 Predictive": {244, 0x00},
+	}
+	profile, ok := profiles[s.Profile]
+	if !ok {
+		profile = profiles["High"]
+	}
+	return fmt.Sprintf("%s.%02X%02X%02X", sampleEntry(s.CodecTag, "avc1", "avc3"), profile[0], profile[1], s.Level)
+}
+
+func (s probedStream) hevcCodec() string {
+	// Profile and the matching general_profile_compatibility_flags, reversed as the RFC requires
+	profiles := map[string][2]string{
+		"Main":               {"1", "6"},
+		"Main 10":            {"2", "4"},
+		"Main Still Picture": {"3", "8"},
+		"Rext":               {"4", "10"},
+	}
+	profile, ok := profiles[s.Profile]
+	if !ok {
+		profile = profiles["Main"]
+	}
+	// ffprobe does not expose the tier, our encodes use the main one
+	return fmt.Sprintf("%s.%s.%s.L%d.B0", sampleEntry(s.CodecTag, "hvc1", "hev1"), profile[0], profile[1], s.Level)
+}
+
+func (s probedStream) av1Codec() string {
+	profiles := map[string]int{"Main": 0, "High": 1, "Professional": 2}
+	bitDepth := 8
+	switch {
+	case strings.Contains(s.PixFmt, "12"):
+		bitDepth = 12
+	case strings.Contains(s.PixFmt, "10"):
+		bitDepth = 10
+	}
+	return fmt.Sprintf("av01.%d.%02dM.%02d", profiles[s.Profile], s.Level, bitDepth)
+}
+
+// sampleEntry returns the probed sample entry if it is one of the allowed ones
+func sampleEntry(tag string, allowed ...string) string {
+	for _, entry := range allowed {
+		if tag == entry {
+			return tag
+		}
+	}
+	return allowed[0]
+}
+
+// WriteMasterCodecs replaces the CODECS attributes of the HLS master of the folder by the
+// codecs probed from the renditions. ffmpeg guesses them, and not at all for stream copies.
+func WriteMasterCodecs(folder string) error {
+	masterPath := filepath.Join(folder, hlsMasterPlaylist)
+	master, err := os.Open(masterPath)
+	if err != nil {
+		return err
+	}
+	variants, audios, err := parseHLSMaster(master)
+	master.Close()
+	if err != nil {
+		return err
+	}
+
+	// Variants announce the codecs of every rendition of their audio group
+	audioCodecs := []string{}
+	for _, audio := range audios {
+		codecs, err := probeRenditionCodecs(folder, audio.URI)
+		if err != nil {
+			return err
+		}
+		for _, codec := range codecs {
+			if !containsString(audioCodecs, codec) {
+				audioCodecs = append(audioCodecs, codec)
+			}
+		}
+	}
+
+	codecsByURI := map[string]string{}
+	for _, variant := range variants {
+		codecs, err := probeRenditionCodecs(folder, variant.URI)
+		if err != nil {
+			return err
+		}
+		if variant.AudioGroup != "" {
+			codecs = append(codecs, audioCodecs...)
+		}
+		codecsByURI[variant.URI] = strings.Join(codecs, ",")
+	}
+
+	content, err := os.ReadFile(masterPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(masterPath, setMasterCodecs(content, codecsByURI), 0644)
+}
+
+func probeRenditionCodecs(folder, uri string) ([]string, error) {
+	playlist, err := readHLSMediaPlaylist(folder, uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(playlist.Segments) == 0 {
+		return nil, fmt.Errorf("No segment in %v", uri)
+	}
+
+	files := []string{}
+	if playlist.InitURI != "" {
+		files = append(files, filepath.Join(folder, filepath.FromSlash(path.Join(path.Dir(uri), playlist.InitURI))))
+	}
+	files = append(files, filepath.Join(folder, filepath.FromSlash(path.Join(path.Dir(uri), playlist.Segments[0].URI))))
+	return ProbeCodecs(files...)
+}
+
+// setMasterCodecs sets the CODECS attribute of the variants of a HLS master, by URI
+func setMasterCodecs(master []byte, codecsByURI map[string]string) []byte {
+	lines := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(string(master)))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	tag := -1
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			tag = i
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			if codecs := codecsByURI[line]; tag >= 0 && codecs != "" {
+				attribute := `CODECS="` + codecs + `"`
+				if hlsCodecsAttributeRegexp.MatchString(lines[tag]) {
+					lines[tag] = hlsCodecsAttributeRegexp.ReplaceAllLiteralString(lines[tag], attribute)
+				} else {
+					lines[tag] += "," + attribute
+				}
+			}
+			tag = -1
+		}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
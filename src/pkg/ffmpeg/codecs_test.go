@@ -0,0 +1,154 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseEncodingCodecs(t *testing.T) {
+	cases := []struct {
+		Name         string
+		GivenNames   []string
+		ExpectCodecs []VideoCodec
+		ExpectError  bool
+	}{
+		{Name: "Default profile", GivenNames: []string{"h264"}, ExpectCodecs: []VideoCodec{H264}},
+		{Name: "H.264 always encoded first", GivenNames: []string{"av1", " hevc"}, ExpectCodecs: []VideoCodec{H264, AV1, HEVC}},
+		{Name: "Aliases", GivenNames: []string{"H265", "hevc", "av01", ""}, ExpectCodecs: []VideoCodec{H264, HEVC, AV1}},
+		{Name: "Unknown codec", GivenNames: []string{"vp8"}, ExpectError: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			codecs, err := ParseEncodingCodecs(tt.GivenNames)
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectCodecs, codecs)
+		})
+	}
+}
+
+func Test_missingEncoders(t *testing.T) {
+	givenOutput := `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libx265              libx265 H.265 / HEVC (codec hevc)
+ A....D aac                  AAC (Advanced Audio Coding)
+`
+
+	cases := []struct {
+		Name          string
+		GivenCodecs   []VideoCodec
+		ExpectMissing []string
+	}{
+		{Name: "Available encoders", GivenCodecs: []VideoCodec{H264, HEVC}, ExpectMissing: []string{}},
+		{Name: "Missing AV1 encoder", GivenCodecs: []VideoCodec{H264, AV1}, ExpectMissing: []string{"libsvtav1"}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, tt.ExpectMissing, missingEncoders(givenOutput, tt.GivenCodecs))
+		})
+	}
+}
+
+func Test_parseCodecsOutput(t *testing.T) {
+	cases := []struct {
+		Name         string
+		GivenOutput  string
+		ExpectCodecs []string
+		ExpectError  bool
+	}{
+		{
+			Name: "H.264 and AAC",
+			GivenOutput: `{"streams": [
+				{"codec_name": "h264", "codec_type": "video", "codec_tag_string": "avc1", "profile": "High", "level": 31, "pix_fmt": "yuv420p"},
+				{"codec_name": "aac", "codec_type": "audio", "codec_tag_string": "mp4a", "profile": "LC"}
+			]}`,
+			ExpectCodecs: []string{"avc1.64001F", "mp4a.40.2"},
+		},
+		{
+			Name:         "Constrained baseline H.264",
+			GivenOutput:  `{"streams": [{"codec_name": "h264", "codec_type": "video", "codec_tag_string": "avc1", "profile": "Constrained Baseline", "level": 30}]}`,
+			ExpectCodecs: []string{"avc1.42401E"},
+		},
+		{
+			Name:         "HEVC main 10",
+			GivenOutput:  `{"streams": [{"codec_name": "hevc", "codec_type": "video", "codec_tag_string": "hvc1", "profile": "Main 10", "level": 120, "pix_fmt": "yuv420p10le"}]}`,
+			ExpectCodecs: []string{"hvc1.2.4.L120.B0"},
+		},
+		{
+			Name:         "HEVC with hev1 sample entry",
+			GivenOutput:  `{"streams": [{"codec_name": "hevc", "codec_type": "video", "codec_tag_string": "hev1", "profile": "Main", "level": 93}]}`,
+			ExpectCodecs: []string{"hev1.1.6.L93.B0"},
+		},
+		{
+			Name:         "AV1 10 bits",
+			GivenOutput:  `{"streams": [{"codec_name": "av1", "codec_type": "video", "codec_tag_string": "av01", "profile": "Main", "level": 8, "pix_fmt": "yuv420p10le"}]}`,
+			ExpectCodecs: []string{"av01.0.08M.10"},
+		},
+		{
+			Name: "Audio codecs",
+			GivenOutput: `{"streams": [
+				{"codec_name": "aac", "codec_type": "audio", "profile": "HE-AAC"},
+				{"codec_name": "ac3", "codec_type": "audio"},
+				{"codec_name": "eac3", "codec_type": "audio"},
+				{"codec_name": "opus", "codec_type": "audio"}
+			]}`,
+			ExpectCodecs: []string{"mp4a.40.5", "ac-3", "ec-3", "Opus"},
+		},
+		{
+			Name:         "Unknown codec",
+			GivenOutput:  `{"streams": [{"codec_name": "mpeg4", "codec_type": "video"}]}`,
+			ExpectCodecs: []string{},
+		},
+		{
+			Name:        "Invalid output",
+			GivenOutput: `Invalid data found when processing input`,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			codecs, err := parseCodecsOutput([]byte(tt.GivenOutput))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectCodecs, codecs)
+		})
+	}
+}
+
+func Test_setMasterCodecs(t *testing.T) {
+	master := "#EXTM3U\n#EXT-X-VERSION:7\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001e,mp4a.40.2\"\nv0/segment_index.m3u8\n\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080\nv1/segment_index.m3u8\n"
+
+	rewritten := setMasterCodecs([]byte(master), map[string]string{
+		"v0/segment_index.m3u8": "avc1.64001E,mp4a.40.2",
+		"v1/segment_index.m3u8": "hvc1.1.6.L120.B0,mp4a.40.2",
+	})
+
+	require.Equal(t, "#EXTM3U\n#EXT-X-VERSION:7\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001E,mp4a.40.2\"\nv0/segment_index.m3u8\n\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS=\"hvc1.1.6.L120.B0,mp4a.40.2\"\nv1/segment_index.m3u8\n", string(rewritten))
+}
+
+func Test_WriteMasterCodecsWithoutMaster(t *testing.T) {
+	require.Error(t, WriteMasterCodecs(t.TempDir()))
+
+	folder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(folder, "master.m3u8"), []byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nv0/segment_index.m3u8\n"), 0644))
+	require.Error(t, WriteMasterCodecs(folder))
+}
@@ -0,0 +1,76 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type CoverSize struct {
+	Name  string
+	Width uint64 // Covers are never upscaled
+}
+
+var CoverSizes = []CoverSize{
+	{Name: "small", Width: 320},
+	{Name: "medium", Width: 640},
+	{Name: "large", Width: 1280},
+}
+
+var CoverFormats = []string{"jpeg", "webp"}
+
+// CoverVariantPath returns the path of a generated cover, relative to the video folder
+func CoverVariantPath(size, format string) string {
+	return "covers/" + size + "." + format
+}
+
+// ExtractCover saves a representative frame of the video as cover: the
+// thumbnail filter picks the most representative frame around the mid-point
+func ExtractCover(source string, duration float64, output string) error {
+	args := generateCoverArgs(source, duration, output)
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+	}
+	return err
+}
+
+func generateCoverArgs(source string, duration float64, output string) []string {
+	// ffmpeg -y -ss <duration/2> -i <source> -vf thumbnail=100 -frames:v 1 -q:v 2 <output>
+	args := []string{"-y"}
+	// Some containers do not expose a duration, the frame is then picked at the beginning
+	if duration > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", duration/2))
+	}
+	return append(args, "-i", source, "-vf", "thumbnail=100", "-frames:v", "1", "-q:v", "2", output)
+}
+
+// GenerateCoverVariants resizes the cover in every size and format.
+// A variant that cannot be generated is skipped, the original cover is served instead.
+func GenerateCoverVariants(cover string) error {
+	if err := os.MkdirAll("covers", os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, size := range CoverSizes {
+		for _, format := range CoverFormats {
+			args := generateCoverVariantArgs(cover, size, format)
+			log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+			if rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput(); err != nil {
+				log.Error("Cannot generate cover ", CoverVariantPath(size.Name, format), " : ", err)
+				log.Debug("FFMPEG output: ", string(rawOutput))
+			}
+		}
+	}
+
+	return nil
+}
+
+func generateCoverVariantArgs(cover string, size CoverSize, format string) []string {
+	// ffmpeg -y -i <cover> -vf scale='min(<width>,iw)':-2 -frames:v 1 covers/<size>.<format>
+	return []string{"-y", "-i", cover, "-vf", fmt.Sprintf("scale='min(%d,iw)':-2", size.Width), "-frames:v", "1", CoverVariantPath(size.Name, format)}
+}
@@ -0,0 +1,39 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateCoverArgs(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenDuration float64
+		ExpectArgs    string
+	}{
+		{
+			Name:          "Frame around the mid-point",
+			GivenDuration: 61.5,
+			ExpectArgs:    "-y -ss 30.750 -i source.mp4 -vf thumbnail=100 -frames:v 1 -q:v 2 cover.jpeg",
+		},
+		{
+			Name:          "Unknown duration",
+			GivenDuration: 0,
+			ExpectArgs:    "-y -i source.mp4 -vf thumbnail=100 -frames:v 1 -q:v 2 cover.jpeg",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			args := generateCoverArgs("source.mp4", tt.GivenDuration, "cover.jpeg")
+			require.Equal(t, tt.ExpectArgs, strings.Join(args, " "))
+		})
+	}
+}
+
+func Test_generateCoverVariantArgs(t *testing.T) {
+	args := generateCoverVariantArgs("cover.png", CoverSize{Name: "medium", Width: 640}, "webp")
+	require.Equal(t, "-y -i cover.png -vf scale='min(640,iw)':-2 -frames:v 1 covers/medium.webp", strings.Join(args, " "))
+}
@@ -0,0 +1,377 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	DASHManifest = "manifest.mpd"
+
+	dashTimescale        = 1000
+	defaultAudioBitrate  = 128000
+	hlsMasterPlaylist    = "master.m3u8"
+	dashManifestProfiles = "urn:mpeg:dash:profile:isoff-main:2011"
+)
+
+// hlsVariant is a rendition declared in a HLS master (#EXT-X-STREAM-INF)
+type hlsVariant struct {
+	URI        string
+	Bandwidth  int
+	Width      int
+	Height     int
+	Codecs     string
+	AudioGroup string
+}
+
+// hlsAudio is an alternate audio rendition declared in a HLS master (#EXT-X-MEDIA)
+type hlsAudio struct {
+	URI      string
+	Name     string
+	Language string
+	Default  bool
+}
+
+// hlsSegment is a media segment of a HLS media playlist
+type hlsSegment struct {
+	URI      string
+	Duration float64
+}
+
+type hlsMediaPlaylist struct {
+	InitURI  string
+	Segments []hlsSegment
+}
+
+type mpd struct {
+	XMLName                   xml.Name `xml:"MPD"`
+	Xmlns                     string   `xml:"xmlns,attr"`
+	Profiles                  string   `xml:"profiles,attr"`
+	Type                      string   `xml:"type,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             string   `xml:"minBufferTime,attr"`
+	Period                    mpdPeriod
+}
+
+type mpdPeriod struct {
+	XMLName        xml.Name           `xml:"Period"`
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ID              int                 `xml:"id,attr"`
+	ContentType     string              `xml:"contentType,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	Lang            string              `xml:"lang,attr,omitempty"`
+	Role            *mpdDescriptor      `xml:"Role,omitempty"`
+	Representations []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdDescriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+type mpdRepresentation struct {
+	ID          string         `xml:"id,attr"`
+	Bandwidth   int            `xml:"bandwidth,attr"`
+	Width       int            `xml:"width,attr,omitempty"`
+	Height      int            `xml:"height,attr,omitempty"`
+	Codecs      string         `xml:"codecs,attr,omitempty"`
+	SegmentList mpdSegmentList `xml:"SegmentList"`
+}
+
+type mpdSegmentList struct {
+	Timescale       int                `xml:"timescale,attr"`
+	Initialization  *mpdURL            `xml:"Initialization,omitempty"`
+	SegmentTimeline []mpdTimelineEntry `xml:"SegmentTimeline>S"`
+	SegmentURLs     []mpdSegmentURL    `xml:"SegmentURL"`
+}
+
+type mpdURL struct {
+	SourceURL string `xml:"sourceURL,attr"`
+}
+
+type mpdTimelineEntry struct {
+	Duration int64 `xml:"d,attr"`
+}
+
+type mpdSegmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// GenerateDASHManifest writes a MPEG-DASH manifest next to the HLS master of the folder.
+// It references the fMP4 init and media segments of the HLS renditions (CMAF), nothing is encoded again.
+func GenerateDASHManifest(folder string) error {
+	master, err := os.Open(filepath.Join(folder, hlsMasterPlaylist))
+	if err != nil {
+		return err
+	}
+	defer master.Close()
+
+	variants, audios, err := parseHLSMaster(master)
+	if err != nil {
+		return err
+	}
+	if len(variants) == 0 {
+		return fmt.Errorf("No variant in %v", hlsMasterPlaylist)
+	}
+
+	manifest := mpd{
+		Xmlns:         "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:      dashManifestProfiles,
+		Type:          "static",
+		MinBufferTime: "PT6S",
+		Period:        mpdPeriod{ID: "0", Start: "PT0S"},
+	}
+
+	duration := 0.0
+	// Players cannot switch between codecs, each one gets its own adaptation set
+	videoSets := []mpdAdaptationSet{}
+	videoSetByCodec := map[VideoCodec]int{}
+	for i, variant := range variants {
+		playlist, err := readHLSMediaPlaylist(folder, variant.URI)
+		if err != nil {
+			return err
+		}
+
+		// Muxed audio stays in the video representation, alternate audio gets its own adaptation set
+		codecs := variant.Codecs
+		if variant.AudioGroup != "" {
+			codecs = filterCodecs(codecs, IsVideoCodec)
+		}
+
+		family := CodecFamily(filterCodecs(variant.Codecs, IsVideoCodec))
+		set, ok := videoSetByCodec[family]
+		if !ok {
+			set = len(videoSets)
+			videoSetByCodec[family] = set
+			videoSets = append(videoSets, mpdAdaptationSet{ID: set, ContentType: "video", MimeType: "video/mp4"})
+		}
+
+		videoSets[set].Representations = append(videoSets[set].Representations, mpdRepresentation{
+			ID:          fmt.Sprintf("video_%d", i),
+			Bandwidth:   variant.Bandwidth,
+			Width:       variant.Width,
+			Height:      variant.Height,
+			Codecs:      codecs,
+			SegmentList: newMPDSegmentList(variant.URI, playlist),
+		})
+		duration = math.Max(duration, playlistDuration(playlist))
+	}
+	manifest.Period.AdaptationSets = append(manifest.Period.AdaptationSets, videoSets...)
+
+	audioCodecs := filterCodecs(variants[0].Codecs, func(codec string) bool { return !IsVideoCodec(codec) })
+	for i, audio := range audios {
+		playlist, err := readHLSMediaPlaylist(folder, audio.URI)
+		if err != nil {
+			return err
+		}
+
+		adaptationSet := mpdAdaptationSet{
+			ID:          len(videoSets) + i,
+			ContentType: "audio",
+			MimeType:    "audio/mp4",
+			Lang:        audio.Language,
+			Representations: []mpdRepresentation{{
+				ID:          fmt.Sprintf("audio_%d", i),
+				Bandwidth:   playlistBitrate(folder, audio.URI, playlist),
+				Codecs:      audioCodecs,
+				SegmentList: newMPDSegmentList(audio.URI, playlist),
+			}},
+		}
+		if audio.Default {
+			adaptationSet.Role = &mpdDescriptor{SchemeIDURI: "urn:mpeg:dash:role:2011", Value: "main"}
+		}
+		manifest.Period.AdaptationSets = append(manifest.Period.AdaptationSets, adaptationSet)
+	}
+	manifest.MediaPresentationDuration = fmt.Sprintf("PT%.3fS", duration)
+
+	output, err := os.Create(filepath.Join(folder, DASHManifest))
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	return writeMPD(output, manifest)
+}
+
+func writeMPD(w io.Writer, manifest mpd) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// newMPDSegmentList lists the segments of a HLS media playlist, relatively to the master
+func newMPDSegmentList(playlistURI string, playlist hlsMediaPlaylist) mpdSegmentList {
+	folder := path.Dir(playlistURI)
+	segmentList := mpdSegmentList{Timescale: dashTimescale}
+	if playlist.InitURI != "" {
+		segmentList.Initialization = &mpdURL{SourceURL: path.Join(folder, playlist.InitURI)}
+	}
+	for _, segment := range playlist.Segments {
+		segmentList.SegmentTimeline = append(segmentList.SegmentTimeline, mpdTimelineEntry{Duration: int64(math.Round(segment.Duration * dashTimescale))})
+		segmentList.SegmentURLs = append(segmentList.SegmentURLs, mpdSegmentURL{Media: path.Join(folder, segment.URI)})
+	}
+	return segmentList
+}
+
+func playlistDuration(playlist hlsMediaPlaylist) float64 {
+	duration := 0.0
+	for _, segment := range playlist.Segments {
+		duration += segment.Duration
+	}
+	return duration
+}
+
+// playlistBitrate computes the average bitrate of a rendition from the size of its segments
+func playlistBitrate(folder, playlistURI string, playlist hlsMediaPlaylist) int {
+	duration := playlistDuration(playlist)
+	size := int64(0)
+	for _, segment := range playlist.Segments {
+		info, err := os.Stat(filepath.Join(folder, filepath.FromSlash(path.Join(path.Dir(playlistURI), segment.URI))))
+		if err != nil {
+			return defaultAudioBitrate
+		}
+		size += info.Size()
+	}
+	if duration <= 0 || size == 0 {
+		return defaultAudioBitrate
+	}
+	return int(float64(size*8) / duration)
+}
+
+func filterCodecs(codecs string, keep func(string) bool) string {
+	kept := []string{}
+	for _, codec := range strings.Split(codecs, ",") {
+		if codec = strings.TrimSpace(codec); codec != "" && keep(codec) {
+			kept = append(kept, codec)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+func readHLSMediaPlaylist(folder, uri string) (hlsMediaPlaylist, error) {
+	f, err := os.Open(filepath.Join(folder, filepath.FromSlash(uri)))
+	if err != nil {
+		return hlsMediaPlaylist{}, err
+	}
+	defer f.Close()
+	return parseHLSMediaPlaylist(f)
+}
+
+func parseHLSMaster(r io.Reader) ([]hlsVariant, []hlsAudio, error) {
+	variants := []hlsVariant{}
+	audios := []hlsAudio{}
+
+	var pending *hlsVariant
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attributes := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			variant := hlsVariant{Codecs: attributes["CODECS"], AudioGroup: attributes["AUDIO"]}
+			variant.Bandwidth, _ = strconv.Atoi(attributes["BANDWIDTH"])
+			if resolution := strings.Split(attributes["RESOLUTION"], "x"); len(resolution) == 2 {
+				variant.Width, _ = strconv.Atoi(resolution[0])
+				variant.Height, _ = strconv.Atoi(resolution[1])
+			}
+			pending = &variant
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			attributes := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-MEDIA:"))
+			if attributes["TYPE"] == "AUDIO" && attributes["URI"] != "" {
+				audios = append(audios, hlsAudio{
+					URI:      attributes["URI"],
+					Name:     attributes["NAME"],
+					Language: attributes["LANGUAGE"],
+					Default:  attributes["DEFAULT"] == "YES",
+				})
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			if pending != nil {
+				pending.URI = line
+				variants = append(variants, *pending)
+				pending = nil
+			}
+		}
+	}
+	return variants, audios, scanner.Err()
+}
+
+func parseHLSMediaPlaylist(r io.Reader) (hlsMediaPlaylist, error) {
+	playlist := hlsMediaPlaylist{}
+
+	duration := -1.0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			playlist.InitURI = parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))["URI"]
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0]
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return playlist, fmt.Errorf("Invalid segment duration %v : %w", value, err)
+			}
+			duration = parsed
+		case strings.HasPrefix(line, "#"):
+		default:
+			if duration < 0 {
+				return playlist, fmt.Errorf("Segment %v without duration", line)
+			}
+			playlist.Segments = append(playlist.Segments, hlsSegment{URI: line, Duration: duration})
+			duration = -1
+		}
+	}
+	return playlist, scanner.Err()
+}
+
+// parseHLSAttributes parses an attribute list, e.g. BANDWIDTH=1210000,CODECS="avc1.64001e,mp4a.40.2"
+func parseHLSAttributes(list string) map[string]string {
+	attributes := map[string]string{}
+	for list != "" {
+		name, rest, found := strings.Cut(list, "=")
+		if !found {
+			break
+		}
+
+		value := ""
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		attributes[strings.TrimSpace(name)] = value
+		list = rest
+	}
+	return attributes
+}
@@ -0,0 +1,201 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseHLSAttributes(t *testing.T) {
+	attributes := parseHLSAttributes(`BANDWIDTH=1210000,RESOLUTION=640x360,CODECS="avc1.64001e,mp4a.40.2",AUDIO="audio"`)
+	require.Equal(t, map[string]string{
+		"BANDWIDTH":  "1210000",
+		"RESOLUTION": "640x360",
+		"CODECS":     "avc1.64001e,mp4a.40.2",
+		"AUDIO":      "audio",
+	}, attributes)
+}
+
+func Test_GenerateDASHManifest(t *testing.T) {
+	cases := []struct {
+		Name           string
+		GivenFiles     map[string]string
+		ExpectManifest string
+		ExpectError    bool
+	}{
+		{
+			Name: "Muxed audio",
+			GivenFiles: map[string]string{
+				"master.m3u8": "#EXTM3U\n#EXT-X-VERSION:7\n" +
+					"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x360,CODECS=\"avc1.64001e,mp4a.40.2\"\nv0/segment_index.m3u8\n\n" +
+					"#EXT-X-STREAM-INF:BANDWIDTH=3110000,RESOLUTION=1280x720,CODECS=\"avc1.64001f,mp4a.40.2\"\nv1/segment_index.m3u8\n",
+				"v0/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_0.mp4\"\n#EXTINF:6.000000,\nsegment0.m4s\n#EXTINF:2.500000,\nsegment1.m4s\n#EXT-X-ENDLIST\n",
+				"v1/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_1.mp4\"\n#EXTINF:6.000000,\nsegment0.m4s\n#EXTINF:2.500000,\nsegment1.m4s\n#EXT-X-ENDLIST\n",
+			},
+			ExpectManifest: `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-main:2011" type="static" mediaPresentationDuration="PT8.500S" minBufferTime="PT6S">
+  <Period id="0" start="PT0S">
+    <AdaptationSet id="0" contentType="video" mimeType="video/mp4">
+      <Representation id="video_0" bandwidth="1210000" width="640" height="360" codecs="avc1.64001e,mp4a.40.2">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v0/init_0.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="6000"></S>
+            <S d="2500"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v0/segment0.m4s"></SegmentURL>
+          <SegmentURL media="v0/segment1.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+      <Representation id="video_1" bandwidth="3110000" width="1280" height="720" codecs="avc1.64001f,mp4a.40.2">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v1/init_1.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="6000"></S>
+            <S d="2500"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v1/segment0.m4s"></SegmentURL>
+          <SegmentURL media="v1/segment1.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`,
+		},
+		{
+			Name: "Alternate audio tracks",
+			GivenFiles: map[string]string{
+				"master.m3u8": "#EXTM3U\n" +
+					"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"audio_0\",DEFAULT=YES,LANGUAGE=\"en\",URI=\"v0/segment_index.m3u8\"\n" +
+					"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"audio_1\",DEFAULT=NO,LANGUAGE=\"fr\",URI=\"v1/segment_index.m3u8\"\n" +
+					"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x360,CODECS=\"avc1.64001e,mp4a.40.2\",AUDIO=\"audio\"\nv2/segment_index.m3u8\n",
+				"v0/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_0.mp4\"\n#EXTINF:4.000000,\nsegment0.m4s\n#EXT-X-ENDLIST\n",
+				"v0/segment0.m4s":       string(make([]byte, 48000)),
+				"v1/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_1.mp4\"\n#EXTINF:4.000000,\nsegment0.m4s\n#EXT-X-ENDLIST\n",
+				"v2/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_2.mp4\"\n#EXTINF:4.000000,\nsegment0.m4s\n#EXT-X-ENDLIST\n",
+			},
+			ExpectManifest: `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-main:2011" type="static" mediaPresentationDuration="PT4.000S" minBufferTime="PT6S">
+  <Period id="0" start="PT0S">
+    <AdaptationSet id="0" contentType="video" mimeType="video/mp4">
+      <Representation id="video_0" bandwidth="1210000" width="640" height="360" codecs="avc1.64001e">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v2/init_2.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="4000"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v2/segment0.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet id="1" contentType="audio" mimeType="audio/mp4" lang="en">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="main"></Role>
+      <Representation id="audio_0" bandwidth="96000" codecs="mp4a.40.2">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v0/init_0.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="4000"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v0/segment0.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" lang="fr">
+      <Representation id="audio_1" bandwidth="128000" codecs="mp4a.40.2">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v1/init_1.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="4000"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v1/segment0.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`,
+		},
+		{
+			Name: "Renditions of several codecs",
+			GivenFiles: map[string]string{
+				"master.m3u8": "#EXTM3U\n" +
+					"#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x480,CODECS=\"avc1.64001E,mp4a.40.2\"\nv0/segment_index.m3u8\n" +
+					"#EXT-X-STREAM-INF:BANDWIDTH=810000,RESOLUTION=640x480,CODECS=\"hvc1.1.6.L90.B0,mp4a.40.2\"\nv1/segment_index.m3u8\n",
+				"v0/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_0.mp4\"\n#EXTINF:4.000000,\nsegment0.m4s\n#EXT-X-ENDLIST\n",
+				"v1/segment_index.m3u8": "#EXTM3U\n#EXT-X-MAP:URI=\"init_1.mp4\"\n#EXTINF:4.000000,\nsegment0.m4s\n#EXT-X-ENDLIST\n",
+			},
+			ExpectManifest: `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-main:2011" type="static" mediaPresentationDuration="PT4.000S" minBufferTime="PT6S">
+  <Period id="0" start="PT0S">
+    <AdaptationSet id="0" contentType="video" mimeType="video/mp4">
+      <Representation id="video_0" bandwidth="1210000" width="640" height="480" codecs="avc1.64001E,mp4a.40.2">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v0/init_0.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="4000"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v0/segment0.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <Representation id="video_1" bandwidth="810000" width="640" height="480" codecs="hvc1.1.6.L90.B0,mp4a.40.2">
+        <SegmentList timescale="1000">
+          <Initialization sourceURL="v1/init_1.mp4"></Initialization>
+          <SegmentTimeline>
+            <S d="4000"></S>
+          </SegmentTimeline>
+          <SegmentURL media="v1/segment0.m4s"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`,
+		},
+		{
+			Name: "Missing variant playlist",
+			GivenFiles: map[string]string{
+				"master.m3u8": "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x360\nv0/segment_index.m3u8\n",
+			},
+			ExpectError: true,
+		},
+		{
+			Name: "Segment without duration",
+			GivenFiles: map[string]string{
+				"master.m3u8":           "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1210000,RESOLUTION=640x360\nv0/segment_index.m3u8\n",
+				"v0/segment_index.m3u8": "#EXTM3U\nsegment0.m4s\n",
+			},
+			ExpectError: true,
+		},
+		{
+			Name:        "Missing master",
+			GivenFiles:  map[string]string{},
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			folder := t.TempDir()
+			for name, content := range tt.GivenFiles {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(folder, name)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(folder, name), []byte(content), 0644))
+			}
+
+			err := GenerateDASHManifest(folder)
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			manifest, err := os.ReadFile(filepath.Join(folder, DASHManifest))
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectManifest, string(manifest))
+		})
+	}
+}
@@ -9,10 +9,20 @@ import (
 )
 
 func Test_GenerateCommand(t *testing.T) {
+	hlsArgs := "-master_pl_name master.m3u8 -f hls -hls_time 6 -hls_playlist_type vod -hls_segment_type fmp4 -hls_list_size 0 -hls_segment_filename v%v/segment%d.m4s v%v/segment_index.m3u8"
+	singleAudio := []MediaStream{{Index: 1, CodecType: AudioStream, CodecName: "aac", Language: "eng"}}
+	severalAudio := []MediaStream{
+		{Index: 1, CodecType: AudioStream, CodecName: "aac", Language: "eng"},
+		{Index: 2, CodecType: AudioStream, CodecName: "aac", Language: "fre", Default: true},
+		{Index: 3, CodecType: AudioStream, CodecName: "ac3", Language: "und"},
+	}
+
 	cases := []struct {
 		Name            string
 		GivenFilePath   string
 		GivenResolution Resolution
+		GivenAudio      []MediaStream
+		GivenProfile    EncodingProfile
 		ExpectCommand   string
 		ExpectArgs      string
 		ExpectError     bool
@@ -21,6 +31,7 @@ func Test_GenerateCommand(t *testing.T) {
 			Name:            "Resolution below minimal",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: Resolution{X: 0, Y: 0},
+			GivenAudio:      singleAudio,
 			ExpectCommand:   "",
 			ExpectError:     true,
 		},
@@ -28,39 +39,139 @@ func Test_GenerateCommand(t *testing.T) {
 			Name:            "With Resolution: 640x480",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: Resolution{X: 640, Y: 480},
+			GivenAudio:      singleAudio,
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -c:v:0 copy -c:a copy -var_stream_map v:0,a:0 " + hlsArgs,
 			ExpectError:     false,
 		},
 		{
 			Name:            "With Resolution: 1280x720",
 			GivenFilePath:   "someName.mp4",
 			GivenResolution: Resolution{X: 1280, Y: 720},
+			GivenAudio:      singleAudio,
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -filter:v:0 scale=-2:480,format=yuv420p -c:v:0 libx264 -crf:v:0 23 -c:v:1 copy -c:a copy -var_stream_map v:0,a:0 v:1,a:1 " + hlsArgs,
 			ExpectError:     false,
 		},
 		{
-			Name:            "With Resolution 1920x1080",
+			Name:            "With Resolution 3840x2160",
 			GivenFilePath:   "someName.mp4",
-			GivenResolution: Resolution{X: 1920, Y: 1080},
+			GivenResolution: Resolution{X: 3840, Y: 2160},
+			GivenAudio:      singleAudio,
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -filter:v:0 scale=-2:480,format=yuv420p -c:v:0 libx264 -crf:v:0 23 -filter:v:1 scale=-2:1080,format=yuv420p -c:v:1 libx264 -crf:v:1 23 -c:v:2 copy -c:a copy -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 " + hlsArgs,
 			ExpectError:     false,
 		},
 		{
-			Name:            "With Resolution 3840x2160",
+			Name:            "With HEVC and AV1 renditions",
 			GivenFilePath:   "someName.mp4",
-			GivenResolution: Resolution{X: 3840, Y: 2160},
+			GivenResolution: Resolution{X: 1280, Y: 720},
+			GivenAudio:      singleAudio,
+			GivenProfile:    EncodingProfile{Codecs: []VideoCodec{H264, HEVC, AV1}},
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs: "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 " +
+				"-filter:v:0 scale=-2:480,format=yuv420p -c:v:0 libx264 -crf:v:0 23 " +
+				"-filter:v:1 scale=-2:480,format=yuv420p -c:v:1 libx265 -crf:v:1 28 -tag:v:1 hvc1 " +
+				"-filter:v:2 scale=-2:480,format=yuv420p -c:v:2 libsvtav1 -crf:v:2 35 -preset:v:2 8 " +
+				"-c:v:3 copy -c:a copy -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 v:3,a:3 " + hlsArgs,
+			ExpectError: false,
+		},
+		{
+			Name:            "With normalized AAC audio",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: Resolution{X: 1280, Y: 720},
+			GivenAudio:      []MediaStream{{Index: 1, CodecType: AudioStream, CodecName: "opus", Loudness: &Loudness{Integrated: -31.2, TruePeak: -8.5, Range: 6.1, Threshold: -41.8, Offset: 0.3}}},
+			GivenProfile:    EncodingProfile{Audio: AudioProfile{Transcode: true, Bitrate: "160k", Downmix: true, Loudnorm: true, TargetLoudness: -23}},
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs: "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -filter:v:0 scale=-2:480,format=yuv420p -c:v:0 libx264 -crf:v:0 23 -c:v:1 copy " +
+				"-c:a aac -b:a 160k " +
+				"-filter:a:0 aformat=channel_layouts=stereo,loudnorm=I=-23:TP=-1:LRA=7:measured_I=-31.20:measured_TP=-8.50:measured_LRA=6.10:measured_thresh=-41.80:offset=0.30:linear=true,aresample=48000 " +
+				"-filter:a:1 aformat=channel_layouts=stereo,loudnorm=I=-23:TP=-1:LRA=7:measured_I=-31.20:measured_TP=-8.50:measured_LRA=6.10:measured_thresh=-41.80:offset=0.30:linear=true,aresample=48000 " +
+				"-var_stream_map v:0,a:0 v:1,a:1 " + hlsArgs,
+			ExpectError: false,
+		},
+		{
+			Name:            "With several audio tracks transcoded, one not measured",
+			GivenFilePath:   "someName.mkv",
+			GivenResolution: Resolution{X: 640, Y: 480},
+			GivenAudio: []MediaStream{
+				{Index: 1, CodecType: AudioStream, CodecName: "ac3", Language: "eng", Loudness: &Loudness{Integrated: -20, TruePeak: -0.5, Range: 9, Threshold: -30, Offset: -0.1}},
+				{Index: 2, CodecType: AudioStream, CodecName: "aac", Language: "fre"},
+			},
+			GivenProfile:  EncodingProfile{Audio: AudioProfile{Loudnorm: true, TargetLoudness: -16}},
+			ExpectCommand: "ffmpeg",
+			ExpectArgs: "-y -i someName.mkv -vcodec copy -preset fast -map 0:a:0 -map 0:a:1 -map 0:V:0 -c:v:0 copy " +
+				"-c:a aac -b:a 128k -filter:a:0 loudnorm=I=-16:TP=-1:LRA=7:measured_I=-20.00:measured_TP=-0.50:measured_LRA=9.00:measured_thresh=-30.00:offset=-0.10:linear=true,aresample=48000 " +
+				"-var_stream_map a:0,agroup:audio,language:eng,default:yes a:1,agroup:audio,language:fre v:0,agroup:audio " + hlsArgs,
+			ExpectError: false,
+		},
+		{
+			Name:            "Portrait video",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: Resolution{X: 1080, Y: 1920},
+			GivenAudio:      singleAudio,
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs:      "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -filter:v:0 scale=480:-2,format=yuv420p -c:v:0 libx264 -crf:v:0 23 -c:v:1 copy -c:a copy -var_stream_map v:0,a:0 v:1,a:1 " + hlsArgs,
+			ExpectError:     false,
+		},
+		{
+			Name:            "Rotated phone video",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: Resolution{X: 1920, Y: 1080, Rotation: 90},
+			GivenAudio:      singleAudio,
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs: "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -filter:v:0 scale=480:-2,format=yuv420p -c:v:0 libx264 -crf:v:0 23 " +
+				"-filter:v:1 format=yuv420p -c:v:1 libx264 -crf:v:1 23 -c:a copy -var_stream_map v:0,a:0 v:1,a:1 " + hlsArgs,
+			ExpectError: false,
+		},
+		{
+			Name:            "Anamorphic video",
+			GivenFilePath:   "someName.mpg",
+			GivenResolution: Resolution{X: 720, Y: 576, SARNum: 64, SARDen: 45},
+			GivenAudio:      singleAudio,
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs: "-y -i someName.mpg -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -filter:v:0 scale=trunc(iw*sar/2)*2:ih,setsar=1,scale=-2:480,format=yuv420p -c:v:0 libx264 -crf:v:0 23 " +
+				"-filter:v:1 scale=trunc(iw*sar/2)*2:ih,setsar=1,format=yuv420p -c:v:1 libx264 -crf:v:1 23 -c:a copy -var_stream_map v:0,a:0 v:1,a:1 " + hlsArgs,
+			ExpectError: false,
+		},
+		{
+			Name:            "Rotated video without lower rendition",
+			GivenFilePath:   "someName.mp4",
+			GivenResolution: Resolution{X: 640, Y: 360, Rotation: 270},
+			GivenAudio:      singleAudio,
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs:      "-y -i someName.mp4 -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -filter:v:0 format=yuv420p -c:v:0 libx264 -crf:v:0 23 -c:a copy -var_stream_map v:0,a:0 " + hlsArgs,
+			ExpectError:     false,
+		},
+		{
+			Name:            "HDR video",
+			GivenFilePath:   "someName.mkv",
+			GivenResolution: Resolution{X: 3840, Y: 2160, Transfer: TransferPQ},
+			GivenAudio:      singleAudio,
+			GivenProfile:    EncodingProfile{Codecs: []VideoCodec{H264, HEVC}},
+			ExpectCommand:   "ffmpeg",
+			ExpectArgs: "-y -i someName.mkv -vcodec copy -preset fast -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 -map 0:V:0 -map 0:a:0 " +
+				"-filter:v:0 scale=-2:480," + sdrToneMapping + " -c:v:0 libx264 -crf:v:0 23 " +
+				"-filter:v:1 scale=-2:480,format=yuv420p10le -c:v:1 libx265 -crf:v:1 28 -tag:v:1 hvc1 -color_primaries:v:1 bt2020 -color_trc:v:1 smpte2084 -colorspace:v:1 bt2020nc " +
+				"-filter:v:2 scale=-2:1080," + sdrToneMapping + " -c:v:2 libx264 -crf:v:2 23 " +
+				"-filter:v:3 scale=-2:1080,format=yuv420p10le -c:v:3 libx265 -crf:v:3 28 -tag:v:3 hvc1 -color_primaries:v:3 bt2020 -color_trc:v:3 smpte2084 -colorspace:v:3 bt2020nc " +
+				"-c:v:4 copy -c:a copy -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 v:3,a:3 v:4,a:4 " + hlsArgs,
+			ExpectError: false,
+		},
+		{
+			Name:            "With several audio tracks",
+			GivenFilePath:   "someName.mkv",
+			GivenResolution: Resolution{X: 1280, Y: 720},
+			GivenAudio:      severalAudio,
 			ExpectCommand:   "ffmpeg",
-			ExpectArgs:      "-y -i someName.mp4 -pix_fmt yuv420p -vcodec libx264 -preset fast -g 48 -sc_threshold 0 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -map 0:0 -map 0:1 -s:v:0 640x480 -c:v:0 libx264 -b:v:0 1000k -s:v:1 1280x720 -c:v:1 libx264 -b:v:1 2000k -s:v:2 1920x1080 -c:v:2 libx264 -b:v:2 4000k -s:v:3 3840x2160 -c:v:3 libx264 -b:v:3 8000k -c:a aac -b:a 128k -ac 2 -var_stream_map v:0,a:0 v:1,a:1 v:2,a:2 v:3,a:3 -master_pl_name master.m3u8 -f hls -hls_time 6 -hls_list_size 0 -hls_segment_filename v%v/segment%d.ts v%v/segment_index.m3u8",
+			ExpectArgs:      "-y -i someName.mkv -vcodec copy -preset fast -map 0:a:0 -map 0:a:1 -map 0:a:2 -map 0:V:0 -map 0:V:0 -filter:v:0 scale=-2:480,format=yuv420p -c:v:0 libx264 -crf:v:0 23 -c:v:1 copy -c:a copy -var_stream_map a:0,agroup:audio,language:eng a:1,agroup:audio,language:fre,default:yes a:2,agroup:audio v:0,agroup:audio v:1,agroup:audio " + hlsArgs,
 			ExpectError:     false,
 		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
-			cmd, args, err := generateCommand(tt.GivenFilePath, tt.GivenResolution)
+			cmd, args, err := generateCommand(tt.GivenFilePath, tt.GivenResolution, tt.GivenAudio, tt.GivenProfile)
 			if tt.ExpectError {
 				require.NotNil(t, err)
 				return
@@ -82,17 +193,17 @@ func Test_convertToHLS(t *testing.T) {
 		GivenResolution Resolution
 		ExpectError     bool
 	}{
-		{Name: "Low quality video (960x400_ocean_with_audio.avi)", GivenFilePath: "../../../../../samples/960x400_ocean_with_audio.avi", GivenResolution: Resolution{960, 400, 0}, ExpectError: false},
-		{Name: "Medium low quality video (1280x720_2mb.mp4)", GivenFilePath: "../../../../../samples/1280x720_2mb.mp4", GivenResolution: Resolution{1280, 720, 0}, ExpectError: false},
-		{Name: "High quality video (4K-10bit.mkv)", GivenFilePath: "../../../../../samples/4K-10bit.mkv", GivenResolution: Resolution{3840, 2160, 0}, ExpectError: false},
-		{Name: "Video that doesn't exists", GivenFilePath: "../../../../../samples/none.mkv", GivenResolution: Resolution{3840, 2160, 0}, ExpectError: true},
+		{Name: "Low quality video (960x400_ocean_with_audio.avi)", GivenFilePath: "../../../../../samples/960x400_ocean_with_audio.avi", GivenResolution: Resolution{X: 960, Y: 400}, ExpectError: false},
+		{Name: "Medium low quality video (1280x720_2mb.mp4)", GivenFilePath: "../../../../../samples/1280x720_2mb.mp4", GivenResolution: Resolution{X: 1280, Y: 720}, ExpectError: false},
+		{Name: "High quality video (4K-10bit.mkv)", GivenFilePath: "../../../../../samples/4K-10bit.mkv", GivenResolution: Resolution{X: 3840, Y: 2160}, ExpectError: false},
+		{Name: "Video that doesn't exists", GivenFilePath: "../../../../../samples/none.mkv", GivenResolution: Resolution{X: 3840, Y: 2160}, ExpectError: true},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
 			_ = os.Mkdir("tmpVideoTest", os.ModePerm)
 			_ = os.Chdir("tmpVideoTest")
-			err := ConvertToHLS(tt.GivenFilePath, tt.GivenResolution)
+			err := ConvertToHLS(tt.GivenFilePath, tt.GivenResolution, nil, EncodingProfile{})
 			if tt.ExpectError {
 				require.NotNil(t, err)
 				return
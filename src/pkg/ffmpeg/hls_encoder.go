@@ -8,8 +8,16 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func ConvertToHLS(source string, res Resolution) error {
-	cmd, args, err := generateCommand(source, res)
+// EncodingProfile describes how the renditions are encoded
+type EncodingProfile struct {
+	Codecs []VideoCodec // Codecs of the lower renditions
+	Audio  AudioProfile
+}
+
+// ConvertToHLS encodes the source in several renditions, each lower rendition once per codec.
+// When the source has several audio tracks, they are exposed as alternate audio renditions.
+func ConvertToHLS(source string, res Resolution, audio []MediaStream, profile EncodingProfile) error {
+	cmd, args, err := generateCommand(source, res, audio, profile)
 	if err != nil {
 		return err
 	}
@@ -20,43 +28,164 @@ func ConvertToHLS(source string, res Resolution) error {
 	return err
 }
 
-func generateCommand(filepath string, res Resolution) (string, []string, error) {
+// Tone mapping of the PQ and HLG sources to SDR BT.709, for the players without HDR support
+const sdrToneMapping = "zscale=t=linear:npl=100,format=gbrpf32le,zscale=p=bt709,tonemap=tonemap=hable:desat=0,zscale=t=bt709:m=bt709:r=tv,format=yuv420p"
+
+// Lower renditions, the short side of the output is scaled to the short side of the rung
+var renditionLadder = []Resolution{{X: 640, Y: 480}, {X: 1920, Y: 1080}}
+
+func generateCommand(filepath string, res Resolution, audio []MediaStream, profile EncodingProfile) (string, []string, error) {
 	// working under assumption that uploaded video is already of correct format
 	// do only minimal processing for the sake of speed
-	if res.X < 640 && res.Y < 480 {
-		return "", nil, fmt.Errorf("Resolution (%d,%d) is below minimal Resolution (640x480)", res.X, res.Y)
+	display := res.Display()
+	if long, short := display.Sides(); long < 640 && short < 480 {
+		return "", nil, fmt.Errorf("Resolution (%d,%d) is below minimal Resolution (640x480)", display.X, display.Y)
+	}
+	codecs := profile.Codecs
+	if len(codecs) == 0 {
+		codecs = []VideoCodec{H264}
+	}
+
+	rungs := []uint64{}
+	for _, rung := range renditionLadder {
+		if display.GreaterResolution(rung) {
+			rungs = append(rungs, rung.Y)
+		}
 	}
 
 	command := "ffmpeg"
 	args := []string{"-y", "-i", filepath, "-vcodec", "copy", "-preset", "fast"}
-	sound := []string{"-map", "0:0", "-map", "0:1"}
 	resolutionTarget := []string{}
-	streamMap := "v:0,a:0"
 	i := 0
-	if res.GreaterResolution(Resolution{X: 640, Y: 480}) {
-		sound = append(sound, "-map", "0:0", "-map", "0:1")
-		i = 1
-		resolutionTarget = append(resolutionTarget, "-s:v:0", "640x480", "-filter:v:0", "format=yuv420p", "-c:v:0", "libx264", "-crf:v:0", "23")
-		streamMap += " v:1,a:1"
+	for _, rung := range rungs {
+		for _, codec := range codecs {
+			resolutionTarget = append(resolutionTarget, fmt.Sprintf("-filter:v:%d", i), videoFilter(res, rung, codec))
+			resolutionTarget = append(resolutionTarget, codec.encoderArgs(i)...)
+			resolutionTarget = append(resolutionTarget, hdrArgs(res, codec, i)...)
+			i++
+		}
 	}
-
-	if res.GreaterResolution(Resolution{X: 1920, Y: 1080}) {
-		i = 2
-		sound = append(sound, "-map", "0:0", "-map", "0:1")
-		resolutionTarget = append(resolutionTarget, "-s:v:1", "1920x1080", "-filter:v:1", "format=yuv420p", "-c:v:1", "libx264", "-crf:v:1", "23")
-		streamMap = streamMap + " v:2,a:2"
+	// Players ignore the rotation of a copied stream, rotated and anamorphic sources are encoded at their display resolution
+	if res.Rotation != 0 || res.IsAnamorphic() {
+		resolutionTarget = append(resolutionTarget, fmt.Sprintf("-filter:v:%d", i), videoFilter(res, 0, H264))
+		resolutionTarget = append(resolutionTarget, H264.encoderArgs(i)...)
+	} else {
+		resolutionTarget = append(resolutionTarget, fmt.Sprintf("-c:v:%d", i), "copy")
 	}
-	resolutionTarget = append(resolutionTarget, fmt.Sprintf("-c:v:%d", i), "copy")
 
-	args = append(args, sound...)
+	maps, streamMap := mapStreams(i+1, audio)
+	args = append(args, maps...)
 	args = append(args, resolutionTarget...)
-	args = append(args, "-c:a", "copy")
+	args = append(args, profile.Audio.audioArgs(audioOutputs(i+1, audio))...)
 	args = append(args, "-var_stream_map", streamMap)
 	args = append(args, "-master_pl_name", "master.m3u8", "-f", "hls", "-hls_time", "6", "-hls_playlist_type", "vod", "-hls_segment_type", "fmp4", "-hls_list_size", "0", "-hls_segment_filename", "v%v/segment%d.m4s", "v%v/segment_index.m3u8")
 	log.Info("Generate command: ", command, " ", strings.Join(args, " "))
 	return command, args, nil
 }
 
+// videoFilter returns the filter chain of an encoded rendition. ffmpeg applies the rotation of the
+// source before it, so the short side is the width of portrait videos. A zero height keeps the size.
+func videoFilter(res Resolution, height uint64, codec VideoCodec) string {
+	filters := []string{}
+	if res.IsAnamorphic() {
+		filters = append(filters, "scale=trunc(iw*sar/2)*2:ih", "setsar=1")
+	}
+	if height != 0 {
+		// -2 keeps the aspect ratio with an even size, as the encoders require
+		if res.IsPortrait() {
+			filters = append(filters, fmt.Sprintf("scale=%d:-2", height))
+		} else {
+			filters = append(filters, fmt.Sprintf("scale=-2:%d", height))
+		}
+	}
+
+	switch {
+	case res.IsHDR() && codec == H264:
+		filters = append(filters, sdrToneMapping)
+	case res.IsHDR():
+		// HEVC and AV1 players usually support HDR, it is kept in 10 bits
+		filters = append(filters, "format=yuv420p10le")
+	default:
+		filters = append(filters, "format=yuv420p")
+	}
+	return strings.Join(filters, ",")
+}
+
+// hdrArgs returns the color description of the HDR renditions encoded from a HDR source
+func hdrArgs(res Resolution, codec VideoCodec, i int) []string {
+	if !res.IsHDR() || codec == H264 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("-color_primaries:v:%d", i), "bt2020",
+		fmt.Sprintf("-color_trc:v:%d", i), res.Transfer,
+		fmt.Sprintf("-colorspace:v:%d", i), "bt2020nc",
+	}
+}
+
+// mapStreams returns the -map arguments and the var_stream_map of the video variants.
+// A single audio track is muxed in every variant, several tracks become the
+// renditions of one audio group (#EXT-X-MEDIA:TYPE=AUDIO) shared by the variants.
+func mapStreams(variants int, audio []MediaStream) ([]string, string) {
+	maps := []string{}
+	streamMap := []string{}
+
+	if len(audio) <= 1 {
+		for v := 0; v < variants; v++ {
+			maps = append(maps, "-map", "0:V:0", "-map", "0:a:0")
+			streamMap = append(streamMap, fmt.Sprintf("v:%d,a:%d", v, v))
+		}
+		return maps, strings.Join(streamMap, " ")
+	}
+
+	defaultAudio := DefaultAudioTrack(audio)
+	for a, stream := range audio {
+		maps = append(maps, "-map", fmt.Sprintf("0:a:%d", a))
+		rendition := fmt.Sprintf("a:%d,agroup:audio", a)
+		if language := stream.hlsLanguage(); language != "" {
+			rendition += ",language:" + language
+		}
+		if a == defaultAudio {
+			rendition += ",default:yes"
+		}
+		streamMap = append(streamMap, rendition)
+	}
+
+	for v := 0; v < variants; v++ {
+		maps = append(maps, "-map", "0:V:0")
+		streamMap = append(streamMap, fmt.Sprintf("v:%d,agroup:audio", v))
+	}
+
+	return maps, strings.Join(streamMap, " ")
+}
+
+// audioOutputs returns the source track of each output audio stream, following mapStreams
+func audioOutputs(variants int, audio []MediaStream) []MediaStream {
+	if len(audio) > 1 {
+		return audio
+	}
+
+	track := MediaStream{CodecType: AudioStream}
+	if len(audio) == 1 {
+		track = audio[0]
+	}
+	outputs := make([]MediaStream, variants)
+	for v := range outputs {
+		outputs[v] = track
+	}
+	return outputs
+}
+
+// DefaultAudioTrack returns the position of the default track among the audio tracks, the first one if none is flagged
+func DefaultAudioTrack(audio []MediaStream) int {
+	for a, stream := range audio {
+		if stream.Default {
+			return a
+		}
+	}
+	return 0
+}
+
 func ConvertToHLSWithDownsample(source string, res Resolution, resTargets ...Resolution) error {
 	cmd, args, err := generateCommandWithDownsampleNvidia(source, res, resTargets...)
 	if err != nil {
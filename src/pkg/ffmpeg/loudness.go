@@ -0,0 +1,120 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// EBU R128 targets of the loudness normalization, the integrated one is configurable
+	loudnessTruePeak = -1.0
+	loudnessRange    = 7.0
+	// loudnorm works at 192 kHz, the output is brought back to a usual rate
+	normalizedSampleRate = "48000"
+)
+
+// AudioProfile is the audio stage of the encoding profile
+type AudioProfile struct {
+	Transcode      bool    // Encode the tracks in AAC instead of copying them
+	Bitrate        string  // AAC bitrate, e.g. 128k
+	Downmix        bool    // Downmix the tracks to stereo
+	Loudnorm       bool    // Two-pass EBU R128 loudness normalization
+	TargetLoudness float64 // Integrated loudness target, in LUFS
+}
+
+// Loudness is the measure of a track by the first loudnorm pass
+type Loudness struct {
+	Integrated float64 `json:"input_i,string"`
+	TruePeak   float64 `json:"input_tp,string"`
+	Range      float64 `json:"input_lra,string"`
+	Threshold  float64 `json:"input_thresh,string"`
+	Offset     float64 `json:"target_offset,string"`
+}
+
+// reencodes tells whether the audio tracks cannot be copied as they are
+func (p AudioProfile) reencodes() bool {
+	return p.Transcode || p.Downmix || p.Loudnorm
+}
+
+// filter returns the audio filter chain of a track, measured is nil for the first loudnorm pass
+func (p AudioProfile) filter(measured *Loudness) string {
+	filters := []string{}
+	if p.Downmix {
+		filters = append(filters, "aformat=channel_layouts=stereo")
+	}
+	if p.Loudnorm {
+		loudnorm := fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g", p.TargetLoudness, loudnessTruePeak, loudnessRange)
+		if measured == nil {
+			loudnorm += ":print_format=json"
+		} else {
+			loudnorm += fmt.Sprintf(":measured_I=%.2f:measured_TP=%.2f:measured_LRA=%.2f:measured_thresh=%.2f:offset=%.2f:linear=true",
+				measured.Integrated, measured.TruePeak, measured.Range, measured.Threshold, measured.Offset)
+		}
+		filters = append(filters, loudnorm, "aresample="+normalizedSampleRate)
+	}
+	return strings.Join(filters, ",")
+}
+
+// audioArgs returns the ffmpeg arguments encoding the output audio streams, outputs[i] being the source track of stream i.
+// Tracks are normalized only when they could be measured.
+func (p AudioProfile) audioArgs(outputs []MediaStream) []string {
+	if !p.reencodes() {
+		return []string{"-c:a", "copy"}
+	}
+
+	bitrate := p.Bitrate
+	if bitrate == "" {
+		bitrate = "128k"
+	}
+	args := []string{"-c:a", "aac", "-b:a", bitrate}
+	for i, stream := range outputs {
+		profile := p
+		profile.Loudnorm = p.Loudnorm && stream.Loudness != nil
+		if filter := profile.filter(stream.Loudness); filter != "" {
+			args = append(args, fmt.Sprintf("-filter:a:%d", i), filter)
+		}
+	}
+	return args
+}
+
+// MeasureLoudness runs the first loudnorm pass on an audio track of the source (0 for the first one)
+func MeasureLoudness(source string, track int, profile AudioProfile) (Loudness, error) {
+	// ffmpeg -hide_banner -nostats -i <source> -map 0:a:<track> -filter:a [aformat=channel_layouts=stereo,]loudnorm=I=-23:TP=-1:LRA=7:print_format=json -f null -
+	profile.Loudnorm = true
+	args := []string{"-hide_banner", "-nostats", "-i", source, "-map", fmt.Sprintf("0:a:%d", track), "-filter:a", profile.filter(nil), "-f", "null", "-"}
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+		return Loudness{}, err
+	}
+	return parseLoudnormOutput(rawOutput)
+}
+
+// parseLoudnormOutput reads the measure printed by loudnorm at the end of the ffmpeg output
+func parseLoudnormOutput(rawOutput []byte) (Loudness, error) {
+	output := string(rawOutput)
+	start, end := strings.LastIndex(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return Loudness{}, errors.New("no loudnorm measure in the ffmpeg output")
+	}
+
+	var loudness Loudness
+	if err := json.Unmarshal([]byte(output[start:end+1]), &loudness); err != nil {
+		return Loudness{}, fmt.Errorf("invalid loudnorm measure : %w", err)
+	}
+
+	// A silent track is measured at -inf, it cannot be normalized
+	for _, value := range []float64{loudness.Integrated, loudness.TruePeak, loudness.Range, loudness.Threshold, loudness.Offset} {
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return Loudness{}, errors.New("track too quiet to be measured")
+		}
+	}
+	return loudness, nil
+}
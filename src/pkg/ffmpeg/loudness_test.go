@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseLoudnormOutput(t *testing.T) {
+	cases := []struct {
+		Name           string
+		GivenOutput    string
+		ExpectLoudness Loudness
+		ExpectError    bool
+	}{
+		{
+			Name: "Measured track",
+			GivenOutput: `Input #0, matroska,webm, from 'source.mkv':
+  Stream #0:1(eng): Audio: opus, 48000 Hz, stereo, fltp (default)
+[Parsed_loudnorm_0 @ 0x55d0c8a4f2c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-23.00",
+	"output_tp" : "-1.00",
+	"output_lra" : "7.00",
+	"output_thresh" : "-34.23",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.00"
+}
+`,
+			ExpectLoudness: Loudness{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.20, Offset: 0},
+		},
+		{
+			Name: "Silent track",
+			GivenOutput: `[Parsed_loudnorm_0 @ 0x55d0c8a4f2c0]
+{
+	"input_i" : "-inf",
+	"input_tp" : "-inf",
+	"input_lra" : "0.00",
+	"input_thresh" : "-70.00",
+	"target_offset" : "inf"
+}
+`,
+			ExpectError: true,
+		},
+		{
+			Name:        "No measure",
+			GivenOutput: `Stream map '0:a:0' matches no streams.`,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			loudness, err := parseLoudnormOutput([]byte(tt.GivenOutput))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectLoudness, loudness)
+		})
+	}
+}
+
+func Test_AudioProfileFilter(t *testing.T) {
+	require.Equal(t, "", AudioProfile{Transcode: true}.filter(nil))
+	require.Equal(t, "aformat=channel_layouts=stereo", AudioProfile{Downmix: true}.filter(nil))
+	require.Equal(t, "loudnorm=I=-23:TP=-1:LRA=7:print_format=json,aresample=48000", AudioProfile{Loudnorm: true, TargetLoudness: -23}.filter(nil))
+	require.Equal(t, []string{"-c:a", "copy"}, AudioProfile{}.audioArgs([]MediaStream{{CodecType: AudioStream}}))
+}
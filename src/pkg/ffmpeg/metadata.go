@@ -1,23 +1,96 @@
 package ffmpeg
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
 	"os/exec"
 	"strconv"
 	"strings"
 )
 
+// Color transfer characteristics of the HDR sources, as reported by ffprobe
+const (
+	TransferPQ  = "smpte2084"
+	TransferHLG = "arib-std-b67"
+)
+
 type Resolution struct {
-	X       uint64
-	Y       uint64
+	X       uint64 // Coded width, before rotation
+	Y       uint64 // Coded height, before rotation
 	Bitrate uint64
+
+	Rotation int    // Clockwise display rotation in degrees: 0, 90, 180 or 270
+	SARNum   uint64 // Sample aspect ratio, anamorphic sources have non-square pixels
+	SARDen   uint64
+	Transfer string // Color transfer characteristic
+}
+
+type ffprobeResolutionOutput struct {
+	Streams []struct {
+		Width             uint64 `json:"width"`
+		Height            uint64 `json:"height"`
+		Bitrate           string `json:"bit_rate"`
+		SampleAspectRatio string `json:"sample_aspect_ratio"`
+		ColorTransfer     string `json:"color_transfer"`
+		Tags              struct {
+			Rotate string `json:"rotate"`
+		} `json:"tags"`
+		SideDataList []struct {
+			Rotation *float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// Sides returns the long and the short side of the resolution, whatever its orientation
+func (r Resolution) Sides() (uint64, uint64) {
+	if r.X >= r.Y {
+		return r.X, r.Y
+	}
+	return r.Y, r.X
 }
 
+// GreaterOrEqualResolution compares the long sides and the short sides, so that a portrait
+// video is compared with a landscape one as if both had the same orientation
 func (r Resolution) GreaterOrEqualResolution(input Resolution) bool {
-	return r.X >= input.X && r.Y >= input.Y
+	long, short := r.Sides()
+	inputLong, inputShort := input.Sides()
+	return long >= inputLong && short >= inputShort
 }
 
+// GreaterResolution compares the long sides and the short sides, see GreaterOrEqualResolution
 func (r Resolution) GreaterResolution(input Resolution) bool {
-	return r.X > input.X && r.Y > input.Y
+	long, short := r.Sides()
+	inputLong, inputShort := input.Sides()
+	return long > inputLong && short > inputShort
+}
+
+// Display returns the resolution the video is displayed at: square pixels, rotation applied
+func (r Resolution) Display() Resolution {
+	display := Resolution{X: r.X, Y: r.Y, Bitrate: r.Bitrate, Transfer: r.Transfer}
+	if r.IsAnamorphic() {
+		display.X = uint64(math.Round(float64(r.X*r.SARNum)/float64(r.SARDen)/2)) * 2
+	}
+	if r.Rotation == 90 || r.Rotation == 270 {
+		display.X, display.Y = display.Y, display.X
+	}
+	return display
+}
+
+// IsPortrait tells whether the video is displayed higher than wide
+func (r Resolution) IsPortrait() bool {
+	display := r.Display()
+	return display.Y > display.X
+}
+
+// IsAnamorphic tells whether the pixels of the video are not square
+func (r Resolution) IsAnamorphic() bool {
+	return r.SARNum != 0 && r.SARDen != 0 && r.SARNum != r.SARDen
+}
+
+// IsHDR tells whether the video uses a PQ or HLG transfer
+func (r Resolution) IsHDR() bool {
+	return r.Transfer == TransferPQ || r.Transfer == TransferHLG
 }
 
 func CheckContainsSound(filepath string) (bool, error) {
@@ -30,29 +103,66 @@ func CheckContainsSound(filepath string) (bool, error) {
 	return haveSound, err
 }
 
-// Extract Resolution of the video
+// Extract Resolution of the video, with its rotation, sample aspect ratio and color transfer
 func ExtractResolution(filepath string) (Resolution, error) {
-	// ffprobe -v error -select_streams v:0 -show_entries stream=width,height -of csv=s=x:p=0 <filepath>
-	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0", "-show_entries", "stream=width,height,bit_rate", "-of", "csv=s=x:p=0", filepath).Output()
+	// ffprobe -v error -select_streams v:0 -show_entries stream=width,height,bit_rate,sample_aspect_ratio,color_transfer:stream_tags=rotate:stream_side_data=rotation -of json <filepath>
+	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0", "-show_entries", "stream=width,height,bit_rate,sample_aspect_ratio,color_transfer:stream_tags=rotate:stream_side_data=rotation", "-of", "json", filepath).Output()
 	if err != nil {
 		return Resolution{}, err
 	}
-	output := string(rawOutput[:])
 
-	//Sometimes, ffprobe return several Resolution despite the video only have one video track
-	firstLine := strings.Trim(strings.Split(output, "\n")[0], "\r") // We get: XRESxYRES
+	return parseResolutionOutput(rawOutput)
+}
 
-	splitResolution := strings.Split(firstLine, "x")
-	var x, y, br uint64
-	if x, err = strconv.ParseUint(splitResolution[0], 10, 32); err != nil {
+func parseResolutionOutput(rawOutput []byte) (Resolution, error) {
+	var output ffprobeResolutionOutput
+	if err := json.Unmarshal(rawOutput, &output); err != nil {
 		return Resolution{}, err
 	}
-	if y, err = strconv.ParseUint(splitResolution[1], 10, 32); err != nil {
-		return Resolution{}, err
+
+	//Sometimes, ffprobe return several Resolution despite the video only have one video track
+	if len(output.Streams) == 0 {
+		return Resolution{}, errors.New("no video stream found")
 	}
-	if br, err = strconv.ParseUint(splitResolution[2], 10, 32); err != nil {
-		return Resolution{}, err
+	stream := output.Streams[0]
+
+	res := Resolution{X: stream.Width, Y: stream.Height, Transfer: stream.ColorTransfer}
+
+	// Some containers, like Matroska, only expose the bitrate of the whole file
+	if stream.Bitrate != "" {
+		br, err := strconv.ParseUint(stream.Bitrate, 10, 64)
+		if err != nil {
+			return Resolution{}, err
+		}
+		res.Bitrate = br
+	}
+
+	// 0:1 or N/A when unknown, the pixels are then assumed square
+	if sar := strings.Split(stream.SampleAspectRatio, ":"); len(sar) == 2 {
+		num, errNum := strconv.ParseUint(sar[0], 10, 64)
+		den, errDen := strconv.ParseUint(sar[1], 10, 64)
+		if errNum == nil && errDen == nil && num != 0 && den != 0 {
+			res.SARNum, res.SARDen = num, den
+		}
+	}
+
+	// The display matrix rotation is counterclockwise, the legacy rotate tag clockwise
+	rotation := 0
+	for _, sideData := range stream.SideDataList {
+		if sideData.Rotation != nil {
+			rotation = -int(math.Round(*sideData.Rotation))
+		}
+	}
+	if rotation == 0 && stream.Tags.Rotate != "" {
+		tag, err := strconv.Atoi(stream.Tags.Rotate)
+		if err != nil {
+			return Resolution{}, err
+		}
+		rotation = tag
 	}
+	// Only quarter turns are supported, in [0, 360)
+	quarters := int(math.Round(float64(rotation) / 90))
+	res.Rotation = ((quarters%4 + 4) % 4) * 90
 
-	return Resolution{x, y, br}, nil
+	return res, nil
 }
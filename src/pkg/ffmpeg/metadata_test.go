@@ -19,31 +19,31 @@ func Test_ExtractResolution(t *testing.T) {
 		{
 			GivenPath:        "../../../../samples/", // FIXME(JPR): Root of the project from the test file (We need may need a better way to address these)
 			GivenFilename:    "320x240_testvideo.mp4",
-			ExpectResolution: Resolution{320, 240, 0},
+			ExpectResolution: Resolution{X: 320, Y: 240},
 			ExpectError:      false,
 		},
 		{
 			GivenPath:        "../../../../samples/", // FIXME(JPR): Root of the project from the test file (We need may need a better way to address these)
 			GivenFilename:    "960x400_ocean_with_audio.avi",
-			ExpectResolution: Resolution{960, 400, 0},
+			ExpectResolution: Resolution{X: 960, Y: 400},
 			ExpectError:      false,
 		},
 		{
 			GivenPath:        "../../../../samples/", // FIXME(JPR): Root of the project from the test file (We need may need a better way to address these)
 			GivenFilename:    "4K-10bit.mkv",
-			ExpectResolution: Resolution{3840, 2160, 0},
+			ExpectResolution: Resolution{X: 3840, Y: 2160},
 			ExpectError:      false,
 		},
 		{
 			GivenPath:        "../../../../samples/", // FIXME(JPR): Root of the project from the test file (We need may need a better way to address these)
 			GivenFilename:    "960x400_ocean_with_audio.mkv",
-			ExpectResolution: Resolution{960, 400, 0},
+			ExpectResolution: Resolution{X: 960, Y: 400},
 			ExpectError:      false,
 		},
 		{
 			GivenPath:        "../../../../samples/", // FIXME(JPR): Root of the project from the test file (We need may need a better way to address these)
 			GivenFilename:    "1280x720_2mb.mp4",
-			ExpectResolution: Resolution{1280, 720, 0},
+			ExpectResolution: Resolution{X: 1280, Y: 720},
 			ExpectError:      false,
 		},
 	}
@@ -63,6 +63,87 @@ func Test_ExtractResolution(t *testing.T) {
 	}
 }
 
+func Test_parseResolutionOutput(t *testing.T) {
+	cases := []struct {
+		Name             string
+		GivenOutput      string
+		ExpectResolution Resolution
+		ExpectError      bool
+	}{
+		{
+			Name:             "Landscape video",
+			GivenOutput:      `{"streams": [{"width": 1920, "height": 1080, "sample_aspect_ratio": "1:1", "bit_rate": "4000000", "color_transfer": "bt709"}]}`,
+			ExpectResolution: Resolution{X: 1920, Y: 1080, Bitrate: 4000000, SARNum: 1, SARDen: 1, Transfer: "bt709"},
+		},
+		{
+			Name:             "Phone video with display matrix",
+			GivenOutput:      `{"streams": [{"width": 1920, "height": 1080, "side_data_list": [{"rotation": -90}]}]}`,
+			ExpectResolution: Resolution{X: 1920, Y: 1080, Rotation: 90},
+		},
+		{
+			Name:             "Legacy rotate tag",
+			GivenOutput:      `{"streams": [{"width": 1920, "height": 1080, "tags": {"rotate": "270"}}]}`,
+			ExpectResolution: Resolution{X: 1920, Y: 1080, Rotation: 270},
+		},
+		{
+			Name:             "Upside down display matrix",
+			GivenOutput:      `{"streams": [{"width": 1280, "height": 720, "side_data_list": [{"rotation": 180}]}]}`,
+			ExpectResolution: Resolution{X: 1280, Y: 720, Rotation: 180},
+		},
+		{
+			Name:             "Anamorphic HDR video",
+			GivenOutput:      `{"streams": [{"width": 1440, "height": 1080, "sample_aspect_ratio": "4:3", "color_transfer": "arib-std-b67"}]}`,
+			ExpectResolution: Resolution{X: 1440, Y: 1080, SARNum: 4, SARDen: 3, Transfer: TransferHLG},
+		},
+		{
+			Name:             "Unknown sample aspect ratio",
+			GivenOutput:      `{"streams": [{"width": 640, "height": 480, "sample_aspect_ratio": "0:1"}]}`,
+			ExpectResolution: Resolution{X: 640, Y: 480},
+		},
+		{
+			Name:        "No video stream",
+			GivenOutput: `{"streams": []}`,
+			ExpectError: true,
+		},
+		{
+			Name:        "Invalid bitrate",
+			GivenOutput: `{"streams": [{"width": 640, "height": 480, "bit_rate": "N/A"}]}`,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			res, err := parseResolutionOutput([]byte(tt.GivenOutput))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectResolution, res)
+		})
+	}
+}
+
+func Test_Resolution(t *testing.T) {
+	portrait := Resolution{X: 1080, Y: 1920}
+	require.True(t, portrait.GreaterResolution(Resolution{X: 640, Y: 480}))
+	require.False(t, portrait.GreaterResolution(Resolution{X: 1920, Y: 1080}))
+	require.True(t, portrait.GreaterOrEqualResolution(Resolution{X: 1920, Y: 1080}))
+	require.True(t, portrait.IsPortrait())
+
+	rotated := Resolution{X: 1920, Y: 1080, Rotation: 90}
+	require.Equal(t, Resolution{X: 1080, Y: 1920}, rotated.Display())
+	require.True(t, rotated.IsPortrait())
+
+	anamorphic := Resolution{X: 720, Y: 576, SARNum: 64, SARDen: 45}
+	require.Equal(t, Resolution{X: 1024, Y: 576}, anamorphic.Display())
+	require.True(t, anamorphic.IsAnamorphic())
+
+	require.True(t, Resolution{Transfer: TransferPQ}.IsHDR())
+	require.False(t, Resolution{Transfer: "bt709"}.IsHDR())
+}
+
 func Test_videoHaveSound(t *testing.T) {
 	t.SkipNow()
 	cases := []struct {
@@ -0,0 +1,46 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PreviewFile = "preview.mp4"
+
+	previewSamples       = 5
+	previewSampleSeconds = 1.0
+	previewHeight        = 240
+)
+
+// GeneratePreview creates a short muted clip made of samples taken across the video
+func GeneratePreview(source string, duration float64, output string) error {
+	args := generatePreviewArgs(source, duration, output)
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+	}
+	return err
+}
+
+func generatePreviewArgs(source string, duration float64, output string) []string {
+	// ffmpeg -y -i <source> -an -sn -vf select='lt(mod(t\,<period>)\,1)',setpts=N/(FRAME_RATE*TB),scale=-2:240 \
+	//        -c:v libx264 -preset veryfast -crf 30 -pix_fmt yuv420p -movflags +faststart <output>
+	filters := []string{}
+
+	// Short videos are kept whole, the others are sampled at regular intervals
+	if duration > previewSamples*previewSampleSeconds {
+		period := duration / previewSamples
+		filters = append(filters,
+			fmt.Sprintf(`select='lt(mod(t\,%.3f)\,%g)'`, period, previewSampleSeconds),
+			"setpts=N/(FRAME_RATE*TB)")
+	}
+	filters = append(filters, fmt.Sprintf("scale=-2:'min(%d,ih)'", previewHeight))
+
+	return []string{"-y", "-i", source, "-an", "-sn", "-vf", strings.Join(filters, ","),
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "30", "-pix_fmt", "yuv420p", "-movflags", "+faststart", output}
+}
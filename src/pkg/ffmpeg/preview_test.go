@@ -0,0 +1,34 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generatePreviewArgs(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenDuration float64
+		ExpectArgs    string
+	}{
+		{
+			Name:          "Samples across the video",
+			GivenDuration: 120,
+			ExpectArgs:    `-y -i source.mp4 -an -sn -vf select='lt(mod(t\,24.000)\,1)',setpts=N/(FRAME_RATE*TB),scale=-2:'min(240,ih)' -c:v libx264 -preset veryfast -crf 30 -pix_fmt yuv420p -movflags +faststart preview.mp4`,
+		},
+		{
+			Name:          "Short video kept whole",
+			GivenDuration: 4.5,
+			ExpectArgs:    `-y -i source.mp4 -an -sn -vf scale=-2:'min(240,ih)' -c:v libx264 -preset veryfast -crf 30 -pix_fmt yuv420p -movflags +faststart preview.mp4`,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			args := generatePreviewArgs("source.mp4", tt.GivenDuration, "preview.mp4")
+			require.Equal(t, tt.ExpectArgs, strings.Join(args, " "))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+)
+
+type VideoProbe struct {
+	Width    uint64
+	Height   uint64
+	Duration float64 // In seconds
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		Width  uint64 `json:"width"`
+		Height uint64 `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// ProbeVideo only reads the container headers, so it is fast enough to be used
+// before accepting an upload. If filepath is empty, the video is read from input.
+func ProbeVideo(ctx context.Context, filepath string, input io.Reader) (VideoProbe, error) {
+	// ffprobe -v error -select_streams v:0 -show_entries stream=width,height:format=duration -of json <filepath>
+	source := filepath
+	if source == "" {
+		source = "pipe:0"
+	}
+
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-select_streams", "v:0", "-show_entries", "stream=width,height:format=duration", "-of", "json", source)
+	if filepath == "" {
+		cmd.Stdin = input
+	}
+
+	rawOutput, err := cmd.Output()
+	if err != nil {
+		return VideoProbe{}, err
+	}
+
+	return parseProbeOutput(rawOutput)
+}
+
+func parseProbeOutput(rawOutput []byte) (VideoProbe, error) {
+	var output ffprobeOutput
+	if err := json.Unmarshal(rawOutput, &output); err != nil {
+		return VideoProbe{}, err
+	}
+
+	if len(output.Streams) == 0 {
+		return VideoProbe{}, errors.New("no video stream found")
+	}
+
+	probe := VideoProbe{
+		Width:  output.Streams[0].Width,
+		Height: output.Streams[0].Height,
+	}
+
+	// Some containers do not expose a duration in their headers
+	if output.Format.Duration != "" {
+		duration, err := strconv.ParseFloat(output.Format.Duration, 64)
+		if err != nil {
+			return VideoProbe{}, err
+		}
+		probe.Duration = duration
+	}
+
+	return probe, nil
+}
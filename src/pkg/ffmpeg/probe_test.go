@@ -0,0 +1,54 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseProbeOutput(t *testing.T) {
+	cases := []struct {
+		Name        string
+		GivenOutput string
+		ExpectProbe VideoProbe
+		ExpectError bool
+	}{
+		{
+			Name:        "Video with duration",
+			GivenOutput: `{"programs": [], "streams": [{"width": 1280, "height": 720}], "format": {"duration": "30.526000"}}`,
+			ExpectProbe: VideoProbe{Width: 1280, Height: 720, Duration: 30.526},
+		},
+		{
+			Name:        "Video without duration",
+			GivenOutput: `{"streams": [{"width": 1080, "height": 1920}], "format": {}}`,
+			ExpectProbe: VideoProbe{Width: 1080, Height: 1920},
+		},
+		{
+			Name:        "No video stream",
+			GivenOutput: `{"streams": [], "format": {"duration": "30.526000"}}`,
+			ExpectError: true,
+		},
+		{
+			Name:        "Invalid duration",
+			GivenOutput: `{"streams": [{"width": 1280, "height": 720}], "format": {"duration": "N/A"}}`,
+			ExpectError: true,
+		},
+		{
+			Name:        "Invalid output",
+			GivenOutput: `Invalid data found when processing input`,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			probe, err := parseProbeOutput([]byte(tt.GivenOutput))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectProbe, probe)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AudioStream    = "audio"
+	SubtitleStream = "subtitle"
+)
+
+// Subtitle codecs that can be converted to WebVTT, bitmap subtitles (PGS, VobSub...) cannot
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+var notLanguageCharRegexp = regexp.MustCompile(`[^A-Za-z0-9-]`)
+
+type MediaStream struct {
+	Index     int // Absolute index of the stream in the source
+	CodecType string
+	CodecName string
+	Language  string // ISO 639-2 code from the stream tags, if any
+	Title     string
+	Default   bool
+	Forced    bool
+	Loudness  *Loudness // Measure of an audio track, set by the encoder before the loudness normalization
+}
+
+type ffprobeStreamsOutput struct {
+	Streams []struct {
+		Index       int    `json:"index"`
+		CodecType   string `json:"codec_type"`
+		CodecName   string `json:"codec_name"`
+		Disposition struct {
+			Default int `json:"default"`
+			Forced  int `json:"forced"`
+		} `json:"disposition"`
+		Tags struct {
+			Language string `json:"language"`
+			Title    string `json:"title"`
+		} `json:"tags"`
+	} `json:"streams"`
+}
+
+// IsTextSubtitle returns true if the stream can be extracted as WebVTT
+func (s MediaStream) IsTextSubtitle() bool {
+	return s.CodecType == SubtitleStream && textSubtitleCodecs[s.CodecName]
+}
+
+// hlsLanguage returns the stream language usable in a var_stream_map, "und" is dropped
+func (s MediaStream) hlsLanguage() string {
+	language := notLanguageCharRegexp.ReplaceAllString(s.Language, "")
+	if language == "und" {
+		return ""
+	}
+	return language
+}
+
+// ProbeStreams lists the audio and subtitle streams of the video, in the source order
+func ProbeStreams(filepath string) ([]MediaStream, error) {
+	// ffprobe -v error -show_entries stream=index,codec_type,codec_name:stream_tags=language,title:stream_disposition=default,forced -of json <filepath>
+	rawOutput, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=index,codec_type,codec_name:stream_tags=language,title:stream_disposition=default,forced", "-of", "json", filepath).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStreamsOutput(rawOutput)
+}
+
+func parseStreamsOutput(rawOutput []byte) ([]MediaStream, error) {
+	var output ffprobeStreamsOutput
+	if err := json.Unmarshal(rawOutput, &output); err != nil {
+		return nil, err
+	}
+
+	streams := []MediaStream{}
+	for _, stream := range output.Streams {
+		if stream.CodecType != AudioStream && stream.CodecType != SubtitleStream {
+			continue
+		}
+		streams = append(streams, MediaStream{
+			Index:     stream.Index,
+			CodecType: stream.CodecType,
+			CodecName: stream.CodecName,
+			Language:  stream.Tags.Language,
+			Title:     stream.Tags.Title,
+			Default:   stream.Disposition.Default == 1,
+			Forced:    stream.Disposition.Forced == 1,
+		})
+	}
+
+	return streams, nil
+}
+
+// FilterStreams returns the streams of the given codec type, keeping their relative order
+func FilterStreams(streams []MediaStream, codecType string) []MediaStream {
+	filtered := []MediaStream{}
+	for _, stream := range streams {
+		if stream.CodecType == codecType {
+			filtered = append(filtered, stream)
+		}
+	}
+	return filtered
+}
+
+// ExtractSubtitles converts a text subtitle stream of the source into a WebVTT file
+func ExtractSubtitles(source string, stream MediaStream, output string) error {
+	if !stream.IsTextSubtitle() {
+		return fmt.Errorf("stream %d (%v) is not a text subtitle stream", stream.Index, stream.CodecName)
+	}
+
+	// ffmpeg -y -i <source> -map 0:<index> -c:s webvtt <output>
+	args := []string{"-y", "-i", source, "-map", fmt.Sprintf("0:%d", stream.Index), "-c:s", "webvtt", output}
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseStreamsOutput(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenOutput   string
+		ExpectStreams []MediaStream
+		ExpectError   bool
+	}{
+		{
+			Name: "MKV with several audio and subtitle tracks",
+			GivenOutput: `{"programs": [], "streams": [
+				{"index": 0, "codec_name": "h264", "codec_type": "video", "disposition": {"default": 1, "forced": 0}},
+				{"index": 1, "codec_name": "aac", "codec_type": "audio", "disposition": {"default": 1, "forced": 0}, "tags": {"language": "eng", "title": "English"}},
+				{"index": 2, "codec_name": "ac3", "codec_type": "audio", "disposition": {"default": 0, "forced": 0}, "tags": {"language": "fre"}},
+				{"index": 3, "codec_name": "subrip", "codec_type": "subtitle", "disposition": {"default": 0, "forced": 1}, "tags": {"language": "fre", "title": "Forced"}},
+				{"index": 4, "codec_name": "hdmv_pgs_subtitle", "codec_type": "subtitle", "disposition": {"default": 0, "forced": 0}},
+				{"index": 5, "codec_name": "ttf", "codec_type": "attachment"}
+			]}`,
+			ExpectStreams: []MediaStream{
+				{Index: 1, CodecType: AudioStream, CodecName: "aac", Language: "eng", Title: "English", Default: true},
+				{Index: 2, CodecType: AudioStream, CodecName: "ac3", Language: "fre"},
+				{Index: 3, CodecType: SubtitleStream, CodecName: "subrip", Language: "fre", Title: "Forced", Forced: true},
+				{Index: 4, CodecType: SubtitleStream, CodecName: "hdmv_pgs_subtitle"},
+			},
+		},
+		{
+			Name:          "Video without audio",
+			GivenOutput:   `{"streams": [{"index": 0, "codec_name": "h264", "codec_type": "video"}]}`,
+			ExpectStreams: []MediaStream{},
+		},
+		{
+			Name:        "Invalid output",
+			GivenOutput: `Invalid data found when processing input`,
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			streams, err := parseStreamsOutput([]byte(tt.GivenOutput))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectStreams, streams)
+		})
+	}
+}
+
+func Test_IsTextSubtitle(t *testing.T) {
+	require.True(t, MediaStream{CodecType: SubtitleStream, CodecName: "subrip"}.IsTextSubtitle())
+	require.True(t, MediaStream{CodecType: SubtitleStream, CodecName: "mov_text"}.IsTextSubtitle())
+	require.False(t, MediaStream{CodecType: SubtitleStream, CodecName: "dvd_subtitle"}.IsTextSubtitle())
+	require.False(t, MediaStream{CodecType: AudioStream, CodecName: "aac"}.IsTextSubtitle())
+}
@@ -0,0 +1,98 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/subtitles"
+)
+
+const (
+	ThumbnailsFolder = "thumbnails"
+	ThumbnailsVTT    = "thumbnails.vtt"
+
+	thumbnailWidth   = 160
+	spriteColumns    = 10
+	spriteRows       = 10
+	thumbnailsSprite = "sprite_%d.jpg"
+)
+
+type ThumbnailsSprites struct {
+	Interval        time.Duration // Time between two thumbnails
+	Duration        time.Duration // Duration of the video
+	ThumbnailWidth  uint64
+	ThumbnailHeight uint64
+}
+
+// NewThumbnailsSprites computes the thumbnails size, keeping the aspect ratio of the video
+func NewThumbnailsSprites(interval time.Duration, probe VideoProbe) (ThumbnailsSprites, error) {
+	if interval <= 0 || probe.Duration <= 0 || probe.Width == 0 || probe.Height == 0 {
+		return ThumbnailsSprites{}, fmt.Errorf("cannot generate thumbnails every %v of a %vx%v video lasting %vs", interval, probe.Width, probe.Height, probe.Duration)
+	}
+
+	// Height must be even for the scale filter
+	height := uint64(math.Round(float64(thumbnailWidth*probe.Height)/float64(probe.Width)/2) * 2)
+	if height == 0 {
+		height = 2
+	}
+
+	return ThumbnailsSprites{
+		Interval:        interval,
+		Duration:        time.Duration(probe.Duration * float64(time.Second)),
+		ThumbnailWidth:  thumbnailWidth,
+		ThumbnailHeight: height,
+	}, nil
+}
+
+// Generate writes the sprite sheets and the WebVTT file mapping the timeline to their regions in the thumbnails folder
+func (t ThumbnailsSprites) Generate(source string) error {
+	if err := os.MkdirAll(ThumbnailsFolder, os.ModePerm); err != nil {
+		return err
+	}
+
+	args := t.generateArgs(source)
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	if rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput(); err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+		return err
+	}
+
+	return os.WriteFile(ThumbnailsFolder+"/"+ThumbnailsVTT, t.WebVTT(), 0644)
+}
+
+func (t ThumbnailsSprites) generateArgs(source string) []string {
+	// ffmpeg -y -i <source> -vf fps=1/<interval>,scale=<w>:<h>,tile=10x10 -q:v 4 -start_number 0 thumbnails/sprite_%d.jpg
+	filter := fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d", t.Interval.Seconds(), t.ThumbnailWidth, t.ThumbnailHeight, spriteColumns, spriteRows)
+	return []string{"-y", "-i", source, "-an", "-sn", "-vf", filter, "-q:v", "4", "-start_number", "0", ThumbnailsFolder + "/" + thumbnailsSprite}
+}
+
+// WebVTT maps each interval of the video to its thumbnail, as a media fragment of the sprite
+func (t ThumbnailsSprites) WebVTT() []byte {
+	perSprite := spriteColumns * spriteRows
+	count := int(math.Ceil(float64(t.Duration) / float64(t.Interval)))
+
+	cues := make([]subtitles.Cue, 0, count)
+	for i := 0; i < count; i++ {
+		end := time.Duration(i+1) * t.Interval
+		if end > t.Duration {
+			end = t.Duration
+		}
+
+		position := i % perSprite
+		x := uint64(position%spriteColumns) * t.ThumbnailWidth
+		y := uint64(position/spriteColumns) * t.ThumbnailHeight
+		cues = append(cues, subtitles.Cue{
+			Start: time.Duration(i) * t.Interval,
+			End:   end,
+			Text:  []string{fmt.Sprintf(thumbnailsSprite+"#xywh=%d,%d,%d,%d", i/perSprite, x, y, t.ThumbnailWidth, t.ThumbnailHeight)},
+		})
+	}
+
+	return subtitles.ToWebVTT(cues)
+}
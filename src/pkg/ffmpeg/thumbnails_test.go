@@ -0,0 +1,61 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewThumbnailsSprites(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenInterval time.Duration
+		GivenProbe    VideoProbe
+		ExpectHeight  uint64
+		ExpectError   bool
+	}{
+		{Name: "Landscape video", GivenInterval: 5 * time.Second, GivenProbe: VideoProbe{Width: 1920, Height: 1080, Duration: 60}, ExpectHeight: 90},
+		{Name: "Portrait video", GivenInterval: 5 * time.Second, GivenProbe: VideoProbe{Width: 1080, Height: 1920, Duration: 60}, ExpectHeight: 284},
+		{Name: "Odd height rounded", GivenInterval: 5 * time.Second, GivenProbe: VideoProbe{Width: 960, Height: 400, Duration: 60}, ExpectHeight: 66},
+		{Name: "Unknown duration", GivenInterval: 5 * time.Second, GivenProbe: VideoProbe{Width: 1920, Height: 1080}, ExpectError: true},
+		{Name: "Disabled", GivenInterval: 0, GivenProbe: VideoProbe{Width: 1920, Height: 1080, Duration: 60}, ExpectError: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			sprites, err := NewThumbnailsSprites(tt.GivenInterval, tt.GivenProbe)
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, uint64(160), sprites.ThumbnailWidth)
+			require.Equal(t, tt.ExpectHeight, sprites.ThumbnailHeight)
+		})
+	}
+}
+
+func Test_ThumbnailsSpritesArgs(t *testing.T) {
+	sprites := ThumbnailsSprites{Interval: 5 * time.Second, Duration: 12 * time.Second, ThumbnailWidth: 160, ThumbnailHeight: 90}
+	require.Equal(t,
+		"-y -i source.mp4 -an -sn -vf fps=1/5,scale=160:90,tile=10x10 -q:v 4 -start_number 0 thumbnails/sprite_%d.jpg",
+		strings.Join(sprites.generateArgs("source.mp4"), " "))
+}
+
+func Test_ThumbnailsSpritesWebVTT(t *testing.T) {
+	sprites := ThumbnailsSprites{Interval: 5 * time.Second, Duration: 12500 * time.Millisecond, ThumbnailWidth: 160, ThumbnailHeight: 90}
+	require.Equal(t, "WEBVTT\n"+
+		"\n00:00:00.000 --> 00:00:05.000\nsprite_0.jpg#xywh=0,0,160,90\n"+
+		"\n00:00:05.000 --> 00:00:10.000\nsprite_0.jpg#xywh=160,0,160,90\n"+
+		"\n00:00:10.000 --> 00:00:12.500\nsprite_0.jpg#xywh=320,0,160,90\n",
+		string(sprites.WebVTT()))
+
+	// A sprite holds 100 thumbnails, the next ones are in the following sprite
+	sprites.Duration = 510 * time.Second
+	vtt := string(sprites.WebVTT())
+	require.Contains(t, vtt, "\n00:08:15.000 --> 00:08:20.000\nsprite_0.jpg#xywh=1440,810,160,90\n")
+	require.Contains(t, vtt, "\n00:08:20.000 --> 00:08:25.000\nsprite_1.jpg#xywh=0,0,160,90\n")
+	require.True(t, strings.HasSuffix(vtt, "\n00:08:25.000 --> 00:08:30.000\nsprite_1.jpg#xywh=160,0,160,90\n"))
+}
@@ -2,32 +2,90 @@ package ffmpeg
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Video filters of the transformers, applied to the segments at playback time or burnt in a rendered video
+const (
+	FlipFilter = "vflip"
+	GrayFilter = "hue=s=0"
 )
 
+// TransformationFilters maps the transformers, as named by the filter parameter of the streams, to their video filter
+var TransformationFilters = map[string]string{
+	"flip": FlipFilter,
+	"gray": GrayFilter,
+}
+
+// TransformationFilterChain returns the video filter applying the transformers in order
+func TransformationFilterChain(transformers []string) (string, error) {
+	if len(transformers) == 0 {
+		return "", errors.New("no transformer")
+	}
+
+	filters := make([]string, 0, len(transformers))
+	for _, name := range transformers {
+		filter, ok := TransformationFilters[name]
+		if !ok {
+			return "", fmt.Errorf("Unknown transformer %v", name)
+		}
+		filters = append(filters, filter)
+	}
+	return strings.Join(filters, ","), nil
+}
+
 func CreateFlipCommand(ctx context.Context) *exec.Cmd {
-	// Create command
-	command := "ffmpeg"
-	args := []string{"-i", "pipe:0"}
-	args = append(args, "-f", "mpegts", "-muxdelay", "0", "-map", "0:0", "-map", "0:1", "-acodec", "copy")
-	args = append(args, "-vcodec", "libx264", "-preset", "fastlibx264", "-preset", "superfast", "-copyts")
-	args = append(args, "-vf", "vflip")
-	args = append(args, "pipe:1")
-	return exec.CommandContext(ctx, command, args...)
+	return createTransformationCommand(ctx, FlipFilter)
 }
 
 func CreateGrayCommand(ctx context.Context) *exec.Cmd {
+	return createTransformationCommand(ctx, GrayFilter)
+}
+
+func createTransformationCommand(ctx context.Context, filter string) *exec.Cmd {
 	// Create command
 	command := "ffmpeg"
 	args := []string{"-i", "pipe:0"}
 	args = append(args, "-f", "mpegts", "-muxdelay", "0", "-map", "0:0", "-map", "0:1", "-acodec", "copy")
 	args = append(args, "-vcodec", "libx264", "-preset", "fastlibx264", "-preset", "superfast", "-copyts")
-	args = append(args, "-vf", "hue=s=0")
+	args = append(args, "-vf", filter)
 	args = append(args, "pipe:1")
 	return exec.CommandContext(ctx, command, args...)
 }
 
+// RenderTransformations burns the transformers in a copy of the source, so that it plays without transforming the segments
+func RenderTransformations(source, output string, transformers []string) error {
+	args, err := generateRenderArgs(source, output, transformers)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("FFMPEG command: ffmpeg ", strings.Join(args, " "))
+	rawOutput, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		log.Debug("FFMPEG output: ", string(rawOutput))
+	}
+	return err
+}
+
+func generateRenderArgs(source, output string, transformers []string) ([]string, error) {
+	// ffmpeg -y -i <source> -map 0:V -map 0:a? -map 0:s? -vf <filters> -c:v libx264 -crf 18 -preset fast -c:a copy -c:s copy <output>
+	filter, err := TransformationFilterChain(transformers)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rendered video is a new source, it is encoded with a high quality
+	return []string{"-y", "-i", source, "-map", "0:V", "-map", "0:a?", "-map", "0:s?", "-vf", filter,
+		"-c:v", "libx264", "-crf", "18", "-preset", "fast", "-c:a", "copy", "-c:s", "copy", output}, nil
+}
+
 func TransformHLSPart(cmd *exec.Cmd, stdin io.Reader, stdout io.Writer) error {
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
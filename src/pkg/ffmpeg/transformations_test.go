@@ -0,0 +1,49 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateRenderArgs(t *testing.T) {
+	cases := []struct {
+		Name              string
+		GivenTransformers []string
+		ExpectArgs        string
+		ExpectError       bool
+	}{
+		{
+			Name:              "Single transformer",
+			GivenTransformers: []string{"gray"},
+			ExpectArgs:        "-y -i parent.mp4 -map 0:V -map 0:a? -map 0:s? -vf hue=s=0 -c:v libx264 -crf 18 -preset fast -c:a copy -c:s copy source.mp4",
+		},
+		{
+			Name:              "Transformers applied in order",
+			GivenTransformers: []string{"flip", "gray"},
+			ExpectArgs:        "-y -i parent.mp4 -map 0:V -map 0:a? -map 0:s? -vf vflip,hue=s=0 -c:v libx264 -crf 18 -preset fast -c:a copy -c:s copy source.mp4",
+		},
+		{
+			Name:              "Unknown transformer",
+			GivenTransformers: []string{"gray", "sepia"},
+			ExpectError:       true,
+		},
+		{
+			Name:        "No transformer",
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			args, err := generateRenderArgs("parent.mp4", "source.mp4", tt.GivenTransformers)
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectArgs, strings.Join(args, " "))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultWatermarkPosition = "bottom-right"
+	DefaultWatermarkOpacity  = 0.5
+)
+
+// Coordinates of the image (overlay filter) and of the text (drawtext filter) at each position, 10px from the edges
+var watermarkPositions = map[string]struct{ image, text string }{
+	"top-left":     {"10:10", "x=10:y=10"},
+	"top-right":    {"W-w-10:10", "x=w-tw-10:y=10"},
+	"bottom-left":  {"10:H-h-10", "x=10:y=h-th-10"},
+	"bottom-right": {"W-w-10:H-h-10", "x=w-tw-10:y=h-th-10"},
+	"center":       {"(W-w)/2:(H-h)/2", "x=(w-tw)/2:y=(h-th)/2"},
+}
+
+// The text is written in the filter graph, its characters must not need escaping there
+var watermarkTextRegexp = regexp.MustCompile(`^[\p{L}\p{N} @._#/+-]{1,100}$`)
+
+// Watermark is an image and/or a text stamped on the video. The text is drawn over the image when both are set.
+type Watermark struct {
+	ImageFile string  // Local path of the overlaid image, none if empty
+	Text      string  // None if empty
+	Position  string  // One of watermarkPositions, DefaultWatermarkPosition if empty
+	Opacity   float64 // From 0 (transparent) to 1 (opaque), DefaultWatermarkOpacity if 0
+}
+
+// Validate checks the text, the position and the opacity of the watermark
+func (w Watermark) Validate() error {
+	if w.Text != "" && !watermarkTextRegexp.MatchString(w.Text) {
+		return errors.New("watermark text must be at most 100 letters, digits, spaces or @._#/+-")
+	}
+	if _, ok := watermarkPositions[w.position()]; !ok {
+		return fmt.Errorf("unknown watermark position %v", w.Position)
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("watermark opacity %v is not between 0 and 1", w.Opacity)
+	}
+	return nil
+}
+
+func (w Watermark) position() string {
+	if w.Position == "" {
+		return DefaultWatermarkPosition
+	}
+	return w.Position
+}
+
+func (w Watermark) opacity() float64 {
+	if w.Opacity == 0 {
+		return DefaultWatermarkOpacity
+	}
+	return w.Opacity
+}
+
+// CreateWatermarkCommand returns the command stamping the watermark on the HLS part read from stdin
+func CreateWatermarkCommand(ctx context.Context, watermark Watermark) (*exec.Cmd, error) {
+	args, err := generateWatermarkArgs(watermark)
+	if err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, "ffmpeg", args...), nil
+}
+
+func generateWatermarkArgs(watermark Watermark) ([]string, error) {
+	// ffmpeg -i pipe:0 [-i <image>] -filter_complex <overlay,drawtext> -f mpegts -muxdelay 0 -map [v] -map 0:1 -acodec copy -vcodec libx264 -preset superfast -copyts pipe:1
+	if err := watermark.Validate(); err != nil {
+		return nil, err
+	}
+	if watermark.ImageFile == "" && watermark.Text == "" {
+		return nil, errors.New("empty watermark")
+	}
+
+	position := watermarkPositions[watermark.position()]
+	opacity := watermark.opacity()
+
+	args := []string{"-i", "pipe:0"}
+	graph := []string{}
+	video := "[0:v]"
+	if watermark.ImageFile != "" {
+		args = append(args, "-i", watermark.ImageFile)
+		graph = append(graph,
+			fmt.Sprintf("[1:v]format=rgba,colorchannelmixer=aa=%.2f[wm]", opacity),
+			fmt.Sprintf("%v[wm]overlay=%v[img]", video, position.image))
+		video = "[img]"
+	}
+	if watermark.Text != "" {
+		// The shadow keeps the text readable on bright and dark frames
+		graph = append(graph, fmt.Sprintf("%vdrawtext=text='%v':expansion=none:fontsize=h/24:fontcolor=white@%.2f:shadowcolor=black@%.2f:shadowx=2:shadowy=2:%v[txt]",
+			video, watermark.Text, opacity, opacity, position.text))
+		video = "[txt]"
+	}
+
+	args = append(args, "-filter_complex", strings.Join(graph, ";"))
+	args = append(args, "-f", "mpegts", "-muxdelay", "0", "-map", video, "-map", "0:1", "-acodec", "copy")
+	args = append(args, "-vcodec", "libx264", "-preset", "superfast", "-copyts")
+	args = append(args, "pipe:1")
+	return args, nil
+}
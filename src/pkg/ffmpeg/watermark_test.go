@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_generateWatermarkArgs(t *testing.T) {
+	cases := []struct {
+		Name           string
+		GivenWatermark Watermark
+		ExpectArgs     string
+		ExpectError    bool
+	}{
+		{
+			Name:           "Image with default position and opacity",
+			GivenWatermark: Watermark{ImageFile: "logo.png"},
+			ExpectArgs: "-i pipe:0 -i logo.png -filter_complex [1:v]format=rgba,colorchannelmixer=aa=0.50[wm];[0:v][wm]overlay=W-w-10:H-h-10[img] " +
+				"-f mpegts -muxdelay 0 -map [img] -map 0:1 -acodec copy -vcodec libx264 -preset superfast -copyts pipe:1",
+		},
+		{
+			Name:           "Text",
+			GivenWatermark: Watermark{Text: "viewer@example.com", Position: "top-left", Opacity: 0.8},
+			ExpectArgs: "-i pipe:0 -filter_complex [0:v]drawtext=text='viewer@example.com':expansion=none:fontsize=h/24:fontcolor=white@0.80:shadowcolor=black@0.80:shadowx=2:shadowy=2:x=10:y=10[txt] " +
+				"-f mpegts -muxdelay 0 -map [txt] -map 0:1 -acodec copy -vcodec libx264 -preset superfast -copyts pipe:1",
+		},
+		{
+			Name:           "Text over image",
+			GivenWatermark: Watermark{ImageFile: "logo.png", Text: "Voogle", Position: "center", Opacity: 1},
+			ExpectArgs: "-i pipe:0 -i logo.png -filter_complex [1:v]format=rgba,colorchannelmixer=aa=1.00[wm];[0:v][wm]overlay=(W-w)/2:(H-h)/2[img];" +
+				"[img]drawtext=text='Voogle':expansion=none:fontsize=h/24:fontcolor=white@1.00:shadowcolor=black@1.00:shadowx=2:shadowy=2:x=(w-tw)/2:y=(h-th)/2[txt] " +
+				"-f mpegts -muxdelay 0 -map [txt] -map 0:1 -acodec copy -vcodec libx264 -preset superfast -copyts pipe:1",
+		},
+		{
+			Name:           "Empty watermark",
+			GivenWatermark: Watermark{Position: "top-left"},
+			ExpectError:    true,
+		},
+		{
+			Name:           "Text escaping the filter",
+			GivenWatermark: Watermark{Text: "a':drawbox"},
+			ExpectError:    true,
+		},
+		{
+			Name:           "Unknown position",
+			GivenWatermark: Watermark{Text: "Voogle", Position: "middle"},
+			ExpectError:    true,
+		},
+		{
+			Name:           "Invalid opacity",
+			GivenWatermark: Watermark{Text: "Voogle", Opacity: 1.5},
+			ExpectError:    true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			args, err := generateWatermarkArgs(tt.GivenWatermark)
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectArgs, strings.Join(args, " "))
+		})
+	}
+}
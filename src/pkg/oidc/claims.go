@@ -0,0 +1,64 @@
+package oidc
+
+import (
+	"strings"
+	"time"
+)
+
+// Claims are the decoded payload of a token
+type Claims map[string]interface{}
+
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Lookup returns the claim at path, nested claims being separated by dots such as "realm_access.roles"
+func (c Claims) Lookup(path string) (interface{}, bool) {
+	var value interface{} = map[string]interface{}(c)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// String returns the string claim at path, an empty string when it is missing or not a string
+func (c Claims) String(path string) string {
+	value, _ := c.Lookup(path)
+	s, _ := value.(string)
+	return s
+}
+
+// Strings returns the claim at path as a list, a single string being a list of one element
+func (c Claims) Strings(path string) []string {
+	value, _ := c.Lookup(path)
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// Time returns the NumericDate claim at path, such as "exp"
+func (c Claims) Time(path string) (time.Time, bool) {
+	value, _ := c.Lookup(path)
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
@@ -0,0 +1,360 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Clock skew tolerated between the provider and the API
+	leeway = time.Minute
+	// Unknown key IDs refresh the keys at most once per period, so that forged tokens cannot flood the provider
+	minRefreshInterval = time.Minute
+	// Discovery documents and key sets are small, larger responses are refused
+	maxResponseBytes = 1 << 20
+)
+
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrExpiredToken         = errors.New("expired token")
+	ErrInvalidClaims        = errors.New("invalid token claims")
+)
+
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// Verifier checks the JWTs issued by an OpenID Connect provider for an audience, with the keys the provider
+// publishes. The keys are fetched again when a token is signed by an unknown one, after a key rotation.
+type Verifier struct {
+	issuer     string
+	audience   string
+	jwksURL    string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mutex       sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	refreshedAt time.Time
+}
+
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewVerifier reads the discovery document of the issuer and fetches its keys
+func NewVerifier(ctx context.Context, issuer, audience string, httpClient *http.Client) (*Verifier, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	var discovery discoveryDocument
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := fetchJSON(ctx, httpClient, discoveryURL, &discovery); err != nil {
+		return nil, fmt.Errorf("cannot read discovery document : %w", err)
+	}
+	if discovery.Issuer != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, expected %q", discovery.Issuer, issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document has no jwks_uri")
+	}
+
+	v := &Verifier{
+		issuer:     issuer,
+		audience:   audience,
+		jwksURL:    discovery.JWKSURI,
+		httpClient: httpClient,
+		now:        time.Now,
+	}
+	if err := v.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// IsJWT tells whether the bearer token looks like a JWT rather than an opaque token
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the signature, issuer, audience and validity period of the token, and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+	hash, ok := algorithms[header.Alg]
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, hash, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) checkClaims(claims Claims) error {
+	if claims.String("iss") != v.issuer {
+		return fmt.Errorf("%w : issuer %q", ErrInvalidClaims, claims.String("iss"))
+	}
+	if claims.Subject() == "" {
+		return fmt.Errorf("%w : missing subject", ErrInvalidClaims)
+	}
+	if !contains(claims.Strings("aud"), v.audience) {
+		return fmt.Errorf("%w : audience %v", ErrInvalidClaims, claims.Strings("aud"))
+	}
+
+	now := v.now()
+	expiresAt, ok := claims.Time("exp")
+	if !ok {
+		return fmt.Errorf("%w : missing expiration", ErrInvalidClaims)
+	}
+	if now.After(expiresAt.Add(leeway)) {
+		return ErrExpiredToken
+	}
+	if notBefore, ok := claims.Time("nbf"); ok && now.Add(leeway).Before(notBefore) {
+		return fmt.Errorf("%w : not valid before %v", ErrInvalidClaims, notBefore)
+	}
+	return nil
+}
+
+// key returns the public key of the ID. Tokens without ID are accepted when the provider has a single key.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mutex.RLock()
+	key, ok := v.lookupKey(kid)
+	canRefresh := v.now().Sub(v.refreshedAt) >= minRefreshInterval
+	v.mutex.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !canRefresh {
+		return nil, ErrUnknownKey
+	}
+
+	if err := v.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if key, ok := v.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (v *Verifier) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *Verifier) refreshKeys(ctx context.Context) error {
+	var set jsonWebKeySet
+	if err := fetchJSON(ctx, v.httpClient, v.jwksURL, &set); err != nil {
+		return fmt.Errorf("cannot fetch signing keys : %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Keys of unsupported types are skipped, the other ones are still usable
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	v.mutex.Lock()
+	v.keys = keys
+	v.refreshedAt = v.now()
+	v.mutex.Unlock()
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, errors.New("unsupported key type " + k.Kty)
+	}
+}
+
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signed string, signature []byte) error {
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(pub, hash, digest, signature) != nil {
+			return ErrInvalidSignature
+		}
+	case *ecdsa.PublicKey:
+		// ES signatures are the fixed size concatenation of r and s
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func fetchJSON(ctx context.Context, httpClient *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v returned %v", url, res.Status)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, maxResponseBytes)).Decode(v)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/oidc/oidctest"
+)
+
+func Test_Verify(t *testing.T) {
+	audience := "voogle"
+	subject := "248289761001"
+
+	issuer, err := oidctest.NewIssuer()
+	require.NoError(t, err)
+	defer issuer.Close()
+
+	otherIssuer, err := oidctest.NewIssuer()
+	require.NoError(t, err)
+	defer otherIssuer.Close()
+
+	cases := []struct {
+		Name        string
+		GivenToken  func() (string, error)
+		ExpectError error
+	}{
+		{
+			Name: "Valid token",
+			GivenToken: func() (string, error) {
+				return issuer.Token(subject, audience, time.Hour, nil)
+			},
+		},
+		{
+			Name: "Valid token with several audiences",
+			GivenToken: func() (string, error) {
+				return issuer.Token(subject, audience, time.Hour, map[string]interface{}{"aud": []string{"other", audience}})
+			},
+		},
+		{
+			Name: "Expired token",
+			GivenToken: func() (string, error) {
+				return issuer.Token(subject, audience, -time.Hour, nil)
+			},
+			ExpectError: ErrExpiredToken,
+		},
+		{
+			Name: "Token not valid yet",
+			GivenToken: func() (string, error) {
+				return issuer.Token(subject, audience, time.Hour, map[string]interface{}{"nbf": time.Now().Add(10 * time.Minute).Unix()})
+			},
+			ExpectError: ErrInvalidClaims,
+		},
+		{
+			Name: "Token for another audience",
+			GivenToken: func() (string, error) {
+				return issuer.Token(subject, "other", time.Hour, nil)
+			},
+			ExpectError: ErrInvalidClaims,
+		},
+		{
+			Name: "Token of another issuer",
+			GivenToken: func() (string, error) {
+				return issuer.Token(subject, audience, time.Hour, map[string]interface{}{"iss": "https://evil.example.com"})
+			},
+			ExpectError: ErrInvalidClaims,
+		},
+		{
+			Name: "Token without subject",
+			GivenToken: func() (string, error) {
+				return issuer.Token("", audience, time.Hour, nil)
+			},
+			ExpectError: ErrInvalidClaims,
+		},
+		{
+			Name: "Token signed by another key",
+			GivenToken: func() (string, error) {
+				return otherIssuer.Token(subject, audience, time.Hour, map[string]interface{}{"iss": issuer.URL()})
+			},
+			ExpectError: ErrInvalidSignature,
+		},
+		{
+			Name: "Token with a forged payload",
+			GivenToken: func() (string, error) {
+				token, err := issuer.Token(subject, audience, time.Hour, nil)
+				parts := strings.Split(token, ".")
+				parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"` + issuer.URL() + `","sub":"admin","aud":"voogle","exp":9999999999}`))
+				return strings.Join(parts, "."), err
+			},
+			ExpectError: ErrInvalidSignature,
+		},
+		{
+			Name: "Unsigned token",
+			GivenToken: func() (string, error) {
+				token, err := issuer.Token(subject, audience, time.Hour, nil)
+				parts := strings.Split(token, ".")
+				parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+				return parts[0] + "." + parts[1] + ".", err
+			},
+			ExpectError: ErrUnsupportedAlgorithm,
+		},
+		{
+			Name: "Malformed token",
+			GivenToken: func() (string, error) {
+				return "not-a-jwt", nil
+			},
+			ExpectError: ErrMalformedToken,
+		},
+	}
+
+	verifier, err := NewVerifier(context.Background(), issuer.URL(), audience, nil)
+	require.NoError(t, err)
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			token, err := tt.GivenToken()
+			require.NoError(t, err)
+
+			claims, err := verifier.Verify(context.Background(), token)
+			if tt.ExpectError != nil {
+				require.ErrorIs(t, err, tt.ExpectError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, subject, claims.Subject())
+		})
+	}
+}
+
+func Test_VerifyAfterKeyRotation(t *testing.T) {
+	issuer, err := oidctest.NewIssuer()
+	require.NoError(t, err)
+	defer issuer.Close()
+
+	verifier, err := NewVerifier(context.Background(), issuer.URL(), "voogle", nil)
+	require.NoError(t, err)
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+
+	require.NoError(t, issuer.RotateKey())
+	token, err := issuer.Token("248289761001", "voogle", time.Hour, nil)
+	require.NoError(t, err)
+
+	// The keys were just fetched, they are not fetched again yet
+	_, err = verifier.Verify(context.Background(), token)
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	now = now.Add(minRefreshInterval)
+	_, err = verifier.Verify(context.Background(), token)
+	require.NoError(t, err)
+}
+
+func Test_NewVerifierWithWrongIssuer(t *testing.T) {
+	issuer, err := oidctest.NewIssuer()
+	require.NoError(t, err)
+	defer issuer.Close()
+
+	_, err = NewVerifier(context.Background(), issuer.URL()+"/", "voogle", nil)
+	require.Error(t, err)
+}
+
+func Test_Claims(t *testing.T) {
+	claims := Claims{
+		"groups":       []interface{}{"voogle-admins", 42, "staff"},
+		"realm_access": map[string]interface{}{"roles": []interface{}{"uploader"}},
+		"email":        "alice@example.com",
+	}
+
+	require.Equal(t, []string{"voogle-admins", "staff"}, claims.Strings("groups"))
+	require.Equal(t, []string{"uploader"}, claims.Strings("realm_access.roles"))
+	require.Equal(t, []string{"alice@example.com"}, claims.Strings("email"))
+	require.Nil(t, claims.Strings("email.domain"))
+	require.Equal(t, "", claims.String("groups"))
+}
@@ -0,0 +1,129 @@
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Issuer is a local OpenID Connect provider for the tests. It serves its discovery document and its
+// key set, and signs tokens with RS256.
+type Issuer struct {
+	server *httptest.Server
+
+	mutex   sync.RWMutex
+	key     *rsa.PrivateKey
+	keyID   string
+	keyGens int
+}
+
+// NewIssuer starts an issuer listening on a local port, it must be closed after use
+func NewIssuer() (*Issuer, error) {
+	issuer := &Issuer{}
+	if err := issuer.RotateKey(); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":   issuer.URL(),
+			"jwks_uri": issuer.URL() + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.RLock()
+		defer issuer.mutex.RUnlock()
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": issuer.keyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(issuer.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(issuer.key.E)).Bytes()),
+			}},
+		})
+	})
+	issuer.server = httptest.NewServer(mux)
+
+	return issuer, nil
+}
+
+// URL is the issuer identifier, expected in the "iss" claim
+func (i *Issuer) URL() string {
+	return i.server.URL
+}
+
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// RotateKey replaces the signing key, the previous one is no longer published
+func (i *Issuer) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.keyGens++
+	i.key = key
+	i.keyID = "test-key-" + strconv.Itoa(i.keyGens)
+	return nil
+}
+
+// Token returns a token of the subject for the audience, valid for ttl (a negative ttl gives an expired token).
+// The extra claims are added to, or override, the standard ones.
+func (i *Issuer) Token(subject, audience string, ttl time.Duration, extra map[string]interface{}) (string, error) {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": i.URL(),
+		"sub": subject,
+		"aud": audience,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return i.Sign(claims)
+}
+
+// Sign returns a token with the claims, signed by the current key
+func (i *Issuer) Sign(claims map[string]interface{}) (string, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": i.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
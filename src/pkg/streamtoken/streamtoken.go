@@ -0,0 +1,138 @@
+package streamtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryParam is the query parameter carrying the token on the playback routes
+const QueryParam = "token"
+
+var (
+	ErrMalformedToken = errors.New("malformed stream token")
+	ErrExpiredToken   = errors.New("expired stream token")
+	ErrInvalidToken   = errors.New("invalid stream token signature")
+)
+
+// Signer mints and checks HMAC signed tokens giving access to the files of a video for a limited time.
+// Tokens are "<key index>.<expiration>.<bound to IP>.<signature>". The signature may also cover parameters
+// of the requests, the binding, which the holder of the token then cannot change.
+type Signer struct {
+	secret   []byte
+	ttl      time.Duration
+	rotation time.Duration
+	bindIP   bool
+	now      func() time.Time
+}
+
+// NewSigner creates a signer of tokens valid for ttl. Signing keys are derived from the secret and
+// change every rotation period, 0 disables the rotation. With bindIP, tokens only work from the
+// address of the client they were given to.
+func NewSigner(secret []byte, ttl, rotation time.Duration, bindIP bool) *Signer {
+	return &Signer{
+		secret:   secret,
+		ttl:      ttl,
+		rotation: rotation,
+		bindIP:   bindIP,
+		now:      time.Now,
+	}
+}
+
+// Sign returns a token giving access to the video with the requests having the binding, and its expiration date
+func (s *Signer) Sign(videoID, clientIP, binding string) (string, time.Time) {
+	now := s.now()
+	expiresAt := time.Unix(now.Add(s.ttl).Unix(), 0)
+
+	bound := "0"
+	if s.bindIP {
+		bound = "1"
+	} else {
+		clientIP = ""
+	}
+
+	keyIndex := s.keyIndex(now)
+	payload := strconv.FormatInt(keyIndex, 10) + "." + strconv.FormatInt(expiresAt.Unix(), 10) + "." + bound
+	return payload + "." + s.signature(keyIndex, payload, videoID, clientIP, binding), expiresAt
+}
+
+// Verify checks that the token gives access to the video from this client, with the binding of the request
+func (s *Signer) Verify(token, videoID, clientIP, binding string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return ErrMalformedToken
+	}
+
+	keyIndex, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	expiration, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	switch parts[2] {
+	case "0":
+		clientIP = ""
+	case "1":
+	default:
+		return ErrMalformedToken
+	}
+
+	// Keys older than the lifetime of a token cannot have signed a valid one
+	now := s.now()
+	if keyIndex > s.keyIndex(now) {
+		return ErrInvalidToken
+	}
+	if keyIndex < s.keyIndex(now.Add(-s.ttl)) {
+		return ErrExpiredToken
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.signature(keyIndex, payload, videoID, clientIP, binding))) {
+		return ErrInvalidToken
+	}
+
+	if now.Unix() >= expiration {
+		return ErrExpiredToken
+	}
+	return nil
+}
+
+func (s *Signer) keyIndex(t time.Time) int64 {
+	if s.rotation <= 0 {
+		return 0
+	}
+	return t.UnixNano() / int64(s.rotation)
+}
+
+func (s *Signer) key(keyIndex int64) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("stream-token-key:" + strconv.FormatInt(keyIndex, 10)))
+	return mac.Sum(nil)
+}
+
+func (s *Signer) signature(keyIndex int64, payload, videoID, clientIP, binding string) string {
+	mac := hmac.New(sha256.New, s.key(keyIndex))
+	mac.Write([]byte(payload + "\n" + videoID + "\n" + clientIP))
+	// The tokens without binding keep their signature
+	if binding != "" {
+		mac.Write([]byte("\n" + binding))
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ClientIP returns the address of the client that sent the request
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
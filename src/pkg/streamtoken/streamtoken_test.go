@@ -0,0 +1,161 @@
+package streamtoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Verify(t *testing.T) {
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	signedAt := time.Date(2022, time.March, 1, 23, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		Name        string
+		GivenBindIP bool
+		GivenToken  func(token string) string
+		GivenVideo  string
+		GivenIP     string
+		// Binding of the signed token, and of the verified request
+		GivenSignBound string
+		GivenBound     string
+		GivenDelay     time.Duration
+		ExpectError    error
+	}{
+		{
+			Name:       "Valid token",
+			GivenVideo: videoID,
+			GivenIP:    "10.0.0.1",
+		},
+		{
+			Name:       "Valid token after a key rotation",
+			GivenVideo: videoID,
+			GivenDelay: time.Hour,
+		},
+		{
+			Name:        "Token of another video",
+			GivenVideo:  "0000a0a0-0aa0-0a00-0000-aa0000aa00aa",
+			ExpectError: ErrInvalidToken,
+		},
+		{
+			Name:        "Expired token",
+			GivenVideo:  videoID,
+			GivenDelay:  2 * time.Hour,
+			ExpectError: ErrExpiredToken,
+		},
+		{
+			Name:        "Token signed with a removed key",
+			GivenVideo:  videoID,
+			GivenDelay:  50 * time.Hour,
+			ExpectError: ErrExpiredToken,
+		},
+		{
+			Name: "Token with a forged expiration",
+			GivenToken: func(token string) string {
+				parts := strings.Split(token, ".")
+				parts[1] = "9999999999"
+				return strings.Join(parts, ".")
+			},
+			GivenVideo:  videoID,
+			ExpectError: ErrInvalidToken,
+		},
+		{
+			Name: "Token with a forged key index",
+			GivenToken: func(token string) string {
+				parts := strings.Split(token, ".")
+				parts[0] = "0"
+				return strings.Join(parts, ".")
+			},
+			GivenVideo:  videoID,
+			ExpectError: ErrExpiredToken,
+		},
+		{
+			Name:        "Token unbound from its client address",
+			GivenBindIP: true,
+			GivenToken: func(token string) string {
+				parts := strings.Split(token, ".")
+				parts[2] = "0"
+				return strings.Join(parts, ".")
+			},
+			GivenVideo:  videoID,
+			GivenIP:     "10.0.0.2",
+			ExpectError: ErrInvalidToken,
+		},
+		{
+			Name:        "Token bound to the client address",
+			GivenBindIP: true,
+			GivenVideo:  videoID,
+			GivenIP:     "10.0.0.1",
+		},
+		{
+			Name:        "Token bound to another client address",
+			GivenBindIP: true,
+			GivenVideo:  videoID,
+			GivenIP:     "10.0.0.2",
+			ExpectError: ErrInvalidToken,
+		},
+		{
+			Name:           "Token with the binding of the request",
+			GivenVideo:     videoID,
+			GivenSignBound: "watermarkText=alice",
+			GivenBound:     "watermarkText=alice",
+		},
+		{
+			Name:           "Token with another binding",
+			GivenVideo:     videoID,
+			GivenSignBound: "watermarkText=alice",
+			GivenBound:     "watermarkText=bob",
+			ExpectError:    ErrInvalidToken,
+		},
+		{
+			Name:           "Token without its binding",
+			GivenVideo:     videoID,
+			GivenSignBound: "watermarkText=alice",
+			ExpectError:    ErrInvalidToken,
+		},
+		{
+			Name:        "Token without binding given one",
+			GivenVideo:  videoID,
+			GivenBound:  "watermarkText=bob",
+			ExpectError: ErrInvalidToken,
+		},
+		{
+			Name:        "Malformed token",
+			GivenToken:  func(token string) string { return "token" },
+			GivenVideo:  videoID,
+			ExpectError: ErrMalformedToken,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			signer := NewSigner([]byte("secret"), 90*time.Minute, 24*time.Hour, tt.GivenBindIP)
+			signer.now = func() time.Time { return signedAt }
+
+			token, expiresAt := signer.Sign(videoID, "10.0.0.1", tt.GivenSignBound)
+			require.Equal(t, signedAt.Add(90*time.Minute).Unix(), expiresAt.Unix())
+			if tt.GivenToken != nil {
+				token = tt.GivenToken(token)
+			}
+
+			signer.now = func() time.Time { return signedAt.Add(tt.GivenDelay) }
+			require.Equal(t, tt.ExpectError, signer.Verify(token, tt.GivenVideo, tt.GivenIP, tt.GivenBound))
+		})
+	}
+}
+
+func Test_SignRotatesKeys(t *testing.T) {
+	videoID := "1508e7d5-5bc6-4a50-9176-ab0371aa65fe"
+	signer := NewSigner([]byte("secret"), time.Hour, time.Hour, false)
+
+	signer.now = func() time.Time { return time.Unix(3600, 0) }
+	first, _ := signer.Sign(videoID, "", "")
+	signer.now = func() time.Time { return time.Unix(7200, 0) }
+	second, _ := signer.Sign(videoID, "", "")
+
+	require.True(t, strings.HasPrefix(first, "1.7200.0."))
+	require.True(t, strings.HasPrefix(second, "2.10800.0."))
+	require.NotEqual(t, strings.Split(first, ".")[3], strings.Split(second, ".")[3])
+}
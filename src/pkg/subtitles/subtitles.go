@@ -0,0 +1,228 @@
+package subtitles
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  []string
+}
+
+// SRT timings are "HH:MM:SS,mmm --> HH:MM:SS,mmm", some tools write a dot instead of the comma
+var srtTiming = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})[,.](\d{3})\s+-->\s+(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+
+// WebVTT timings are "[HH:]MM:SS.mmm --> [HH:]MM:SS.mmm [settings]"
+var vttTiming = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})[ \t]+-->[ \t]+(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})(?:[ \t]|$)`)
+
+// Language tags such as "en", "fr-CA" or "und" (undetermined)
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// MaxLabelLength is the size of the label column
+const MaxLabelLength = 64
+
+// IsValidLanguage checks the track language is a BCP 47 like tag
+func IsValidLanguage(language string) bool {
+	return languageTag.MatchString(language)
+}
+
+// IsValidLabel checks the track label can be written as a quoted string in a HLS playlist
+func IsValidLabel(label string) bool {
+	return !strings.ContainsAny(label, "\"\r\n") && len(label) <= MaxLabelLength
+}
+
+// ParseSRT reads and validates a SubRip file
+func ParseSRT(input io.Reader) ([]Cue, error) {
+	blocks, err := readBlocks(input)
+	if err != nil {
+		return nil, err
+	}
+
+	cues := make([]Cue, 0, len(blocks))
+	for i, block := range blocks {
+		// The cue number is optional in practice
+		lines := block
+		if _, err := strconv.Atoi(strings.TrimSpace(lines[0])); err == nil {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("cue %d : missing timing", i+1)
+		}
+
+		match := srtTiming.FindStringSubmatch(strings.TrimSpace(lines[0]))
+		if match == nil {
+			return nil, fmt.Errorf("cue %d : invalid timing '%v'", i+1, lines[0])
+		}
+
+		cue, err := newCue(match[1:5], match[5:9], lines[1:])
+		if err != nil {
+			return nil, fmt.Errorf("cue %d : %w", i+1, err)
+		}
+		cues = append(cues, cue)
+	}
+
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("no cue found")
+	}
+
+	return cues, nil
+}
+
+// ParseWebVTT reads and validates a WebVTT file. NOTE, STYLE and REGION blocks are skipped.
+func ParseWebVTT(input io.Reader) ([]Cue, error) {
+	blocks, err := readBlocks(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("missing WEBVTT header")
+	}
+	header := blocks[0][0]
+	if header != "WEBVTT" && !strings.HasPrefix(header, "WEBVTT ") && !strings.HasPrefix(header, "WEBVTT\t") {
+		return nil, fmt.Errorf("missing WEBVTT header")
+	}
+
+	cues := make([]Cue, 0, len(blocks)-1)
+	for i, block := range blocks[1:] {
+		if strings.HasPrefix(block[0], "NOTE") || block[0] == "STYLE" || block[0] == "REGION" {
+			continue
+		}
+
+		// The cue identifier is optional
+		lines := block
+		if !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("block %d : missing timing", i+1)
+		}
+
+		match := vttTiming.FindStringSubmatch(lines[0])
+		if match == nil {
+			return nil, fmt.Errorf("block %d : invalid timing '%v'", i+1, lines[0])
+		}
+
+		cue, err := newCue(match[1:5], match[5:9], lines[1:])
+		if err != nil {
+			return nil, fmt.Errorf("block %d : %w", i+1, err)
+		}
+		cues = append(cues, cue)
+	}
+
+	return cues, nil
+}
+
+// ToWebVTT writes cues as a WebVTT file
+func ToWebVTT(cues []Cue) []byte {
+	var output bytes.Buffer
+	output.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		output.WriteString("\n" + formatTimestamp(cue.Start) + " --> " + formatTimestamp(cue.End) + "\n")
+		for _, line := range cue.Text {
+			output.WriteString(line + "\n")
+		}
+	}
+	return output.Bytes()
+}
+
+// Duration returns the end of the last cue
+func Duration(cues []Cue) time.Duration {
+	var duration time.Duration
+	for _, cue := range cues {
+		if cue.End > duration {
+			duration = cue.End
+		}
+	}
+	return duration
+}
+
+// readBlocks splits the input on blank lines, removing the BOM and the carriage returns
+func readBlocks(input io.Reader) ([][]string, error) {
+	scanner := bufio.NewScanner(input)
+	var blocks [][]string
+	var block []string
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+func newCue(start, end, text []string) (Cue, error) {
+	startTime, err := parseTimestamp(start)
+	if err != nil {
+		return Cue{}, err
+	}
+	endTime, err := parseTimestamp(end)
+	if err != nil {
+		return Cue{}, err
+	}
+	if endTime < startTime {
+		return Cue{}, fmt.Errorf("cue ends before it starts")
+	}
+
+	for _, line := range text {
+		if strings.Contains(line, "-->") {
+			return Cue{}, fmt.Errorf("cue text cannot contain '-->'")
+		}
+	}
+
+	return Cue{Start: startTime, End: endTime, Text: text}, nil
+}
+
+// parseTimestamp reads hours (optional), minutes, seconds and milliseconds
+func parseTimestamp(parts []string) (time.Duration, error) {
+	units := []time.Duration{time.Hour, time.Minute, time.Second, time.Millisecond}
+	var timestamp time.Duration
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+		if (i == 1 || i == 2) && value > 59 {
+			return 0, fmt.Errorf("invalid timestamp %v", strings.Join(parts, ":"))
+		}
+		timestamp += time.Duration(value) * units[i]
+	}
+	return timestamp, nil
+}
+
+func formatTimestamp(timestamp time.Duration) string {
+	hours := timestamp / time.Hour
+	minutes := (timestamp % time.Hour) / time.Minute
+	seconds := (timestamp % time.Minute) / time.Second
+	milliseconds := (timestamp % time.Second) / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, milliseconds)
+}
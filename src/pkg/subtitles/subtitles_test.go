@@ -0,0 +1,122 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseSRT(t *testing.T) {
+	cases := []struct {
+		Name        string
+		GivenSRT    string
+		ExpectCues  []Cue
+		ExpectError bool
+	}{
+		{
+			Name:     "Valid SRT with BOM and CRLF",
+			GivenSRT: "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n\r\n2\r\n00:01:02,000 --> 00:01:04,000\r\n<i>Two</i>\r\nlines\r\n",
+			ExpectCues: []Cue{
+				{Start: time.Second, End: 2500 * time.Millisecond, Text: []string{"Hello"}},
+				{Start: 62 * time.Second, End: 64 * time.Second, Text: []string{"<i>Two</i>", "lines"}},
+			},
+		},
+		{
+			Name:     "SRT without cue number",
+			GivenSRT: "01:00:00,000 --> 01:00:01,000\nHello\n",
+			ExpectCues: []Cue{
+				{Start: time.Hour, End: time.Hour + time.Second, Text: []string{"Hello"}},
+			},
+		},
+		{
+			Name:        "Invalid timing",
+			GivenSRT:    "1\n00:00:01 --> 00:00:02\nHello\n",
+			ExpectError: true,
+		},
+		{
+			Name:        "Cue ending before it starts",
+			GivenSRT:    "1\n00:00:02,000 --> 00:00:01,000\nHello\n",
+			ExpectError: true,
+		},
+		{
+			Name:        "Invalid minutes",
+			GivenSRT:    "1\n00:75:00,000 --> 00:76:00,000\nHello\n",
+			ExpectError: true,
+		},
+		{
+			Name:        "Empty file",
+			GivenSRT:    "",
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			cues, err := ParseSRT(strings.NewReader(tt.GivenSRT))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectCues, cues)
+		})
+	}
+}
+
+func Test_ParseWebVTT(t *testing.T) {
+	cases := []struct {
+		Name        string
+		GivenVTT    string
+		ExpectCues  []Cue
+		ExpectError bool
+	}{
+		{
+			Name:     "Valid WebVTT with note, style and identifiers",
+			GivenVTT: "WEBVTT - Some title\n\nNOTE a comment\n\nSTYLE\n::cue { color: red }\n\nintro\n00:01.000 --> 00:02.000 align:start\nHello\n\n01:00:00.000 --> 01:00:01.000\nBye\n",
+			ExpectCues: []Cue{
+				{Start: time.Second, End: 2 * time.Second, Text: []string{"Hello"}},
+				{Start: time.Hour, End: time.Hour + time.Second, Text: []string{"Bye"}},
+			},
+		},
+		{
+			Name:        "Missing header",
+			GivenVTT:    "00:01.000 --> 00:02.000\nHello\n",
+			ExpectError: true,
+		},
+		{
+			Name:        "SRT timing in WebVTT",
+			GivenVTT:    "WEBVTT\n\n00:00:01,000 --> 00:00:02,000\nHello\n",
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			cues, err := ParseWebVTT(strings.NewReader(tt.GivenVTT))
+			if tt.ExpectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectCues, cues)
+		})
+	}
+}
+
+func Test_ToWebVTT(t *testing.T) {
+	cues := []Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: []string{"Hello"}},
+		{Start: time.Hour + 62*time.Second, End: time.Hour + 64*time.Second, Text: []string{"<i>Two</i>", "lines"}},
+	}
+
+	vtt := ToWebVTT(cues)
+	require.Equal(t, "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n01:01:02.000 --> 01:01:04.000\n<i>Two</i>\nlines\n", string(vtt))
+
+	// The output must be readable as WebVTT
+	parsed, err := ParseWebVTT(strings.NewReader(string(vtt)))
+	require.NoError(t, err)
+	require.Equal(t, cues, parsed)
+	require.Equal(t, time.Hour+64*time.Second, Duration(parsed))
+}
@@ -14,7 +14,7 @@ func newFlipServer(s3Client clients.IS3Client, discoveryClient clients.ServiceDi
 		TransformerServer: TransformerServer{
 			DiscoveryClient:         discoveryClient,
 			S3Client:                s3Client,
-			CreateTransformationCmd: ffmpeg.CreateFlipCommand,
+			CreateTransformationCmd: staticTransformationCmd(ffmpeg.CreateFlipCommand),
 		},
 	}
 }
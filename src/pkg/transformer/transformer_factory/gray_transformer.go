@@ -14,7 +14,7 @@ func newGrayServer(s3Client clients.IS3Client, discoveryClient clients.ServiceDi
 		TransformerServer: TransformerServer{
 			DiscoveryClient:         discoveryClient,
 			S3Client:                s3Client,
-			CreateTransformationCmd: ffmpeg.CreateGrayCommand,
+			CreateTransformationCmd: staticTransformationCmd(ffmpeg.CreateGrayCommand),
 		},
 	}
 }
@@ -25,7 +25,7 @@ type ITransformerServer interface {
 }
 
 type TransformerServer struct {
-	CreateTransformationCmd func(ctx context.Context) *exec.Cmd
+	CreateTransformationCmd func(ctx context.Context, args *transformer.TransformVideoRequest) (*exec.Cmd, error)
 	DiscoveryClient         clients.ServiceDiscovery
 	S3Client                clients.IS3Client
 }
@@ -60,6 +60,12 @@ func (t TransformerServer) TransformVideo(ctx context.Context, args *transformer
 	// Transformer will start video transformation, remove itself from the list
 	args.TransformerList = args.TransformerList[:len(args.TransformerList)-1]
 	if len(args.TransformerList) == 0 {
+		cmd, err := t.CreateTransformationCmd(ctx, args)
+		if err != nil {
+			log.Error("Cannot create transformation command : ", err)
+			return err
+		}
+
 		// Retrieve the video part from aws S3
 		videoPart, err := t.S3Client.GetObject(ctx, args.GetVideopath())
 		if err != nil {
@@ -70,7 +76,7 @@ func (t TransformerServer) TransformVideo(ctx context.Context, args *transformer
 		transformedVideoPartReader, transformedVideoPartWriter := io.Pipe()
 		go func() {
 			defer transformedVideoPartWriter.Close()
-			if err := ffmpeg.TransformHLSPart(t.CreateTransformationCmd(ctx), videoPart, transformedVideoPartWriter); err != nil {
+			if err := ffmpeg.TransformHLSPart(cmd, videoPart, transformedVideoPartWriter); err != nil {
 				log.Error("Cannot run ffmpeg command : ", err)
 			}
 		}()
@@ -78,6 +84,13 @@ func (t TransformerServer) TransformVideo(ctx context.Context, args *transformer
 		return t.sendVideoPartStream(transformedVideoPartReader, stream)
 
 	} else {
+		// Create transformation command, before asking for a video part it could not transform
+		cmd, err := t.CreateTransformationCmd(ctx, args)
+		if err != nil {
+			log.Error("Cannot create transformation command : ", err)
+			return err
+		}
+
 		// Ask next transformer for videoPart. We will receive it as stream
 		videoPart, err := t.sendToNextTransformer(ctx, args)
 		if err != nil {
@@ -85,8 +98,7 @@ func (t TransformerServer) TransformVideo(ctx context.Context, args *transformer
 			return err
 		}
 
-		// Create transformation command and init a pipe for stdin
-		cmd := t.CreateTransformationCmd(ctx)
+		// Init a pipe for stdin
 		stdinWriter, err := cmd.StdinPipe()
 		if err != nil {
 			log.Error("Cannot create pipe stdin : ", err)
@@ -128,6 +140,13 @@ func (t TransformerServer) TransformVideo(ctx context.Context, args *transformer
 	}
 }
 
+// staticTransformationCmd returns the command creation of a transformer without parameters
+func staticTransformationCmd(createCmd func(ctx context.Context) *exec.Cmd) func(ctx context.Context, args *transformer.TransformVideoRequest) (*exec.Cmd, error) {
+	return func(ctx context.Context, _ *transformer.TransformVideoRequest) (*exec.Cmd, error) {
+		return createCmd(ctx), nil
+	}
+}
+
 func (t TransformerServer) Stop() {
 	t.DiscoveryClient.Stop()
 }
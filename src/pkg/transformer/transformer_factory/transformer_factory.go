@@ -13,5 +13,8 @@ func GetTransformer(transformerType string, s3Client clients.IS3Client, discover
 	if transformerType == "Gray" {
 		return newGrayServer(s3Client, discoveryClient), nil
 	}
+	if transformerType == "Watermark" {
+		return newWatermarkServer(s3Client, discoveryClient), nil
+	}
 	return nil, fmt.Errorf("Unknown transformer")
 }
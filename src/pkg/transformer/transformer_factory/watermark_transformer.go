@@ -0,0 +1,92 @@
+package transformer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/ffmpeg"
+	"github.com/Sogilis/Voogle/src/pkg/transformer/v1"
+)
+
+type WatermarkServer struct {
+	TransformerServer
+}
+
+func newWatermarkServer(s3Client clients.IS3Client, discoveryClient clients.ServiceDiscovery) ITransformerServer {
+	imagesFolder := filepath.Join(os.TempDir(), "voogle-watermarks")
+	return &WatermarkServer{
+		TransformerServer: TransformerServer{
+			DiscoveryClient: discoveryClient,
+			S3Client:        s3Client,
+			CreateTransformationCmd: func(ctx context.Context, args *transformer.TransformVideoRequest) (*exec.Cmd, error) {
+				return createWatermarkCmd(ctx, s3Client, imagesFolder, args.GetWatermark())
+			},
+		},
+	}
+}
+
+func createWatermarkCmd(ctx context.Context, s3Client clients.IS3Client, imagesFolder string, params *transformer.Watermark) (*exec.Cmd, error) {
+	watermark := ffmpeg.Watermark{
+		Text:     params.GetText(),
+		Position: params.GetPosition(),
+		Opacity:  params.GetOpacity(),
+	}
+	if err := watermark.Validate(); err != nil {
+		return nil, err
+	}
+
+	if params.GetImagePath() != "" {
+		imageFile, err := fetchWatermarkImage(ctx, s3Client, imagesFolder, params.GetImagePath())
+		if err != nil {
+			log.Error("Cannot fetch watermark image : ", err)
+			return nil, err
+		}
+		watermark.ImageFile = imageFile
+	}
+
+	return ffmpeg.CreateWatermarkCommand(ctx, watermark)
+}
+
+// fetchWatermarkImage returns the local copy of an image stored on S3. Every segment of a stream is
+// stamped with the same image, it is downloaded once: a new image must be uploaded under a new path.
+func fetchWatermarkImage(ctx context.Context, s3Client clients.IS3Client, imagesFolder, imagePath string) (string, error) {
+	sum := sha256.Sum256([]byte(imagePath))
+	imageFile := filepath.Join(imagesFolder, hex.EncodeToString(sum[:16])+filepath.Ext(imagePath))
+	if _, err := os.Stat(imageFile); err == nil {
+		return imageFile, nil
+	}
+
+	if err := os.MkdirAll(imagesFolder, os.ModePerm); err != nil {
+		return "", err
+	}
+	image, err := s3Client.GetObject(ctx, imagePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Concurrent requests download the image in their own file, the complete copy is then renamed
+	tmp, err := os.CreateTemp(imagesFolder, "download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, image); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), imageFile); err != nil {
+		return "", err
+	}
+	return imageFile, nil
+}
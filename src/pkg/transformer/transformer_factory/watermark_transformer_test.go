@@ -0,0 +1,85 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sogilis/Voogle/src/pkg/clients"
+	"github.com/Sogilis/Voogle/src/pkg/transformer/v1"
+)
+
+func Test_createWatermarkCmd(t *testing.T) {
+	cases := []struct {
+		Name          string
+		GivenParams   *transformer.Watermark
+		GivenS3Err    bool
+		ExpectInArgs  string
+		ExpectError   bool
+		ExpectFetches int
+	}{
+		{
+			Name:          "Image fetched once",
+			GivenParams:   &transformer.Watermark{ImagePath: "watermarks/logo.png", Position: "top-right", Opacity: 0.3},
+			ExpectInArgs:  "overlay=W-w-10:10",
+			ExpectFetches: 1,
+		},
+		{
+			Name:         "Text only",
+			GivenParams:  &transformer.Watermark{Text: "viewer-42"},
+			ExpectInArgs: "drawtext=text='viewer-42'",
+		},
+		{
+			Name:        "Missing image",
+			GivenParams: &transformer.Watermark{ImagePath: "watermarks/logo.png"},
+			GivenS3Err:  true,
+			ExpectError: true,
+		},
+		{
+			Name:        "Invalid parameters",
+			GivenParams: &transformer.Watermark{Text: "viewer", Position: "middle"},
+			ExpectError: true,
+		},
+		{
+			Name:        "No parameters",
+			ExpectError: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			fetches := 0
+			s3Client := clients.NewS3ClientDummy(nil, func(key string) (io.Reader, error) {
+				if tt.GivenS3Err {
+					return nil, fmt.Errorf("no such key")
+				}
+				fetches++
+				return strings.NewReader("image of " + key), nil
+			}, nil, nil, nil)
+			imagesFolder := t.TempDir()
+
+			// Every segment creates its own command
+			for i := 0; i < 2; i++ {
+				cmd, err := createWatermarkCmd(context.Background(), s3Client, imagesFolder, tt.GivenParams)
+				if tt.ExpectError {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Contains(t, strings.Join(cmd.Args, " "), tt.ExpectInArgs)
+			}
+			require.Equal(t, tt.ExpectFetches, fetches)
+
+			if tt.ExpectFetches > 0 {
+				images, err := os.ReadDir(imagesFolder)
+				require.NoError(t, err)
+				require.Len(t, images, 1)
+			}
+		})
+	}
+}